@@ -0,0 +1,188 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNack is the reason of a delivery negatively acknowledged without error
+var ErrNack = errors.New("nack")
+
+// ErrAckTimeout is the reason of a delivery not acknowledged in time
+var ErrAckTimeout = errors.New("ack timeout")
+
+// DefaultAckTimeout is used by RequireAck if AckConfig.Timeout is zero
+const DefaultAckTimeout = 30 * time.Second
+
+// AckConfig configures deliveries that must be acknowledged, see RequireAck
+type AckConfig struct {
+	// Timeout after the delivery start to receive Ack or Nack,
+	// DefaultAckTimeout if zero
+	Timeout time.Duration
+	// MaxRedeliveries limits deliveries after Nack or timeout.
+	// Zero sends a failed delivery to DeadLetter immediately.
+	MaxRedeliveries int
+	// RetryDelay is a pause before every redelivery
+	RetryDelay time.Duration
+	// DeadLetter is the topic receiving *DeadLetter when redeliveries
+	// are exhausted. If nil the event is dropped and counted in HealthInfo.Dropped.
+	DeadLetter *Topic
+}
+
+// DeadLetter is the payload published to AckConfig.DeadLetter topic
+type DeadLetter struct {
+	Topic    *Topic // Topic of the original event
+	Payload  any    // Payload of the original event
+	SubID    SubID  // Subscription that failed to process the event
+	Attempts int    // Number of deliveries made
+	Err      error  // Reason of the last failure
+}
+
+// AckHandle acknowledges a delivery of a subscription with RequireAck.
+// The first Ack or Nack settles the delivery, later calls are ignored.
+// The handle may be passed to other goroutines, e.g. to acknowledge
+// after asynchronous processing.
+type AckHandle struct {
+	h       *Hub
+	ctx     context.Context
+	s       *sub
+	e       *event
+	attempt int
+	settled atomic.Bool
+	timer   atomic.Pointer[Timer]
+}
+
+// AckFrom returns the acknowledgement handle of the running delivery.
+// Returns nil outside of handlers of subscriptions with RequireAck.
+func AckFrom(ctx context.Context) *AckHandle {
+	a, _ := ctx.Value(ctxKeyAck).(*AckHandle)
+	return a
+}
+
+// Ack acknowledges the running delivery, see AckHandle.Ack.
+// Returns false outside of handlers of subscriptions with RequireAck.
+func Ack(ctx context.Context) bool {
+	return AckFrom(ctx).Ack()
+}
+
+// Nack negatively acknowledges the running delivery, see AckHandle.Nack.
+// Returns false outside of handlers of subscriptions with RequireAck.
+func Nack(ctx context.Context, err error) bool {
+	return AckFrom(ctx).Nack(err)
+}
+
+// Attempt returns the number of the delivery starting from 1
+func (a *AckHandle) Attempt() int {
+	if a == nil {
+		return 0
+	}
+	return a.attempt
+}
+
+// Ack marks the event processed. Returns false if the delivery
+// is already settled. a may be nil.
+func (a *AckHandle) Ack() bool {
+	return a.settle(nil)
+}
+
+// Nack marks the event failed, it is redelivered or sent to the dead letter
+// topic. nil err is replaced with ErrNack. Returns false if the delivery is
+// already settled. a may be nil.
+func (a *AckHandle) Nack(err error) bool {
+	if err == nil {
+		err = ErrNack
+	}
+	return a.settle(err)
+}
+
+// settle completes the delivery, nil err acknowledges it
+func (a *AckHandle) settle(err error) bool {
+	if a == nil || !a.settled.CompareAndSwap(false, true) {
+		return false
+	}
+	if t := a.timer.Load(); t != nil {
+		(*t).Stop()
+	}
+	if err != nil {
+		a.h.retry(a, err)
+	}
+	return true
+}
+
+// newAck creates a handle for attempt of the delivery and starts its timeout
+func (h *Hub) newAck(ctx context.Context, s *sub, e *event, attempt int) *AckHandle {
+	a := &AckHandle{
+		h:       h,
+		ctx:     ctx,
+		s:       s,
+		e:       e,
+		attempt: attempt,
+	}
+	timeout := s.ack.Timeout
+	if timeout <= 0 {
+		timeout = DefaultAckTimeout
+	}
+	t := h.clock.AfterFunc(timeout, func() {
+		a.settle(ErrAckTimeout)
+	})
+	a.timer.Store(&t)
+	return a
+}
+
+// retry redelivers the event after a failed attempt or sends it
+// to the dead letter topic when redeliveries are exhausted
+func (h *Hub) retry(a *AckHandle, reason error) {
+	s, cfg := a.s, a.s.ack
+	if s.removed.Load() {
+		return
+	}
+	if a.attempt > cfg.MaxRedeliveries {
+		if cfg.DeadLetter == nil {
+			h.health.dropped.Add(1)
+			return
+		}
+		// dead letter subscribers start their own deliveries and attempts
+		_ = h.Publish(detachedContext(a.ctx), cfg.DeadLetter, &DeadLetter{
+			Topic:    a.e.topic,
+			Payload:  a.e.payload,
+			SubID:    s.id,
+			Attempts: a.attempt,
+			Err:      reason,
+		})
+		return
+	}
+
+	ctx := context.WithValue(a.ctx, ctxKeyAttempt, a.attempt+1)
+	run := func() {
+		defer h.active.end()
+		defer h.deliveries.end()
+		h.call(ctx, s, a.e)
+		if s.shouldRemove() {
+			h.Unsubscribe(ctx, s.id)
+		}
+	}
+	schedule := func() {
+		// counted by Drain and InFlight from the moment it is scheduled
+		h.active.begin()
+		h.deliveries.begin()
+		if s.serial != nil {
+			s.serial.push(a.e.priority, run, h.spawn)
+			return
+		}
+		h.spawn(a.e.priority, run)
+	}
+	if cfg.RetryDelay > 0 {
+		h.clock.AfterFunc(cfg.RetryDelay, schedule)
+		return
+	}
+	schedule()
+}
+
+// attemptFromContext returns the number of redelivery attempt, 0 for
+// the first delivery
+func attemptFromContext(ctx context.Context) int {
+	n, _ := ctx.Value(ctxKeyAttempt).(int)
+	return n
+}
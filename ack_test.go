@@ -0,0 +1,168 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// deadLetters subscribes to the dead letter topic of tests
+func deadLetters(t *testing.T, h *Hub) <-chan *DeadLetter {
+	ch := make(chan *DeadLetter, 1)
+	_, err := h.Subscribe(context.Background(), T("type=dead"), func(ctx context.Context, dl *DeadLetter) {
+		ch <- dl
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+func TestRequireAck(t *testing.T) {
+	t.Parallel()
+
+	if Ack(context.Background()) || Nack(context.Background(), nil) {
+		t.Error("Ack() succeeded outside of handler")
+	}
+
+	t.Run("redelivery", func(t *testing.T) {
+		ctx := context.Background()
+		h := New()
+		dead := deadLetters(t, h)
+
+		acked := make(chan int, 1)
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) error {
+			a := AckFrom(ctx)
+			switch a.Attempt() {
+			case 1:
+				return errors.New("failed")
+			case 2:
+				Nack(ctx, nil)
+				return nil
+			}
+			Ack(ctx)
+			acked <- a.Attempt()
+			return nil
+		}, RequireAck(AckConfig{MaxRedeliveries: 2, DeadLetter: T("type=dead")}))
+
+		_ = h.Publish(ctx, T("type=job"), nil)
+		select {
+		case n := <-acked:
+			if n != 3 {
+				t.Errorf("acked on attempt %d, want 3", n)
+			}
+		case dl := <-dead:
+			t.Fatalf("dead letter: %+v", dl)
+		case <-time.After(time.Second):
+			t.Fatal("not acked")
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		ctx := context.Background()
+		h := New()
+		dead := deadLetters(t, h)
+
+		errFailed := errors.New("failed")
+		var calls atomic.Int32
+		id, _ := h.Subscribe(ctx, T("type=job"), func(ctx context.Context) error {
+			calls.Add(1)
+			return errFailed
+		}, RequireAck(AckConfig{MaxRedeliveries: 2, RetryDelay: time.Millisecond, DeadLetter: T("type=dead")}))
+
+		_ = h.Publish(ctx, T("type=job", "id=1"), "payload")
+		select {
+		case dl := <-dead:
+			if dl.Attempts != 3 || dl.SubID != id || !errors.Is(dl.Err, errFailed) ||
+				dl.Payload != "payload" || dl.Topic.Get("id") != "1" {
+				t.Errorf("dead letter = %+v", dl)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no dead letter")
+		}
+		if n := calls.Load(); n != 3 {
+			t.Errorf("calls = %d, want 3", n)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx := context.Background()
+		h := New()
+		dead := deadLetters(t, h)
+
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {},
+			RequireAck(AckConfig{Timeout: 10 * time.Millisecond, MaxRedeliveries: 1, DeadLetter: T("type=dead")}))
+
+		_ = h.Publish(ctx, T("type=job"), nil)
+		select {
+		case dl := <-dead:
+			if dl.Attempts != 2 || !errors.Is(dl.Err, ErrAckTimeout) {
+				t.Errorf("dead letter = %+v", dl)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no dead letter")
+		}
+	})
+
+	t.Run("acked dead letters", func(t *testing.T) {
+		ctx := context.Background()
+		h := New()
+
+		attempts := make(chan int, 4)
+		_, _ = h.Subscribe(ctx, T("type=dead"), func(ctx context.Context, dl *DeadLetter) error {
+			attempts <- AckFrom(ctx).Attempt()
+			if AckFrom(ctx).Attempt() == 1 {
+				return errors.New("dead letter store is down")
+			}
+			return nil
+		}, RequireAck(AckConfig{MaxRedeliveries: 1, DeadLetter: T("type=dead2")}))
+		dead := make(chan *DeadLetter, 1)
+		_, _ = h.Subscribe(ctx, T("type=dead2"), func(ctx context.Context, dl *DeadLetter) {
+			dead <- dl
+		})
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) error {
+			return errors.New("failed")
+		}, RequireAck(AckConfig{MaxRedeliveries: 2, DeadLetter: T("type=dead")}))
+
+		_ = h.Publish(ctx, T("type=job"), nil)
+		for _, want := range []int{1, 2} {
+			select {
+			case n := <-attempts:
+				if n != want {
+					t.Fatalf("dead letter delivered on attempt %d, want %d", n, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("attempt %d of dead letter not delivered", want)
+			}
+		}
+		if err := h.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case dl := <-dead:
+			t.Errorf("dead letter of dead letter: %+v", dl)
+		default:
+		}
+	})
+
+	t.Run("ack after return", func(t *testing.T) {
+		ctx := context.Background()
+		h := New()
+
+		handles := make(chan *AckHandle, 1)
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {
+			handles <- AckFrom(ctx)
+		}, RequireAck(AckConfig{Timeout: time.Second}))
+
+		_ = h.Publish(ctx, T("type=job"), nil, Wait(true))
+		a := <-handles
+		if !a.Ack() {
+			t.Error("Ack() failed")
+		}
+		if a.Ack() || a.Nack(nil) {
+			t.Error("settled delivery acknowledged again")
+		}
+	})
+}
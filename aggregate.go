@@ -0,0 +1,143 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Window defines tumbling windows of Aggregate. A window closes after
+// Count events or when Duration elapses, whichever comes first.
+// Zero fields disable the corresponding limit, at least one must be set.
+type Window struct {
+	// Duration of consecutive time windows starting when Aggregate is
+	// called. A window closed early by Count is followed by a window
+	// lasting until the end of the current interval.
+	Duration time.Duration
+	// Count of events in a window
+	Count int
+}
+
+// TimeWindow returns a Window of duration d
+func TimeWindow(d time.Duration) Window {
+	return Window{Duration: d}
+}
+
+// CountWindow returns a Window of n events
+func CountWindow(n int) Window {
+	return Window{Count: n}
+}
+
+// Aggregate reduces payloads of events matching src in tumbling windows
+// and publishes the result of every non-empty window to dst. reduce is
+// called with the zero A for the first event of a window, payloads of
+// other types than P produce CastError as in SubscribeT. Time windows use
+// the hub Clock. Aggregation stops when ctx is done, the unfinished window
+// is discarded.
+//
+// Example:
+//
+//	// publish number of orders per minute
+//	err := hub.Aggregate(ctx, h, hub.T("type=order"), hub.TimeWindow(time.Minute),
+//	    func(n int, o Order) int { return n + 1 },
+//	    hub.T("type=stats", "metric=orders_per_minute"),
+//	)
+func Aggregate[A, P any](ctx context.Context, h *Hub, src *Topic, window Window, reduce func(acc A, payload P) A, dst *Topic) error {
+	if window.Duration <= 0 && window.Count <= 0 {
+		return errors.New("hub: aggregate window has neither Duration nor Count")
+	}
+	if src.Match(dst) {
+		return fmt.Errorf("hub: aggregate destination %s matches source %s", dst, src)
+	}
+
+	a := &aggregator[A]{h: h, dst: dst}
+	id, err := SubscribeT(ctx, h, src, func(ctx context.Context, p P) error {
+		a.mu.Lock()
+		a.acc = reduce(a.acc, p)
+		a.n++
+		if window.Count <= 0 || a.n < window.Count {
+			a.mu.Unlock()
+			return nil
+		}
+		return a.flush(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	if window.Duration > 0 {
+		a.end = h.clock.Now()
+		a.tick(ctx, window.Duration)
+	}
+	context.AfterFunc(ctx, func() {
+		h.Unsubscribe(context.WithoutCancel(ctx), id)
+		a.mu.Lock()
+		a.stopped = true
+		if a.timer != nil {
+			a.timer.Stop()
+		}
+		a.mu.Unlock()
+	})
+	return nil
+}
+
+// aggregator holds the open window of Aggregate
+type aggregator[A any] struct {
+	h   *Hub
+	dst *Topic
+
+	mu      sync.Mutex
+	acc     A         // Result of reduce for the window
+	n       int       // Number of events in the window
+	timer   Timer     // Closes the time window
+	end     time.Time // End of the time window
+	stopped bool
+}
+
+// tick schedules closing of the next time window of duration d.
+// Windows end at fixed intervals, so delays of timers don't accumulate.
+func (a *aggregator[A]) tick(ctx context.Context, d time.Duration) {
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		return
+	}
+	a.end = a.end.Add(d)
+	end := a.end
+	a.mu.Unlock()
+
+	// the lock isn't held: a Clock may call overdue f in this goroutine
+	t := a.h.clock.AfterFunc(end.Sub(a.h.clock.Now()), func() {
+		a.mu.Lock()
+		_ = a.flush(context.WithoutCancel(ctx))
+		a.tick(ctx, d)
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		t.Stop()
+		return
+	}
+	if a.end.Equal(end) {
+		// not replaced by a window scheduled from f
+		a.timer = t
+	}
+}
+
+// flush publishes the result of a non-empty window and opens a new one.
+// Must be called under a.mu, which is released.
+func (a *aggregator[A]) flush(ctx context.Context) error {
+	acc, n := a.acc, a.n
+	var zero A
+	a.acc, a.n = zero, 0
+	stopped := a.stopped
+	a.mu.Unlock()
+
+	if n == 0 || stopped {
+		return nil
+	}
+	return a.h.Publish(ctx, a.dst, acc)
+}
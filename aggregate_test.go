@@ -0,0 +1,161 @@
+package hub
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAggregateCount(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var sums []int
+	if _, err := h.Subscribe(ctx, T("type=sum"), func(ctx context.Context, n int) {
+		sums = append(sums, n)
+	}, Inline(true)); err != nil {
+		t.Fatal(err)
+	}
+	err := Aggregate(ctx, h, T("type=value"), CountWindow(3), func(acc, v int) int {
+		return acc + v
+	}, T("type=sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for v := 1; v <= 7; v++ {
+		_ = h.Publish(ctx, T("type=value"), v, Sync(true))
+	}
+	if !slices.Equal(sums, []int{6, 15}) {
+		t.Errorf("sums = %v, want [6 15]", sums)
+	}
+
+	// stopped aggregation unsubscribes and discards the open window
+	cancel()
+	for h.Len() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAggregateTime(t *testing.T) {
+	t.Parallel()
+
+	clock := &manualClock{now: time.Unix(0, 0)}
+	h := New(WithClock(clock))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var counts []int
+	if _, err := h.Subscribe(ctx, T("type=rate"), func(ctx context.Context, n int) {
+		counts = append(counts, n)
+	}, Inline(true)); err != nil {
+		t.Fatal(err)
+	}
+	window := Window{Duration: time.Minute, Count: 3}
+	err := Aggregate(ctx, h, T("type=hit"), window, func(n int, _ string) int {
+		return n + 1
+	}, T("type=rate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hit := func(n int) {
+		for range n {
+			_ = h.Publish(ctx, T("type=hit"), "GET /", Sync(true))
+		}
+	}
+	hit(2)
+	clock.advance(time.Minute)
+	clock.advance(time.Minute) // empty window isn't published
+	hit(4)                     // Count closes the window early
+	clock.advance(30 * time.Second)
+	hit(1)
+	clock.advance(30 * time.Second)
+	if !slices.Equal(counts, []int{2, 3, 2}) {
+		t.Errorf("counts = %v, want [2 3 2]", counts)
+	}
+
+	cancel()
+	for clock.pending() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAggregateInvalid(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx := context.Background()
+	sum := func(acc, v int) int { return acc + v }
+	if Aggregate(ctx, h, T("type=value"), Window{}, sum, T("type=sum")) == nil {
+		t.Error("Aggregate() with empty window succeeded")
+	}
+	if Aggregate(ctx, h, T("type=value"), CountWindow(2), sum, T("type=value", "agg=sum")) == nil {
+		t.Error("Aggregate() with destination matching source succeeded")
+	}
+}
+
+// manualClock is a Clock running timers when advanced by the test
+type manualClock struct {
+	realClock
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	c    *manualClock
+	when time.Time
+	f    func()
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	t := &manualTimer{c: c, when: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+	return t
+}
+
+func (t *manualTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	n := len(t.c.timers)
+	t.c.timers = slices.DeleteFunc(t.c.timers, func(o *manualTimer) bool { return o == t })
+	return len(t.c.timers) != n
+}
+
+// advance moves the time and runs due timers
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []*manualTimer
+	c.timers = slices.DeleteFunc(c.timers, func(t *manualTimer) bool {
+		if t.when.After(c.now) {
+			return false
+		}
+		due = append(due, t)
+		return true
+	})
+	c.mu.Unlock()
+	for _, t := range due {
+		t.f()
+	}
+}
+
+func (c *manualClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
@@ -0,0 +1,89 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a published event for the audit log
+type AuditRecord struct {
+	Time    time.Time // Publish time
+	EventID EventID   // Event ID, see EventIDFromContext
+	Topic   *Topic    // Published topic (after stamping)
+	Payload any       // Payload after redaction
+}
+
+// MarshalJSON encodes the record as an object with time, id, topic,
+// payload type and payload. Payloads which can't be encoded as JSON
+// are replaced with their Go syntax representation.
+func (r AuditRecord) MarshalJSON() ([]byte, error) {
+	var payload json.RawMessage
+	if data, err := json.Marshal(r.Payload); err == nil {
+		payload = data
+	} else if payload, err = json.Marshal(fmt.Sprintf("%#v", r.Payload)); err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Time        time.Time       `json:"time"`
+		EventID     EventID         `json:"id"`
+		Topic       string          `json:"topic"`
+		PayloadType string          `json:"payload_type"`
+		Payload     json.RawMessage `json:"payload"`
+	}{
+		Time:        r.Time,
+		EventID:     r.EventID,
+		Topic:       r.Topic.String(),
+		PayloadType: fmt.Sprintf("%T", r.Payload),
+		Payload:     payload,
+	})
+}
+
+// AuditConfig configures the audit log of published events (see WithAudit).
+type AuditConfig struct {
+	// Writer receives records as JSON lines, may be nil
+	Writer io.Writer
+	// Func receives records, may be nil
+	Func func(ctx context.Context, r AuditRecord)
+	// Sample selects events to record, all events are recorded if nil
+	Sample func(t *Topic, payload any) bool
+	// Redact replaces payload before recording (e.g. masks secrets), may be nil
+	Redact func(t *Topic, payload any) any
+}
+
+// auditLog writes audit records according to config
+type auditLog struct {
+	cfg AuditConfig
+	mu  sync.Mutex // Serializes writes to cfg.Writer
+}
+
+// record writes the published event to the audit sinks
+func (a *auditLog) record(ctx context.Context, e *event, now time.Time) {
+	if a.cfg.Sample != nil && !a.cfg.Sample(e.topic, e.payload) {
+		return
+	}
+	r := AuditRecord{
+		Time:    now,
+		EventID: e.id,
+		Topic:   e.topic,
+		Payload: e.payload,
+	}
+	if a.cfg.Redact != nil {
+		r.Payload = a.cfg.Redact(e.topic, e.payload)
+	}
+
+	if a.cfg.Writer != nil {
+		if data, err := json.Marshal(r); err == nil {
+			data = append(data, '\n')
+			a.mu.Lock()
+			_, _ = a.cfg.Writer.Write(data)
+			a.mu.Unlock()
+		}
+	}
+	if a.cfg.Func != nil {
+		a.cfg.Func(ctx, r)
+	}
+}
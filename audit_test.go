@@ -0,0 +1,79 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWithAudit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	var records []AuditRecord
+	h := New(WithAudit(AuditConfig{
+		Writer: &buf,
+		Func: func(ctx context.Context, r AuditRecord) {
+			records = append(records, r)
+		},
+		Sample: func(t *Topic, p any) bool {
+			return t.Get("type") != "debug"
+		},
+		Redact: func(t *Topic, p any) any {
+			if t.Get("type") == "login" {
+				return "<redacted>"
+			}
+			return p
+		},
+	}))
+
+	var got []any
+	_, _ = h.Subscribe(ctx, T("type=*"), func(ctx context.Context, p any) {
+		got = append(got, p)
+	})
+
+	_ = h.Publish(ctx, T("type=order"), map[string]any{"id": 1}, Sync(true))
+	_ = h.Publish(ctx, T("type=debug"), "noise", Sync(true))
+	_ = h.Publish(ctx, T("type=login"), "secret", Sync(true))
+	_ = h.Publish(ctx, T("type=func"), func() {}, Sync(true))
+
+	if len(got) != 4 {
+		t.Errorf("handler calls = %d, want 4", len(got))
+	}
+	if got[2] != "secret" {
+		t.Errorf("redaction must not affect handlers, got %v", got[2])
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want 3", len(records))
+	}
+	if records[1].Payload != "<redacted>" || records[1].EventID != 3 {
+		t.Errorf("unexpected record: %+v", records[1])
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %d, want 3:\n%s", len(lines), buf.String())
+	}
+	var rec struct {
+		ID          uint64          `json:"id"`
+		Topic       string          `json:"topic"`
+		PayloadType string          `json:"payload_type"`
+		Payload     json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID != 1 || rec.Topic != "type=order" || rec.PayloadType != "map[string]interface {}" || string(rec.Payload) != `{"id":1}` {
+		t.Errorf("unexpected record: %s", lines[0])
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.PayloadType != "func()" || !strings.HasPrefix(string(rec.Payload), `"(func())`) {
+		t.Errorf("unexpected record for non-JSON payload: %s", lines[2])
+	}
+}
@@ -0,0 +1,47 @@
+package hub
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrForbidden may be returned by authorizers to deny an operation
+var ErrForbidden = errors.New("hub: operation is not allowed")
+
+// Op is an operation checked by authorizers, see WithAuthorizer
+type Op int
+
+const (
+	// OpSubscribe is a Subscribe call, the topic is the subscription pattern
+	OpSubscribe Op = iota + 1
+	// OpPublish is a Publish call (including PublishAfter, Request and
+	// other publishing methods), the topic is the published one
+	OpPublish
+)
+
+// String returns the operation name
+func (o Op) String() string {
+	switch o {
+	case OpSubscribe:
+		return "subscribe"
+	case OpPublish:
+		return "publish"
+	default:
+		return "unknown"
+	}
+}
+
+// Authorizer decides whether the caller identified by ctx may perform op
+// on topic t. A non-nil error denies the operation and is returned to the
+// caller as is.
+type Authorizer func(ctx context.Context, op Op, t *Topic) error
+
+// authorize consults all authorizers, the first error denies the operation
+func (h *Hub) authorize(ctx context.Context, op Op, t *Topic) error {
+	for _, a := range h.authorizers {
+		if err := a(ctx, op, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type pluginKey struct{}
+
+func TestWithAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	var ops []string
+	h := New(
+		WithAuthorizer(func(ctx context.Context, op Op, t *Topic) error {
+			ops = append(ops, op.String())
+			return nil
+		}),
+		WithAuthorizer(func(ctx context.Context, op Op, t *Topic) error {
+			plugin, _ := ctx.Value(pluginKey{}).(string)
+			if plugin == "" || t.Get("plugin") == plugin {
+				return nil
+			}
+			if op == OpSubscribe && t.Get("plugin") == Any {
+				return errors.New("plugins can't subscribe to all plugins")
+			}
+			return ErrForbidden
+		}),
+	)
+	ctx := context.Background()
+	pluginX := context.WithValue(ctx, pluginKey{}, "x")
+
+	var got []string
+	if _, err := h.Subscribe(ctx, T("plugin=*"), func(ctx context.Context, s string) {
+		got = append(got, s)
+	}); err != nil {
+		t.Fatalf("Subscribe() of host = %v", err)
+	}
+	if _, err := h.Subscribe(pluginX, T("plugin=*"), func(ctx context.Context) {}); err == nil || errors.Is(err, ErrForbidden) {
+		t.Errorf("Subscribe() of plugin = %v, want authorizer error", err)
+	}
+
+	if err := h.Publish(pluginX, T("plugin=x"), "own", Sync(true)); err != nil {
+		t.Errorf("Publish() own topic = %v", err)
+	}
+	if err := h.Publish(pluginX, T("plugin=y"), "foreign", Sync(true)); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Publish() foreign topic = %v, want ErrForbidden", err)
+	}
+	if _, err := h.PublishAfter(pluginX, time.Millisecond, T("plugin=y"), "delayed"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("PublishAfter() foreign topic = %v, want ErrForbidden", err)
+	}
+	if len(got) != 1 || got[0] != "own" {
+		t.Errorf("delivered %v, want [own]", got)
+	}
+	want := []string{"subscribe", "subscribe", "publish", "publish", "publish"}
+	if len(ops) != len(want) {
+		t.Errorf("authorized ops %v, want %v", ops, want)
+	}
+}
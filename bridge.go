@@ -0,0 +1,97 @@
+package hub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+)
+
+// Reserved attributes stamped by bridges to prevent forwarding loops,
+// see BridgeLoop
+const (
+	AttrOrigin = "_origin" // Bridge which first forwarded the event out of its hub
+	AttrHops   = "_hops"   // Number of times the event was forwarded by bridges
+)
+
+// DefaultMaxHops limits forwarding of BridgeLoop with zero MaxHops
+const DefaultMaxHops = 8
+
+// BridgeLoop prevents infinite forwarding between bridges connecting hubs
+// to each other or to external brokers (NATS, Kafka, ...) in both
+// directions. Outgoing events are stamped with AttrOrigin and AttrHops,
+// events returning to the bridge they originate from are dropped, and
+// forwarding stops after MaxHops. Bridges call Outgoing before sending
+// an event and Incoming before publishing a received one into the hub.
+//
+// Example:
+//
+//	loop := hub.BridgeLoop{Origin: hub.NewOrigin()}
+//	// sending
+//	t, ok := loop.Outgoing(t)
+//	if !ok {
+//	    return nil
+//	}
+//	// receiving
+//	if loop.Incoming(t) {
+//	    h.Publish(ctx, t, payload)
+//	}
+type BridgeLoop struct {
+	// Origin identifies the bridge, must be unique among connected bridges.
+	// If empty, only hops are limited.
+	Origin string
+	// MaxHops limits the number of bridges an event passes,
+	// DefaultMaxHops if zero
+	MaxHops int
+}
+
+// NewOrigin returns a random bridge origin
+func NewOrigin() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// maxHops returns the effective hops limit
+func (l BridgeLoop) maxHops() int {
+	if l.MaxHops <= 0 {
+		return DefaultMaxHops
+	}
+	return l.MaxHops
+}
+
+// hops returns AttrHops of the topic, 0 if missing or malformed
+func hops(t *Topic) int {
+	n, err := strconv.Atoi(t.Get(AttrHops))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Outgoing returns the topic to send with AttrOrigin set if missing and
+// AttrHops incremented. Returns false if the event must not be sent:
+// it came from this bridge around a loop or passed MaxHops bridges.
+func (l BridgeLoop) Outgoing(t *Topic) (*Topic, bool) {
+	origin := t.Get(AttrOrigin)
+	if l.Origin != "" && origin == l.Origin {
+		return t, false
+	}
+	n := hops(t)
+	if n >= l.maxHops() {
+		return t, false
+	}
+	mp := t.mp.Set(AttrHops, strconv.Itoa(n+1))
+	if origin == "" && l.Origin != "" {
+		mp = mp.Set(AttrOrigin, l.Origin)
+	}
+	return &Topic{mp: mp}, true
+}
+
+// Incoming reports whether the received event must be published into the
+// hub. Events sent by this bridge and events exceeding MaxHops are not.
+func (l BridgeLoop) Incoming(t *Topic) bool {
+	if l.Origin != "" && t.Get(AttrOrigin) == l.Origin {
+		return false
+	}
+	return hops(t) <= l.maxHops()
+}
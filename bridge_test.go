@@ -0,0 +1,71 @@
+package hub
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBridgeLoop(t *testing.T) {
+	t.Parallel()
+
+	l := BridgeLoop{Origin: "a", MaxHops: 2}
+	out, ok := l.Outgoing(T("type=x"))
+	if !ok || !out.Equal(T("type=x", "_origin=a", "_hops=1")) {
+		t.Errorf("Outgoing() = %v, %v", out, ok)
+	}
+	if !l.Incoming(T("type=x", "_origin=b", "_hops=1")) {
+		t.Error("Incoming() rejected foreign event")
+	}
+	if l.Incoming(out) {
+		t.Error("Incoming() accepted own event")
+	}
+	if l.Incoming(T("type=x", "_origin=b", "_hops=3")) {
+		t.Error("Incoming() accepted event over MaxHops")
+	}
+
+	// origin of the first bridge is kept
+	out, ok = l.Outgoing(T("type=x", "_origin=b", "_hops=1"))
+	if !ok || !out.Equal(T("type=x", "_origin=b", "_hops=2")) {
+		t.Errorf("Outgoing() = %v, %v", out, ok)
+	}
+	if _, ok := l.Outgoing(out); ok {
+		t.Error("Outgoing() forwarded event over MaxHops")
+	}
+	if _, ok := l.Outgoing(T("type=x", "_origin=a", "_hops=1")); ok {
+		t.Error("Outgoing() forwarded own event")
+	}
+	if _, ok := (BridgeLoop{}).Outgoing(T("type=x", "_hops=8")); ok {
+		t.Error("Outgoing() ignored DefaultMaxHops")
+	}
+	if NewOrigin() == NewOrigin() {
+		t.Error("NewOrigin() is not random")
+	}
+}
+
+func TestBridgeRing(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// hubs connected in a ring by one-way bridges
+	hubs := []*Hub{New(), New(), New()}
+	received := make([]int, len(hubs))
+	for i, h := range hubs {
+		next := hubs[(i+1)%len(hubs)]
+		loop := BridgeLoop{Origin: NewOrigin()}
+		_, _ = h.Subscribe(ctx, T("type=*"), func(ctx context.Context, t *Topic, p any) {
+			received[i]++
+			if out, ok := loop.Outgoing(t); ok {
+				_ = next.Publish(ctx, out, p, Sync(true))
+			}
+		})
+	}
+
+	_ = hubs[0].Publish(ctx, T("type=x"), nil, Sync(true))
+	want := []int{2, 1, 1}
+	for i := range want {
+		if received[i] != want[i] {
+			t.Errorf("received = %v, want %v", received, want)
+			break
+		}
+	}
+}
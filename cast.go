@@ -0,0 +1,70 @@
+package hub
+
+import (
+	"reflect"
+	"sync"
+)
+
+// castRegistry stores custom conversion functions by target type
+var castRegistry struct {
+	sync.RWMutex
+	m map[reflect.Type]func(any) (any, error)
+}
+
+// RegisterCast registers a conversion function used by typed callbacks
+// with parameter of type T when the payload is not T already.
+// It takes precedence over spf13/cast for built-in types and enables
+// conversion for domain types (UUID, decimals, custom enums).
+// Registering nil removes the conversion. Safe for concurrent use,
+// but is usually called from init().
+//
+// Example:
+//
+//	hub.RegisterCast(func(p any) (Level, error) {
+//	    s, ok := p.(string)
+//	    if !ok {
+//	        return 0, fmt.Errorf("unexpected %T", p)
+//	    }
+//	    return ParseLevel(s)
+//	})
+//
+//	h.Subscribe(ctx, topic, func(ctx context.Context, l Level) error { ... })
+func RegisterCast[T any](fn func(any) (T, error)) {
+	castRegistry.Lock()
+	defer castRegistry.Unlock()
+
+	t := reflect.TypeFor[T]()
+	if fn == nil {
+		delete(castRegistry.m, t)
+		return
+	}
+	if castRegistry.m == nil {
+		castRegistry.m = make(map[reflect.Type]func(any) (any, error))
+	}
+	castRegistry.m[t] = func(p any) (any, error) {
+		return fn(p)
+	}
+}
+
+// lookupCast returns registered conversion function for type t
+func lookupCast(t reflect.Type) (func(any) (any, error), bool) {
+	castRegistry.RLock()
+	defer castRegistry.RUnlock()
+	fn, ok := castRegistry.m[t]
+	return fn, ok
+}
+
+// castTo converts payload to T with registered conversion if any,
+// otherwise with fallback function
+func castTo[T any](p any, fallback func(any) (T, error)) (T, error) {
+	fn, ok := lookupCast(reflect.TypeFor[T]())
+	if !ok {
+		return fallback(p)
+	}
+	v, err := fn(p)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
@@ -0,0 +1,79 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testLevel int
+
+func TestRegisterCast(t *testing.T) {
+	ctx := context.Background()
+
+	RegisterCast(func(p any) (testLevel, error) {
+		switch p {
+		case "debug":
+			return 1, nil
+		case "error":
+			return 2, nil
+		}
+		return 0, fmt.Errorf("unknown level %v", p)
+	})
+	defer RegisterCast[testLevel](nil)
+
+	t.Run("custom type", func(t *testing.T) {
+		h := New()
+		handler, err := h.ToHandler(ctx, func(ctx context.Context, l testLevel) error {
+			if l != 2 {
+				return errors.New("unexpected value")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ToHandler() error = %v", err)
+		}
+		if err := handler(ctx, T(), "error"); err != nil {
+			t.Errorf("handler() error = %v", err)
+		}
+		if err := handler(ctx, T(), testLevel(2)); err != nil {
+			t.Errorf("handler() error = %v", err)
+		}
+		var ce *CastError
+		if err := handler(ctx, T(), "fatal"); !errors.As(err, &ce) {
+			t.Errorf("Expected CastError, got %v", err)
+		}
+	})
+
+	t.Run("overrides built-in type", func(t *testing.T) {
+		RegisterCast(func(p any) (int, error) {
+			return 100, nil
+		})
+		defer RegisterCast[int](nil)
+
+		h := New()
+		var got int
+		handler, _ := h.ToHandler(ctx, func(ctx context.Context, v int) {
+			got = v
+		})
+		handler(ctx, T(), "5")
+		if got != 100 {
+			t.Errorf("Got %d, want 100 from registered cast", got)
+		}
+		handler(ctx, T(), 7)
+		if got != 7 {
+			t.Errorf("Got %d, want 7 without conversion", got)
+		}
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		RegisterCast[testLevel](nil)
+		defer RegisterCast(func(p any) (testLevel, error) { return 0, nil })
+
+		if _, ok := lookupCast(reflect.TypeFor[testLevel]()); ok {
+			t.Error("Expected conversion to be removed")
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package hub
+
+import "time"
+
+// Clock is the source of time for time-dependent features: EventTTL,
+// PublishAfter, RequireAck timeouts and redeliveries, Aggregate windows,
+// system timestamps, audit records and health. Set it with WithClock to
+// control time in tests, see hubtest.Clock. Handler durations reported to
+// Metrics are always measured with the real clock.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel
+	After(d time.Duration) <-chan time.Time
+	// AfterFunc waits for the duration to elapse and then calls f
+	// in its own goroutine
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call created by Clock.AfterFunc
+type Timer interface {
+	// Stop prevents the call, returns false if it already happened
+	// or the timer was stopped
+	Stop() bool
+}
+
+// realClock implements Clock with the time package
+type realClock struct{}
+
+// Now implements Clock
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements Clock
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// AfterFunc implements Clock
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,57 @@
+package hub
+
+import "context"
+
+// EventID identifies a published event. IDs are assigned per hub
+// in publish order starting from 1 and match AttrSequence when
+// the sequence is stamped.
+type EventID uint64
+
+// LastEventID returns ID of the last published event, 0 if none
+func (h *Hub) LastEventID() EventID {
+	return EventID(h.pubSeq.Load())
+}
+
+// eventFromContext returns event being dispatched to the handler
+func eventFromContext(ctx context.Context) *event {
+	e, _ := ctx.Value(ctxKeyEvent).(*event)
+	return e
+}
+
+// detachedContext returns ctx without cancellation and without values
+// of the running delivery (event, subscription, ack, attempt and reply
+// collector), for events published on behalf of the hub itself.
+func detachedContext(ctx context.Context) context.Context {
+	ctx = context.WithoutCancel(ctx)
+	for _, k := range []ctxKey{ctxKeyReply, ctxKeyEvent, ctxKeySub, ctxKeyAck, ctxKeyAttempt} {
+		if ctx.Value(k) != nil {
+			ctx = context.WithValue(ctx, k, nil)
+		}
+	}
+	return ctx
+}
+
+// TopicFromContext returns topic of the event being handled.
+// Useful for minimal handlers without topic in their signature.
+// Returns nil outside of handler calls.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=alert"), func(ctx context.Context) {
+//	    log.Printf("alert: %s", hub.TopicFromContext(ctx))
+//	})
+func TopicFromContext(ctx context.Context) *Topic {
+	if e := eventFromContext(ctx); e != nil {
+		return e.topic
+	}
+	return nil
+}
+
+// EventIDFromContext returns ID of the event being handled.
+// Returns false outside of handler calls.
+func EventIDFromContext(ctx context.Context) (EventID, bool) {
+	if e := eventFromContext(ctx); e != nil {
+		return e.id, true
+	}
+	return 0, false
+}
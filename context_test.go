@@ -0,0 +1,54 @@
+package hub
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextAccessors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	if TopicFromContext(ctx) != nil {
+		t.Error("TopicFromContext() should be nil outside of handler")
+	}
+	if _, ok := EventIDFromContext(ctx); ok {
+		t.Error("EventIDFromContext() should fail outside of handler")
+	}
+
+	h := New(WithSystemAttributes(SystemAttributes{Sequence: true}))
+
+	var topics []*Topic
+	var ids []EventID
+	_, err := h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {
+		topics = append(topics, TopicFromContext(ctx))
+		id, ok := EventIDFromContext(ctx)
+		if !ok {
+			t.Error("EventIDFromContext() failed inside handler")
+		}
+		ids = append(ids, id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = h.Publish(ctx, T("type=a", "n", "1"), nil, Sync(true))
+	_ = h.Publish(ctx, T("type=b"), nil, Sync(true))
+	_ = h.Publish(ctx, T("type=a", "n", "2"), nil, Sync(true))
+
+	if len(topics) != 2 {
+		t.Fatalf("handler calls = %d, want 2", len(topics))
+	}
+	if v := topics[1].Get("n"); v != "2" {
+		t.Errorf("topic n = %q, want 2", v)
+	}
+	if id := h.LastEventID(); id != 3 {
+		t.Errorf("LastEventID() = %d, want 3", id)
+	}
+	if ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("ids = %v, want [1 3]", ids)
+	}
+	if v := topics[1].Get(AttrSequence); v != "3" {
+		t.Errorf("%s = %q, want 3", AttrSequence, v)
+	}
+}
@@ -0,0 +1,74 @@
+package hub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// DumpIndexes writes human readable description of internal indexes to w:
+// keys with value cardinalities and sizes of subscription lists.
+// Helps diagnose hot keys and index bloat in long-running services.
+//
+// Output example:
+//
+//	subscriptions: 4
+//	empty: 1
+//	key "type": subscriptions=3 values=3 operators=0
+//	  "*": 1
+//	  "alert": 1
+//	  "info": 1
+//
+// For every key the number of subscriptions with it, the number of distinct
+// values and the number of subscriptions using operators are reported,
+// followed by the number of subscriptions per value.
+func (h *Hub) DumpIndexes(w io.Writer) error {
+	// consistent snapshot: indexes are replaced under the Hub's lock
+	h.RLock()
+	defer h.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "subscriptions: %d\n", len(h.subs))
+	fmt.Fprintf(bw, "empty: %d\n", h.indexEmpty.Load().len())
+
+	// every key is stored in its own shard only
+	keys := make(map[string]struct{})
+	for i := range h.shards {
+		sh := h.shards[i].load()
+		for k := range sh.indexKeyValue {
+			keys[k] = struct{}{}
+		}
+		for k := range sh.indexKey {
+			keys[k] = struct{}{}
+		}
+		for k := range sh.indexKeyOp {
+			keys[k] = struct{}{}
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(keys)) {
+		sh := h.shards[shardIndex(k)].load()
+		vals := sh.indexKeyValue[k]
+		fmt.Fprintf(bw, "key %q: subscriptions=%d values=%d operators=%d\n",
+			k, sublistLen(sh.indexKey[k]), vals.len(), sublistLen(sh.indexKeyOp[k]))
+		values := make([]string, 0, vals.len())
+		vals.each(func(v string, _ *sublist) {
+			values = append(values, v)
+		})
+		slices.Sort(values)
+		for _, v := range values {
+			fmt.Fprintf(bw, "  %q: %d\n", v, vals.get(v).len())
+		}
+	}
+	return bw.Flush()
+}
+
+// sublistLen returns length of possibly nil sublist
+func sublistLen(sl *sublist) int {
+	if sl == nil {
+		return 0
+	}
+	return sl.len()
+}
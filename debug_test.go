@@ -0,0 +1,43 @@
+package hub
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDumpIndexes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	for _, topic := range []*Topic{
+		T("type=alert"),
+		T("type=info", "level", "1"),
+		T("type=*"),
+		T("level>=2"),
+		T(),
+	} {
+		if _, err := h.Subscribe(ctx, topic, func(ctx context.Context) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b strings.Builder
+	if err := h.DumpIndexes(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `subscriptions: 5
+empty: 1
+key "level": subscriptions=2 values=1 operators=1
+  "1": 1
+key "type": subscriptions=3 values=3 operators=0
+  "*": 1
+  "alert": 1
+  "info": 1
+`
+	if b.String() != want {
+		t.Errorf("DumpIndexes() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
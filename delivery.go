@@ -0,0 +1,78 @@
+package hub
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Delivery is a handle of a published event returned by PublishCancelable
+// and PublishAfter. Cancel prevents handler invocations that haven't
+// started yet, e.g. queued async deliveries or a delayed publish.
+type Delivery struct {
+	canceled atomic.Bool
+	timer    atomic.Pointer[Timer]
+}
+
+// Cancel prevents not-yet-started handler invocations of the event.
+// Handlers already running are not interrupted. OnFinish callbacks are
+// still called when the remaining deliveries are skipped, except for
+// PublishAfter canceled before the event was published.
+// Safe to call several times and concurrently.
+func (d *Delivery) Cancel() {
+	d.canceled.Store(true)
+	if t := d.timer.Load(); t != nil {
+		(*t).Stop()
+	}
+}
+
+// Canceled reports whether Cancel was called
+func (d *Delivery) Canceled() bool {
+	return d.canceled.Load()
+}
+
+// PublishCancelable publishes event like Publish and returns a handle
+// to cancel deliveries that haven't started yet.
+//
+// Example:
+//
+//	d, _ := h.PublishCancelable(ctx, hub.T("type=preview"), doc)
+//	// document changed again, the preview is stale
+//	d.Cancel()
+func (h *Hub) PublishCancelable(ctx context.Context, topic *Topic, payload any, opts ...PublishOption) (*Delivery, error) {
+	d := &Delivery{}
+	if err := h.publish(ctx, topic, payload, d, opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// PublishAfter publishes event after the delay and returns a handle
+// to cancel it. The topic is validated and authorized immediately. The event is not
+// published if ctx is done by that time.
+//
+// Example:
+//
+//	d, _ := h.PublishAfter(ctx, 5*time.Second, hub.T("type=reminder"), msg)
+//	if answered {
+//	    d.Cancel()
+//	}
+func (h *Hub) PublishAfter(ctx context.Context, delay time.Duration, topic *Topic, payload any, opts ...PublishOption) (*Delivery, error) {
+	if h.policy != nil {
+		if err := h.policy.check(topic, true); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.authorize(ctx, OpPublish, topic); err != nil {
+		return nil, err
+	}
+	d := &Delivery{}
+	t := h.clock.AfterFunc(delay, func() {
+		if d.Canceled() || ctx.Err() != nil {
+			return
+		}
+		_ = h.publish(ctx, topic, payload, d, opts)
+	})
+	d.timer.Store(&t)
+	return d, nil
+}
@@ -0,0 +1,83 @@
+package hub
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeliveryCancel(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var second atomic.Int32
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {
+		second.Add(1)
+	})
+
+	d := &Delivery{}
+	finished := make(chan struct{})
+	go func() {
+		_ = h.publish(ctx, T("type=job"), nil, d, []PublishOption{Sync(true), OnFinish(func(ctx context.Context) {
+			close(finished)
+		})})
+	}()
+
+	<-started
+	d.Cancel()
+	d.Cancel()
+	close(release)
+	<-finished
+
+	if second.Load() != 0 {
+		t.Error("canceled delivery called the second handler")
+	}
+	if !d.Canceled() {
+		t.Error("Canceled() = false")
+	}
+
+	d, err := h.PublishCancelable(ctx, T("type=other"), nil)
+	if err != nil || d == nil {
+		t.Fatalf("PublishCancelable() = %v, %v", d, err)
+	}
+}
+
+func TestPublishAfter(t *testing.T) {
+	ctx := context.Background()
+	h := New(WithTopicPolicy(TopicPolicy{RequiredKeys: []string{"type"}}))
+
+	fired := make(chan string, 2)
+	_, _ = h.Subscribe(ctx, T("type=reminder"), func(ctx context.Context, s string) {
+		fired <- s
+	})
+
+	if _, err := h.PublishAfter(ctx, time.Millisecond, T("kind=x"), nil); err == nil {
+		t.Error("PublishAfter() should validate topic immediately")
+	}
+
+	canceled, _ := h.PublishAfter(ctx, 50*time.Millisecond, T("type=reminder"), "canceled")
+	_, _ = h.PublishAfter(ctx, time.Millisecond, T("type=reminder"), "fired")
+	canceled.Cancel()
+
+	select {
+	case s := <-fired:
+		if s != "fired" {
+			t.Errorf("got %q, want fired", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("delayed event was not published")
+	}
+
+	select {
+	case s := <-fired:
+		t.Errorf("canceled event was published: %q", s)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,103 @@
+package hub
+
+import (
+	"context"
+	"sync"
+)
+
+// activity counts work of the hub in progress and lets callers
+// wait until there is none
+type activity struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // closed when n drops to 0
+}
+
+// begin registers a unit of background work
+func (a *activity) begin() {
+	a.mu.Lock()
+	if a.n == 0 {
+		a.idle = make(chan struct{})
+	}
+	a.n++
+	a.mu.Unlock()
+}
+
+// end completes a unit of work registered by begin
+func (a *activity) end() {
+	a.mu.Lock()
+	a.n--
+	if a.n == 0 {
+		close(a.idle)
+	}
+	a.mu.Unlock()
+}
+
+// count returns the number of units of work in progress
+func (a *activity) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.n
+}
+
+// wait blocks until there is no background work or ctx is done
+func (a *activity) wait(ctx context.Context) error {
+	a.mu.Lock()
+	if a.n == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	idle := a.idle
+	a.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain waits until all async deliveries, including ones queued by
+// Serialized and Durable subscriptions and worker pools, and their
+// OnFinish callbacks complete. Work started while waiting is waited for too. Events of
+// PublishAfter and RequireAck redeliveries are waited for only after
+// their timers fire. Returns ctx error if ctx is done first.
+// Calling Drain from an async handler never returns before ctx is done.
+//
+// The hub starts no goroutines outliving its async work, and Drain waits
+// on channels only, so code using the hub can be tested with virtual
+// time in a testing/synctest bubble:
+//
+//	synctest.Test(t, func(t *testing.T) {
+//	    h := hub.New()
+//	    h.Subscribe(ctx, hub.T("type=job"), slowHandler)
+//	    h.Publish(ctx, hub.T("type=job"), job)
+//	    _ = h.Drain(ctx) // returns once slowHandler completes in virtual time
+//	})
+func (h *Hub) Drain(ctx context.Context) error {
+	return h.active.wait(ctx)
+}
+
+// InFlight returns the number of deliveries scheduled or running,
+// including handlers called synchronously by publishers.
+func (h *Hub) InFlight() int {
+	return h.deliveries.count()
+}
+
+// WaitIdle waits until InFlight drops to zero, so tests and shutdown code
+// don't need to sleep to let handlers run. Unlike Drain, it waits for
+// sync handlers running in other goroutines and doesn't wait for
+// OnFinish callbacks called after the last handler. Returns ctx error
+// if ctx is done first. Calling WaitIdle from a handler never returns
+// before ctx is done.
+//
+// Example:
+//
+//	h.Publish(ctx, hub.T("type=order"), order)
+//	if err := h.WaitIdle(ctx); err != nil {
+//	    t.Fatal(err)
+//	}
+func (h *Hub) WaitIdle(ctx context.Context) error {
+	return h.deliveries.wait(ctx)
+}
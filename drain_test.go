@@ -0,0 +1,173 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		opts []HubOption
+		sub  []SubscribeOption
+	}{
+		{"goroutines", nil, nil},
+		{"workers", []HubOption{WithWorkers(2)}, nil},
+		{"serialized", nil, []SubscribeOption{Serialized(true)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(tc.opts...)
+			if err := h.Drain(ctx); err != nil {
+				t.Fatalf("Drain() of idle hub = %v", err)
+			}
+
+			var handled, finished atomic.Int32
+			_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context, n int) {
+				time.Sleep(time.Millisecond)
+				if n > 0 {
+					// nested publish is waited for too
+					_ = h.Publish(ctx, T("type=job"), n-1)
+				}
+				handled.Add(1)
+			}, tc.sub...)
+
+			for range 5 {
+				_ = h.Publish(ctx, T("type=job"), 2, OnFinish(func(ctx context.Context) {
+					finished.Add(1)
+				}))
+			}
+			if err := h.Drain(ctx); err != nil {
+				t.Fatalf("Drain() = %v", err)
+			}
+			if n := handled.Load(); n != 15 {
+				t.Errorf("handled = %d, want 15", n)
+			}
+			if n := finished.Load(); n != 5 {
+				t.Errorf("finished = %d, want 5", n)
+			}
+		})
+	}
+
+	t.Run("context", func(t *testing.T) {
+		h := New()
+		release := make(chan struct{})
+		defer close(release)
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {
+			<-release
+		})
+		_ = h.Publish(ctx, T("type=job"), nil)
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if err := h.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Drain() = %v, want DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestDrainRedeliveries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	var calls, done atomic.Int32
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			return errors.New("retry")
+		}
+		time.Sleep(20 * time.Millisecond)
+		done.Add(1)
+		return nil
+	}, RequireAck(AckConfig{MaxRedeliveries: 1}))
+
+	_ = h.Publish(ctx, T("type=job"), nil)
+	if err := h.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 || done.Load() != 1 {
+		t.Errorf("calls = %d, done = %d after Drain, want 2 and 1", calls.Load(), done.Load())
+	}
+	if n := h.InFlight(); n != 0 {
+		t.Errorf("InFlight() = %d, want 0", n)
+	}
+}
+
+func TestDrainDurable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New(WithStore(newMemStore()))
+	_ = h.Publish(ctx, T("type=order"), "1")
+
+	var handled atomic.Int32
+	_, err := h.Subscribe(ctx, T("type=order"), func(ctx context.Context) {
+		time.Sleep(10 * time.Millisecond)
+		handled.Add(1)
+	}, Durable("d1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replayed event
+	if err := h.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := handled.Load(); n != 1 {
+		t.Errorf("handled after replay = %d, want 1", n)
+	}
+
+	// new event
+	_ = h.Publish(ctx, T("type=order"), "2")
+	if err := h.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := handled.Load(); n != 2 {
+		t.Errorf("handled = %d, want 2", n)
+	}
+}
+
+func TestWaitIdle(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {
+		started <- struct{}{}
+		<-release
+	}, Once(true))
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {
+		started <- struct{}{}
+		<-release
+	}, Inline(true))
+
+	// inline handler blocks the publisher in its own goroutine
+	go func() { _ = h.Publish(ctx, T("type=job"), nil) }()
+	<-started
+	<-started
+	if n := h.InFlight(); n != 2 {
+		t.Errorf("InFlight() = %d, want 2", n)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := h.WaitIdle(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitIdle() = %v, want DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := h.WaitIdle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := h.InFlight(); n != 0 {
+		t.Errorf("InFlight() = %d, want 0", n)
+	}
+	if n := h.Len(); n != 1 {
+		t.Errorf("Len() = %d, want Once subscription removed", n)
+	}
+}
@@ -0,0 +1,74 @@
+package hub
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// Encryptor encrypts payloads leaving the process: events written to the
+// Store (see WithEncryptor) and messages of hubnats, hubkafka and hubpg
+// bridges and hubhook webhooks.
+// Implementations must be safe for concurrent use.
+type Encryptor interface {
+	// Encrypt returns ciphertext of the plaintext
+	Encrypt(plaintext []byte) ([]byte, error)
+	// Decrypt returns plaintext of the ciphertext made by Encrypt
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// ErrDecrypt is returned by Decrypt of AES-GCM Encryptor for ciphertexts
+// which are malformed or were encrypted with another key
+var ErrDecrypt = errors.New("hub: payload decryption failed")
+
+// aesGCM implements Encryptor with AES-GCM, the random nonce is
+// prepended to the ciphertext
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM creates an Encryptor using AES-GCM with a 16, 24 or 32 byte
+// key for AES-128, AES-192 or AES-256.
+//
+// Example:
+//
+//	enc, err := hub.NewAESGCM(key)
+//	if err != nil {
+//	    return err
+//	}
+//	h := hub.New(hub.WithStore(store), hub.WithEncryptor(enc))
+func NewAESGCM(key []byte) (Encryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCM{aead: aead}, nil
+}
+
+// Encrypt implements Encryptor
+func (a *aesGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	n := a.aead.NonceSize()
+	out := make([]byte, n, n+len(plaintext)+a.aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(out, out, plaintext, nil), nil
+}
+
+// Decrypt implements Encryptor
+func (a *aesGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := a.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := a.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
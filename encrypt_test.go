@@ -0,0 +1,95 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAESGCM(t *testing.T) {
+	t.Parallel()
+	if _, err := NewAESGCM([]byte("short")); err == nil {
+		t.Error("NewAESGCM() accepted 5 byte key")
+	}
+	enc, err := NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewAESGCM(bytes.Repeat([]byte{2}, 32))
+
+	plain := []byte(`{"card":"4111"}`)
+	c1, err := enc.Encrypt(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _ := enc.Encrypt(plain)
+	if bytes.Equal(c1, c2) || bytes.Contains(c1, []byte("4111")) {
+		t.Errorf("ciphertexts %x and %x", c1, c2)
+	}
+	got, err := enc.Decrypt(c1)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Decrypt() = %q, %v", got, err)
+	}
+
+	c1[len(c1)-1] ^= 1
+	for name, c := range map[string][]byte{"tampered": c1, "other key": c2, "short": c2[:4]} {
+		d := enc
+		if name == "other key" {
+			d = other
+		}
+		if _, err := d.Decrypt(c); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: Decrypt() error = %v, want ErrDecrypt", name, err)
+		}
+	}
+}
+
+func TestWithEncryptor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	enc, _ := NewAESGCM(bytes.Repeat([]byte{1}, 16))
+	store := newMemStore()
+
+	h := New(WithStore(store), WithEncryptor(enc))
+	_ = h.Publish(ctx, T("type=payment"), map[string]any{"card": "4111"})
+	for _, ev := range store.events {
+		if strings.Contains(string(ev.Payload), "4111") {
+			t.Errorf("stored payload in plaintext: %s", ev.Payload)
+		}
+	}
+
+	// events are decrypted for durable subscriptions
+	h = New(WithStore(store), WithEncryptor(enc))
+	cards := make(chan string, 1)
+	_, err := h.Subscribe(ctx, T("type=payment"), func(ctx context.Context, p map[string]any) {
+		cards <- p["card"].(string)
+	}, Durable("billing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case card := <-cards:
+		if card != "4111" {
+			t.Errorf("card = %q", card)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not replayed")
+	}
+
+	// without the key handlers get the ciphertext
+	h = New(WithStore(store))
+	raw := make(chan any, 1)
+	_, _ = h.Subscribe(ctx, T("type=payment"), func(ctx context.Context, p any) {
+		raw <- p
+	}, Durable("audit"))
+	select {
+	case p := <-raw:
+		if s, ok := p.(string); !ok || strings.Contains(s, "4111") {
+			t.Errorf("payload without key = %v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not replayed")
+	}
+}
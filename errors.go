@@ -1,13 +1,47 @@
 package hub
 
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrKeyNotFound is returned by typed Topic accessors when the key is missing.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrQueryOperator is returned by Topic.Query for attributes with
+// operators other than "=".
+var ErrQueryOperator = errors.New("operator is not representable in URL query")
+
+// ErrClosed is returned by Subscribe and Publish after Hub.Close.
+var ErrClosed = errors.New("hub is closed")
+
 // CastError represents an error that occurs during type casting.
+// Payload conversion failures carry the expected and actual types,
+// failures inside subscription handlers also carry the subscription ID and topic.
 type CastError struct {
+	Expected reflect.Type // Declared type, nil if unknown
+	Actual   reflect.Type // Type of the converted value, nil for nil payload
+	SubID    SubID        // Subscription ID, 0 outside of handler calls
+	Topic    *Topic       // Topic of the event (or accessed topic), may be nil
+
 	orig error
 }
 
 // Error implements the error interface for CastError.
 func (e *CastError) Error() string {
-	return e.orig.Error()
+	if e.SubID == 0 {
+		return e.orig.Error()
+	}
+	if e.Topic == nil {
+		return fmt.Sprintf("%s (subscription %d)", e.orig, e.SubID)
+	}
+	return fmt.Sprintf("%s (subscription %d, topic %s)", e.orig, e.SubID, e.Topic)
+}
+
+// Unwrap returns the underlying conversion error.
+func (e *CastError) Unwrap() error {
+	return e.orig
 }
 
 // newCastError creates a new instance of CastError.
@@ -16,3 +50,69 @@ func newCastError(orig error) *CastError {
 		orig: orig,
 	}
 }
+
+// newPayloadCastError creates CastError for value p which can't be converted
+// to expected type.
+func newPayloadCastError(orig error, expected reflect.Type, p any, t *Topic) *CastError {
+	e := newCastError(orig)
+	e.Expected = expected
+	e.Actual = reflect.TypeOf(p)
+	e.Topic = t
+	return e
+}
+
+// PolicyError is returned by Subscribe and Publish when a topic
+// violates the hub TopicPolicy.
+type PolicyError struct {
+	Topic  *Topic // Offending topic
+	Key    string // Offending key, empty for topic-wide rules
+	Reason string // Violated rule
+}
+
+// Error implements the error interface for PolicyError.
+func (e *PolicyError) Error() string {
+	if e.Key == "" {
+		return "topic policy violation: " + e.Reason
+	}
+	return "topic policy violation: " + e.Reason + ": " + e.Key
+}
+
+// newPolicyError creates a new instance of PolicyError.
+func newPolicyError(t *Topic, key, reason string) *PolicyError {
+	return &PolicyError{
+		Topic:  t,
+		Key:    key,
+		Reason: reason,
+	}
+}
+
+// HandlerError is the payload published to the topic set by WithErrorTopic
+// when a handler or a callback fails.
+type HandlerError struct {
+	Topic *Topic // Topic of the failed event
+	SubID SubID  // Failed subscription, 0 for callbacks like OnFinish
+	Err   error  // Error reported to OnError hooks
+}
+
+// Error implements the error interface for HandlerError.
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("subscription %d failed on %s: %v", e.SubID, e.Topic, e.Err)
+}
+
+// Unwrap returns the handler error.
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is reported to OnError hooks when a callback run by the hub
+// panics, e.g. an OnFinish callback. The panic is recovered, so other
+// callbacks and handlers are not affected.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace of the panicking goroutine
+}
+
+// Error implements the error interface for PanicError.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
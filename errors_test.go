@@ -1,7 +1,9 @@
 package hub
 
 import (
+	"context"
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -53,3 +55,62 @@ func TestCastError(t *testing.T) {
 		}
 	})
 }
+
+func TestCastErrorDetails(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unwrap", func(t *testing.T) {
+		origErr := errors.New("test error")
+		ce := newPayloadCastError(origErr, reflect.TypeFor[int](), "abc", T("type=a"))
+		if !errors.Is(ce, origErr) {
+			t.Error("errors.Is should find original error")
+		}
+		if ce.Expected != reflect.TypeFor[int]() || ce.Actual != reflect.TypeFor[string]() {
+			t.Errorf("unexpected types: expected=%v actual=%v", ce.Expected, ce.Actual)
+		}
+		if newPayloadCastError(origErr, nil, nil, nil).Actual != nil {
+			t.Error("Actual should be nil for nil payload")
+		}
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		ctx := context.Background()
+		var got *CastError
+		h := New(OnError(func(ctx context.Context, _ *Topic, _ SubID, err error) {
+			errors.As(err, &got)
+		}))
+		id, err := h.Subscribe(ctx, T("type=num"), func(ctx context.Context, v int) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = h.Publish(ctx, T("type=num", "n", "1"), struct{}{}, Sync(true))
+
+		if got == nil {
+			t.Fatal("expected CastError to be reported")
+		}
+		if got.SubID != id {
+			t.Errorf("SubID = %d, want %d", got.SubID, id)
+		}
+		if !got.Topic.Equal(T("type=num", "n", "1")) {
+			t.Errorf("Topic = %s", got.Topic)
+		}
+		if got.Expected != reflect.TypeFor[int]() || got.Actual != reflect.TypeFor[struct{}]() {
+			t.Errorf("unexpected types: expected=%v actual=%v", got.Expected, got.Actual)
+		}
+		if want := got.orig.Error() + " (subscription 1, topic n=1 type=num)"; got.Error() != want {
+			t.Errorf("Error() = %q, want %q", got.Error(), want)
+		}
+	})
+
+	t.Run("topic accessor", func(t *testing.T) {
+		tp := T("n=abc")
+		_, err := tp.GetInt("n")
+		var ce *CastError
+		if !errors.As(err, &ce) {
+			t.Fatalf("expected CastError, got %v", err)
+		}
+		if ce.Topic != tp || ce.Expected != reflect.TypeFor[int]() || ce.Actual != reflect.TypeFor[string]() {
+			t.Errorf("unexpected details: %+v", ce)
+		}
+	})
+}
@@ -2,17 +2,37 @@ package hub
 
 import (
 	"context"
+	"time"
 )
 
 // Event represents a message sent to a specific topic in the event hub.
 // It contains the topic, payload data, and processing instructions.
 // Event is immutable - all modifier methods return a new copy.
 type event struct {
+	id       EventID
 	topic    *Topic
 	payload  any
 	onFinish []func(ctx context.Context)
 	wait     bool
 	sync     bool
+	finishIn FinishPlacement
+	group    *eventGroup   // nil unless WaitFirstError is set
+	ttl      time.Duration // Set by EventTTL, 0 if none
+	expires  time.Time     // Deadline of ttl, zero if none
+	priority int           // Lane of queued deliveries, see Priority
+	offset   uint64        // Position in the Store, 0 if not stored
+	delivery *Delivery     // nil if the event can't be canceled
+	trace    *EventTrace   // nil unless Trace is set
+}
+
+// canceled reports whether pending deliveries of the event were canceled
+func (e *event) canceled() bool {
+	return e.delivery != nil && e.delivery.Canceled()
+}
+
+// expired reports whether the EventTTL of the event has passed
+func (e *event) expired(c Clock) bool {
+	return !e.expires.IsZero() && c.Now().After(e.expires)
 }
 
 // hasOnFinish indicates whether the event has any finish callbacks registered.
@@ -21,12 +41,8 @@ func (e *event) hasOnFinish() bool {
 	return len(e.onFinish) > 0
 }
 
-// finish executes all registered finish callbacks in sequence.
-// Called automatically by the hub after event processing completes.
-func (e *event) finish(ctx context.Context) {
-	for _, cb := range e.onFinish {
-		if cb != nil {
-			cb(ctx)
-		}
-	}
+// waitAll indicates whether Publish waits for all handlers:
+// explicitly with Wait or to run OnFinish in the publisher's goroutine.
+func (e *event) waitAll() bool {
+	return e.wait || (e.finishIn == FinishInPublisher && e.hasOnFinish())
 }
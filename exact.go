@@ -0,0 +1,112 @@
+package hub
+
+import (
+	"maps"
+	"sync/atomic"
+
+	"github.com/lomik/hub/pkg/kv"
+)
+
+// exactMaxLen is the longest topic served by the exact-topic fast path
+const exactMaxLen = 8
+
+// exactIndex counts subscriptions by topic length to decide when a
+// published topic can be matched by the canonical-topic lookup alone.
+// Plain subscriptions themselves are stored in indexes.exact of the
+// shard selected by topic hash.
+type exactIndex struct {
+	byLen    [exactMaxLen + 1]atomic.Int64 // All subscriptions by topic length
+	nonPlain [exactMaxLen + 1]atomic.Int64 // Subscriptions with wildcards, sets or operators
+}
+
+// usable reports whether only subscriptions with exactly the same
+// plain topic can match a plain topic of length n.
+// It holds when there are no shorter subscriptions and no patterns
+// of the same length: longer topics never match.
+func (x *exactIndex) usable(n int) bool {
+	if n > exactMaxLen || x.nonPlain[n].Load() != 0 {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		if x.byLen[i].Load() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// count adjusts counters for subscription topic t by delta
+func (x *exactIndex) count(t *Topic, delta int64) {
+	n := t.Len()
+	if n > exactMaxLen {
+		return
+	}
+	x.byLen[n].Add(delta)
+	if !t.isPlain() {
+		x.nonPlain[n].Add(delta)
+	}
+}
+
+// reset zeroes all counters
+func (x *exactIndex) reset() {
+	for i := range x.byLen {
+		x.byLen[i].Store(0)
+		x.nonPlain[i].Store(0)
+	}
+}
+
+// exactShard returns shard number holding plain topics with hash h
+func exactShard(h uint64) int {
+	return int(h % numShards)
+}
+
+// updateExact adds or removes plain subscription s in the exact index.
+// Must be called while holding the Hub's lock (h.Lock()).
+func (h *Hub) updateExact(s *sub, add bool) {
+	if s.topic.Len() > exactMaxLen || !s.topic.isPlain() {
+		return
+	}
+	hash := s.topic.Hash()
+	sh := &h.shards[exactShard(hash)]
+	ix := sh.load().clone()
+	ix.exact = maps.Clone(ix.exact)
+	if add {
+		ix.exact[hash] = ix.exact[hash].with(s)
+	} else if sl := ix.exact[hash].without(s.id); sl != nil {
+		ix.exact[hash] = sl
+	} else {
+		delete(ix.exact, hash)
+	}
+	sh.p.Store(ix)
+}
+
+// matchExact appends subscriptions with topic equal to t.
+// ok is false if the fast path is not applicable and full matching is required.
+func (h *Hub) matchExact(t *Topic, dst []*sub) (_ []*sub, ok bool) {
+	if !h.exact.usable(t.Len()) || !t.isPlain() {
+		return dst, false
+	}
+	hash := t.Hash()
+	sl := h.shards[exactShard(hash)].load().exact[hash]
+	if sl == nil {
+		return dst, true
+	}
+	for _, s := range sl.lst {
+		// Hash collisions are possible
+		if s.topic.Equal(t) {
+			dst = append(dst, s)
+		}
+	}
+	return dst, true
+}
+
+// isPlain reports whether all attributes are single concrete values
+// compared for equality: no Any, alternatives or operators.
+func (t *Topic) isPlain() bool {
+	for p := range t.pairs() {
+		if p.Op() != kv.OpEq || p.IsSet() || p.Value() == Any {
+			return false
+		}
+	}
+	return true
+}
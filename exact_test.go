@@ -0,0 +1,109 @@
+package hub
+
+import (
+	"context"
+	"maps"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestMatchExact(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	noop := func(ctx context.Context) {}
+
+	id1, _ := h.Subscribe(ctx, T("type=alert", "level=high"), noop)
+	_, _ = h.Subscribe(ctx, T("type=alert", "level=low"), noop)
+	_, _ = h.Subscribe(ctx, T("type=alert", "level=high", "source=db"), noop)
+
+	got, ok := h.matchExact(T("level=high", "type=alert"), nil)
+	if !ok {
+		t.Fatal("fast path not used")
+	}
+	if len(got) != 1 || got[0].id != id1 {
+		t.Fatalf("matchExact() = %v, want [%d]", got, id1)
+	}
+
+	if _, ok := h.matchExact(T("type=*", "level=high"), nil); ok {
+		t.Error("fast path used for wildcard topic")
+	}
+
+	// Pattern of the same length disables the fast path
+	pid, _ := h.Subscribe(ctx, T("type=alert", "level=*"), noop)
+	if _, ok := h.matchExact(T("type=alert", "level=high"), nil); ok {
+		t.Error("fast path used with pattern subscription")
+	}
+	// but not for shorter topics
+	if _, ok := h.matchExact(T("type=alert"), nil); !ok {
+		t.Error("fast path not used for shorter topic")
+	}
+	h.Unsubscribe(ctx, pid)
+	if _, ok := h.matchExact(T("type=alert", "level=high"), nil); !ok {
+		t.Error("fast path not restored after unsubscribe")
+	}
+
+	// Shorter subscription may match longer topics
+	sid, _ := h.Subscribe(ctx, T("type=alert"), noop)
+	if _, ok := h.matchExact(T("type=alert", "level=high"), nil); ok {
+		t.Error("fast path used with shorter subscription")
+	}
+	h.Unsubscribe(ctx, sid)
+
+	h.Clear(ctx)
+	if got, ok := h.matchExact(T("type=alert", "level=high"), nil); !ok || len(got) != 0 {
+		t.Errorf("matchExact() after Clear = %v, %v", got, ok)
+	}
+}
+
+func TestMatchExactConsistent(t *testing.T) {
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(1))
+	noop := func(ctx context.Context) {}
+
+	randTopic := func(wildcards bool) *Topic {
+		var args []string
+		for _, k := range []string{"a", "b", "c"} {
+			switch rnd.Intn(3) {
+			case 0:
+				continue
+			case 1:
+				if wildcards && rnd.Intn(3) == 0 {
+					args = append(args, k+"=*")
+					continue
+				}
+			}
+			args = append(args, k+"="+strconv.Itoa(rnd.Intn(2)))
+		}
+		return T(args...)
+	}
+
+	for round := 0; round < 50; round++ {
+		h := New()
+		for i := 0; i < 5; i++ {
+			_, _ = h.Subscribe(ctx, randTopic(round%2 == 0), noop)
+		}
+		for i := 0; i < 20; i++ {
+			tp := randTopic(false)
+			var want []SubID
+			for _, id := range slices.Sorted(maps.Keys(h.subs)) {
+				if h.subs[id].topic.Match(tp) {
+					want = append(want, id)
+				}
+			}
+			var got []SubID
+			for _, s := range h.match(tp, nil) {
+				got = append(got, s.id)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("match(%s) = %v, want %v", tp, got, want)
+			}
+			for j := range got {
+				if got[j] != want[j] {
+					t.Fatalf("match(%s) = %v, want %v", tp, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package hub
+
+import (
+	"strings"
+
+	"github.com/lomik/hub/pkg/kv"
+)
+
+// MatchReason describes the outcome of matching a single attribute
+type MatchReason int
+
+const (
+	// MatchOK means the attribute matched
+	MatchOK MatchReason = iota
+	// MatchMissingKey means the key is absent in the other topic
+	MatchMissingKey
+	// MatchValueMismatch means the value doesn't satisfy the attribute
+	MatchValueMismatch
+)
+
+// String returns human readable reason
+func (r MatchReason) String() string {
+	switch r {
+	case MatchOK:
+		return "matched"
+	case MatchMissingKey:
+		return "missing key"
+	case MatchValueMismatch:
+		return "value mismatch"
+	default:
+		return "unknown"
+	}
+}
+
+// KeyMatch describes matching of a single attribute
+type KeyMatch struct {
+	Key      string      // Attribute key
+	Expected string      // Attribute of the pattern topic, e.g. "level!=debug"
+	Actual   string      // Value in the other topic, empty if missing
+	Reason   MatchReason // Outcome
+}
+
+// MatchResult is a detailed report of Topic.Match
+type MatchResult struct {
+	Matched bool       // Same as result of Topic.Match
+	Keys    []KeyMatch // Per attribute results in sorted key order
+}
+
+// Failed returns only attributes that didn't match
+func (r MatchResult) Failed() []KeyMatch {
+	var ret []KeyMatch
+	for _, k := range r.Keys {
+		if k.Reason != MatchOK {
+			ret = append(ret, k)
+		}
+	}
+	return ret
+}
+
+// String returns multiline report, one attribute per line
+func (r MatchResult) String() string {
+	var sb strings.Builder
+	if r.Matched {
+		sb.WriteString("matched")
+	} else {
+		sb.WriteString("not matched")
+	}
+	for _, k := range r.Keys {
+		sb.WriteString("\n  ")
+		sb.WriteString(k.Expected)
+		sb.WriteString(": ")
+		sb.WriteString(k.Reason.String())
+		if k.Reason == MatchValueMismatch {
+			sb.WriteString(" (got ")
+			sb.WriteString(k.Actual)
+			sb.WriteString(")")
+		}
+	}
+	return sb.String()
+}
+
+// Explain reports why this Topic matches or doesn't match another Topic.
+// Uses the same rules as Match, but checks every attribute instead of
+// stopping at the first failure.
+//
+// Example:
+//
+//	r := T("type=log", "level!=debug").Explain(T("type=log", "level=debug"))
+//	fmt.Println(r)
+//	// not matched
+//	//   level!=debug: value mismatch (got debug)
+//	//   type=log: matched
+func (t *Topic) Explain(other *Topic) MatchResult {
+	r := MatchResult{
+		Matched: true,
+		Keys:    make([]KeyMatch, 0, t.Len()),
+	}
+
+	t.eachPair(func(p kv.KV) {
+		km := KeyMatch{
+			Key:      p.Key(),
+			Expected: p.String(),
+		}
+
+		o, ok := other.mp.Lookup(p.Key())
+		switch {
+		case !ok:
+			km.Reason = MatchMissingKey
+		case !p.MatchValue(o):
+			km.Actual = o.Value()
+			km.Reason = MatchValueMismatch
+		default:
+			km.Actual = o.Value()
+			km.Reason = MatchOK
+		}
+
+		if km.Reason != MatchOK {
+			r.Matched = false
+		}
+		r.Keys = append(r.Keys, km)
+	})
+
+	return r
+}
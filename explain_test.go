@@ -0,0 +1,78 @@
+package hub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTopic_Explain(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern *Topic
+		other   *Topic
+		matched bool
+		reasons map[string]MatchReason
+	}{
+		{
+			name:    "all matched",
+			pattern: T("type=log", "level!=debug"),
+			other:   T("type=log", "level=info", "extra=1"),
+			matched: true,
+			reasons: map[string]MatchReason{"type": MatchOK, "level": MatchOK},
+		},
+		{
+			name:    "value mismatch",
+			pattern: T("type=log", "level!=debug"),
+			other:   T("type=log", "level=debug"),
+			matched: false,
+			reasons: map[string]MatchReason{"type": MatchOK, "level": MatchValueMismatch},
+		},
+		{
+			name:    "missing key",
+			pattern: T("type=log", "severity>=3"),
+			other:   T("type=metric"),
+			matched: false,
+			reasons: map[string]MatchReason{"type": MatchValueMismatch, "severity": MatchMissingKey},
+		},
+		{
+			name:    "empty pattern",
+			pattern: T(),
+			other:   T("type=log"),
+			matched: true,
+			reasons: map[string]MatchReason{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.pattern.Explain(tt.other)
+			if r.Matched != tt.matched {
+				t.Errorf("Matched = %v, want %v", r.Matched, tt.matched)
+			}
+			if r.Matched != tt.pattern.Match(tt.other) {
+				t.Error("Explain() disagrees with Match()")
+			}
+			if len(r.Keys) != len(tt.reasons) {
+				t.Fatalf("Keys = %v, want %d items", r.Keys, len(tt.reasons))
+			}
+			for _, k := range r.Keys {
+				if k.Reason != tt.reasons[k.Key] {
+					t.Errorf("Reason for %s = %v, want %v", k.Key, k.Reason, tt.reasons[k.Key])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchResult_String(t *testing.T) {
+	r := T("type=log", "level!=debug").Explain(T("type=log", "level=debug"))
+	want := "not matched\n  level!=debug: value mismatch (got debug)\n  type=log: matched"
+	if r.String() != want {
+		t.Errorf("String() = %q, want %q", r.String(), want)
+	}
+
+	failed := r.Failed()
+	if len(failed) != 1 || !strings.HasPrefix(failed[0].Expected, "level") {
+		t.Errorf("Failed() = %v", failed)
+	}
+}
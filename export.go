@@ -0,0 +1,133 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// SubscriptionSpec is a serializable description of a subscription
+// returned by Export. Handlers are not serializable, they are referenced
+// by the name given with the Name option.
+type SubscriptionSpec struct {
+	Name         string     `json:"name"`
+	Topic        *Topic     `json:"topic"`
+	Once         bool       `json:"once,omitempty"`
+	Async        bool       `json:"async,omitempty"`
+	Inline       bool       `json:"inline,omitempty"`
+	Serialized   bool       `json:"serialized,omitempty"`
+	MaxInFlight  int        `json:"max_in_flight,omitempty"`
+	DropWhenBusy bool       `json:"drop_when_busy,omitempty"`
+	Sample       int        `json:"sample,omitempty"`
+	SampleRate   float64    `json:"sample_rate,omitempty"`
+	QueueGroup   string     `json:"queue_group,omitempty"`
+	StickyBy     string     `json:"sticky_by,omitempty"`
+	Consistent   bool       `json:"consistent,omitempty"`
+	Ack          *AckConfig `json:"ack,omitempty"`
+}
+
+// options converts spec to subscribe options
+func (spec *SubscriptionSpec) options() []SubscribeOption {
+	opts := []SubscribeOption{
+		Name(spec.Name),
+		Once(spec.Once),
+		Async(spec.Async),
+		Inline(spec.Inline),
+		Serialized(spec.Serialized),
+		MaxInFlight(spec.MaxInFlight),
+		DropWhenBusy(spec.DropWhenBusy),
+		Sample(spec.Sample),
+		SampleRate(spec.SampleRate),
+		QueueGroup(spec.QueueGroup),
+		&optionSubscribeStickyBy{v: spec.StickyBy, consistent: spec.Consistent},
+	}
+	if spec.Ack != nil {
+		opts = append(opts, RequireAck(*spec.Ack))
+	}
+	return opts
+}
+
+// Export returns descriptions of active subscriptions in subscription order,
+// so a process doing graceful restart can transfer routing state to a new
+// Hub with Import. Subscriptions without Name are skipped as their
+// handlers can't be re-bound.
+//
+// Example:
+//
+//	data, _ := json.Marshal(h.Export())
+func (h *Hub) Export() []SubscriptionSpec {
+	h.RLock()
+	defer h.RUnlock()
+
+	specs := make([]SubscriptionSpec, 0, len(h.subs))
+	for _, id := range slices.Sorted(maps.Keys(h.subs)) {
+		s := h.subs[id]
+		if s.name == "" || s.shouldRemove() {
+			continue
+		}
+		specs = append(specs, SubscriptionSpec{
+			Name:         s.name,
+			Topic:        s.topic,
+			Once:         s.once,
+			Async:        s.async,
+			Inline:       s.inline,
+			Serialized:   s.serial != nil,
+			MaxInFlight:  cap(s.inFlight),
+			DropWhenBusy: s.dropWhenBusy,
+			Sample:       int(s.sampleN),
+			SampleRate:   s.sampleRate,
+			QueueGroup:   s.group,
+			StickyBy:     s.stickyBy,
+			Consistent:   s.consistent,
+			Ack:          cloneAckConfig(s.ack),
+		})
+	}
+	return specs
+}
+
+// Import subscribes handlers by specs produced by Export. Handlers are
+// looked up by spec name and may have any signature supported by Subscribe.
+// Returns IDs of new subscriptions in specs order. On error no
+// subscriptions are created.
+//
+// Example:
+//
+//	var specs []hub.SubscriptionSpec
+//	_ = json.Unmarshal(data, &specs)
+//	ids, err := h.Import(ctx, specs, map[string]any{
+//	    "audit":  auditHandler,
+//	    "mailer": mailHandler,
+//	})
+func (h *Hub) Import(ctx context.Context, specs []SubscriptionSpec, handlers map[string]any) ([]SubID, error) {
+	for _, spec := range specs {
+		if _, ok := handlers[spec.Name]; !ok {
+			return nil, fmt.Errorf("import: no handler %q", spec.Name)
+		}
+		if spec.Topic == nil {
+			return nil, fmt.Errorf("import: no topic for handler %q", spec.Name)
+		}
+	}
+
+	ids := make([]SubID, 0, len(specs))
+	for _, spec := range specs {
+		id, err := h.Subscribe(ctx, spec.Topic, handlers[spec.Name], spec.options()...)
+		if err != nil {
+			for _, id := range ids {
+				h.Unsubscribe(ctx, id)
+			}
+			return nil, fmt.Errorf("import %q: %w", spec.Name, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// cloneAckConfig returns a copy of cfg, nil if cfg is nil
+func cloneAckConfig(cfg *AckConfig) *AckConfig {
+	if cfg == nil {
+		return nil
+	}
+	c := *cfg
+	return &c
+}
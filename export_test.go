@@ -0,0 +1,75 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	old := New()
+
+	noop := func(ctx context.Context) {}
+	_, _ = old.Subscribe(ctx, T("type=audit"), noop, Name("audit"), Serialized(true))
+	_, _ = old.Subscribe(ctx, T("type=mail", "priority=high|critical"), noop, Name("mailer"), MaxInFlight(4), DropWhenBusy(true), Once(true))
+	_, _ = old.Subscribe(ctx, T("type=anonymous"), noop)
+
+	data, err := json.Marshal(old.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var specs []SubscriptionSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		t.Fatal(err)
+	}
+	want := []SubscriptionSpec{
+		{Name: "audit", Topic: T("type=audit"), Serialized: true},
+		{Name: "mailer", Topic: T("type=mail", "priority=high|critical"), MaxInFlight: 4, DropWhenBusy: true, Once: true},
+	}
+	if len(specs) != len(want) {
+		t.Fatalf("Export() = %s", data)
+	}
+	for i := range want {
+		if !specs[i].Topic.Equal(want[i].Topic) {
+			t.Errorf("spec %d topic = %s, want %s", i, specs[i].Topic, want[i].Topic)
+		}
+		specs[i].Topic, want[i].Topic = nil, nil
+		if !reflect.DeepEqual(specs[i], want[i]) {
+			t.Errorf("spec %d = %+v, want %+v", i, specs[i], want[i])
+		}
+	}
+
+	// Restore topics cleared for comparison
+	_ = json.Unmarshal(data, &specs)
+
+	h := New()
+	if _, err := h.Import(ctx, specs, map[string]any{"audit": noop}); err == nil {
+		t.Error("Import() without handler should fail")
+	}
+	if h.Len() != 0 {
+		t.Errorf("Len() after failed import = %d", h.Len())
+	}
+
+	var audit, mail atomic.Int32
+	ids, err := h.Import(ctx, specs, map[string]any{
+		"audit":  func(ctx context.Context) { audit.Add(1) },
+		"mailer": func(ctx context.Context) { mail.Add(1) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Import() ids = %v", ids)
+	}
+
+	_ = h.Publish(ctx, T("type=audit"), nil, Sync(true))
+	_ = h.Publish(ctx, T("type=mail", "priority=critical"), nil, Sync(true))
+	_ = h.Publish(ctx, T("type=mail", "priority=critical"), nil, Sync(true))
+	if audit.Load() != 1 || mail.Load() != 1 {
+		t.Errorf("calls audit=%d mail=%d, want 1 and 1 (once)", audit.Load(), mail.Load())
+	}
+}
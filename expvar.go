@@ -0,0 +1,81 @@
+package hub
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// expvarMu serializes lookup and creation of expvar maps of hubs
+var expvarMu sync.Mutex
+
+// expvarMetrics implements Metrics with expvar counters
+type expvarMetrics struct {
+	publishes     *expvar.Int
+	deliveries    *expvar.Int
+	errors        *expvar.Int
+	inFlight      *expvar.Int
+	subscriptions *expvar.Int
+	subs          atomic.Int64 // Subscriptions of this hub counted in subscriptions
+}
+
+// newExpvarMetrics creates counters in expvar map with the given name.
+// Existing map is reused, so hubs with the same name share counters.
+// If name is taken by another kind of variable, counters are not published.
+func newExpvarMetrics(name string) *expvarMetrics {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	var mp *expvar.Map
+	switch v := expvar.Get(name).(type) {
+	case nil:
+		mp = expvar.NewMap(name)
+	case *expvar.Map:
+		mp = v
+	default:
+		mp = new(expvar.Map)
+	}
+	return &expvarMetrics{
+		publishes:     expvarInt(mp, "publishes"),
+		deliveries:    expvarInt(mp, "deliveries"),
+		errors:        expvarInt(mp, "errors"),
+		inFlight:      expvarInt(mp, "in_flight"),
+		subscriptions: expvarInt(mp, "subscriptions"),
+	}
+}
+
+// expvarInt returns integer variable of the map, creating it if needed.
+// Must be called under expvarMu.
+func expvarInt(mp *expvar.Map, key string) *expvar.Int {
+	if v, ok := mp.Get(key).(*expvar.Int); ok {
+		return v
+	}
+	v := new(expvar.Int)
+	mp.Set(key, v)
+	return v
+}
+
+// EventPublished implements Metrics
+func (m *expvarMetrics) EventPublished(*Topic) {
+	m.publishes.Add(1)
+}
+
+// EventDelivered implements Metrics
+func (m *expvarMetrics) EventDelivered(_ *Topic, _ SubID, _ time.Duration, err error) {
+	m.deliveries.Add(1)
+	if err != nil {
+		m.errors.Add(1)
+	}
+}
+
+// HandlersInFlight implements Metrics
+func (m *expvarMetrics) HandlersInFlight(delta int) {
+	m.inFlight.Add(int64(delta))
+}
+
+// Subscriptions implements Metrics.
+// The gauge is shared by hubs with the same name, each adds its change.
+func (m *expvarMetrics) Subscriptions(n int) {
+	m.subscriptions.Add(int64(n) - m.subs.Swap(int64(n)))
+}
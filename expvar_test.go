@@ -0,0 +1,104 @@
+package hub
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// expvarSeq makes expvar names unique across test runs of the process
+var expvarSeq atomic.Int64
+
+// expvarValues returns integer counters of expvar map name
+func expvarValues(t *testing.T, name string) map[string]int64 {
+	t.Helper()
+	mp, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		t.Fatalf("expvar map %q is not published", name)
+	}
+	values := make(map[string]int64)
+	mp.Do(func(kv expvar.KeyValue) {
+		if v, ok := kv.Value.(*expvar.Int); ok {
+			values[kv.Key] = v.Value()
+		}
+	})
+	return values
+}
+
+func TestWithExpvar(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	name := fmt.Sprintf("hub_test_expvar_%d", expvarSeq.Add(1))
+	m := &testMetrics{}
+	h := New(WithExpvar(name), WithMetrics(m))
+
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {})
+	_ = h.Publish(ctx, T("type=a"), nil, Sync(true))
+	_ = h.Publish(ctx, T("type=b"), nil, Sync(true))
+
+	want := map[string]int64{
+		"publishes":     2,
+		"deliveries":    1,
+		"errors":        0,
+		"in_flight":     0,
+		"subscriptions": 1,
+	}
+	got := expvarValues(t, name)
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %d, want %d", k, got[k], v)
+		}
+	}
+
+	// other sinks still receive metrics
+	if m.published != 2 {
+		t.Errorf("published = %d, want 2", m.published)
+	}
+
+	// second hub with the same name shares counters
+	h2 := New(WithExpvar(name))
+	id, _ := h2.Subscribe(ctx, T("type=a"), func(ctx context.Context) {})
+	_, _ = h2.Subscribe(ctx, T("type=b"), func(ctx context.Context) {})
+	_ = h2.Publish(ctx, T("type=a"), nil, Sync(true))
+	h2.Unsubscribe(ctx, id)
+	got = expvarValues(t, name)
+	if got["publishes"] != 3 || got["subscriptions"] != 2 {
+		t.Errorf("publishes = %d, subscriptions = %d, want 3 and 2", got["publishes"], got["subscriptions"])
+	}
+	h2.Clear(ctx)
+	if got = expvarValues(t, name); got["subscriptions"] != 1 {
+		t.Errorf("subscriptions after Clear = %d, want 1", got["subscriptions"])
+	}
+}
+
+func TestWithExpvarNames(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// name of a variable of other kind
+	h := New(WithExpvar("cmdline"))
+	if err := h.Publish(ctx, T("type=a"), nil, Sync(true)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := expvar.Get("cmdline").(*expvar.Map); ok {
+		t.Error("cmdline is replaced")
+	}
+
+	// concurrent hubs share a single map
+	name := fmt.Sprintf("hub_test_expvar_%d", expvarSeq.Add(1))
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = New(WithExpvar(name)).Publish(ctx, T("type=a"), nil, Sync(true))
+		}()
+	}
+	wg.Wait()
+	if got := expvarValues(t, name)["publishes"]; got != 8 {
+		t.Errorf("publishes = %d, want 8", got)
+	}
+}
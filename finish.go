@@ -0,0 +1,122 @@
+package hub
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// FinishPlacement selects the goroutine running OnFinish callbacks of
+// an event. Callbacks always run after all handlers of the event complete.
+type FinishPlacement int
+
+const (
+	// FinishDefault runs callbacks in the publisher's goroutine when no
+	// handler is left running after delivery, otherwise in a separate goroutine
+	// waiting for handlers.
+	FinishDefault FinishPlacement = iota
+	// FinishInPublisher makes Publish wait for all handlers like Wait(true)
+	// and runs callbacks in the publisher's goroutine.
+	FinishInPublisher
+	// FinishInHandler runs callbacks in the goroutine of the last completing
+	// asynchronous handler, without an extra waiting goroutine. If all handlers
+	// complete before delivery returns, the publisher's goroutine is used.
+	FinishInHandler
+)
+
+// String returns the name of the placement
+func (p FinishPlacement) String() string {
+	switch p {
+	case FinishDefault:
+		return "default"
+	case FinishInPublisher:
+		return "publisher"
+	case FinishInHandler:
+		return "handler"
+	}
+	return "unknown"
+}
+
+// tracker counts asynchronous deliveries of an event
+type tracker struct {
+	wg     sync.WaitGroup
+	n      atomic.Int64
+	onLast func() // Called by whoever completes the last delivery, may be nil
+}
+
+// add registers a started delivery
+func (tr *tracker) add() {
+	tr.wg.Add(1)
+	tr.n.Add(1)
+}
+
+// done marks a delivery completed
+func (tr *tracker) done() {
+	last := tr.n.Add(-1) == 0
+	tr.wg.Done()
+	if last && tr.onLast != nil {
+		tr.onLast()
+	}
+}
+
+// newTracker creates a tracker of event deliveries.
+// With FinishInHandler the publisher holds a guard delivery until
+// finishAfter, so callbacks can't run before all handlers are started.
+func (h *Hub) newTracker(ctx context.Context, e *event) *tracker {
+	tr := &tracker{}
+	if e.finishIn == FinishInHandler && e.hasOnFinish() && !e.waitAll() {
+		tr.onLast = func() {
+			h.finish(ctx, e)
+		}
+		tr.add()
+	}
+	return tr
+}
+
+// finishAfter runs OnFinish callbacks of the event after all deliveries
+// tracked by tr complete, in the goroutine selected by the event placement
+func (h *Hub) finishAfter(ctx context.Context, e *event, tr *tracker) {
+	switch {
+	case e.waitAll():
+		tr.wg.Wait()
+		h.finish(ctx, e)
+	case !e.hasOnFinish():
+	case tr.onLast != nil:
+		// release the guard
+		tr.done()
+	case tr.n.Load() == 0:
+		h.finish(ctx, e)
+	default:
+		h.active.begin()
+		go func() {
+			defer h.active.end()
+			tr.wg.Wait()
+			h.finish(ctx, e)
+		}()
+	}
+}
+
+// finish executes all finish callbacks of the event in sequence.
+// A panicking callback is reported to OnError hooks as PanicError
+// and doesn't prevent the rest from running.
+func (h *Hub) finish(ctx context.Context, e *event) {
+	for _, cb := range e.onFinish {
+		if cb != nil {
+			h.runFinish(ctx, e, cb)
+		}
+	}
+}
+
+// runFinish calls a finish callback recovering a panic
+func (h *Hub) runFinish(ctx context.Context, e *event, cb func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &PanicError{Value: r, Stack: debug.Stack()}
+			for _, hook := range h.onError {
+				hook(ctx, e.topic, 0, err)
+			}
+		}
+	}()
+	cb(ctx)
+}
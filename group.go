@@ -0,0 +1,36 @@
+package hub
+
+import (
+	"context"
+	"sync"
+)
+
+// eventGroup collects the first handler error of an event published
+// with WaitFirstError and cancels the context of other handlers
+type eventGroup struct {
+	once   sync.Once
+	err    error
+	cancel context.CancelCauseFunc
+}
+
+// fail records err if it is the first one and cancels the shared context
+func (g *eventGroup) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel(err)
+		}
+	})
+}
+
+// publishEventGroup delivers the event waiting for all handlers
+// and returns the first handler error
+func (h *Hub) publishEventGroup(ctx context.Context, e *event) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	e.group.cancel = cancel
+	e.wait = true
+
+	h.deliver(ctx, e)
+	return e.group.err
+}
@@ -2,7 +2,12 @@ package hub
 
 import (
 	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"time"
 
 	"github.com/spf13/cast"
@@ -22,38 +27,122 @@ import (
 //	h.Subscribe(ctx, topic, myHandler)
 type Handler func(ctx context.Context, t *Topic, p any) error
 
-func toHandlerWithError[T any](cb func(context.Context, T) error, castFunc func(any) (T, error)) Handler {
+// Chain composes handlers into one that calls them sequentially
+// and stops at the first error, which is returned.
+// Nil handlers are skipped.
+//
+// Usage:
+//
+//	h.Subscribe(ctx, topic, hub.Chain(validate, logEvent, process))
+func Chain(handlers ...Handler) Handler {
+	handlers = slices.Clone(handlers)
+	return func(ctx context.Context, t *Topic, p any) error {
+		for _, h := range handlers {
+			if h == nil {
+				continue
+			}
+			if err := h(ctx, t, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Tee composes handlers into one that calls all of them sequentially
+// regardless of errors. Returned errors are combined with errors.Join.
+// Nil handlers are skipped.
+//
+// Usage:
+//
+//	h.Subscribe(ctx, topic, hub.Tee(writeAudit, updateMetrics))
+func Tee(handlers ...Handler) Handler {
+	handlers = slices.Clone(handlers)
+	return func(ctx context.Context, t *Topic, p any) error {
+		var errs []error
+		for _, h := range handlers {
+			if h == nil {
+				continue
+			}
+			if err := h(ctx, t, p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+func toHandlerWithError[T any](cb func(context.Context, T) error, castFunc func(any) (T, error), strict bool) Handler {
 	return func(ctx context.Context, t *Topic, p any) error {
 		if v, ok := p.(T); ok {
 			return cb(ctx, v)
 		}
-		v, err := castFunc(p)
+		if strict {
+			return newPayloadCastError(strictTypeError(p, reflect.TypeFor[T]()), reflect.TypeFor[T](), p, t)
+		}
+		v, err := castTo(p, castFunc)
 		if err != nil {
-			return newCastError(err)
+			return newPayloadCastError(err, reflect.TypeFor[T](), p, t)
 		}
 		return cb(ctx, v)
 	}
 }
 
-func toHandlerNoError[T any](cb func(context.Context, T), castFunc func(any) (T, error)) Handler {
+func toHandlerNoError[T any](cb func(context.Context, T), castFunc func(any) (T, error), strict bool) Handler {
 	return func(ctx context.Context, t *Topic, p any) error {
 		if v, ok := p.(T); ok {
 			cb(ctx, v)
 			return nil
 		}
-		v, err := castFunc(p)
+		if strict {
+			return newPayloadCastError(strictTypeError(p, reflect.TypeFor[T]()), reflect.TypeFor[T](), p, t)
+		}
+		v, err := castTo(p, castFunc)
 		if err != nil {
-			return newCastError(err)
+			return newPayloadCastError(err, reflect.TypeFor[T](), p, t)
 		}
 		cb(ctx, v)
 		return nil
 	}
 }
 
+// strictTypeError describes payload rejected in StrictTypes mode
+func strictTypeError(p any, t reflect.Type) error {
+	return fmt.Errorf("strict types: payload %#v of type %T is not %s", p, p, t)
+}
+
+// RegisterConverter adds a custom callback converter after the hub
+// is created, e.g. by plugins. It works the same way as ToHandler option:
+// converters are tried in registration order before built-in ones.
+// Already created subscriptions are not affected.
+//
+// Example:
+//
+//	h.RegisterConverter(func(ctx context.Context, cb any) (hub.Handler, error) {
+//	    if fn, ok := cb.(func(string) error); ok {
+//	        return func(ctx context.Context, t *hub.Topic, p any) error {
+//	            return fn(t.String())
+//	        }, nil
+//	    }
+//	    return nil, nil
+//	})
+func (h *Hub) RegisterConverter(converter func(ctx context.Context, cb any) (Handler, error)) {
+	if converter == nil {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	// Readers keep the old slice, never append into its spare capacity
+	h.convertToHandler = append(slices.Clip(h.convertToHandler), converter)
+}
+
 // ToHandler converts various callback signatures into a standardized Event handler function.
 func (h *Hub) ToHandler(ctx context.Context, cb any) (Handler, error) {
-	// custom converters
-	for _, c := range h.convertToHandler {
+	// custom converters, called without lock as they may use the hub
+	h.RLock()
+	converters := h.convertToHandler
+	h.RUnlock()
+	for _, c := range converters {
 		ret, err := c(ctx, cb)
 		if err != nil {
 			return nil, err
@@ -83,90 +172,109 @@ func (h *Hub) ToHandler(ctx context.Context, cb any) (Handler, error) {
 			cbt(ctx, t, p)
 			return nil
 		}, nil
+	case func(ctx context.Context, t *Topic) error:
+		return func(ctx context.Context, t *Topic, p any) error {
+			return cbt(ctx, t)
+		}, nil
+	case func(ctx context.Context, t *Topic):
+		return func(ctx context.Context, t *Topic, p any) error {
+			cbt(ctx, t)
+			return nil
+		}, nil
 
 	// Numeric types
 	case func(context.Context, int) error:
-		return toHandlerWithError(cbt, cast.ToIntE), nil
+		return toHandlerWithError(cbt, cast.ToIntE, h.strictTypes), nil
 	case func(context.Context, int):
-		return toHandlerNoError(cbt, cast.ToIntE), nil
+		return toHandlerNoError(cbt, cast.ToIntE, h.strictTypes), nil
 	case func(context.Context, int8) error:
-		return toHandlerWithError(cbt, cast.ToInt8E), nil
+		return toHandlerWithError(cbt, cast.ToInt8E, h.strictTypes), nil
 	case func(context.Context, int8):
-		return toHandlerNoError(cbt, cast.ToInt8E), nil
+		return toHandlerNoError(cbt, cast.ToInt8E, h.strictTypes), nil
 	case func(context.Context, int16) error:
-		return toHandlerWithError(cbt, cast.ToInt16E), nil
+		return toHandlerWithError(cbt, cast.ToInt16E, h.strictTypes), nil
 	case func(context.Context, int16):
-		return toHandlerNoError(cbt, cast.ToInt16E), nil
+		return toHandlerNoError(cbt, cast.ToInt16E, h.strictTypes), nil
 	case func(context.Context, int32) error:
-		return toHandlerWithError(cbt, cast.ToInt32E), nil
+		return toHandlerWithError(cbt, cast.ToInt32E, h.strictTypes), nil
 	case func(context.Context, int32):
-		return toHandlerNoError(cbt, cast.ToInt32E), nil
+		return toHandlerNoError(cbt, cast.ToInt32E, h.strictTypes), nil
 	case func(context.Context, int64) error:
-		return toHandlerWithError(cbt, cast.ToInt64E), nil
+		return toHandlerWithError(cbt, cast.ToInt64E, h.strictTypes), nil
 	case func(context.Context, int64):
-		return toHandlerNoError(cbt, cast.ToInt64E), nil
+		return toHandlerNoError(cbt, cast.ToInt64E, h.strictTypes), nil
 
 	// Unsigned integers
 	case func(context.Context, uint) error:
-		return toHandlerWithError(cbt, cast.ToUintE), nil
+		return toHandlerWithError(cbt, cast.ToUintE, h.strictTypes), nil
 	case func(context.Context, uint):
-		return toHandlerNoError(cbt, cast.ToUintE), nil
+		return toHandlerNoError(cbt, cast.ToUintE, h.strictTypes), nil
 	case func(context.Context, uint8) error:
-		return toHandlerWithError(cbt, cast.ToUint8E), nil
+		return toHandlerWithError(cbt, cast.ToUint8E, h.strictTypes), nil
 	case func(context.Context, uint8):
-		return toHandlerNoError(cbt, cast.ToUint8E), nil
+		return toHandlerNoError(cbt, cast.ToUint8E, h.strictTypes), nil
 	case func(context.Context, uint16) error:
-		return toHandlerWithError(cbt, cast.ToUint16E), nil
+		return toHandlerWithError(cbt, cast.ToUint16E, h.strictTypes), nil
 	case func(context.Context, uint16):
-		return toHandlerNoError(cbt, cast.ToUint16E), nil
+		return toHandlerNoError(cbt, cast.ToUint16E, h.strictTypes), nil
 	case func(context.Context, uint32) error:
-		return toHandlerWithError(cbt, cast.ToUint32E), nil
+		return toHandlerWithError(cbt, cast.ToUint32E, h.strictTypes), nil
 	case func(context.Context, uint32):
-		return toHandlerNoError(cbt, cast.ToUint32E), nil
+		return toHandlerNoError(cbt, cast.ToUint32E, h.strictTypes), nil
 	case func(context.Context, uint64) error:
-		return toHandlerWithError(cbt, cast.ToUint64E), nil
+		return toHandlerWithError(cbt, cast.ToUint64E, h.strictTypes), nil
 	case func(context.Context, uint64):
-		return toHandlerNoError(cbt, cast.ToUint64E), nil
+		return toHandlerNoError(cbt, cast.ToUint64E, h.strictTypes), nil
 
 	// Floating point
 	case func(context.Context, float32) error:
-		return toHandlerWithError(cbt, cast.ToFloat32E), nil
+		return toHandlerWithError(cbt, cast.ToFloat32E, h.strictTypes), nil
 	case func(context.Context, float32):
-		return toHandlerNoError(cbt, cast.ToFloat32E), nil
+		return toHandlerNoError(cbt, cast.ToFloat32E, h.strictTypes), nil
 	case func(context.Context, float64) error:
-		return toHandlerWithError(cbt, cast.ToFloat64E), nil
+		return toHandlerWithError(cbt, cast.ToFloat64E, h.strictTypes), nil
 	case func(context.Context, float64):
-		return toHandlerNoError(cbt, cast.ToFloat64E), nil
+		return toHandlerNoError(cbt, cast.ToFloat64E, h.strictTypes), nil
 
 	// String and bool
 	case func(context.Context, string) error:
-		return toHandlerWithError(cbt, cast.ToStringE), nil
+		return toHandlerWithError(cbt, cast.ToStringE, h.strictTypes), nil
 	case func(context.Context, string):
-		return toHandlerNoError(cbt, cast.ToStringE), nil
+		return toHandlerNoError(cbt, cast.ToStringE, h.strictTypes), nil
 	case func(context.Context, bool) error:
-		return toHandlerWithError(cbt, cast.ToBoolE), nil
+		return toHandlerWithError(cbt, cast.ToBoolE, h.strictTypes), nil
 	case func(context.Context, bool):
-		return toHandlerNoError(cbt, cast.ToBoolE), nil
+		return toHandlerNoError(cbt, cast.ToBoolE, h.strictTypes), nil
 
 	// Time and duration
 	case func(context.Context, time.Time) error:
-		return toHandlerWithError(cbt, cast.ToTimeE), nil
+		return toHandlerWithError(cbt, cast.ToTimeE, h.strictTypes), nil
 	case func(context.Context, time.Time):
-		return toHandlerNoError(cbt, cast.ToTimeE), nil
+		return toHandlerNoError(cbt, cast.ToTimeE, h.strictTypes), nil
 	case func(context.Context, time.Duration) error:
-		return toHandlerWithError(cbt, cast.ToDurationE), nil
+		return toHandlerWithError(cbt, cast.ToDurationE, h.strictTypes), nil
 	case func(context.Context, time.Duration):
-		return toHandlerNoError(cbt, cast.ToDurationE), nil
+		return toHandlerNoError(cbt, cast.ToDurationE, h.strictTypes), nil
 
 	// Slices and maps
 	case func(context.Context, []string) error:
-		return toHandlerWithError(cbt, cast.ToStringSliceE), nil
+		return toHandlerWithError(cbt, cast.ToStringSliceE, h.strictTypes), nil
 	case func(context.Context, []string):
-		return toHandlerNoError(cbt, cast.ToStringSliceE), nil
+		return toHandlerNoError(cbt, cast.ToStringSliceE, h.strictTypes), nil
 	case func(context.Context, map[string]any) error:
-		return toHandlerWithError(cbt, cast.ToStringMapE), nil
+		return toHandlerWithError(cbt, cast.ToStringMapE, h.strictTypes), nil
 	case func(context.Context, map[string]any):
-		return toHandlerNoError(cbt, cast.ToStringMapE), nil
+		return toHandlerNoError(cbt, cast.ToStringMapE, h.strictTypes), nil
+
+	// Raw bytes
+	case func(context.Context, []byte) error:
+		return toHandlerWithError(cbt, toBytesE, h.strictTypes), nil
+	case func(context.Context, []byte):
+		return toHandlerNoError(cbt, toBytesE, h.strictTypes), nil
+	case func(context.Context, json.RawMessage) error:
+		return toHandlerWithError(cbt, toRawMessageE, h.strictTypes), nil
+	case func(context.Context, json.RawMessage):
+		return toHandlerNoError(cbt, toRawMessageE, h.strictTypes), nil
 	case func(ctx context.Context, a any) error:
 		return func(ctx context.Context, t *Topic, p any) error {
 			return cbt(ctx, p)
@@ -177,9 +285,59 @@ func (h *Hub) ToHandler(ctx context.Context, cb any) (Handler, error) {
 			return nil
 		}, nil
 
+	// Reply-returning
+	case func(ctx context.Context, a any) (any, error):
+		return func(ctx context.Context, t *Topic, p any) error {
+			v, err := cbt(ctx, p)
+			addReply(ctx, v, err)
+			return err
+		}, nil
+
 	// default
 	default:
+		// Struct parameters with automatic decoding
+		if ret := toHandlerReflect(cb, h.strictTypes); ret != nil {
+			return ret, nil
+		}
 		// Return error for unsupported types
 		return nil, fmt.Errorf("unsupported callback type: %T", cb)
 	}
 }
+
+// toBytesE converts payload to raw bytes:
+//   - string is converted directly
+//   - encoding.TextMarshaler and encoding.BinaryMarshaler are marshaled,
+//     text form is preferred
+//   - other types are encoded as JSON
+func toBytesE(p any) ([]byte, error) {
+	switch v := p.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case json.RawMessage:
+		return v, nil
+	case encoding.TextMarshaler:
+		return v.MarshalText()
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// toRawMessageE converts payload to JSON:
+//   - []byte and string are passed through as already encoded JSON
+//   - other types are encoded with json.Marshal
+func toRawMessageE(p any) (json.RawMessage, error) {
+	switch v := p.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return json.RawMessage(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,161 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// toHandlerReflect wraps callbacks with parameter of arbitrary type T:
+//
+//	func(ctx context.Context, v T) error
+//	func(ctx context.Context, v T)
+//
+// Payload is type asserted to T, CastError is returned on mismatch.
+// Struct (and *struct) parameters additionally accept JSON-like payloads.
+//
+// Reply-returning callbacks are supported as well:
+//
+//	func(ctx context.Context, v T) (R, error)
+//
+// their results are routed to Request/Gather caller.
+// In strict mode payload must be assignable to T, no conversion is made.
+// Returns nil if the callback has other signature.
+func toHandlerReflect(cb any, strict bool) Handler {
+	if cb == nil {
+		return nil
+	}
+	fn := reflect.ValueOf(cb)
+	ft := fn.Type()
+	if ft.Kind() != reflect.Func || ft.IsVariadic() || ft.NumIn() != 2 || ft.In(0) != contextType {
+		return nil
+	}
+	switch {
+	case ft.NumOut() == 0:
+	case ft.NumOut() == 1 && ft.Out(0) == errorType:
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+	default:
+		return nil
+	}
+
+	pt := ft.In(1)
+	return func(ctx context.Context, t *Topic, p any) error {
+		arg, err := convertPayload(p, pt, strict)
+		if err != nil {
+			return newPayloadCastError(err, pt, p, t)
+		}
+		out := fn.Call([]reflect.Value{reflect.ValueOf(&ctx).Elem(), arg})
+		if len(out) == 0 {
+			return nil
+		}
+
+		var cbErr error
+		if e := out[len(out)-1]; !e.IsNil() {
+			cbErr = e.Interface().(error)
+		}
+		if len(out) == 2 {
+			addReply(ctx, out[0].Interface(), cbErr)
+		}
+		return cbErr
+	}
+}
+
+// structType returns struct type for Struct and *Struct, nil otherwise
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// convertPayload converts payload to value of type pt.
+// Payloads of the same type (or pointer/value of it) are used directly,
+// then conversion registered with RegisterCast is tried,
+// map[string]any, []byte and json.RawMessage are decoded as JSON into structs.
+// In strict mode only payloads assignable to pt are accepted.
+func convertPayload(p any, pt reflect.Type, strict bool) (reflect.Value, error) {
+	if p == nil && pt.Kind() == reflect.Interface {
+		return reflect.Zero(pt), nil
+	}
+	if p != nil {
+		v := reflect.ValueOf(p)
+		if v.Type().AssignableTo(pt) {
+			return v, nil
+		}
+	}
+	if strict {
+		return reflect.Value{}, strictTypeError(p, pt)
+	}
+	if p != nil {
+		v := reflect.ValueOf(p)
+
+		// Value payload for pointer parameter and vice versa
+		if pt.Kind() == reflect.Pointer && v.Type().AssignableTo(pt.Elem()) {
+			ptr := reflect.New(pt.Elem())
+			ptr.Elem().Set(v)
+			return ptr, nil
+		}
+		if v.Kind() == reflect.Pointer && !v.IsNil() && v.Type().Elem().AssignableTo(pt) {
+			return v.Elem(), nil
+		}
+	}
+
+	if fn, ok := lookupCast(pt); ok {
+		v, err := fn(p)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if v == nil {
+			return reflect.Zero(pt), nil
+		}
+		return reflect.ValueOf(v), nil
+	}
+
+	if st := structType(pt); st != nil {
+		if v, ok, err := decodeStruct(p, st); ok {
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if pt.Kind() == reflect.Pointer {
+				return v, nil
+			}
+			return v.Elem(), nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("unable to cast %#v of type %T to %s", p, p, pt)
+}
+
+// decodeStruct decodes JSON-like payloads into new value of struct type st.
+// Returns pointer to the struct, false if the payload is not decodable.
+func decodeStruct(p any, st reflect.Type) (reflect.Value, bool, error) {
+	var data []byte
+	switch pv := p.(type) {
+	case []byte:
+		data = pv
+	case json.RawMessage:
+		data = pv
+	case map[string]any:
+		var err error
+		if data, err = json.Marshal(pv); err != nil {
+			return reflect.Value{}, true, err
+		}
+	default:
+		return reflect.Value{}, false, nil
+	}
+
+	ptr := reflect.New(st)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return reflect.Value{}, true, err
+	}
+	return ptr, true, nil
+}
@@ -0,0 +1,153 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type testOrder struct {
+	ID    string  `json:"id"`
+	Total float64 `json:"total"`
+}
+
+func TestStructCallbacks(t *testing.T) {
+	h := New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		payload any
+		wantErr bool
+	}{
+		{"same type", testOrder{ID: "1", Total: 10}, false},
+		{"pointer", &testOrder{ID: "1", Total: 10}, false},
+		{"map", map[string]any{"id": "1", "total": 10}, false},
+		{"json bytes", []byte(`{"id":"1","total":10}`), false},
+		{"raw message", json.RawMessage(`{"id":"1","total":10}`), false},
+		{"invalid json", []byte(`{"id":1`), true},
+		{"unsupported payload", 42, true},
+		{"nil payload", nil, true},
+	}
+
+	callbacks := map[string]any{
+		"value": func(ctx context.Context, o testOrder) error {
+			if o.ID != "1" || o.Total != 10 {
+				return errors.New("unexpected value")
+			}
+			return nil
+		},
+		"pointer": func(ctx context.Context, o *testOrder) error {
+			if o == nil || o.ID != "1" || o.Total != 10 {
+				return errors.New("unexpected value")
+			}
+			return nil
+		},
+	}
+
+	for cbName, cb := range callbacks {
+		handler, err := h.ToHandler(ctx, cb)
+		if err != nil {
+			t.Fatalf("ToHandler(%s) error = %v", cbName, err)
+		}
+
+		for _, tt := range tests {
+			t.Run(cbName+"/"+tt.name, func(t *testing.T) {
+				err := handler(ctx, T("type=order"), tt.payload)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("handler() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				var ce *CastError
+				if tt.wantErr && !errors.As(err, &ce) {
+					t.Errorf("Expected CastError, got %T", err)
+				}
+			})
+		}
+	}
+}
+
+func TestStructCallbackNoError(t *testing.T) {
+	h := New()
+	ctx := context.Background()
+
+	var got testOrder
+	if _, err := h.Subscribe(ctx, T("type=order"), func(ctx context.Context, o testOrder) {
+		got = o
+	}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	h.Publish(ctx, T("type=order"), map[string]any{"id": "7"}, Sync(true))
+	if got.ID != "7" {
+		t.Errorf("Received %v, want ID 7", got)
+	}
+}
+
+func TestStructCallbackHandlerError(t *testing.T) {
+	h := New()
+	ctx := context.Background()
+	want := errors.New("handler error")
+
+	handler, err := h.ToHandler(ctx, func(ctx context.Context, o testOrder) error {
+		return want
+	})
+	if err != nil {
+		t.Fatalf("ToHandler() error = %v", err)
+	}
+	if err := handler(ctx, T(), testOrder{}); err != want {
+		t.Errorf("handler() error = %v, want %v", err, want)
+	}
+}
+
+func TestReflectFallback(t *testing.T) {
+	h := New()
+	ctx := context.Background()
+
+	t.Run("slice of structs", func(t *testing.T) {
+		handler, err := h.ToHandler(ctx, func(ctx context.Context, orders []testOrder) error {
+			if len(orders) != 2 {
+				return errors.New("unexpected value")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ToHandler() error = %v", err)
+		}
+		if err := handler(ctx, T(), []testOrder{{}, {}}); err != nil {
+			t.Errorf("handler() error = %v", err)
+		}
+		var ce *CastError
+		if err := handler(ctx, T(), []int{1, 2}); !errors.As(err, &ce) {
+			t.Errorf("Expected CastError, got %v", err)
+		}
+	})
+
+	t.Run("interface parameter", func(t *testing.T) {
+		handler, err := h.ToHandler(ctx, func(ctx context.Context, e error) error {
+			if e.Error() != "boom" {
+				return errors.New("unexpected value")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ToHandler() error = %v", err)
+		}
+		if err := handler(ctx, T(), errors.New("boom")); err != nil {
+			t.Errorf("handler() error = %v", err)
+		}
+		if err := handler(ctx, T(), "boom"); err == nil {
+			t.Error("Expected error for payload not implementing interface")
+		}
+	})
+
+	t.Run("nil context", func(t *testing.T) {
+		handler, err := h.ToHandler(ctx, func(ctx context.Context, ch chan int) {})
+		if err != nil {
+			t.Fatalf("ToHandler() error = %v", err)
+		}
+		if err := handler(nil, T(), make(chan int)); err != nil {
+			t.Errorf("handler() error = %v", err)
+		}
+	})
+}
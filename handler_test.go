@@ -2,8 +2,10 @@ package hub
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -52,12 +54,20 @@ func TestToHandler(t *testing.T) {
 			errContains: "unsupported callback type: func(string) error",
 		},
 		{
-			name: "unsupported type",
-			cb: func(ctx context.Context, ch chan int) error {
+			name: "too many parameters",
+			cb: func(ctx context.Context, a int, b int) error {
 				return nil
 			},
 			wantErr:     true,
-			errContains: "unsupported callback type: func(context.Context, chan int) error",
+			errContains: "unsupported callback type: func(context.Context, int, int) error",
+		},
+		{
+			name: "invalid result with custom type",
+			cb: func(ctx context.Context, ch chan int) int {
+				return 0
+			},
+			wantErr:     true,
+			errContains: "unsupported callback type: func(context.Context, chan int) int",
 		},
 
 		// Valid callbacks
@@ -87,6 +97,19 @@ func TestToHandler(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "topic callback",
+			cb: func(ctx context.Context, t *Topic) error {
+				return nil
+			},
+			wantErr: false,
+		},
+		{
+			name: "topic callback (no error)",
+			cb: func(ctx context.Context, t *Topic) {
+			},
+			wantErr: false,
+		},
 		{
 			name: "generic any callback",
 			cb: func(ctx context.Context, a any) error {
@@ -127,6 +150,8 @@ func TestToHandler(t *testing.T) {
 		{"[]string", func(ctx context.Context, s []string) error { return nil }},
 		{"map[string]any", func(ctx context.Context, m map[string]any) error { return nil }},
 		{"any", func(ctx context.Context, a any) error { return nil }},
+		{"[]byte", func(ctx context.Context, b []byte) error { return nil }},
+		{"json.RawMessage", func(ctx context.Context, m json.RawMessage) error { return nil }},
 
 		// without error
 		{"string (no error)", func(ctx context.Context, s string) {}},
@@ -148,6 +173,12 @@ func TestToHandler(t *testing.T) {
 		{"[]string (no error)", func(ctx context.Context, s []string) {}},
 		{"map[string]any (no error)", func(ctx context.Context, m map[string]any) {}},
 		{"any (no error)", func(ctx context.Context, a any) {}},
+		{"[]byte (no error)", func(ctx context.Context, b []byte) {}},
+		{"json.RawMessage (no error)", func(ctx context.Context, m json.RawMessage) {}},
+
+		// reflection fallback
+		{"chan int", func(ctx context.Context, ch chan int) error { return nil }},
+		{"[]int (no error)", func(ctx context.Context, s []int) {}},
 	}
 
 	for _, typ := range supportedTypes {
@@ -369,3 +400,193 @@ func TestWrappedCallbackExecution(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[:len(substr)] == substr
 }
+
+func TestToBytesE(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload any
+		want    string
+	}{
+		{"bytes", []byte("raw"), "raw"},
+		{"string", "text", "text"},
+		{"raw message", json.RawMessage(`{"a":1}`), `{"a":1}`},
+		{"text marshaler", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "2024-01-02T03:04:05Z"},
+		{"number", 42, "42"},
+		{"map", map[string]any{"a": 1}, `{"a":1}`},
+		{"nil", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toBytesE(tt.payload)
+			if err != nil {
+				t.Fatalf("toBytesE() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("toBytesE() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		h := New()
+		handler, _ := h.ToHandler(context.Background(), func(ctx context.Context, b []byte) {})
+		err := handler(context.Background(), T(), make(chan int))
+		if _, ok := err.(*CastError); !ok {
+			t.Errorf("Expected CastError, got %v", err)
+		}
+	})
+}
+
+func TestToRawMessageE(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload any
+		want    string
+	}{
+		{"bytes", []byte(`{"a":1}`), `{"a":1}`},
+		{"string", `[1,2]`, `[1,2]`},
+		{"raw message", json.RawMessage(`null`), `null`},
+		{"struct", struct {
+			A int `json:"a"`
+		}{1}, `{"a":1}`},
+		{"text marshaler", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), `"2024-01-02T03:04:05Z"`},
+		{"nil", nil, `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toRawMessageE(tt.payload)
+			if err != nil {
+				t.Fatalf("toRawMessageE() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("toRawMessageE() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopicCallback(t *testing.T) {
+	h := New()
+	ctx := context.Background()
+
+	var got []*Topic
+	h.Subscribe(ctx, T("type=cache"), func(ctx context.Context, topic *Topic) error {
+		got = append(got, topic)
+		return nil
+	})
+	h.Subscribe(ctx, T("type=cache"), func(ctx context.Context, topic *Topic) {
+		got = append(got, topic)
+	})
+
+	h.Publish(ctx, T("type=cache", "key=users"), "payload is ignored", Sync(true))
+
+	if len(got) != 2 {
+		t.Fatalf("Handlers called %d times, want 2", len(got))
+	}
+	for _, topic := range got {
+		if topic.Get("key") != "users" {
+			t.Errorf("Unexpected topic %v", topic)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	step := func(name string, err error) Handler {
+		return func(ctx context.Context, t *Topic, p any) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	errB := errors.New("b failed")
+	err := Chain(step("a", nil), nil, step("b", errB), step("c", nil))(context.Background(), T("type=x"), 1)
+	if !errors.Is(err, errB) {
+		t.Errorf("Chain() error = %v, want %v", err, errB)
+	}
+	if !reflect.DeepEqual(calls, []string{"a", "b"}) {
+		t.Errorf("calls = %v, want [a b]", calls)
+	}
+
+	if err := Chain()(context.Background(), nil, nil); err != nil {
+		t.Errorf("empty Chain() error = %v", err)
+	}
+}
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	step := func(name string, err error) Handler {
+		return func(ctx context.Context, t *Topic, p any) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	errA := errors.New("a failed")
+	errC := errors.New("c failed")
+	err := Tee(step("a", errA), step("b", nil), nil, step("c", errC))(context.Background(), T("type=x"), 1)
+	if !errors.Is(err, errA) || !errors.Is(err, errC) {
+		t.Errorf("Tee() error = %v, want both errors", err)
+	}
+	if !reflect.DeepEqual(calls, []string{"a", "b", "c"}) {
+		t.Errorf("calls = %v, want [a b c]", calls)
+	}
+
+	if err := Tee(step("d", nil))(context.Background(), nil, nil); err != nil {
+		t.Errorf("Tee() error = %v", err)
+	}
+}
+
+func TestRegisterConverter(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+
+	type plugin func(s string)
+	if _, err := h.ToHandler(ctx, plugin(func(string) {})); err == nil {
+		t.Fatal("expected error before registration")
+	}
+
+	var got []string
+	h.RegisterConverter(nil)
+	h.RegisterConverter(func(ctx context.Context, cb any) (Handler, error) {
+		if fn, ok := cb.(plugin); ok {
+			return func(ctx context.Context, t *Topic, p any) error {
+				fn(t.String())
+				return nil
+			}, nil
+		}
+		return nil, nil
+	})
+
+	_, err := h.Subscribe(ctx, T("type=alert"), plugin(func(s string) {
+		got = append(got, s)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Publish(ctx, T("type=alert"), nil, Sync(true))
+	if len(got) != 1 || got[0] != "type=alert" {
+		t.Errorf("got %v, want [type=alert]", got)
+	}
+
+	// Registration is safe concurrently with subscriptions
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.RegisterConverter(func(ctx context.Context, cb any) (Handler, error) { return nil, nil })
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = h.Subscribe(ctx, T("type=x"), func(ctx context.Context) {})
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,52 @@
+package hub
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// HealthInfo is a snapshot of hub load returned by Hub.Health
+type HealthInfo struct {
+	Subscriptions int       // Number of active subscriptions
+	InFlight      int64     // Handlers being executed
+	Pending       int64     // Async deliveries scheduled but not started yet
+	Dropped       uint64    // Deliveries dropped by the hub
+	Expired       uint64    // Deliveries dropped because of EventTTL, included in Dropped
+	Errors        uint64    // Handler calls returned error
+	LastError     time.Time // Time of the last handler error, zero if none
+}
+
+// healthCounters holds counters behind HealthInfo
+type healthCounters struct {
+	inFlight  atomic.Int64
+	pending   atomic.Int64
+	dropped   atomic.Uint64
+	expired   atomic.Uint64
+	errors    atomic.Uint64
+	lastError atomic.Int64 // Unix nanoseconds, 0 if none
+}
+
+// Health returns current load of the hub, so services can wire it
+// into /healthz endpoints and shed load when the hub is saturated.
+//
+// Example:
+//
+//	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+//	    if h.Health().Pending > 10000 {
+//	        w.WriteHeader(http.StatusServiceUnavailable)
+//	    }
+//	})
+func (h *Hub) Health() HealthInfo {
+	info := HealthInfo{
+		Subscriptions: h.Len(),
+		InFlight:      h.health.inFlight.Load(),
+		Pending:       h.health.pending.Load(),
+		Dropped:       h.health.dropped.Load(),
+		Expired:       h.health.expired.Load(),
+		Errors:        h.health.errors.Load(),
+	}
+	if ts := h.health.lastError.Load(); ts != 0 {
+		info.LastError = time.Unix(0, ts)
+	}
+	return info
+}
@@ -0,0 +1,49 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	if info := h.Health(); info != (HealthInfo{}) {
+		t.Errorf("Health() of new hub = %+v", info)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	_, _ = h.Subscribe(ctx, T("type=slow"), func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	_, _ = h.Subscribe(ctx, T("type=fail"), func(ctx context.Context) error {
+		return errors.New("fail")
+	})
+
+	done := make(chan struct{})
+	_ = h.Publish(ctx, T("type=slow"), nil, OnFinish(func(ctx context.Context) {
+		close(done)
+	}))
+	<-started
+
+	info := h.Health()
+	if info.Subscriptions != 2 || info.InFlight != 1 || info.Pending != 0 {
+		t.Errorf("Health() while handler is running = %+v", info)
+	}
+	close(release)
+	<-done
+
+	before := time.Now()
+	_ = h.Publish(ctx, T("type=fail"), nil, Sync(true))
+
+	info = h.Health()
+	if info.InFlight != 0 || info.Errors != 1 || info.LastError.Before(before) {
+		t.Errorf("Health() after error = %+v", info)
+	}
+}
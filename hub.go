@@ -2,39 +2,78 @@ package hub
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/lomik/hub/pkg/kv"
 )
 
 // Hub implements a pub/sub system with optimized subscription matching
 // using multi-level indexes for efficient event distribution
 type Hub struct {
-	sync.RWMutex
-	seq atomic.Uint64 // Atomic counter for generating subscription IDs
+	sync.RWMutex               // Protects subs
+	seq          atomic.Uint64 // Atomic counter for generating subscription IDs
 
-	all *sublist
-	// Index structures:
-	indexKeyValue map[string]map[string]*sublist // Exact key-value pair index
-	indexKey      map[string]*sublist            // Wildcard value index (key=*)
-	indexEmpty    *sublist                       // Subscriptions without topic attributes
+	subs map[SubID]*sub // All active subscriptions by ID
+	// Index structures, sharded by key. Publish reads immutable snapshots,
+	// Subscribe and Unsubscribe replace them while holding the Hub's lock
+	shards     [numShards]shard
+	indexEmpty atomic.Pointer[sublist] // Subscriptions without topic attributes
+	exact      exactIndex              // Counters for the exact-topic fast path
 
 	// customize
 	convertToHandler [](func(ctx context.Context, cb any) (Handler, error))
+	policy           *TopicPolicy
+	authorizers      []Authorizer
+	upcasters        map[string]upcaster // By source version, see WithUpcaster
+	encryptor        Encryptor           // nil if stored payloads are not encrypted
+	intern           *internCache        // nil if interning is disabled
+	sysAttrs         *SystemAttributes   // nil if stamping is disabled
+	pubSeq           atomic.Uint64       // Publish counter for EventID and AttrSequence
+	strictTypes      bool                // Disable cast-based payload coercion
+	onError          []func(ctx context.Context, t *Topic, id SubID, err error)
+	metrics          Metrics   // nil if metrics are disabled
+	audit            *auditLog // nil if audit log is disabled
+	health           healthCounters
+	cache            *matchCache // nil if match cache is disabled
+	hints            capacityHints
+	profiles         []Profile
+	pendingSem       chan struct{} // nil if pending deliveries are not limited
+	overflow         OverflowPolicy
+	middleware       []Middleware
+	workers          *workerPool       // nil unless WithWorkers is set
+	executor         func(task func()) // nil to run handlers in new goroutines
+	store            Store             // nil unless WithStore is set
+	storeMu          sync.Mutex        // Serializes store access and durable queueing
+	durables         atomic.Pointer[[]*sub]
+	closed           atomic.Bool
+	active           activity // Async work waited by Drain
+	deliveries       activity // Deliveries waited by WaitIdle
+	clock            Clock
+	quotas           quotas
+	added            chan struct{} // Closed by notifyAdded, nil if nobody waits
+	tenantsMu        sync.Mutex
+	tenants          map[string]*Tenant // By name, see Hub.Tenant
 }
 
 // New creates and initializes a new Hub instance
 func New(opts ...HubOption) *Hub {
-	h := &Hub{
-		all:           &sublist{},
-		indexKeyValue: make(map[string]map[string]*sublist),
-		indexKey:      make(map[string]*sublist),
-		indexEmpty:    &sublist{},
-	}
-
+	h := &Hub{}
 	for _, o := range opts {
 		o.modifyHub(h)
 	}
 
+	if h.clock == nil {
+		h.clock = realClock{}
+	}
+	h.subs = make(map[SubID]*sub, h.hints.subs)
+	h.resetIndexes()
+	h.durables.Store(&[]*sub{})
+
 	return h
 }
 
@@ -47,12 +86,20 @@ func New(opts ...HubOption) *Hub {
 //  2. With original topic:
 //     func(ctx context.Context, topic *Topic, payload any) error
 //     func(ctx context.Context, topic *Topic, payload any)
+//     func(ctx context.Context, topic *Topic) error
+//     func(ctx context.Context, topic *Topic)
 //  3. Typed payload:
 //     func(ctx context.Context, payload Type) error
 //     func(ctx context.Context, payload Type)
 //  4. Generic payload without topic:
 //     func(ctx context.Context, payload any) error
 //     func(ctx context.Context, payload any)
+//  5. Any other payload type (via reflection):
+//     func(ctx context.Context, payload T) error
+//     func(ctx context.Context, payload T)
+//  6. Reply-returning, the result is routed to Request/Gather caller:
+//     func(ctx context.Context, payload any) (any, error)
+//     func(ctx context.Context, payload T) (R, error)
 //
 // Supported payload types (Type):
 //   - All integer types (int8-int64, uint8-uint64)
@@ -60,6 +107,10 @@ func New(opts ...HubOption) *Hub {
 //   - String and boolean
 //   - Time types (time.Time, time.Duration)
 //   - Common collections ([]string, map[string]any)
+//   - Raw bytes ([]byte), other payloads are marshaled
+//   - JSON (json.RawMessage), other payloads are encoded as JSON
+//   - Any other type T, the payload must be assignable to T
+//     (structs also accept JSON-like payloads)
 //
 // Parameters:
 //   - ctx: Context for cancellation and timeouts
@@ -72,11 +123,14 @@ func New(opts ...HubOption) *Hub {
 //   - Error if:
 //   - Callback signature is invalid
 //   - Topic is nil
-//   - Unsupported parameter type in callback
+//   - Topic violates the hub TopicPolicy
+//   - Hub is closed (ErrClosed)
 //
 // Behavior:
 //   - For typed callbacks, attempts direct type assertion first
 //   - Falls back to automatic conversion using spf13/cast
+//   - Struct callbacks decode map[string]any, []byte and json.RawMessage
+//     payloads as JSON
 //   - Returns conversion errors during event delivery
 //   - Supports all standard SubscribeOption configurations
 //
@@ -106,13 +160,37 @@ func New(opts ...HubOption) *Hub {
 // - The generic 'any' signature provides flexibility at small performance cost
 // - All type validation occurs during subscription, not event delivery
 func (h *Hub) Subscribe(ctx context.Context, t *Topic, cb interface{}, opts ...SubscribeOption) (SubID, error) {
+	if h.policy != nil {
+		if err := h.policy.check(t, false); err != nil {
+			return 0, err
+		}
+	}
+	if err := h.authorize(ctx, OpSubscribe, t); err != nil {
+		return 0, err
+	}
+
 	eventCb, err := h.ToHandler(ctx, cb)
 	if err != nil {
 		return 0, err
 	}
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		eventCb = h.middleware[i](eventCb)
+	}
+
+	if h.intern != nil {
+		// Share keys and values between index and subscriptions
+		t = t.intern()
+	}
 
 	h.Lock()
-	defer h.Unlock()
+	if h.closed.Load() {
+		h.Unlock()
+		return 0, ErrClosed
+	}
+	if err := h.checkSubscribe(t); err != nil {
+		h.Unlock()
+		return 0, err
+	}
 
 	id := SubID(h.seq.Add(1))
 	s := &sub{
@@ -121,6 +199,7 @@ func (h *Hub) Subscribe(ctx context.Context, t *Topic, cb interface{}, opts ...S
 		handler: eventCb,
 	}
 
+	h.applySubscribeProfiles(ctx, s)
 	for _, o := range opts {
 		if o == nil {
 			continue
@@ -128,36 +207,87 @@ func (h *Hub) Subscribe(ctx context.Context, t *Topic, cb interface{}, opts ...S
 		o.modifySub(ctx, s)
 	}
 
+	if s.durable != "" && s.serial == nil {
+		// offsets are committed in delivery order
+		s.serial = &serialQueue{}
+	}
+	start := false
+	if s.durable != "" && h.store != nil {
+		if start, err = h.addDurable(ctx, s); err != nil {
+			h.Unlock()
+			return 0, err
+		}
+	}
+
 	h.add(ctx, s)
+	if h.metrics != nil {
+		h.metrics.Subscriptions(len(h.subs))
+	}
+	h.Unlock()
+
+	// replayed events are delivered outside of the lock:
+	// an executor may run the worker in this goroutine
+	if start {
+		h.spawn(0, s.serial.work)
+	}
 	return id, nil
 }
 
-// add adds a subscription to all relevant indexes
+// SubscribeT registers a type-safe handler for payloads of type T.
+// Unlike Subscribe, the callback signature is checked at compile time
+// and any type (structs, slices of structs, etc.) is supported.
+// The payload is only type asserted, no conversion is performed:
+// events with payload of other type produce CastError and don't
+// invoke the callback.
+//
+// Example:
+//
+//	type Order struct {
+//	    ID    string
+//	    Total float64
+//	}
+//
+//	id, err := hub.SubscribeT(ctx, h, hub.T("type=order"),
+//	    func(ctx context.Context, o Order) error {
+//	        log.Printf("Order %s: %.2f", o.ID, o.Total)
+//	        return nil
+//	    },
+//	)
+func SubscribeT[T any](ctx context.Context, h *Hub, t *Topic, cb func(ctx context.Context, v T) error, opts ...SubscribeOption) (SubID, error) {
+	return h.Subscribe(ctx, t, Handler(func(ctx context.Context, et *Topic, p any) error {
+		v, ok := p.(T)
+		if !ok {
+			var zero T
+			err := fmt.Errorf("unable to cast %#v of type %T to %T", p, p, zero)
+			return newPayloadCastError(err, reflect.TypeFor[T](), p, et)
+		}
+		return cb(ctx, v)
+	}), opts...)
+}
+
+// add adds a subscription to all relevant indexes.
+// Must be called while holding the Hub's lock (h.Lock()).
 func (h *Hub) add(_ context.Context, s *sub) {
-	h.all.add(s)
+	h.subs[s.id] = s
 
-	// Process each key-value pair in the topic
-	s.topic.Each(func(k, v string) {
-		// Initialize nested maps if needed
-		if _, exists := h.indexKeyValue[k]; !exists {
-			h.indexKeyValue[k] = make(map[string]*sublist)
-		}
-		if _, exists := h.indexKeyValue[k][v]; !exists {
-			h.indexKeyValue[k][v] = &sublist{}
-		}
-		h.indexKeyValue[k][v].add(s)
+	// Counters go first: a pattern disables the fast path before
+	// it becomes visible in the indexes
+	h.exact.count(s.topic, 1)
+	h.updateExact(s, true)
+	h.quotas.countTopic(s.topic, 1)
+	h.notifyAdded()
 
-		// Add to wildcard index for this key
-		if _, exists := h.indexKey[k]; !exists {
-			h.indexKey[k] = &sublist{}
-		}
-		h.indexKey[k].add(s)
+	// Process each key-value pair in the topic
+	h.updateShards(s.topic, func(ix *indexes, p kv.KV) {
+		ix.add(s, p)
 	})
 
 	// Handle empty topics
 	if s.topic.Len() == 0 {
-		h.indexEmpty.add(s)
+		h.indexEmpty.Store(h.indexEmpty.Load().with(s))
 	}
+
+	h.cache.invalidate()
 }
 
 // Publish sends an event to all subscribers of the specified topic with the given payload.
@@ -170,6 +300,13 @@ func (h *Hub) add(_ context.Context, s *sub) {
 //   - hub.Wait(true) - wait for all handlers to complete
 //   - hub.Sync(true) - process handlers synchronously
 //   - hub.OnFinish() - add completion callback
+//   - hub.WaitFirstError(true) - wait and return the first handler error
+//
+// Returns:
+//   - Error if topic violates the hub TopicPolicy, nothing is delivered then
+//   - First handler error if hub.WaitFirstError(true) is set
+//   - Error if the event can't be written to the Store, nothing is delivered then
+//   - ErrClosed after Close
 //
 // Behavior:
 //   - Creates a new Event with the provided topic and payload
@@ -199,170 +336,340 @@ func (h *Hub) add(_ context.Context, s *sub) {
 // - The payload will be automatically converted when subscribers use typed callbacks
 // - Topic is required (use hub.T() to create topics)
 // - Safe for concurrent use
-func (h *Hub) Publish(ctx context.Context, topic *Topic, payload any, opts ...PublishOption) {
+func (h *Hub) Publish(ctx context.Context, topic *Topic, payload any, opts ...PublishOption) error {
+	return h.publish(ctx, topic, payload, nil, opts)
+}
+
+// publish implements Publish, d is nil for events that can't be canceled
+func (h *Hub) publish(ctx context.Context, topic *Topic, payload any, d *Delivery, opts []PublishOption) error {
+	if h.closed.Load() {
+		return ErrClosed
+	}
+	if h.policy != nil {
+		if err := h.policy.check(topic, true); err != nil {
+			return err
+		}
+	}
+	if err := h.authorize(ctx, OpPublish, topic); err != nil {
+		return err
+	}
+	if r := h.quotas.rate; r != nil && !r.allow(h.clock.Now()) {
+		return &QuotaError{Quota: QuotaPublishRate, Limit: r.rate, Topic: topic}
+	}
+	if h.upcasters != nil {
+		var err error
+		topic, payload, err = h.upcast(ctx, topic, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	id := EventID(h.pubSeq.Add(1))
+	if h.sysAttrs != nil {
+		topic = h.stamp(topic, id)
+	}
+	if h.metrics != nil {
+		h.metrics.EventPublished(topic)
+	}
+
 	e := &event{
-		topic:   topic,
-		payload: payload,
+		id:       id,
+		topic:    topic,
+		payload:  payload,
+		delivery: d,
+	}
+	ctx = context.WithValue(ctx, ctxKeyEvent, e)
+
+	if h.audit != nil {
+		h.audit.record(ctx, e, h.clock.Now())
 	}
 
+	h.applyPublishProfiles(ctx, e)
 	for _, o := range opts {
 		if o == nil {
 			continue
 		}
 		o.modifyEvent(ctx, e)
 	}
+	if e.ttl > 0 {
+		e.expires = h.clock.Now().Add(e.ttl)
+	}
 
-	if e.sync {
-		h.publishEventSync(ctx, e)
-		return
+	if h.store != nil {
+		if err := h.persist(ctx, e); err != nil {
+			return err
+		}
 	}
 
-	if e.wait {
-		h.publishEventAsyncWait(ctx, e)
+	if e.group != nil {
+		return h.publishEventGroup(ctx, e)
+	}
+
+	h.deliver(ctx, e)
+	return nil
+}
+
+// deliver runs handlers of the event in the selected mode
+func (h *Hub) deliver(ctx context.Context, e *event) {
+	if e.sync {
+		h.publishEventSync(ctx, e)
 		return
 	}
 
-	if e.hasOnFinish() {
-		h.publishEventAsyncNoWaitFinish(ctx, e)
+	if e.wait || e.hasOnFinish() {
+		h.publishEventAsync(ctx, e)
 		return
 	}
 
 	h.publishEventAsyncNoWaitNoFinish(ctx, e)
 }
 
-// match finds subscriptions that match the event.
-// Must be called while holding the Hub's read lock (h.RLock()).
-func (h *Hub) match(t *Topic, cb func(s *sub)) int {
+// match appends subscriptions that match the event topic to dst.
+// Indexes are read from immutable snapshots without locks.
+func (h *Hub) match(t *Topic, dst []*sub) []*sub {
+	// Concrete topic with only exact subscriptions possible
+	if dst, ok := h.matchExact(t, dst); ok {
+		return dst
+	}
+
 	// Collect potential candidate subscriptions lists
-	candidates := make([]*sublist, 0)
+	var buf [8]*sublist
+	candidates := buf[:0]
 
 	// Query indexes for each event attribute
-	t.Each(func(k, v string) {
-		// For any values add only list by key
-		if v == Any {
-			if sl, exists := h.indexKey[k]; exists {
-				candidates = append(candidates, sl)
-			}
-			return
-		}
+	for p := range t.pairs() {
+		candidates = h.shards[shardIndex(p.Key())].load().candidates(p, candidates)
+	}
 
-		// Check exact value matches
-		if vals, exists := h.indexKeyValue[k]; exists {
-			if sl, exists := vals[v]; exists {
-				candidates = append(candidates, sl)
-			}
-			// Check wildcard matches for this key
-			if sl, exists := vals[Any]; exists {
-				candidates = append(candidates, sl)
+	// Include subscriptions without topic attributes
+	if empty := h.indexEmpty.Load(); empty.len() > 0 {
+		candidates = append(candidates, empty)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return dst
+	case 1:
+		// Single list (typical for single-key topics) is already sorted
+		// and has no duplicates, scan it directly
+		for _, s := range candidates[0].lst {
+			if s.topic.Match(t) {
+				dst = append(dst, s)
 			}
 		}
-	})
-
-	// Include subscriptions without topic attributes
-	if h.indexEmpty.len() > 0 {
-		candidates = append(candidates, h.indexEmpty)
+		return dst
 	}
 
-	var matched int
 	for s := range mergeSubLists(candidates...) {
 		if s.topic.Match(t) {
-			matched++
-			cb(s)
+			dst = append(dst, s)
 		}
 	}
-	return matched
+	return dst
 }
 
-// sync = true
-func (h *Hub) publishEventSync(ctx context.Context, e *event) {
-	var unsub []SubID
+// call invokes subscription handler and reports returned error to OnError hooks.
+// ErrUnsubscribe marks the subscription for removal instead,
+// ErrStopPropagation and canceled delivery are returned as stop.
+func (h *Hub) call(ctx context.Context, s *sub, e *event) (stop bool) {
+	if e.canceled() {
+		e.trace.skip(s, DeliveryCanceled)
+		return true
+	}
+	attempt := 1
+	if s.ack != nil {
+		attempt = max(attemptFromContext(ctx), 1)
+	}
+	if attempt == 1 && !s.sampled() {
+		e.trace.skip(s, DeliverySampledOut)
+		return false
+	}
+	if !s.acquire(ctx) {
+		// MaxInFlight limit is reached
+		h.health.dropped.Add(1)
+		e.trace.skip(s, DeliveryBusy)
+		return false
+	}
+	defer s.release()
+	if s.serial != nil {
+		s.serial.exec.Lock()
+		defer s.serial.exec.Unlock()
+	}
+	if e.expired(h.clock) {
+		// waited in a queue longer than EventTTL
+		h.health.dropped.Add(1)
+		h.health.expired.Add(1)
+		e.trace.skip(s, DeliveryExpired)
+		return false
+	}
 
-	h.RLock()
-	h.match(e.topic, func(s *sub) {
-		_ = s.call(ctx, e)
+	var ack *AckHandle
+	if s.ack != nil {
+		// redeliveries outlive the publish
+		ack = h.newAck(context.WithoutCancel(ctx), s, e, attempt)
+		ctx = context.WithValue(ctx, ctxKeyAck, ack)
+	}
+
+	var err error
+	var start time.Time
+	if e.trace != nil {
+		start = h.clock.Now()
+	}
+	h.health.inFlight.Add(1)
+	if h.metrics != nil {
+		h.metrics.HandlersInFlight(1)
+		start := time.Now()
+		err = s.call(ctx, e)
+		h.metrics.EventDelivered(e.topic, s.id, time.Since(start), err)
+		h.metrics.HandlersInFlight(-1)
+	} else {
+		err = s.call(ctx, e)
+	}
+	h.health.inFlight.Add(-1)
+	if e.trace != nil {
+		e.trace.called(s, start, h.clock.Now(), err)
+	}
+	if err == nil {
+		return false
+	}
+	stop = errors.Is(err, ErrStopPropagation)
+	if errors.Is(err, ErrUnsubscribe) {
+		s.removed.Store(true)
+		ack.Ack()
+		return stop
+	}
+	if stop {
+		ack.Ack()
+		return true
+	}
+	h.health.errors.Add(1)
+	h.health.lastError.Store(h.clock.Now().UnixNano())
+	var castErr *CastError
+	if errors.As(err, &castErr) && castErr.SubID == 0 {
+		castErr.SubID = s.id
+	}
+	for _, cb := range h.onError {
+		cb(ctx, e.topic, s.id, err)
+	}
+	if e.group != nil {
+		e.group.fail(err)
+	}
+	ack.Nack(err)
+	return false
+}
+
+// callInline calls handler in the current goroutine
+func (h *Hub) callInline(ctx context.Context, s *sub, e *event) (stop bool) {
+	h.deliveries.begin()
+	defer h.deliveries.end()
+	stop = h.call(ctx, s, e)
+	// handle limited subscription
+	if s.shouldRemove() {
+		h.Unsubscribe(ctx, s.id)
+	}
+	return stop
+}
+
+// callAsync calls handler in a new goroutine, tr may be nil
+func (h *Hub) callAsync(ctx context.Context, s *sub, e *event, tr *tracker) {
+	if h.pendingSem != nil && !h.acquirePending(ctx) {
+		// MaxPending limit is reached
+		if h.overflow == OverflowInline {
+			h.callInline(ctx, s, e)
+		} else {
+			h.health.dropped.Add(1)
+		}
+		return
+	}
+	if tr != nil {
+		tr.add()
+	}
+	h.active.begin()
+	h.deliveries.begin()
+	h.health.pending.Add(1)
+	run := func() {
+		defer h.active.end()
+		h.health.pending.Add(-1)
+		if h.pendingSem != nil {
+			<-h.pendingSem
+		}
+		h.call(ctx, s, e)
 		// handle limited subscription
 		if s.shouldRemove() {
-			unsub = append(unsub, s.id)
+			h.Unsubscribe(ctx, s.id)
 		}
-	})
-	h.RUnlock()
-
-	e.finish(ctx)
+		h.deliveries.end()
+		if tr != nil {
+			tr.done()
+		}
+	}
+	if s.serial != nil {
+		s.serial.push(e.priority, run, h.spawn)
+		return
+	}
+	h.spawn(e.priority, run)
+}
 
-	for _, sid := range unsub {
-		h.Unsubscribe(ctx, sid)
+// spawn runs task in a new goroutine, with the executor set by WithExecutor
+// or in lane prio of the worker pool set by WithWorkers
+func (h *Hub) spawn(prio int, task func()) {
+	if h.workers != nil {
+		h.workers.submit(prio, task)
+		return
+	}
+	if h.executor != nil {
+		h.executor(task)
+		return
 	}
+	go task()
 }
 
-// sync = false, wait = true
-func (h *Hub) publishEventAsyncWait(ctx context.Context, e *event) {
-	var wg sync.WaitGroup
+// sync = true
+func (h *Hub) publishEventSync(ctx context.Context, e *event) {
+	tr := h.newTracker(ctx, e)
 
-	h.RLock()
-	h.match(e.topic, func(s *sub) {
-		wg.Add(1)
-		go func(s *sub) {
-			_ = s.call(ctx, e)
-			wg.Done()
-			// handle limited subscription
-			if s.shouldRemove() {
-				// will remove after unlock
-				h.Unsubscribe(ctx, s.id)
-			}
-		}(s)
-	})
-	h.RUnlock()
+	var buf [16]*sub
+	for _, s := range h.targets(e, buf[:0]) {
+		if s.async {
+			// subscription forced to run in its own goroutine
+			h.callAsync(ctx, s, e, tr)
+			continue
+		}
+		if h.callInline(ctx, s, e) {
+			break
+		}
+	}
 
-	wg.Wait()
-	e.finish(ctx)
+	h.finishAfter(ctx, e, tr)
 }
 
-// sync = false, wait = false, hasOnFinish = true
-func (h *Hub) publishEventAsyncNoWaitFinish(ctx context.Context, e *event) {
-	var wg sync.WaitGroup
-	var once sync.Once
-
-	h.RLock()
-	n := h.match(e.topic, func(s *sub) {
-		wg.Add(1)
-		go func(s *sub) {
-			_ = s.call(ctx, e)
-			wg.Done()
-
-			once.Do(func() {
-				wg.Wait()
-				e.finish(ctx)
-			})
-
-			// handle limited subscription
-			if s.shouldRemove() {
-				// will remove after unlock
-				h.Unsubscribe(ctx, s.id)
-			}
-		}(s)
-	})
-	h.RUnlock()
+// sync = false, wait = true or hasOnFinish = true
+func (h *Hub) publishEventAsync(ctx context.Context, e *event) {
+	tr := h.newTracker(ctx, e)
 
-	if n == 0 {
-		go e.finish(ctx)
+	var buf [16]*sub
+	for _, s := range h.targets(e, buf[:0]) {
+		if s.runInline() {
+			h.callInline(ctx, s, e)
+			continue
+		}
+		h.callAsync(ctx, s, e, tr)
 	}
+
+	h.finishAfter(ctx, e, tr)
 }
 
 // sync = false, wait = false, hasOnFinish = false
 func (h *Hub) publishEventAsyncNoWaitNoFinish(ctx context.Context, e *event) {
 	// run all async and don't wait anything
-	h.RLock()
-	h.match(e.topic, func(s *sub) {
-		go func(s *sub) {
-			_ = s.call(ctx, e)
-			// handle limited subscription
-			if s.shouldRemove() {
-				// will remove after unlock
-				h.Unsubscribe(ctx, s.id)
-			}
-		}(s)
-	})
-	h.RUnlock()
+	var buf [16]*sub
+	for _, s := range h.targets(e, buf[:0]) {
+		if s.runInline() {
+			h.callInline(ctx, s, e)
+			continue
+		}
+		h.callAsync(ctx, s, e, nil)
+	}
 }
 
 // Unsubscribe removes a subscription by ID
@@ -370,45 +677,39 @@ func (h *Hub) Unsubscribe(ctx context.Context, id SubID) {
 	h.Lock()
 	defer h.Unlock()
 
-	// Find the subscription in the main list
-	idx := h.all.find(id)
-	if idx == -1 {
+	// Find the subscription
+	s, exists := h.subs[id]
+	if !exists {
 		return // Subscription not found
 	}
 
-	s := h.all.lst[idx] // Get the subscription
+	// Remove from the main map first
+	delete(h.subs, id)
+	if s.durable != "" {
+		h.removeDurable(s)
+	}
 
-	// Remove from the main list first
-	h.all.remove(id)
+	// Handler is not called anymore even if the subscription
+	// was already matched by concurrent publish
+	s.removed.Store(true)
 
 	// Remove from all key-value indexes
-	s.topic.Each(func(k, v string) {
-		// Remove from exact value index
-		if vals, exists := h.indexKeyValue[k]; exists {
-			if sl, exists := vals[v]; exists {
-				sl.remove(id)
-
-				// Cleanup empty sublists
-				if sl.len() == 0 {
-					delete(h.indexKeyValue[k], v)
-				}
-			}
-		}
-
-		// Remove from wildcard index
-		if sl, exists := h.indexKey[k]; exists {
-			sl.remove(id)
-
-			// Cleanup empty sublists
-			if sl.len() == 0 {
-				delete(h.indexKey, k)
-			}
-		}
+	h.updateShards(s.topic, func(ix *indexes, p kv.KV) {
+		ix.remove(id, p)
 	})
 
 	// Remove from empty topic index if needed
 	if s.topic.Len() == 0 {
-		h.indexEmpty.remove(id)
+		h.indexEmpty.Store(h.indexEmpty.Load().without(id))
+	}
+
+	h.updateExact(s, false)
+	h.exact.count(s.topic, -1)
+	h.quotas.countTopic(s.topic, -1)
+	h.cache.invalidate()
+
+	if h.metrics != nil {
+		h.metrics.Subscriptions(len(h.subs))
 	}
 }
 
@@ -417,16 +718,48 @@ func (h *Hub) Clear(ctx context.Context) {
 	h.Lock()
 	defer h.Unlock()
 
-	h.all = &sublist{}
-	h.indexKeyValue = make(map[string]map[string]*sublist)
-	h.indexKey = make(map[string]*sublist)
-	h.indexEmpty = &sublist{}
+	for _, s := range h.subs {
+		s.removed.Store(true)
+	}
+	h.subs = make(map[SubID]*sub, h.hints.subs)
+	h.resetIndexes()
+	h.durables.Store(&[]*sub{})
+
+	if h.metrics != nil {
+		h.metrics.Subscriptions(0)
+	}
+}
+
+// resetIndexes replaces all indexes with empty ones
+func (h *Hub) resetIndexes() {
+	for i := range h.shards {
+		h.shards[i].p.Store(newIndexes(h.hints))
+	}
+	h.indexEmpty.Store(nil)
+	h.exact.reset()
+	if h.quotas.perKey != nil {
+		clear(h.quotas.perKey)
+	}
+	h.cache.invalidate()
+}
 
+// Close removes all subscriptions, further Subscribe and Publish calls
+// return ErrClosed. Handlers already running are not interrupted.
+// Closing a closed hub does nothing.
+func (h *Hub) Close() error {
+	if h.closed.Swap(true) {
+		return nil
+	}
+	h.Clear(context.Background())
+	h.Lock()
+	h.notifyAdded()
+	h.Unlock()
+	return nil
 }
 
 // Len returns current number of active subscriptions
 func (h *Hub) Len() int {
 	h.RLock()
 	defer h.RUnlock()
-	return h.all.len()
+	return len(h.subs)
 }
@@ -0,0 +1,157 @@
+package hub
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// benchShape describes subscriptions registered for publish benchmarks
+type benchShape struct {
+	name  string
+	sub   func(i int) *Topic // Topic of i-th subscription
+	topic func(i int) *Topic // Topic of i-th publish
+}
+
+var benchShapes = []benchShape{
+	{
+		// every publish matches one subscription by exact topic
+		name:  "exact",
+		sub:   func(i int) *Topic { return T("type=event", "id="+strconv.Itoa(i)) },
+		topic: func(i int) *Topic { return T("type=event", "id="+strconv.Itoa(i)) },
+	},
+	{
+		// every publish matches a tenth of subscriptions by wildcard
+		name:  "wildcard",
+		sub:   func(i int) *Topic { return T("type=event", "group="+strconv.Itoa(i%10), "id=*") },
+		topic: func(i int) *Topic { return T("type=event", "group="+strconv.Itoa(i%10), "id="+strconv.Itoa(i)) },
+	},
+}
+
+// newBenchHub creates hub with n subscriptions of the shape and
+// precomputed topics to publish
+func newBenchHub(b *testing.B, shape benchShape, n int) (*Hub, []*Topic) {
+	ctx := context.Background()
+	h := New()
+	for i := 0; i < n; i++ {
+		if _, err := h.Subscribe(ctx, shape.sub(i), func(ctx context.Context) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	topics := make([]*Topic, 1024)
+	for i := range topics {
+		topics[i] = shape.topic(i % n)
+	}
+	return h, topics
+}
+
+// BenchmarkPublish measures publish throughput for different numbers of
+// subscriptions, topic shapes and delivery modes. Profile a single case with
+//
+//	go test -run XXX -bench 'Publish/subs=1000/exact/sync' -cpuprofile cpu.out
+func BenchmarkPublish(b *testing.B) {
+	ctx := context.Background()
+	modes := []struct {
+		name string
+		opts []PublishOption
+	}{
+		{"sync", []PublishOption{Sync(true)}},
+		{"async", []PublishOption{Wait(true)}},
+	}
+
+	for _, n := range []int{1, 10, 1000, 100000} {
+		for _, shape := range benchShapes {
+			if n == 100000 && testing.Short() {
+				continue
+			}
+			h, topics := newBenchHub(b, shape, n)
+			for _, mode := range modes {
+				b.Run("subs="+strconv.Itoa(n)+"/"+shape.name+"/"+mode.name, func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						_ = h.Publish(ctx, topics[i%len(topics)], nil, mode.opts...)
+					}
+				})
+			}
+			b.Run("subs="+strconv.Itoa(n)+"/"+shape.name+"/parallel", func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					i := 0
+					for pb.Next() {
+						_ = h.Publish(ctx, topics[i%len(topics)], nil, Sync(true))
+						i++
+					}
+				})
+			})
+		}
+	}
+}
+
+func BenchmarkPublishParallelDisjointKeys(b *testing.B) {
+	ctx := context.Background()
+	h := New()
+
+	const keys = 64
+	for i := 0; i < keys; i++ {
+		k := "key" + strconv.Itoa(i)
+		if _, err := h.Subscribe(ctx, T(k, "v"), func(ctx context.Context) {}, Inline(true)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var next atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		// every goroutine publishes to its own key
+		t := T("key"+strconv.Itoa(int(next.Add(1)%keys)), "v")
+		for pb.Next() {
+			_ = h.Publish(ctx, t, nil)
+		}
+	})
+}
+
+func BenchmarkMatch(b *testing.B) {
+	ctx := context.Background()
+	h := New()
+	for _, t := range []*Topic{T("type=alert"), T("type=alert", "level=high"), T("type=*"), T("level!=low")} {
+		if _, err := h.Subscribe(ctx, t, func(ctx context.Context) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, t := range []*Topic{T("type=alert"), T("type=alert", "level=high")} {
+		b.Run(t.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var buf [16]*sub
+				_ = h.match(t, buf[:0])
+			}
+		})
+	}
+}
+
+func BenchmarkUnsubscribe(b *testing.B) {
+	ctx := context.Background()
+	h := New()
+
+	const n = 1000
+	ids := make([]SubID, 0, n)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		ids = ids[:0]
+		for j := 0; j < n; j++ {
+			id, err := h.Subscribe(ctx, T("type=alert", "id="+strconv.Itoa(j)), func(ctx context.Context) {})
+			if err != nil {
+				b.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		b.StartTimer()
+		for _, id := range ids {
+			h.Unsubscribe(ctx, id)
+		}
+	}
+}
@@ -50,3 +50,739 @@ func (o *optionHubToHandler) modifyHub(h *Hub) {
 		h.convertToHandler = append(h.convertToHandler, o.v)
 	}
 }
+
+// WithTopicPolicy enables validation of topics passed to Subscribe and Publish.
+// Topics violating the policy are rejected with *PolicyError.
+// Hub.ParseTopic applies size and character limits of the policy
+// while parsing untrusted input.
+//
+// Example:
+//
+//	hub.New(
+//	    hub.WithTopicPolicy(hub.TopicPolicy{
+//	        RequiredKeys:  []string{"type"},
+//	        AllowedKeys:   []string{"type", "priority", "source"},
+//	        MaxAttributes: 3,
+//	    }),
+//	)
+func WithTopicPolicy(policy TopicPolicy) HubOption {
+	return &optionHubTopicPolicy{
+		v: policy,
+	}
+}
+
+// optionHubTopicPolicy implements the HubOption interface for topic policy
+type optionHubTopicPolicy struct {
+	v TopicPolicy
+}
+
+// modifyHub applies the topic policy to the Hub instance
+func (o *optionHubTopicPolicy) modifyHub(h *Hub) {
+	h.policy = &o.v
+}
+
+// WithInterning enables interning of topic keys and values inside the hub.
+// Subscription topics share memory for equal strings, and Hub.T caches
+// parsed topics, so publishing to a small set of topics many times
+// doesn't allocate and parse them again.
+//
+// Example:
+//
+//	h := hub.New(hub.WithInterning(true))
+//	h.Publish(ctx, h.T("type=alert"), "server is down")
+func WithInterning(v bool) HubOption {
+	return &optionHubInterning{
+		v: v,
+	}
+}
+
+// optionHubInterning implements the HubOption interface for interning
+type optionHubInterning struct {
+	v bool
+}
+
+// modifyHub enables or disables interning on the Hub instance
+func (o *optionHubInterning) modifyHub(h *Hub) {
+	if o.v {
+		h.intern = &internCache{
+			topics: make(map[string]*Topic),
+		}
+	} else {
+		h.intern = nil
+	}
+}
+
+// WithSystemAttributes stamps reserved attributes (AttrTimestamp, AttrSequence,
+// AttrSource) onto every published topic, so handlers, bridges and audit logs
+// get consistent metadata without cooperation of publishers.
+// Stamping happens after TopicPolicy checks, publisher provided values of
+// reserved attributes are overwritten.
+//
+// Example:
+//
+//	hub.New(
+//	    hub.WithSystemAttributes(hub.SystemAttributes{
+//	        Timestamp: true,
+//	        Sequence:  true,
+//	        Source:    "billing",
+//	    }),
+//	)
+func WithSystemAttributes(attrs SystemAttributes) HubOption {
+	return &optionHubSystemAttributes{
+		v: attrs,
+	}
+}
+
+// optionHubSystemAttributes implements the HubOption interface for system attributes
+type optionHubSystemAttributes struct {
+	v SystemAttributes
+}
+
+// modifyHub enables stamping of system attributes on the Hub instance
+func (o *optionHubSystemAttributes) modifyHub(h *Hub) {
+	if !o.v.Timestamp && !o.v.Sequence && o.v.Source == "" {
+		h.sysAttrs = nil
+		return
+	}
+	h.sysAttrs = &o.v
+}
+
+// StrictTypes disables cast-based coercion of payloads for typed callbacks.
+// Typed handlers only fire when the payload is exactly the declared type
+// (or assignable to it), otherwise *CastError is reported to OnError hooks.
+// Silent lossy conversions (e.g. struct to 0 via cast) often hide real bugs.
+//
+// Example:
+//
+//	h := hub.New(
+//	    hub.StrictTypes(true),
+//	    hub.OnError(func(ctx context.Context, t *hub.Topic, id hub.SubID, err error) {
+//	        log.Printf("handler %d failed on %s: %v", id, t, err)
+//	    }),
+//	)
+func StrictTypes(v bool) HubOption {
+	return &optionHubStrictTypes{
+		v: v,
+	}
+}
+
+// optionHubStrictTypes implements the HubOption interface for strict typing
+type optionHubStrictTypes struct {
+	v bool
+}
+
+// modifyHub enables or disables strict typing on the Hub instance
+func (o *optionHubStrictTypes) modifyHub(h *Hub) {
+	h.strictTypes = o.v
+}
+
+// OnError registers a hook called when a subscription handler returns an error,
+// including *CastError for payloads the handler can't accept.
+// Hooks are called in the goroutine of the handler, in registration order.
+//
+// Example:
+//
+//	hub.New(
+//	    hub.OnError(func(ctx context.Context, t *hub.Topic, id hub.SubID, err error) {
+//	        log.Printf("handler %d failed on %s: %v", id, t, err)
+//	    }),
+//	)
+func OnError(cb func(ctx context.Context, t *Topic, id SubID, err error)) HubOption {
+	return &optionHubOnError{
+		v: cb,
+	}
+}
+
+// optionHubOnError implements the HubOption interface for error hooks
+type optionHubOnError struct {
+	v func(ctx context.Context, t *Topic, id SubID, err error)
+}
+
+// modifyHub registers the error hook on the Hub instance
+func (o *optionHubOnError) modifyHub(h *Hub) {
+	if o.v != nil {
+		h.onError = append(h.onError, o.v)
+	}
+}
+
+// WithErrorTopic republishes errors reported to OnError hooks as events
+// with *HandlerError payload on topic t, so error dashboards and alerting
+// can be built by subscribing to it. Error events are published with
+// the values of the failed handler context, but are not canceled with it.
+// Errors of handlers receiving *HandlerError events are not republished
+// to avoid loops.
+//
+// Example:
+//
+//	h := hub.New(hub.WithErrorTopic(hub.T("hub=error")))
+//	h.Subscribe(ctx, hub.T("hub=error"), func(ctx context.Context, e *hub.HandlerError) {
+//	    alerts.Inc(e.Topic.Get("type"))
+//	})
+func WithErrorTopic(t *Topic) HubOption {
+	return &optionHubErrorTopic{
+		v: t,
+	}
+}
+
+// optionHubErrorTopic implements the HubOption interface for error topic
+type optionHubErrorTopic struct {
+	v *Topic
+}
+
+// modifyHub registers the error hook republishing errors on the Hub instance
+func (o *optionHubErrorTopic) modifyHub(h *Hub) {
+	if o.v == nil {
+		return
+	}
+	t := o.v
+	h.onError = append(h.onError, func(ctx context.Context, topic *Topic, id SubID, err error) {
+		if e, ok := ctx.Value(ctxKeyEvent).(*event); ok {
+			if _, loop := e.payload.(*HandlerError); loop {
+				return
+			}
+		}
+		// The failed delivery may be canceled and must not receive replies or acks
+		_ = h.Publish(detachedContext(ctx), t, &HandlerError{Topic: topic, SubID: id, Err: err})
+	})
+}
+
+// WithMetrics reports hub activity (published events, deliveries, handler
+// errors and durations, handlers in flight, subscription count) to m.
+// Metrics are disabled by default. Several sinks may be registered.
+//
+// Example:
+//
+//	h := hub.New(hub.WithMetrics(hubprom.NewCollector("myhub")))
+func WithMetrics(m Metrics) HubOption {
+	return &optionHubMetrics{
+		v: m,
+	}
+}
+
+// optionHubMetrics implements the HubOption interface for metrics
+type optionHubMetrics struct {
+	v Metrics
+}
+
+// modifyHub adds the metrics sink to the Hub instance
+func (o *optionHubMetrics) modifyHub(h *Hub) {
+	if o.v != nil {
+		h.addMetrics(o.v)
+	}
+}
+
+// WithExpvar publishes hub counters under expvar map with the given name:
+// publishes, deliveries, errors, in_flight (handlers being executed)
+// and subscriptions. Existing debug endpoints (/debug/vars) pick them up
+// with no extra code. Hubs created with the same name share counters.
+// If name is taken by an expvar variable other than a map, counters
+// are not published.
+//
+// Example:
+//
+//	h := hub.New(hub.WithExpvar("myhub"))
+func WithExpvar(name string) HubOption {
+	return &optionHubExpvar{
+		v: name,
+	}
+}
+
+// optionHubExpvar implements the HubOption interface for expvar metrics
+type optionHubExpvar struct {
+	v string
+}
+
+// modifyHub adds expvar metrics to the Hub instance
+func (o *optionHubExpvar) modifyHub(h *Hub) {
+	h.addMetrics(newExpvarMetrics(o.v))
+}
+
+// WithAudit streams every published event to the audit sinks:
+// JSON lines to cfg.Writer and/or records to cfg.Func.
+// Events are recorded after TopicPolicy checks and stamping,
+// before delivery to handlers. Sampling and redaction hooks
+// select recorded events and hide sensitive payloads.
+//
+// Example:
+//
+//	hub.New(
+//	    hub.WithAudit(hub.AuditConfig{
+//	        Writer: auditFile,
+//	        Redact: func(t *hub.Topic, p any) any {
+//	            if t.Get("type") == "login" {
+//	                return "<redacted>"
+//	            }
+//	            return p
+//	        },
+//	    }),
+//	)
+func WithAudit(cfg AuditConfig) HubOption {
+	return &optionHubAudit{
+		v: cfg,
+	}
+}
+
+// optionHubAudit implements the HubOption interface for audit log
+type optionHubAudit struct {
+	v AuditConfig
+}
+
+// modifyHub enables audit log on the Hub instance
+func (o *optionHubAudit) modifyHub(h *Hub) {
+	if o.v.Writer == nil && o.v.Func == nil {
+		h.audit = nil
+		return
+	}
+	h.audit = &auditLog{cfg: o.v}
+}
+
+// MatchCache enables caching of resolved subscriber sets for published
+// topics. Repeated publishes to the same topics skip matching until
+// the next Subscribe, Unsubscribe or Clear. Size is the number of cached
+// topics, rounded up to a power of two. Hit/miss counters are reported
+// by Hub.Stats.
+//
+// Example:
+//
+//	h := hub.New(hub.MatchCache(1024))
+func MatchCache(size int) HubOption {
+	return &optionHubMatchCache{
+		v: size,
+	}
+}
+
+// optionHubMatchCache implements the HubOption interface for match cache
+type optionHubMatchCache struct {
+	v int
+}
+
+// modifyHub enables match cache for the Hub instance
+func (o *optionHubMatchCache) modifyHub(h *Hub) {
+	h.cache = newMatchCache(o.v)
+}
+
+// WithCapacityHints preallocates index structures for the expected number
+// of distinct topic keys, values per key and subscriptions. It avoids
+// repeated growth when applications register a known large number
+// of subscriptions at startup. Hints only affect performance.
+//
+// Example:
+//
+//	h := hub.New(hub.WithCapacityHints(16, 10000, 50000))
+func WithCapacityHints(keys, valuesPerKey, subs int) HubOption {
+	return &optionHubCapacityHints{
+		v: capacityHints{
+			keys:   max(keys, 0),
+			values: max(valuesPerKey, 0),
+			subs:   max(subs, 0),
+		},
+	}
+}
+
+// optionHubCapacityHints implements the HubOption interface for capacity hints
+type optionHubCapacityHints struct {
+	v capacityHints
+}
+
+// modifyHub sets capacity hints for the Hub instance
+func (o *optionHubCapacityHints) modifyHub(h *Hub) {
+	h.hints = o.v
+}
+
+// WithProfile registers default options for topics matching the profile
+// pattern. It centralizes delivery policy for applications where many
+// teams publish to one hub.
+//
+// Example:
+//
+//	hub.New(
+//	    hub.WithProfile(hub.Profile{
+//	        Pattern: hub.T("type=audit"),
+//	        Publish: []hub.PublishOption{hub.Sync(true)},
+//	    }),
+//	)
+func WithProfile(p Profile) HubOption {
+	return &optionHubProfile{
+		v: p,
+	}
+}
+
+// optionHubProfile implements the HubOption interface for option profiles
+type optionHubProfile struct {
+	v Profile
+}
+
+// modifyHub registers the profile in the Hub instance
+func (o *optionHubProfile) modifyHub(h *Hub) {
+	h.profiles = append(h.profiles, o.v)
+}
+
+// MaxPending bounds the total number of queued but not yet started async
+// deliveries across the hub. When the limit is reached new deliveries
+// are handled according to the overflow policy. It protects services
+// from a runaway publisher. Non-positive n disables the limit.
+//
+// Example:
+//
+//	h := hub.New(hub.MaxPending(10000, hub.OverflowDrop))
+//	...
+//	log.Printf("dropped: %d", h.Health().Dropped)
+func MaxPending(n int, policy OverflowPolicy) HubOption {
+	return &optionHubMaxPending{
+		n:      n,
+		policy: policy,
+	}
+}
+
+// optionHubMaxPending implements the HubOption interface for pending limit
+type optionHubMaxPending struct {
+	n      int
+	policy OverflowPolicy
+}
+
+// modifyHub sets the pending limit for the Hub instance
+func (o *optionHubMaxPending) modifyHub(h *Hub) {
+	if o.n <= 0 {
+		h.pendingSem = nil
+		return
+	}
+	h.pendingSem = make(chan struct{}, o.n)
+	h.overflow = o.policy
+}
+
+// Middleware wraps subscription handlers, e.g. for logging, tracing
+// or fault injection
+type Middleware func(next Handler) Handler
+
+// WithMiddleware wraps handlers of all subscriptions created after New.
+// Several middlewares are applied so that the first one is the outermost.
+//
+// Example:
+//
+//	hub.New(hub.WithMiddleware(func(next hub.Handler) hub.Handler {
+//	    return func(ctx context.Context, t *hub.Topic, p any) error {
+//	        start := time.Now()
+//	        err := next(ctx, t, p)
+//	        log.Printf("%s handled in %s", t, time.Since(start))
+//	        return err
+//	    }
+//	}))
+func WithMiddleware(mw ...Middleware) HubOption {
+	return &optionHubMiddleware{
+		v: mw,
+	}
+}
+
+// optionHubMiddleware implements the HubOption interface for middlewares
+type optionHubMiddleware struct {
+	v []Middleware
+}
+
+// modifyHub registers middlewares in the Hub instance
+func (o *optionHubMiddleware) modifyHub(h *Hub) {
+	for _, mw := range o.v {
+		if mw != nil {
+			h.middleware = append(h.middleware, mw)
+		}
+	}
+}
+
+// WithExecutor routes execution of async handlers through the executor
+// instead of starting a goroutine per handler call, e.g. to use a worker
+// pool. The executor must eventually run every task; it may run the task
+// in the calling goroutine, which makes the delivery synchronous.
+//
+// Example:
+//
+//	pool, _ := ants.NewPool(100)
+//	h := hub.New(hub.WithExecutor(func(task func()) {
+//	    _ = pool.Submit(task)
+//	}))
+func WithExecutor(executor func(task func())) HubOption {
+	return &optionHubExecutor{
+		v: executor,
+	}
+}
+
+// optionHubExecutor implements the HubOption interface for handler executor
+type optionHubExecutor struct {
+	v func(task func())
+}
+
+// modifyHub sets the executor for the Hub instance
+func (o *optionHubExecutor) modifyHub(h *Hub) {
+	h.executor = o.v
+}
+
+// WithWorkers runs async handlers on a pool of at most n goroutines.
+// Queued deliveries are taken by priority lanes, so events published with
+// a higher Priority bypass a backlog of lower priority ones. Within a lane
+// deliveries start in publish order. Idle workers exit, so the pool holds
+// no goroutines when there is nothing to run. Overrides WithExecutor.
+//
+// Example:
+//
+//	h := hub.New(hub.WithWorkers(16))
+//	h.Publish(ctx, hub.T("type=metrics"), sample)
+//	h.Publish(ctx, hub.T("type=alert"), alert, hub.Priority(1)) // runs before queued metrics
+func WithWorkers(n int) HubOption {
+	return &optionHubWorkers{
+		v: n,
+	}
+}
+
+// optionHubWorkers implements the HubOption interface for worker pool
+type optionHubWorkers struct {
+	v int
+}
+
+// modifyHub sets the worker pool for the Hub instance
+func (o *optionHubWorkers) modifyHub(h *Hub) {
+	if o.v <= 0 {
+		h.workers = nil
+		return
+	}
+	h.workers = &workerPool{size: o.v}
+}
+
+// WithStore writes every published event to the store before delivery,
+// so Durable subscriptions receive events published while the process
+// was down. Publish returns an error if the payload can't be encoded
+// to JSON or written. Stored events are replayed with JSON decoded
+// payloads (maps, strings, float64), typed handlers convert them as usual.
+//
+// Example:
+//
+//	store, err := hubstore.Open("/var/lib/app/events")
+//	if err != nil {
+//	    return err
+//	}
+//	h := hub.New(hub.WithStore(store))
+func WithStore(store Store) HubOption {
+	return &optionHubStore{
+		v: store,
+	}
+}
+
+// optionHubStore implements the HubOption interface for event store
+type optionHubStore struct {
+	v Store
+}
+
+// modifyHub sets the store for the Hub instance
+func (o *optionHubStore) modifyHub(h *Hub) {
+	h.store = o.v
+}
+
+// WithClock sets the source of time of the hub, see Clock.
+// The real clock is used by default.
+//
+// Example:
+//
+//	clock := hubtest.NewClock(time.Now())
+//	h := hub.New(hub.WithClock(clock))
+//	h.PublishAfter(ctx, time.Minute, hub.T("type=reminder"), nil)
+//	clock.Advance(time.Minute) // reminder is published
+func WithClock(c Clock) HubOption {
+	return &optionHubClock{
+		v: c,
+	}
+}
+
+// optionHubClock implements the HubOption interface for the clock
+type optionHubClock struct {
+	v Clock
+}
+
+// modifyHub sets the clock for the Hub instance
+func (o *optionHubClock) modifyHub(h *Hub) {
+	h.clock = o.v
+}
+
+// WithUpcaster registers conversion of payloads of events with AttrVersion
+// from to version to. Events are upgraded through all registered steps
+// before delivery, including events replayed from the Store, so handlers
+// only deal with the latest format. The topic gets AttrVersion of the
+// last step, subscriptions match the upgraded topic. Publish returns
+// an error if a conversion fails, for replayed events it is reported
+// to OnError hooks and the event is skipped.
+//
+// Example:
+//
+//	hub.New(
+//	    hub.WithUpcaster("1", "2", func(ctx context.Context, p any) (any, error) {
+//	        v1 := p.(map[string]any)
+//	        return map[string]any{"name": v1["first"].(string) + " " + v1["last"].(string)}, nil
+//	    }),
+//	)
+func WithUpcaster(from, to string, fn Upcaster) HubOption {
+	return &optionHubUpcaster{
+		from: from,
+		to:   to,
+		fn:   fn,
+	}
+}
+
+// optionHubUpcaster implements the HubOption interface for upcasters
+type optionHubUpcaster struct {
+	from string
+	to   string
+	fn   Upcaster
+}
+
+// modifyHub registers the upcaster on the Hub instance
+func (o *optionHubUpcaster) modifyHub(h *Hub) {
+	if o.fn == nil {
+		return
+	}
+	if h.upcasters == nil {
+		h.upcasters = make(map[string]upcaster)
+	}
+	h.upcasters[o.from] = upcaster{to: o.to, fn: o.fn}
+}
+
+// WithEncryptor encrypts payloads of events written to the Store, so they
+// never reach the disk or a database in plaintext. Stored payloads are
+// JSON strings with base64 encoded ciphertext: a hub without the
+// encryptor replays them as strings, a hub with it fails to replay
+// plaintext payloads.
+//
+// Example:
+//
+//	enc, err := hub.NewAESGCM(key)
+//	if err != nil {
+//	    return err
+//	}
+//	h := hub.New(hub.WithStore(store), hub.WithEncryptor(enc))
+func WithEncryptor(enc Encryptor) HubOption {
+	return &optionHubEncryptor{
+		v: enc,
+	}
+}
+
+// optionHubEncryptor implements the HubOption interface for payload encryption
+type optionHubEncryptor struct {
+	v Encryptor
+}
+
+// modifyHub sets the encryptor for the Hub instance
+func (o *optionHubEncryptor) modifyHub(h *Hub) {
+	h.encryptor = o.v
+}
+
+// WithAuthorizer consults fn on every Subscribe and Publish, so a hub shared
+// by tenants or plugins can restrict which topics each of them uses.
+// The caller is identified by values of its ctx. Several authorizers
+// may be registered, all of them must allow the operation. Delayed
+// publishing is authorized when scheduled.
+//
+// Example:
+//
+//	hub.New(hub.WithAuthorizer(func(ctx context.Context, op hub.Op, t *hub.Topic) error {
+//	    plugin := pluginFromContext(ctx)
+//	    if op == hub.OpPublish && t.Get("plugin") != plugin {
+//	        return hub.ErrForbidden
+//	    }
+//	    return nil
+//	}))
+func WithAuthorizer(fn Authorizer) HubOption {
+	return &optionHubAuthorizer{
+		v: fn,
+	}
+}
+
+// optionHubAuthorizer implements the HubOption interface for authorizers
+type optionHubAuthorizer struct {
+	v Authorizer
+}
+
+// modifyHub registers the authorizer on the Hub instance
+func (o *optionHubAuthorizer) modifyHub(h *Hub) {
+	if o.v != nil {
+		h.authorizers = append(h.authorizers, o.v)
+	}
+}
+
+// WithMaxSubscriptions limits the number of active subscriptions, Subscribe
+// returns QuotaError when n subscriptions are active. Protects a shared hub
+// from a component subscribing in a loop.
+//
+// Example:
+//
+//	h := hub.New(hub.WithMaxSubscriptions(10000))
+//	_, err := h.Subscribe(ctx, t, handler)
+//	if errors.Is(err, hub.ErrQuotaExceeded) {
+//	    // too many subscriptions
+//	}
+func WithMaxSubscriptions(n int) HubOption {
+	return &optionHubMaxSubscriptions{
+		v: n,
+	}
+}
+
+// optionHubMaxSubscriptions implements the HubOption interface for subscription limit
+type optionHubMaxSubscriptions struct {
+	v int
+}
+
+// modifyHub sets the subscription limit for the Hub instance
+func (o *optionHubMaxSubscriptions) modifyHub(h *Hub) {
+	h.quotas.subs = max(o.v, 0)
+}
+
+// WithMaxTopicSubscriptions limits the number of active subscriptions with
+// the same topic key regardless of its value, e.g. handlers a leaking
+// component adds for "order=<id>" again and again. A subscription counts
+// towards every key of its topic. Subscribe returns QuotaError with the
+// key when the limit is reached.
+//
+// Example:
+//
+//	hub.New(hub.WithMaxTopicSubscriptions(100))
+func WithMaxTopicSubscriptions(n int) HubOption {
+	return &optionHubMaxTopicSubscriptions{
+		v: n,
+	}
+}
+
+// optionHubMaxTopicSubscriptions implements the HubOption interface for per-key subscription limit
+type optionHubMaxTopicSubscriptions struct {
+	v int
+}
+
+// modifyHub sets the per-key subscription limit for the Hub instance
+func (o *optionHubMaxTopicSubscriptions) modifyHub(h *Hub) {
+	h.quotas.topicSubs = max(o.v, 0)
+	h.quotas.perKey = nil
+	if o.v > 0 {
+		h.quotas.perKey = make(map[string]int)
+	}
+}
+
+// WithPublishRate limits publishing to rate events per second with bursts
+// of up to burst events. Publish returns QuotaError when the rate is
+// exceeded, events are not queued. The rate is measured by the hub Clock.
+//
+// Example:
+//
+//	hub.New(hub.WithPublishRate(1000, 100))
+func WithPublishRate(rate float64, burst int) HubOption {
+	return &optionHubPublishRate{
+		rate:  rate,
+		burst: burst,
+	}
+}
+
+// optionHubPublishRate implements the HubOption interface for publish rate limit
+type optionHubPublishRate struct {
+	rate  float64
+	burst int
+}
+
+// modifyHub sets the publish rate limit for the Hub instance
+func (o *optionHubPublishRate) modifyHub(h *Hub) {
+	h.quotas.rate = nil
+	if o.rate > 0 {
+		h.quotas.rate = newRateLimiter(o.rate, o.burst)
+	}
+}
@@ -3,7 +3,12 @@ package hub
 import (
 	"context"
 	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/spf13/cast"
 )
@@ -183,3 +188,333 @@ func TestMultipleConverters(t *testing.T) {
 		}
 	})
 }
+
+func TestStrictTypes(t *testing.T) {
+	t.Parallel()
+
+	type point struct{ X, Y int }
+
+	tests := []struct {
+		name    string
+		cb      any
+		payload any
+		wantErr bool
+	}{
+		{"int exact", func(ctx context.Context, v int) error { return nil }, 42, false},
+		{"int from string", func(ctx context.Context, v int) error { return nil }, "42", true},
+		{"int from struct", func(ctx context.Context, v int) {}, point{}, true},
+		{"string from int", func(ctx context.Context, v string) {}, 42, true},
+		{"bytes from string", func(ctx context.Context, v []byte) error { return nil }, "data", true},
+		{"any accepts all", func(ctx context.Context, v any) error { return nil }, point{}, false},
+		{"struct exact", func(ctx context.Context, v point) error { return nil }, point{1, 2}, false},
+		{"struct from pointer", func(ctx context.Context, v point) error { return nil }, &point{1, 2}, true},
+		{"struct from map", func(ctx context.Context, v point) error { return nil }, map[string]any{"X": 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := New(StrictTypes(true))
+			handler, err := h.ToHandler(context.Background(), tt.cb)
+			if err != nil {
+				t.Fatalf("ToHandler() error = %v", err)
+			}
+			err = handler(context.Background(), nil, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("handler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var castErr *CastError
+			if tt.wantErr && !errors.As(err, &castErr) {
+				t.Errorf("expected *CastError, got %T", err)
+			}
+		})
+	}
+
+	// coercion is enabled by default
+	h := New()
+	handler, err := h.ToHandler(context.Background(), func(ctx context.Context, v int) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handler(context.Background(), nil, "42"); err != nil {
+		t.Errorf("non-strict handler() error = %v", err)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	type report struct {
+		topic *Topic
+		id    SubID
+		err   error
+	}
+	var reports []report
+
+	h := New(
+		StrictTypes(true),
+		OnError(func(ctx context.Context, t *Topic, id SubID, err error) {
+			reports = append(reports, report{t, id, err})
+		}),
+	)
+
+	var got []int
+	id, err := h.Subscribe(ctx, T("type=num"), func(ctx context.Context, v int) {
+		got = append(got, v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failErr := errors.New("fail")
+	failID, err := h.Subscribe(ctx, T("type=fail"), func(ctx context.Context) error {
+		return failErr
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = h.Publish(ctx, T("type=num"), 1, Sync(true))
+	_ = h.Publish(ctx, T("type=num"), "2", Sync(true))
+	_ = h.Publish(ctx, T("type=fail"), nil, Sync(true))
+
+	if len(got) != 1 || got[0] != 1 {
+		t.Errorf("handler got %v, want [1]", got)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("got %d error reports, want 2", len(reports))
+	}
+	var castErr *CastError
+	if reports[0].id != id || !errors.As(reports[0].err, &castErr) || !reports[0].topic.Equal(T("type=num")) {
+		t.Errorf("unexpected first report: %+v", reports[0])
+	}
+	if reports[1].id != failID || !errors.Is(reports[1].err, failErr) {
+		t.Errorf("unexpected second report: %+v", reports[1])
+	}
+}
+
+func TestWithCapacityHints(t *testing.T) {
+	ctx := context.Background()
+	h := New(WithCapacityHints(2, 100, 1000))
+
+	var calls atomic.Int32
+	ids := make([]SubID, 0, 200)
+	for i := 0; i < 200; i++ {
+		id, err := h.Subscribe(ctx, T("type=alert", "id="+strconv.Itoa(i%50)), func(ctx context.Context) {
+			calls.Add(1)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	// Each key gets its share of the expected subscriptions
+	if n := cap(h.shards[shardIndex("type")].load().indexKey["type"].lst); n < 500 || n >= 1000 {
+		t.Errorf("indexKey capacity = %d, want 500", n)
+	}
+
+	_ = h.Publish(ctx, T("type=alert", "id=7"), nil, Sync(true))
+	if calls.Load() != 4 {
+		t.Errorf("calls = %d, want 4", calls.Load())
+	}
+
+	for _, id := range ids[:100] {
+		h.Unsubscribe(ctx, id)
+	}
+	_ = h.Publish(ctx, T("type=alert", "id=7"), nil, Sync(true))
+	if calls.Load() != 6 {
+		t.Errorf("calls after unsubscribe = %d, want 6", calls.Load())
+	}
+}
+
+func TestWithMiddleware(t *testing.T) {
+	ctx := context.Background()
+	var trace []string
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, t *Topic, p any) error {
+				trace = append(trace, name)
+				return next(ctx, t, p)
+			}
+		}
+	}
+	h := New(WithMiddleware(mw("outer"), nil), WithMiddleware(mw("inner")))
+
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {
+		trace = append(trace, "handler")
+	})
+	_ = h.Publish(ctx, T("type=a"), nil, Sync(true))
+
+	if want := []string{"outer", "inner", "handler"}; !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestWithExecutor(t *testing.T) {
+	ctx := context.Background()
+
+	// fixed pool of two workers
+	tasks := make(chan func())
+	var submitted atomic.Int32
+	for i := 0; i < 2; i++ {
+		go func() {
+			for task := range tasks {
+				task()
+			}
+		}()
+	}
+	defer close(tasks)
+
+	h := New(WithExecutor(func(task func()) {
+		submitted.Add(1)
+		tasks <- task
+	}))
+
+	var calls atomic.Int32
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) { calls.Add(1) })
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) { calls.Add(1) }, Serialized(true))
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) { calls.Add(1) }, Inline(true))
+
+	for i := 0; i < 5; i++ {
+		_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+	}
+
+	if calls.Load() != 15 {
+		t.Errorf("calls = %d, want 15", calls.Load())
+	}
+	// one task per plain async call and one serial worker per publish at most
+	if n := submitted.Load(); n < 6 || n > 10 {
+		t.Errorf("submitted tasks = %d, want 6..10", n)
+	}
+}
+
+func TestWithWorkers(t *testing.T) {
+	ctx := context.Background()
+	h := New(WithWorkers(1))
+
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	var mu sync.Mutex
+	var got []string
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context, name string) {
+		if name == "block" {
+			close(started)
+			<-gate
+		}
+		mu.Lock()
+		got = append(got, name)
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	publish := func(name string, opts ...PublishOption) {
+		wg.Add(1)
+		_ = h.Publish(ctx, T("type=a"), name, append(opts, OnFinish(func(ctx context.Context) { wg.Done() }))...)
+	}
+	publish("block")
+	<-started
+	publish("low1")
+	publish("low2")
+	publish("alert", Priority(1))
+	close(gate)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the single worker is busy with "block" while others are queued
+	want := []string{"block", "alert", "low1", "low2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestWithErrorTopic(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New(WithErrorTopic(T("hub=error")))
+
+	var mu sync.Mutex
+	var got []*HandlerError
+	_, _ = h.Subscribe(ctx, T("hub=error"), func(ctx context.Context, e *HandlerError) error {
+		mu.Lock()
+		got = append(got, e)
+		mu.Unlock()
+		// failing error handler must not cause a loop
+		return errors.New("dashboard is down")
+	})
+
+	failErr := errors.New("fail")
+	id, _ := h.Subscribe(ctx, T("type=order"), func(ctx context.Context) error {
+		return failErr
+	})
+	_ = h.Publish(ctx, T("type=order", "id=1"), nil, Sync(true))
+	_ = h.Publish(ctx, T("type=order", "id=2"), nil, Sync(true), OnFinish(func(ctx context.Context) {
+		panic("boom")
+	}))
+	if err := h.WaitIdle(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 3 {
+		t.Fatalf("got %d error events, want 3", len(got))
+	}
+	var failed, panicked int
+	for _, e := range got {
+		var pe *PanicError
+		switch {
+		case e.SubID == id && errors.Is(e, failErr) && T("type=order").Match(e.Topic):
+			failed++
+		case e.SubID == 0 && errors.As(e, &pe) && e.Topic.Equal(T("type=order", "id=2")):
+			panicked++
+		default:
+			t.Errorf("unexpected error event %+v", e)
+		}
+	}
+	if failed != 2 || panicked != 1 {
+		t.Errorf("handler errors = %d, panics = %d, want 2 and 1", failed, panicked)
+	}
+}
+
+func TestWithErrorTopicContext(t *testing.T) {
+	t.Parallel()
+	h := New(WithErrorTopic(T("hub=error")))
+
+	type ctxKeyTrace struct{}
+	type seen struct {
+		err   error
+		reply bool
+		trace any
+		topic *Topic
+	}
+	got := make(chan seen, 1)
+	_, _ = h.Subscribe(context.Background(), T("hub=error"), func(ctx context.Context, e *HandlerError) {
+		got <- seen{
+			err:   ctx.Err(),
+			reply: ctx.Value(ctxKeyReply) != nil,
+			trace: ctx.Value(ctxKeyTrace{}),
+			topic: TopicFromContext(ctx),
+		}
+	})
+
+	// The failed request is canceled before the error is republished
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKeyTrace{}, "abc"))
+	_, _ = h.Subscribe(ctx, T("rpc=fail"), func(ctx context.Context, in any) (string, error) {
+		cancel()
+		return "", errors.New("fail")
+	}, Inline(true))
+	if _, err := h.Request(ctx, T("rpc=fail"), nil); err == nil {
+		t.Fatal("Request() = nil error")
+	}
+
+	select {
+	case s := <-got:
+		if s.err != nil || s.reply || s.trace != "abc" || !s.topic.Equal(T("hub=error")) {
+			t.Errorf("error handler context = %+v", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error event was not delivered")
+	}
+}
@@ -2,8 +2,10 @@ package hub
 
 import (
 	"context"
+	"errors"
 	"math"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -106,10 +108,10 @@ func TestHubUnsubscribe(t *testing.T) {
 
 		h.RLock()
 		defer h.RUnlock()
-		if h.indexKey["type"].len() != 0 {
+		if h.shards[shardIndex("type")].load().indexKey["type"].len() != 0 {
 			t.Error("Subscription not removed from key index")
 		}
-		if h.indexKeyValue["type"]["alert"].len() != 0 {
+		if h.shards[shardIndex("type")].load().indexKeyValue["type"].get("alert").len() != 0 {
 			t.Error("Subscription not removed from key-value index")
 		}
 	})
@@ -129,14 +131,36 @@ func TestHubClear(t *testing.T) {
 
 	h.RLock()
 	defer h.RUnlock()
-	if len(h.indexKey) != 0 {
+	if indexLen(h, func(ix *indexes) int { return len(ix.indexKey) }) != 0 {
 		t.Error("Expected empty key index after clear")
 	}
-	if len(h.indexKeyValue) != 0 {
+	if indexLen(h, func(ix *indexes) int { return len(ix.indexKeyValue) }) != 0 {
 		t.Error("Expected empty key-value index after clear")
 	}
 }
 
+func TestHubClose(t *testing.T) {
+	h := New()
+	ctx := context.Background()
+
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {})
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 0 {
+		t.Error("Expected 0 subscriptions after close")
+	}
+	if _, err := h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe() after close = %v, want ErrClosed", err)
+	}
+	if err := h.Publish(ctx, T("type=a"), nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish() after close = %v, want ErrClosed", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+}
+
 func TestHubConcurrency(t *testing.T) {
 	h := New()
 	ctx := context.Background()
@@ -179,7 +203,7 @@ func TestHubIndexes(t *testing.T) {
 	// Test key-value index
 	h.Subscribe(ctx, T("type=alert"), Handler(nil))
 	h.RLock()
-	if h.indexKeyValue["type"]["alert"].len() != 1 {
+	if h.shards[shardIndex("type")].load().indexKeyValue["type"].get("alert").len() != 1 {
 		t.Error("Subscription not added to key-value index")
 	}
 	h.RUnlock()
@@ -187,7 +211,7 @@ func TestHubIndexes(t *testing.T) {
 	// Test wildcard index
 	h.Subscribe(ctx, T("type=*"), Handler(nil))
 	h.RLock()
-	if h.indexKey["type"].len() != 2 {
+	if h.shards[shardIndex("type")].load().indexKey["type"].len() != 2 {
 		t.Error("Subscription not added to key index")
 	}
 	h.RUnlock()
@@ -195,7 +219,7 @@ func TestHubIndexes(t *testing.T) {
 	// Test empty topic
 	h.Subscribe(ctx, T(""), Handler(nil))
 	h.RLock()
-	if h.indexEmpty.len() != 1 {
+	if h.indexEmpty.Load().len() != 1 {
 		t.Error("Subscription not added to empty index")
 	}
 	h.RUnlock()
@@ -322,7 +346,7 @@ func TestHubPubSubSyncAsync(t *testing.T) {
 		s := time.Now()
 		h.Publish(ctx, T("a=10", "b=*"), nil)
 		checkT(t, s, 0)
-		time.Sleep(2 * time.Second)
+		_ = h.WaitIdle(ctx)
 		checkC(t, map[string]int{"a=10, b=21": 1, "a=*, b=21": 1, "a=10, b=20": 1})
 	})
 
@@ -405,10 +429,10 @@ func TestHubPubSubOnce(t *testing.T) {
 			c.Add("once", 1)
 		}, Once(true), nil)
 		h.Publish(ctx, T("a=10", "b=*"), nil)
-		time.Sleep(100 * time.Millisecond)
+		_ = h.WaitIdle(ctx)
 		checkC(t, map[string]int{"a=10, b=21": 1, "a=*, b=21": 1, "a=10, b=20": 1, "once": 1})
 		h.Publish(ctx, T("a=10", "b=*"), nil)
-		time.Sleep(100 * time.Millisecond)
+		_ = h.WaitIdle(ctx)
 		checkC(t, map[string]int{"a=10, b=21": 2, "a=*, b=21": 2, "a=10, b=20": 2, "once": 1})
 	})
 
@@ -418,10 +442,10 @@ func TestHubPubSubOnce(t *testing.T) {
 			c.Add("once", 1)
 		}, Once(true), nil)
 		h.Publish(ctx, T("a=10", "b=*"), nil, OnFinish(func(ctx context.Context) {}))
-		time.Sleep(100 * time.Millisecond)
+		_ = h.WaitIdle(ctx)
 		checkC(t, map[string]int{"a=10, b=21": 1, "a=*, b=21": 1, "a=10, b=20": 1, "once": 1})
 		h.Publish(ctx, T("a=10", "b=*"), nil, OnFinish(func(ctx context.Context) {}))
-		time.Sleep(100 * time.Millisecond)
+		_ = h.WaitIdle(ctx)
 		checkC(t, map[string]int{"a=10, b=21": 2, "a=*, b=21": 2, "a=10, b=20": 2, "once": 1})
 	})
 
@@ -437,3 +461,266 @@ func TestHubPubSubOnce(t *testing.T) {
 	})
 
 }
+
+func TestHubMultiValue(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	c := cmap.New()
+
+	id, _ := h.Subscribe(ctx, T("type=alert", "priority=high|critical"), func(ctx context.Context) {
+		c.Add("alert", 1)
+	})
+
+	h.Publish(ctx, T("type=alert", "priority=high"), nil, Sync(true))
+	h.Publish(ctx, T("type=alert", "priority=critical"), nil, Sync(true))
+	h.Publish(ctx, T("type=alert", "priority=low"), nil, Sync(true))
+	h.Publish(ctx, T("type=alert", "priority=low|high"), nil, Sync(true))
+
+	if !c.Eq(map[string]int{"alert": 3}) {
+		t.Error("Result mismatch")
+	}
+
+	h.Unsubscribe(ctx, id)
+
+	h.RLock()
+	defer h.RUnlock()
+	if h.shards[shardIndex("priority")].load().indexKeyValue["priority"].len() != 0 {
+		t.Error("Multi-value subscription not removed from key-value index")
+	}
+}
+
+func TestHubNegation(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	c := cmap.New()
+
+	id, _ := h.Subscribe(ctx, T("type=log", "level!=debug"), func(ctx context.Context) {
+		c.Add("log", 1)
+	})
+
+	h.Publish(ctx, T("type=log", "level=info"), nil, Sync(true))
+	h.Publish(ctx, T("type=log", "level=error"), nil, Sync(true))
+	h.Publish(ctx, T("type=log", "level=debug"), nil, Sync(true))
+	h.Publish(ctx, T("type=log"), nil, Sync(true))
+	h.Publish(ctx, T("level=info"), nil, Sync(true))
+
+	if !c.Eq(map[string]int{"log": 2}) {
+		t.Error("Result mismatch")
+	}
+
+	h.Unsubscribe(ctx, id)
+
+	h.RLock()
+	defer h.RUnlock()
+	if indexLen(h, func(ix *indexes) int { return len(ix.indexKeyOp) }) != 0 {
+		t.Error("Subscription not removed from operator index")
+	}
+	if h.shards[shardIndex("level")].load().indexKeyValue["level"].len() != 0 {
+		t.Error("Negated value must not be added to key-value index")
+	}
+}
+
+func TestHubNumeric(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	c := cmap.New()
+
+	h.Subscribe(ctx, T("type=alert", "severity>=3"), func(ctx context.Context) {
+		c.Add("severe", 1)
+	})
+	h.Subscribe(ctx, T("type=alert", "severity<3"), func(ctx context.Context) {
+		c.Add("mild", 1)
+	})
+
+	for _, severity := range []string{"1", "2", "3", "4.5", "unknown"} {
+		h.Publish(ctx, T("type=alert", "severity="+severity), nil, Sync(true))
+	}
+
+	if !c.Eq(map[string]int{"severe": 2, "mild": 2}) {
+		t.Error("Result mismatch")
+	}
+
+	if _, err := NewTopic("severity>=high"); err == nil {
+		t.Error("Expected error for non-numeric value")
+	}
+}
+
+func TestSubscribeT(t *testing.T) {
+	type order struct {
+		ID    string
+		Items []string
+	}
+
+	ctx := context.Background()
+	h := New()
+
+	var got []order
+	_, err := SubscribeT(ctx, h, T("type=order"), func(ctx context.Context, o order) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeT() error = %v", err)
+	}
+
+	h.Publish(ctx, T("type=order"), order{ID: "1", Items: []string{"a"}}, Sync(true))
+	h.Publish(ctx, T("type=order"), "not an order", Sync(true))
+
+	if len(got) != 1 || got[0].ID != "1" {
+		t.Errorf("Received %v, want one order", got)
+	}
+}
+
+func TestSubscribeTCastError(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+
+	if _, err := SubscribeT(ctx, h, T("type=order"), func(ctx context.Context, v []int) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("SubscribeT() error = %v", err)
+	}
+
+	var err error
+	for _, s := range h.match(T("type=order"), nil) {
+		err = s.handler(ctx, T("type=order"), "wrong")
+	}
+
+	if _, ok := err.(*CastError); !ok {
+		t.Errorf("Expected CastError, got %v", err)
+	}
+}
+
+func TestHubPubSubAsyncSubscription(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	release := make(chan struct{})
+	slowDone := make(chan struct{})
+	fast := 0
+
+	_, err := h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {
+		<-release
+		close(slowDone)
+	}, Async(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {
+		fast++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finished := make(chan struct{})
+	// must not block on slow handler
+	_ = h.Publish(ctx, T("type=a"), nil, Sync(true), OnFinish(func(ctx context.Context) {
+		close(finished)
+	}))
+	if fast != 1 {
+		t.Errorf("fast handler calls = %d, want 1", fast)
+	}
+
+	select {
+	case <-finished:
+		t.Fatal("OnFinish called before async handler completed")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-slowDone
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("OnFinish not called")
+	}
+
+	// Sync + Wait waits for async handlers too
+	var calls atomic.Int32
+	h2 := New()
+	_, _ = h2.Subscribe(ctx, T("type=b"), func(ctx context.Context) {
+		time.Sleep(10 * time.Millisecond)
+		calls.Add(1)
+	}, Async(true))
+	_ = h2.Publish(ctx, T("type=b"), nil, Sync(true), Wait(true))
+	if calls.Load() != 1 {
+		t.Errorf("async handler calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestHubPubSubInlineSubscription(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	// plain counter is safe: inline handler runs in publisher goroutine
+	count := 0
+	_, err := h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {
+		count++
+	}, Inline(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {}, Inline(true), Once(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = h.Publish(ctx, T("type=a"), nil)
+	if count != 1 {
+		t.Errorf("count = %d after async publish, want 1", count)
+	}
+
+	finished := make(chan struct{})
+	_ = h.Publish(ctx, T("type=a"), nil, OnFinish(func(ctx context.Context) {
+		close(finished)
+	}))
+	if count != 2 {
+		t.Errorf("count = %d after publish with OnFinish, want 2", count)
+	}
+	<-finished
+
+	_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+	if count != 3 {
+		t.Errorf("count = %d after publish with Wait, want 3", count)
+	}
+
+	if n := len(h.subs); n != 1 {
+		t.Errorf("subscriptions = %d, want 1 (Once subscription removed)", n)
+	}
+}
+
+// indexLen sums size of index over all shards
+func indexLen(h *Hub, size func(ix *indexes) int) int {
+	n := 0
+	for i := range h.shards {
+		n += size(h.shards[i].load())
+	}
+	return n
+}
+
+func TestMatchNoAllocs(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	for _, tp := range []*Topic{T("type=alert"), T("type=alert", "level=high"), T("type=*"), T("level!=low"), T()} {
+		if _, err := h.Subscribe(ctx, tp, func(ctx context.Context) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tp := range []*Topic{T("type=alert"), T("type=alert", "level=high"), T("other=1")} {
+		var n int
+		allocs := testing.AllocsPerRun(100, func() {
+			var buf [16]*sub
+			n = len(h.match(tp, buf[:0]))
+		})
+		if allocs != 0 {
+			t.Errorf("match(%s) allocs = %v, want 0", tp, allocs)
+		}
+		if n == 0 {
+			t.Errorf("match(%s) found nothing", tp)
+		}
+	}
+}
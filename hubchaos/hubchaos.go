@@ -0,0 +1,130 @@
+// Package hubchaos injects faults into hub deliveries: latency, dropped
+// deliveries and handler errors with given probabilities. Random decisions
+// come from a seeded source, so a test publishing synchronously gets the
+// same faults on every run.
+//
+// Example:
+//
+//	h := hub.New(hubchaos.Option(hubchaos.Config{
+//	    Seed:               42,
+//	    DropProbability:    0.1,
+//	    ErrorProbability:   0.05,
+//	    LatencyProbability: 0.5,
+//	    Latency:            10 * time.Millisecond,
+//	}))
+package hubchaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// ErrInjected is returned by handlers when Config.Error is nil
+var ErrInjected = errors.New("hubchaos: injected error")
+
+// Config defines faults and their probabilities in range [0, 1]
+type Config struct {
+	// Seed of the random source
+	Seed int64
+	// Topic limits faults to deliveries of matching events, all if nil
+	Topic *hub.Topic
+
+	// DropProbability of skipping the handler call
+	DropProbability float64
+	// ErrorProbability of returning Error instead of calling the handler
+	ErrorProbability float64
+	// Error returned by failed deliveries, ErrInjected if nil
+	Error error
+
+	// LatencyProbability of sleeping before the handler call
+	LatencyProbability float64
+	// Latency is the minimal injected delay
+	Latency time.Duration
+	// Jitter is the maximal random delay added to Latency
+	Jitter time.Duration
+}
+
+// Option returns HubOption injecting faults into all subscriptions
+func Option(cfg Config) hub.HubOption {
+	return hub.WithMiddleware(Middleware(cfg))
+}
+
+// Middleware returns hub middleware injecting faults, for use with
+// hub.WithMiddleware together with other middlewares
+func Middleware(cfg Config) hub.Middleware {
+	c := &chaos{
+		cfg: cfg,
+		rnd: rand.New(rand.NewSource(cfg.Seed)),
+	}
+	if c.cfg.Error == nil {
+		c.cfg.Error = ErrInjected
+	}
+	return c.wrap
+}
+
+// chaos holds shared random source of a middleware
+type chaos struct {
+	cfg Config
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// fault is a decision for a single delivery
+type fault struct {
+	drop  bool
+	fail  bool
+	delay time.Duration
+}
+
+// next draws the decision for a delivery. All values are drawn every time,
+// so the sequence doesn't depend on probabilities of other faults.
+func (c *chaos) next() fault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	drop := c.rnd.Float64()
+	fail := c.rnd.Float64()
+	slow := c.rnd.Float64()
+	jitter := c.rnd.Float64()
+
+	f := fault{
+		drop: drop < c.cfg.DropProbability,
+		fail: fail < c.cfg.ErrorProbability,
+	}
+	if slow < c.cfg.LatencyProbability {
+		f.delay = c.cfg.Latency + time.Duration(jitter*float64(c.cfg.Jitter))
+	}
+	return f
+}
+
+// wrap injects faults into the handler
+func (c *chaos) wrap(next hub.Handler) hub.Handler {
+	return func(ctx context.Context, t *hub.Topic, p any) error {
+		if c.cfg.Topic != nil && !c.cfg.Topic.Match(t) {
+			return next(ctx, t, p)
+		}
+
+		f := c.next()
+		if f.delay > 0 {
+			timer := time.NewTimer(f.delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+		if f.drop {
+			return nil
+		}
+		if f.fail {
+			return c.cfg.Error
+		}
+		return next(ctx, t, p)
+	}
+}
@@ -0,0 +1,105 @@
+package hubchaos
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// run publishes n events synchronously and returns delivered payloads
+// and number of injected errors
+func run(t *testing.T, cfg Config, n int) ([]int, int) {
+	t.Helper()
+	ctx := context.Background()
+	var injected int
+	h := hub.New(Option(cfg), hub.OnError(func(ctx context.Context, _ *hub.Topic, _ hub.SubID, err error) {
+		if errors.Is(err, ErrInjected) {
+			injected++
+		}
+	}))
+
+	var got []int
+	_, err := h.Subscribe(ctx, hub.T("type=a"), func(ctx context.Context, v int) {
+		got = append(got, v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		_ = h.Publish(ctx, hub.T("type=a"), i, hub.Sync(true))
+	}
+	return got, injected
+}
+
+func TestDeterministic(t *testing.T) {
+	cfg := Config{
+		Seed:             1,
+		DropProbability:  0.3,
+		ErrorProbability: 0.2,
+	}
+	got1, errs1 := run(t, cfg, 100)
+	got2, errs2 := run(t, cfg, 100)
+
+	if !slices.Equal(got1, got2) || errs1 != errs2 {
+		t.Error("same seed produced different faults")
+	}
+	if len(got1) == 100 || len(got1) == 0 {
+		t.Errorf("delivered %d of 100, want some dropped", len(got1))
+	}
+	if errs1 == 0 {
+		t.Error("no errors injected")
+	}
+	if len(got1)+errs1 > 100 {
+		t.Errorf("delivered %d + failed %d > 100", len(got1), errs1)
+	}
+}
+
+func TestNoFaults(t *testing.T) {
+	got, errs := run(t, Config{Seed: 1}, 10)
+	if len(got) != 10 || errs != 0 {
+		t.Errorf("got %d deliveries and %d errors, want 10 and 0", len(got), errs)
+	}
+}
+
+func TestTopicAndLatency(t *testing.T) {
+	ctx := context.Background()
+	custom := errors.New("boom")
+	var reported []error
+	h := hub.New(Option(Config{
+		Topic:              hub.T("type=slow"),
+		ErrorProbability:   1,
+		Error:              custom,
+		LatencyProbability: 1,
+		Latency:            20 * time.Millisecond,
+	}), hub.OnError(func(ctx context.Context, _ *hub.Topic, _ hub.SubID, err error) {
+		reported = append(reported, err)
+	}))
+	_, _ = h.Subscribe(ctx, hub.T("type=*"), func(ctx context.Context) {})
+
+	publish := func(ctx context.Context, tp *hub.Topic) time.Duration {
+		start := time.Now()
+		_ = h.Publish(ctx, tp, nil, hub.Sync(true))
+		return time.Since(start)
+	}
+
+	if d := publish(ctx, hub.T("type=fast")); d >= 20*time.Millisecond {
+		t.Errorf("non-matching topic delayed by %s", d)
+	}
+	if d := publish(ctx, hub.T("type=slow")); d < 20*time.Millisecond {
+		t.Errorf("matching topic delayed by %s, want at least 20ms", d)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], custom) {
+		t.Errorf("reported errors = %v, want [%v]", reported, custom)
+	}
+
+	// Canceled context interrupts injected latency
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if d := publish(cctx, hub.T("type=slow")); d >= 20*time.Millisecond {
+		t.Errorf("latency is not interrupted by context, took %s", d)
+	}
+}
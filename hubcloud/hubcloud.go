@@ -0,0 +1,248 @@
+// Package hubcloud implements gocloud.dev/pubsub driver backed by the hub,
+// so code written against the portable API runs against the in-process
+// hub in tests and small deployments.
+//
+// It is a separate module, so the hub itself doesn't depend on gocloud.dev.
+//
+// Usage:
+//
+//	topic := hubcloud.OpenTopic(h, hub.T("type=order"))
+//	sub, err := hubcloud.OpenSubscription(ctx, h, hub.T("type=order"))
+//	err = topic.Send(ctx, &pubsub.Message{Body: []byte("hello")})
+//	msg, err := sub.Receive(ctx)
+package hubcloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/lomik/hub"
+	"gocloud.dev/gcerrors"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/driver"
+)
+
+// Message is the hub payload of messages sent through the driver
+type Message struct {
+	Body     []byte
+	Metadata map[string]string
+}
+
+// QueueSize limits messages received from the hub and not yet
+// delivered to pubsub.Subscription.Receive
+const QueueSize = 1024
+
+// ErrQueueFull is reported to hub OnError hooks for messages dropped
+// because the receiver doesn't keep up
+var ErrQueueFull = errors.New("hubcloud: subscription queue is full")
+
+// errClosed is returned after subscription is closed
+var errClosed = errors.New("hubcloud: subscription is closed")
+
+// OpenTopic returns pubsub.Topic publishing messages into hub h with topic t
+func OpenTopic(h *hub.Hub, t *hub.Topic) *pubsub.Topic {
+	return pubsub.NewTopic(&topic{h: h, t: t}, nil)
+}
+
+// topic implements driver.Topic
+type topic struct {
+	h *hub.Hub
+	t *hub.Topic
+}
+
+// SendBatch implements driver.Topic
+func (d *topic) SendBatch(ctx context.Context, ms []*driver.Message) error {
+	for _, m := range ms {
+		if m.BeforeSend != nil {
+			if err := m.BeforeSend(func(any) bool { return false }); err != nil {
+				return err
+			}
+		}
+		if err := d.h.Publish(ctx, d.t, Message{Body: m.Body, Metadata: m.Metadata}); err != nil {
+			return err
+		}
+		if m.AfterSend != nil {
+			if err := m.AfterSend(func(any) bool { return false }); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IsRetryable implements driver.Topic
+func (*topic) IsRetryable(error) bool { return false }
+
+// As implements driver.Topic
+func (*topic) As(any) bool { return false }
+
+// ErrorAs implements driver.Topic
+func (*topic) ErrorAs(error, any) bool { return false }
+
+// ErrorCode implements driver.Topic
+func (*topic) ErrorCode(err error) gcerrors.ErrorCode { return errorCode(err) }
+
+// Close implements driver.Topic
+func (*topic) Close() error { return nil }
+
+// OpenSubscription returns pubsub.Subscription receiving hub events
+// matching t. Messages published by OpenTopic keep their body and metadata,
+// other payloads are encoded: []byte and string as is, others as JSON.
+// Hub topic is added to metadata under "topic" key in this case.
+// Messages are delivered at most once: acks are no-ops and nacks are not supported.
+// Up to QueueSize messages are buffered for a slow receiver, newer ones are
+// dropped with ErrQueueFull reported to hub OnError hooks.
+func OpenSubscription(ctx context.Context, h *hub.Hub, t *hub.Topic) (*pubsub.Subscription, error) {
+	s := &subscription{
+		h:     h,
+		ready: make(chan struct{}, 1),
+	}
+	id, err := h.Subscribe(ctx, t, s.handle)
+	if err != nil {
+		return nil, err
+	}
+	s.id = id
+	return pubsub.NewSubscription(s, nil, nil), nil
+}
+
+// subscription implements driver.Subscription
+type subscription struct {
+	h  *hub.Hub
+	id hub.SubID
+
+	mu     sync.Mutex
+	queue  []*driver.Message
+	seq    uint64
+	closed bool
+	ready  chan struct{} // Signals non-empty queue
+}
+
+// handle queues hub event
+func (s *subscription) handle(ctx context.Context, t *hub.Topic, p any) error {
+	m, err := toDriverMessage(t, p)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	if len(s.queue) >= QueueSize {
+		return ErrQueueFull
+	}
+	s.seq++
+	m.LoggableID = strconv.FormatUint(s.seq, 10)
+	m.AckID = s.seq
+	s.queue = append(s.queue, m)
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// toDriverMessage converts hub event to driver message
+func toDriverMessage(t *hub.Topic, p any) (*driver.Message, error) {
+	switch v := p.(type) {
+	case Message:
+		return &driver.Message{Body: v.Body, Metadata: v.Metadata}, nil
+	case []byte:
+		return &driver.Message{Body: v, Metadata: map[string]string{"topic": t.String()}}, nil
+	case string:
+		return &driver.Message{Body: []byte(v), Metadata: map[string]string{"topic": t.String()}}, nil
+	default:
+		body, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return &driver.Message{Body: body, Metadata: map[string]string{"topic": t.String()}}, nil
+	}
+}
+
+// ReceiveBatch implements driver.Subscription
+func (s *subscription) ReceiveBatch(ctx context.Context, maxMessages int) ([]*driver.Message, error) {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return nil, errClosed
+		}
+		if len(s.queue) > 0 {
+			n := min(maxMessages, len(s.queue))
+			batch := s.queue[:n:n]
+			s.queue = s.queue[n:]
+			if len(s.queue) > 0 {
+				select {
+				case s.ready <- struct{}{}:
+				default:
+				}
+			}
+			s.mu.Unlock()
+			return batch, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.ready:
+		}
+	}
+}
+
+// SendAcks implements driver.Subscription
+func (*subscription) SendAcks(context.Context, []driver.AckID) error { return nil }
+
+// CanNack implements driver.Subscription
+func (*subscription) CanNack() bool { return false }
+
+// SendNacks implements driver.Subscription
+func (*subscription) SendNacks(context.Context, []driver.AckID) error {
+	return errors.New("hubcloud: nacks are not supported")
+}
+
+// IsRetryable implements driver.Subscription
+func (*subscription) IsRetryable(error) bool { return false }
+
+// As implements driver.Subscription
+func (*subscription) As(any) bool { return false }
+
+// ErrorAs implements driver.Subscription
+func (*subscription) ErrorAs(error, any) bool { return false }
+
+// ErrorCode implements driver.Subscription
+func (*subscription) ErrorCode(err error) gcerrors.ErrorCode { return errorCode(err) }
+
+// Close implements driver.Subscription
+func (s *subscription) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.queue = nil
+	s.mu.Unlock()
+	s.h.Unsubscribe(context.Background(), s.id)
+	return nil
+}
+
+// errorCode maps errors to gcerrors codes
+func errorCode(err error) gcerrors.ErrorCode {
+	var policyErr *hub.PolicyError
+	switch {
+	case err == nil:
+		return gcerrors.OK
+	case errors.Is(err, errClosed):
+		return gcerrors.FailedPrecondition
+	case errors.As(err, &policyErr):
+		return gcerrors.InvalidArgument
+	case errors.Is(err, context.Canceled):
+		return gcerrors.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return gcerrors.DeadlineExceeded
+	default:
+		return gcerrors.Unknown
+	}
+}
@@ -0,0 +1,126 @@
+package hubcloud
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+	"gocloud.dev/gcerrors"
+	"gocloud.dev/pubsub"
+)
+
+func TestSendReceive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := hub.New()
+
+	sub, err := OpenSubscription(ctx, h, hub.T("type=order"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic := OpenTopic(h, hub.T("type=order", "id=1"))
+
+	err = topic.Send(ctx, &pubsub.Message{Body: []byte("hello"), Metadata: map[string]string{"k": "v"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := sub.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Body) != "hello" || msg.Metadata["k"] != "v" {
+		t.Errorf("Receive() = %q %v", msg.Body, msg.Metadata)
+	}
+	msg.Ack()
+
+	// other payloads are encoded
+	for _, tc := range []struct {
+		payload any
+		body    string
+	}{
+		{"text", "text"},
+		{[]byte("raw"), "raw"},
+		{map[string]int{"n": 1}, `{"n":1}`},
+	} {
+		if err := h.Publish(ctx, hub.T("type=order", "id=2"), tc.payload); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg.Body) != tc.body || msg.Metadata["topic"] != "id=2 type=order" {
+			t.Errorf("Receive() of %v = %q %v", tc.payload, msg.Body, msg.Metadata)
+		}
+		msg.Ack()
+	}
+
+	if err := topic.Shutdown(ctx); err != nil {
+		t.Errorf("Topic.Shutdown() = %v", err)
+	}
+	if err := sub.Shutdown(ctx); err != nil {
+		t.Errorf("Subscription.Shutdown() = %v", err)
+	}
+	if n := h.Len(); n != 0 {
+		t.Errorf("hub subscriptions after Shutdown = %d, want 0", n)
+	}
+	if _, err := sub.Receive(ctx); err == nil {
+		t.Error("Receive() after Shutdown succeeded")
+	}
+}
+
+func TestReceiveCanceled(t *testing.T) {
+	h := hub.New()
+	sub, err := OpenSubscription(context.Background(), h, hub.T("type=order"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := sub.Receive(ctx); gcerrors.Code(err) != gcerrors.DeadlineExceeded {
+		t.Errorf("Receive() = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var dropped atomic.Int32
+	h := hub.New(hub.OnError(func(ctx context.Context, t *hub.Topic, id hub.SubID, err error) {
+		if errors.Is(err, ErrQueueFull) {
+			dropped.Add(1)
+		}
+	}))
+	sub, err := OpenSubscription(ctx, h, hub.T("type=order"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Shutdown(ctx)
+
+	for i := range QueueSize + 2 {
+		if err := h.Publish(ctx, hub.T("type=order"), i, hub.Sync(true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := dropped.Load(); n != 2 {
+		t.Errorf("dropped = %d, want 2", n)
+	}
+
+	// queued messages are received in order
+	for i := range QueueSize {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg.Body) != strconv.Itoa(i) {
+			t.Fatalf("message %d = %q", i, msg.Body)
+		}
+		msg.Ack()
+	}
+}
@@ -0,0 +1,164 @@
+// Package hubhook delivers hub events to HTTP webhooks.
+//
+// Each matching event is POSTed as JSON:
+//
+//	{"id": 42, "topic": "type=order", "payload": {...}}
+//
+// Failed deliveries (network errors, 429 and 5xx responses) are retried
+// with exponential backoff. Requests are signed with HMAC-SHA256
+// when a secret is configured. With Options.Encryptor the payload is
+// sent as base64 encoded ciphertext of its JSON.
+package hubhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// HeaderSignature holds "sha256=<hex HMAC of body>" when Options.Secret is set
+const HeaderSignature = "X-Hub-Signature-256"
+
+// Default delivery options
+const (
+	DefaultRetries = 3
+	DefaultBackoff = 500 * time.Millisecond
+)
+
+// Options configures webhook delivery
+type Options struct {
+	// Client sends requests, http.DefaultClient if nil
+	Client *http.Client
+	// Secret signs request body, requests are not signed if empty
+	Secret []byte
+	// Header is added to every request
+	Header http.Header
+	// Retries is the number of retries after the first attempt,
+	// DefaultRetries if 0, negative disables retries
+	Retries int
+	// Backoff is the delay before the first retry, doubled for each next one,
+	// DefaultBackoff if 0
+	Backoff time.Duration
+	// Encryptor encrypts JSON of event payloads, Body.Payload is then
+	// the ciphertext encoded as base64 string. Payloads are sent
+	// in plaintext if nil.
+	Encryptor hub.Encryptor
+}
+
+// Body is the JSON document POSTed to the webhook
+type Body struct {
+	ID      hub.EventID `json:"id"`
+	Topic   string      `json:"topic"`
+	Payload any         `json:"payload"`
+}
+
+// New subscribes webhook url to events matching topic.
+// The webhook is a normal asynchronous subscription: it shows up in hub
+// introspection, is removed with h.Unsubscribe, and delivery errors
+// are reported to hub OnError hooks.
+//
+// Example:
+//
+//	id, err := hubhook.New(ctx, h, hub.T("type=order"), "https://example.com/hook", hubhook.Options{
+//	    Secret: []byte("s3cr3t"),
+//	})
+func New(ctx context.Context, h *hub.Hub, topic *hub.Topic, url string, opts Options) (hub.SubID, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	w := &webhook{url: url, opts: opts}
+	return h.Subscribe(ctx, topic, w.handle, hub.Async(true))
+}
+
+// webhook delivers events to url
+type webhook struct {
+	url  string
+	opts Options
+}
+
+// handle encodes the event and delivers it with retries
+func (w *webhook) handle(ctx context.Context, t *hub.Topic, p any) error {
+	id, _ := hub.EventIDFromContext(ctx)
+	if w.opts.Encryptor != nil {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if p, err = w.opts.Encryptor.Encrypt(data); err != nil {
+			return err
+		}
+	}
+	body, err := json.Marshal(Body{
+		ID:      id,
+		Topic:   t.String(),
+		Payload: p,
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := w.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.opts.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends body once, reports whether failed request can be retried
+func (w *webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range w.opts.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.opts.Secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(w.opts.Secret, body))
+	}
+
+	resp, err := w.opts.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook %s: unexpected status %s", w.url, resp.Status)
+}
+
+// Sign returns value of HeaderSignature for body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,149 @@
+package hubhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("secret")
+
+	var attempts atomic.Int32
+	received := make(chan Body, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail first attempt to check retries
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderSignature) != Sign(secret, data) {
+			t.Error("invalid signature")
+		}
+		if r.Header.Get("X-Test") != "1" {
+			t.Error("custom header is missing")
+		}
+		var b Body
+		if err := json.Unmarshal(data, &b); err != nil {
+			t.Error(err)
+		}
+		received <- b
+	}))
+	defer srv.Close()
+
+	h := hub.New()
+	id, err := New(ctx, h, hub.T("type=order"), srv.URL, Options{
+		Secret:  secret,
+		Header:  http.Header{"X-Test": []string{"1"}},
+		Backoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == 0 || h.Len() != 1 {
+		t.Fatalf("webhook is not subscribed: id=%d len=%d", id, h.Len())
+	}
+
+	_ = h.Publish(ctx, hub.T("type=order"), map[string]any{"total": 10}, hub.Wait(true))
+
+	select {
+	case b := <-received:
+		if b.ID != 1 || b.Topic != "type=order" || b.Payload.(map[string]any)["total"] != 10.0 {
+			t.Errorf("unexpected body: %+v", b)
+		}
+	default:
+		t.Fatal("webhook is not called")
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("attempts = %d, want 2", n)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var errs atomic.Int32
+	h := hub.New(hub.OnError(func(ctx context.Context, _ *hub.Topic, _ hub.SubID, err error) {
+		errs.Add(1)
+	}))
+	_, _ = New(ctx, h, hub.T("type=bad"), srv.URL+"/bad", Options{Backoff: time.Millisecond})
+	_, _ = New(ctx, h, hub.T("type=fail"), srv.URL+"/fail", Options{Retries: 2, Backoff: time.Millisecond})
+
+	_ = h.Publish(ctx, hub.T("type=bad"), nil, hub.Wait(true))
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("client errors must not be retried, attempts = %d", n)
+	}
+
+	_ = h.Publish(ctx, hub.T("type=fail"), nil, hub.Wait(true))
+	if n := attempts.Load(); n != 4 {
+		t.Errorf("attempts = %d, want 4", n)
+	}
+	if n := errs.Load(); n != 2 {
+		t.Errorf("reported errors = %d, want 2", n)
+	}
+
+	if err := (&webhook{url: "://bad", opts: Options{Client: http.DefaultClient}}).handle(ctx, hub.T(), nil); err == nil {
+		t.Error("expected error for invalid url")
+	}
+}
+
+func TestNewEncryptor(t *testing.T) {
+	ctx := context.Background()
+	enc, err := hub.NewAESGCM(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received <- data
+	}))
+	defer srv.Close()
+
+	h := hub.New()
+	if _, err := New(ctx, h, hub.T("type=secret"), srv.URL, Options{Encryptor: enc}); err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Publish(ctx, hub.T("type=secret"), map[string]string{"token": "abc"}, hub.Wait(true))
+
+	data := <-received
+	if bytes.Contains(data, []byte("abc")) {
+		t.Errorf("body contains plaintext: %s", data)
+	}
+	var b struct {
+		Topic   string
+		Payload []byte
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		t.Fatal(err)
+	}
+	plain, err := enc.Decrypt(b.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Topic != "type=secret" || string(plain) != `{"token":"abc"}` {
+		t.Errorf("body = %s, payload = %s", data, plain)
+	}
+}
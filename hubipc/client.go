@@ -0,0 +1,126 @@
+package hubipc
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+
+	"github.com/lomik/hub"
+)
+
+// Client is a connection to a hub served by Serve.
+// Remote events matching client subscriptions are published
+// into the local hub, so handlers subscribe to them as usual.
+type Client struct {
+	local *hub.Hub
+	nc    net.Conn
+
+	wmu sync.Mutex // Serializes writes
+
+	done chan struct{} // Closed when read loop exits
+
+	onMessage func(m *Message) // Called for every received message, may be nil
+}
+
+// Dial connects to the unix socket at path. Remote events are published
+// into local hub.
+//
+// Example:
+//
+//	c, err := hubipc.Dial(ctx, "/run/myapp/hub.sock", h)
+//	...
+//	c.Subscribe(hub.T("type=alert"))
+//	h.Subscribe(ctx, hub.T("type=alert"), func(ctx context.Context, p []byte) { ... })
+func Dial(ctx context.Context, path string, local *hub.Hub) (*Client, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(nc, local), nil
+}
+
+// NewClient creates Client over established connection
+func NewClient(nc net.Conn, local *hub.Hub) *Client {
+	return newClient(nc, local, nil)
+}
+
+// newClient creates Client with message hook
+func newClient(nc net.Conn, local *hub.Hub, onMessage func(m *Message)) *Client {
+	c := &Client{
+		local:     local,
+		nc:        nc,
+		done:      make(chan struct{}),
+		onMessage: onMessage,
+	}
+	go c.read()
+	return c
+}
+
+// read publishes received events into the local hub
+func (c *Client) read() {
+	defer close(c.done)
+
+	r := bufio.NewReader(c.nc)
+	for {
+		m, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		if c.onMessage != nil {
+			c.onMessage(m)
+		}
+		if m.Op != OpEvent {
+			continue
+		}
+		t, err := c.local.ParseTopicText(m.Topic)
+		if err != nil {
+			continue
+		}
+		var payload any
+		if len(m.Payload) > 0 {
+			payload = []byte(m.Payload)
+		}
+		_ = c.local.Publish(context.Background(), t, payload)
+	}
+}
+
+// Subscribe asks the server to forward events matching t
+func (c *Client) Subscribe(t *hub.Topic) error {
+	return c.write(&Message{Op: OpSubscribe, Topic: t.String()})
+}
+
+// Unsubscribe stops forwarding of events matching t
+func (c *Client) Unsubscribe(t *hub.Topic) error {
+	return c.write(&Message{Op: OpUnsubscribe, Topic: t.String()})
+}
+
+// Publish publishes event into the server hub.
+// Payload is encoded as JSON, []byte payload must be JSON already.
+func (c *Client) Publish(t *hub.Topic, payload any) error {
+	data, err := encodePayload(payload)
+	if err != nil {
+		return err
+	}
+	return c.write(&Message{Op: OpPublish, Topic: t.String(), Payload: data})
+}
+
+// Done is closed when the connection is lost or closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	err := c.nc.Close()
+	<-c.done
+	return err
+}
+
+// write sends message to the server
+func (c *Client) write(m *Message) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return writeMessage(c.nc, m)
+}
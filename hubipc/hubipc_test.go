@@ -0,0 +1,112 @@
+package hubipc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// waitLen waits for number of hub subscriptions
+func waitLen(t *testing.T, h *hub.Hub, n int) {
+	t.Helper()
+	for i := 0; i < 100 && h.Len() != n; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if h.Len() != n {
+		t.Fatalf("subscriptions = %d, want %d", h.Len(), n)
+	}
+}
+
+func TestServeDial(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "hub.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := hub.New()
+	served := make(chan error)
+	go func() {
+		served <- Serve(ctx, server, ln)
+	}()
+
+	local := hub.New()
+	c, err := Dial(ctx, path, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// remote events are published into local hub
+	got := make(chan []byte, 1)
+	_, _ = local.Subscribe(ctx, hub.T("type=alert"), func(ctx context.Context, p []byte) {
+		got <- p
+	})
+	if err := c.Subscribe(hub.T("type=alert")); err != nil {
+		t.Fatal(err)
+	}
+	waitLen(t, server, 1)
+
+	_ = server.Publish(ctx, hub.T("type=alert"), map[string]int{"n": 1})
+	select {
+	case p := <-got:
+		if !bytes.Equal(p, []byte(`{"n":1}`)) {
+			t.Errorf("payload = %s", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event is not received")
+	}
+
+	// client publishes into server hub, the event is not echoed back
+	remote := make(chan []byte, 1)
+	_, _ = server.Subscribe(ctx, hub.T("type=alert"), func(ctx context.Context, p []byte) {
+		remote <- p
+	})
+	if err := c.Publish(hub.T("type=alert"), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-remote:
+		if string(p) != `"hello"` {
+			t.Errorf("payload = %s", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event is not published on server")
+	}
+	select {
+	case p := <-got:
+		t.Errorf("event echoed back: %s", p)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := c.Unsubscribe(hub.T("type=alert")); err != nil {
+		t.Fatal(err)
+	}
+	waitLen(t, server, 1)
+
+	// subscriptions are removed on disconnect
+	_ = c.Subscribe(hub.T("type=other"))
+	waitLen(t, server, 2)
+	_ = c.Close()
+	waitLen(t, server, 1)
+
+	cancel()
+	if err := <-served; err != context.Canceled {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+	if _, err := readMessage(&buf); err != errMessageTooLarge {
+		t.Errorf("readMessage() error = %v", err)
+	}
+}
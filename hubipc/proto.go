@@ -0,0 +1,86 @@
+package hubipc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxMessageSize limits size of one protocol message
+const MaxMessageSize = 16 << 20
+
+// Message operations
+const (
+	OpSubscribe   = "sub"   // client -> server: forward events matching Topic
+	OpUnsubscribe = "unsub" // client -> server: stop forwarding Topic
+	OpPublish     = "pub"   // client -> server: publish event into server hub
+	OpEvent       = "event" // server -> client: event matching subscription
+	OpError       = "error" // server -> client: failed command, Error is set
+	OpHello       = "hello" // server -> client: sent on connect, ID is the last event ID
+)
+
+// Message is a protocol message. On the wire each message is
+// a 4 byte big-endian length followed by JSON encoded Message.
+type Message struct {
+	Op      string          `json:"op"`
+	ID      uint64          `json:"id,omitempty"` // Server hub event ID`
+	Topic   string          `json:"topic,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// errMessageTooLarge is returned for messages exceeding MaxMessageSize
+var errMessageTooLarge = errors.New("hubipc: message too large")
+
+// writeMessage writes length-prefixed message
+func writeMessage(w io.Writer, m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxMessageSize {
+		return errMessageTooLarge
+	}
+	buf := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	_, err = w.Write(append(buf, data...))
+	return err
+}
+
+// readMessage reads length-prefixed message
+func readMessage(r io.Reader) (*Message, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > MaxMessageSize {
+		return nil, errMessageTooLarge
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	m := &Message{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("hubipc: invalid message: %w", err)
+	}
+	return m, nil
+}
+
+// encodePayload converts payload to JSON, []byte payloads
+// are expected to be JSON already
+func encodePayload(p any) (json.RawMessage, error) {
+	switch v := p.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return v, nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,217 @@
+package hubipc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// ErrNotConnected is returned by ReconnectingClient.Publish while
+// the connection is down
+var ErrNotConnected = errors.New("hubipc: not connected")
+
+// Default reconnect backoff
+const (
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// Gap is a range of server event IDs published while the client was
+// disconnected. Events of the range matching client subscriptions
+// were missed.
+type Gap struct {
+	From hub.EventID // First possibly missed event
+	To   hub.EventID // Last possibly missed event
+}
+
+// ReconnectOptions configures ReconnectingClient
+type ReconnectOptions struct {
+	// MinBackoff is the delay before the first reconnect attempt,
+	// doubled after each failure, DefaultMinBackoff if 0
+	MinBackoff time.Duration
+	// MaxBackoff limits the delay, DefaultMaxBackoff if 0
+	MaxBackoff time.Duration
+	// OnConnect is called after (re)connect and resubscribe, may be nil
+	OnConnect func()
+	// OnGap is called after reconnect if events were published
+	// on the server while the client was disconnected, may be nil
+	OnGap func(g Gap)
+}
+
+// ReconnectingClient is a Client which persists its subscription set,
+// reconnects after connection loss and restores subscriptions.
+type ReconnectingClient struct {
+	dial  func(ctx context.Context) (net.Conn, error)
+	local *hub.Hub
+	opts  ReconnectOptions
+
+	mu     sync.Mutex
+	cur    *Client               // nil while disconnected
+	subs   map[string]*hub.Topic // Subscriptions by canonical topic
+	lastID hub.EventID           // Last known server event ID
+	seen   bool                  // Connected at least once
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// DialReconnect connects to the unix socket at path in background
+// and keeps the connection until ctx is done or Close is called.
+// Remote events are published into local hub.
+//
+// Example:
+//
+//	c := hubipc.DialReconnect(ctx, "/run/myapp/hub.sock", h, hubipc.ReconnectOptions{
+//	    OnGap: func(g hubipc.Gap) {
+//	        log.Printf("missed events %d..%d, resyncing", g.From, g.To)
+//	    },
+//	})
+//	c.Subscribe(hub.T("type=alert"))
+func DialReconnect(ctx context.Context, path string, local *hub.Hub, opts ReconnectOptions) *ReconnectingClient {
+	return newReconnectingClient(ctx, func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}, local, opts)
+}
+
+// newReconnectingClient starts connection loop with dial function
+func newReconnectingClient(ctx context.Context, dial func(ctx context.Context) (net.Conn, error), local *hub.Hub, opts ReconnectOptions) *ReconnectingClient {
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = DefaultMinBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c := &ReconnectingClient{
+		dial:   dial,
+		local:  local,
+		opts:   opts,
+		subs:   make(map[string]*hub.Topic),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go c.run(ctx)
+	return c
+}
+
+// run connects and reconnects until ctx is done
+func (c *ReconnectingClient) run(ctx context.Context) {
+	defer close(c.done)
+
+	backoff := c.opts.MinBackoff
+	for ctx.Err() == nil {
+		nc, err := c.dial(ctx)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, c.opts.MaxBackoff)
+			continue
+		}
+		backoff = c.opts.MinBackoff
+
+		cl := newClient(nc, c.local, c.onMessage)
+		if c.attach(cl) {
+			if c.opts.OnConnect != nil {
+				c.opts.OnConnect()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = cl.Close()
+		case <-cl.Done():
+		}
+
+		c.mu.Lock()
+		c.cur = nil
+		c.mu.Unlock()
+	}
+}
+
+// attach restores subscriptions on the new connection
+func (c *ReconnectingClient) attach(cl *Client) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range c.subs {
+		if err := cl.Subscribe(t); err != nil {
+			_ = cl.nc.Close()
+			return false
+		}
+	}
+	c.cur = cl
+	return true
+}
+
+// onMessage tracks server event IDs and detects gaps
+func (c *ReconnectingClient) onMessage(m *Message) {
+	id := hub.EventID(m.ID)
+	switch m.Op {
+	case OpHello:
+		c.mu.Lock()
+		var gap *Gap
+		if c.seen && id > c.lastID {
+			gap = &Gap{From: c.lastID + 1, To: id}
+		}
+		c.seen = true
+		c.lastID = max(c.lastID, id)
+		c.mu.Unlock()
+
+		if gap != nil && c.opts.OnGap != nil {
+			c.opts.OnGap(*gap)
+		}
+	case OpEvent:
+		c.mu.Lock()
+		c.lastID = max(c.lastID, id)
+		c.mu.Unlock()
+	}
+}
+
+// Subscribe asks the server to forward events matching t.
+// The subscription is restored after reconnects.
+func (c *ReconnectingClient) Subscribe(t *hub.Topic) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs[t.String()] = t
+	if c.cur == nil {
+		return nil
+	}
+	return c.cur.Subscribe(t)
+}
+
+// Unsubscribe stops forwarding of events matching t
+func (c *ReconnectingClient) Unsubscribe(t *hub.Topic) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subs, t.String())
+	if c.cur == nil {
+		return nil
+	}
+	return c.cur.Unsubscribe(t)
+}
+
+// Publish publishes event into the server hub.
+// Returns ErrNotConnected while the connection is down.
+func (c *ReconnectingClient) Publish(t *hub.Topic, payload any) error {
+	c.mu.Lock()
+	cur := c.cur
+	c.mu.Unlock()
+	if cur == nil {
+		return ErrNotConnected
+	}
+	return cur.Publish(t, payload)
+}
+
+// Close stops reconnecting and closes the connection
+func (c *ReconnectingClient) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
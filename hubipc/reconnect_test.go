@@ -0,0 +1,103 @@
+package hubipc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// startServer serves hub h on unix socket path until returned stop is called
+func startServer(t *testing.T, h *hub.Hub, path string) (stop func()) {
+	t.Helper()
+	_ = os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = Serve(ctx, h, ln)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestReconnectingClient(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "hub.sock")
+
+	server := hub.New()
+	stop := startServer(t, server, path)
+
+	local := hub.New()
+	got := make(chan string, 10)
+	_, _ = local.Subscribe(ctx, hub.T("type=alert"), func(ctx context.Context, p []byte) {
+		got <- string(p)
+	})
+
+	connected := make(chan struct{}, 10)
+	gaps := make(chan Gap, 10)
+	c := DialReconnect(ctx, path, local, ReconnectOptions{
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		OnConnect:  func() { connected <- struct{}{} },
+		OnGap:      func(g Gap) { gaps <- g },
+	})
+	defer c.Close()
+
+	if err := c.Subscribe(hub.T("type=alert")); err != nil {
+		t.Fatal(err)
+	}
+	<-connected
+	waitLen(t, server, 1)
+
+	_ = server.Publish(ctx, hub.T("type=alert"), "one")
+	if p := <-got; p != `"one"` {
+		t.Errorf("payload = %s", p)
+	}
+
+	// connection loss
+	stop()
+	err := c.Publish(hub.T("type=x"), nil)
+	for i := 0; i < 100 && err != ErrNotConnected; i++ {
+		time.Sleep(time.Millisecond)
+		err = c.Publish(hub.T("type=x"), nil)
+	}
+	if err != ErrNotConnected {
+		t.Errorf("Publish() error = %v, want ErrNotConnected", err)
+	}
+	_ = server.Publish(ctx, hub.T("type=alert"), "missed")
+	_ = server.Publish(ctx, hub.T("type=other"), "missed")
+
+	// reconnect restores subscription and reports gap
+	stop = startServer(t, server, path)
+	defer stop()
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("client is not reconnected")
+	}
+	select {
+	case g := <-gaps:
+		if g.From != 2 || g.To != 3 {
+			t.Errorf("gap = %+v, want 2..3", g)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("gap is not reported")
+	}
+	waitLen(t, server, 1)
+
+	_ = server.Publish(ctx, hub.T("type=alert"), "two")
+	if p := <-got; p != `"two"` {
+		t.Errorf("payload = %s", p)
+	}
+}
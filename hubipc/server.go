@@ -0,0 +1,163 @@
+package hubipc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/lomik/hub"
+)
+
+// Serve accepts connections on ln and attaches them to hub h
+// until ctx is done or ln fails. Subscriptions of a client are
+// removed when it disconnects.
+//
+// Example:
+//
+//	ln, err := net.Listen("unix", "/run/myapp/hub.sock")
+//	...
+//	go hubipc.Serve(ctx, h, ln)
+func Serve(ctx context.Context, h *hub.Hub, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		c := &serverConn{
+			h:    h,
+			nc:   nc,
+			subs: make(map[string]hub.SubID),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.serve(ctx)
+		}()
+	}
+}
+
+// serverConn is a client connection on the server side
+type serverConn struct {
+	h  *hub.Hub
+	nc net.Conn
+
+	wmu sync.Mutex // Serializes writes
+
+	mu   sync.Mutex
+	subs map[string]hub.SubID // Subscriptions by canonical topic
+}
+
+// ctxKey marks events published by a client connection
+type ctxKey struct{}
+
+// serve executes client commands until disconnect
+func (c *serverConn) serve(ctx context.Context) {
+	defer c.close()
+
+	stop := context.AfterFunc(ctx, func() {
+		c.nc.Close()
+	})
+	defer stop()
+
+	if c.write(&Message{Op: OpHello, ID: uint64(c.h.LastEventID())}) != nil {
+		return
+	}
+
+	r := bufio.NewReader(c.nc)
+	for {
+		m, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		if err := c.command(ctx, m); err != nil {
+			if c.write(&Message{Op: OpError, Topic: m.Topic, Error: err.Error()}) != nil {
+				return
+			}
+		}
+	}
+}
+
+// command executes client command
+func (c *serverConn) command(ctx context.Context, m *Message) error {
+	t, err := c.h.ParseTopicText(m.Topic)
+	if err != nil {
+		return err
+	}
+	key := t.String()
+
+	switch m.Op {
+	case OpSubscribe:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subs[key]; ok {
+			return nil
+		}
+		id, err := c.h.Subscribe(ctx, t, c.deliver, hub.Async(true))
+		if err != nil {
+			return err
+		}
+		c.subs[key] = id
+		return nil
+	case OpUnsubscribe:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if id, ok := c.subs[key]; ok {
+			c.h.Unsubscribe(ctx, id)
+			delete(c.subs, key)
+		}
+		return nil
+	case OpPublish:
+		var payload any
+		if len(m.Payload) > 0 {
+			payload = m.Payload
+		}
+		return c.h.Publish(context.WithValue(ctx, ctxKey{}, c), t, payload)
+	default:
+		return errors.New("hubipc: unknown op: " + m.Op)
+	}
+}
+
+// deliver sends hub event to the client
+func (c *serverConn) deliver(ctx context.Context, t *hub.Topic, p any) error {
+	if ctx.Value(ctxKey{}) == c {
+		// published by this client
+		return nil
+	}
+	payload, err := encodePayload(p)
+	if err != nil {
+		return err
+	}
+	id, _ := hub.EventIDFromContext(ctx)
+	return c.write(&Message{Op: OpEvent, ID: uint64(id), Topic: t.String(), Payload: payload})
+}
+
+// write sends message to the client
+func (c *serverConn) write(m *Message) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return writeMessage(c.nc, m)
+}
+
+// close removes subscriptions and closes the connection
+func (c *serverConn) close() {
+	c.mu.Lock()
+	for key, id := range c.subs {
+		c.h.Unsubscribe(context.Background(), id)
+		delete(c.subs, key)
+	}
+	c.mu.Unlock()
+	c.nc.Close()
+}
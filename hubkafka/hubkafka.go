@@ -0,0 +1,259 @@
+// Package hubkafka bridges hub events to Kafka topics and back.
+//
+// The package doesn't depend on a Kafka client: Producer and Consumer
+// are small interfaces easily implemented on top of sarama, franz-go
+// or kafka-go.
+//
+// Outgoing events are keyed by a chosen topic attribute, so events
+// with the same attribute value land in the same partition and keep
+// their order. Incoming records are published into the hub and their
+// offsets are committed after all handlers complete.
+package hubkafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// HeaderTopic is a record header with the canonical hub topic
+const HeaderTopic = "hub-topic"
+
+// Record is a Kafka record
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+}
+
+// Producer sends records to Kafka
+type Producer interface {
+	Produce(ctx context.Context, r Record) error
+}
+
+// Consumer receives records from Kafka.
+// Fetch blocks until the next record is available or ctx is done.
+// Commit marks the record (and all previous records of its partition) processed.
+type Consumer interface {
+	Fetch(ctx context.Context) (Record, error)
+	Commit(ctx context.Context, r Record) error
+}
+
+// Config configures the bridge
+type Config struct {
+	// Out selects hub events sent to Kafka, nil disables outgoing direction
+	Out *hub.Topic
+	// Producer sends outgoing events, required if Out is set
+	Producer Producer
+	// KafkaTopic is the Kafka topic for outgoing events
+	KafkaTopic string
+	// KeyAttr is the hub topic attribute used as partition key,
+	// records are not keyed if empty
+	KeyAttr string
+	// Encode converts payload to record value, []byte and string payloads
+	// are sent as is, others are encoded with json.Marshal by default
+	Encode func(p any) ([]byte, error)
+
+	// Consumer receives incoming records, nil disables incoming direction
+	Consumer Consumer
+	// Topic builds hub topic for records without HeaderTopic,
+	// "kafka=<record topic>" by default
+	Topic func(r Record) *hub.Topic
+
+	// OnCommit is called after record offset is committed (or commit failed),
+	// may be nil
+	OnCommit func(ctx context.Context, r Record, err error)
+	// OnError is called on produce, fetch and decode errors, may be nil
+	OnError func(ctx context.Context, err error)
+
+	// Origin identifies the bridge in AttrOrigin of forwarded topics,
+	// random if empty, see hub.BridgeLoop
+	Origin string
+	// MaxHops limits forwarding between bridges, hub.DefaultMaxHops if zero
+	MaxHops int
+	// Encryptor encrypts outgoing record values and decrypts incoming,
+	// values are sent in plaintext if nil
+	Encryptor hub.Encryptor
+}
+
+// fetchRetryDelay is a pause after failed Fetch
+const fetchRetryDelay = 100 * time.Millisecond
+
+// ctxKey marks events published into the hub by a bridge
+type ctxKey struct{}
+
+// Run bridges events until ctx is done:
+// hub events matching cfg.Out are produced to cfg.KafkaTopic
+// and records fetched from cfg.Consumer are published into the hub.
+// Records published by this bridge are not sent back to Kafka, records
+// coming back through other bridges are skipped (see hub.BridgeLoop).
+// Returns ctx error.
+//
+// Example:
+//
+//	err := hubkafka.Run(ctx, h, hubkafka.Config{
+//	    Out:        hub.T("type=order"),
+//	    Producer:   producer,
+//	    KafkaTopic: "orders",
+//	    KeyAttr:    "customer",
+//	    Consumer:   consumer,
+//	})
+func Run(ctx context.Context, h *hub.Hub, cfg Config) error {
+	if cfg.Encode == nil {
+		cfg.Encode = encode
+	}
+	if cfg.Topic == nil {
+		cfg.Topic = func(r Record) *hub.Topic {
+			return hub.T("kafka", r.Topic)
+		}
+	}
+
+	if cfg.Origin == "" {
+		cfg.Origin = hub.NewOrigin()
+	}
+
+	b := &bridge{h: h, cfg: cfg, loop: hub.BridgeLoop{Origin: cfg.Origin, MaxHops: cfg.MaxHops}}
+
+	if cfg.Out != nil {
+		if cfg.Producer == nil {
+			return errors.New("hubkafka: Producer is required for outgoing events")
+		}
+		id, err := h.Subscribe(ctx, cfg.Out, b.out)
+		if err != nil {
+			return err
+		}
+		defer h.Unsubscribe(context.Background(), id)
+	}
+
+	if cfg.Consumer != nil {
+		b.consume(ctx)
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// bridge holds state of running bridge
+type bridge struct {
+	h    *hub.Hub
+	cfg  Config
+	loop hub.BridgeLoop
+}
+
+// out produces hub event to Kafka
+func (b *bridge) out(ctx context.Context, t *hub.Topic, p any) error {
+	if ctx.Value(ctxKey{}) == b {
+		// received from Kafka by this bridge
+		return nil
+	}
+	t, ok := b.loop.Outgoing(t)
+	if !ok {
+		return nil
+	}
+	value, err := b.cfg.Encode(p)
+	if err != nil {
+		b.error(ctx, err)
+		return err
+	}
+	if b.cfg.Encryptor != nil {
+		if value, err = b.cfg.Encryptor.Encrypt(value); err != nil {
+			b.error(ctx, err)
+			return err
+		}
+	}
+	r := Record{
+		Topic:   b.cfg.KafkaTopic,
+		Value:   value,
+		Headers: map[string]string{HeaderTopic: t.String()},
+	}
+	if b.cfg.KeyAttr != "" {
+		r.Key = []byte(t.Get(b.cfg.KeyAttr))
+	}
+	if err := b.cfg.Producer.Produce(ctx, r); err != nil {
+		b.error(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// consume publishes fetched records into the hub until ctx is done
+func (b *bridge) consume(ctx context.Context) {
+	for {
+		r, err := b.cfg.Consumer.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			b.error(ctx, err)
+			// don't spin on persistent errors
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(fetchRetryDelay):
+			}
+			continue
+		}
+
+		t, err := b.topic(r)
+		if err != nil {
+			b.error(ctx, err)
+			continue
+		}
+
+		value := r.Value
+		if b.cfg.Encryptor != nil {
+			if value, err = b.cfg.Encryptor.Decrypt(value); err != nil {
+				b.error(ctx, err)
+				continue
+			}
+		}
+
+		if b.loop.Incoming(t) {
+			pubCtx := context.WithValue(ctx, ctxKey{}, b)
+			err = b.h.Publish(pubCtx, t, value, hub.Wait(true))
+			if err != nil {
+				b.error(ctx, err)
+				continue
+			}
+		}
+
+		// all handlers are completed or the record looped back
+		err = b.cfg.Consumer.Commit(ctx, r)
+		if b.cfg.OnCommit != nil {
+			b.cfg.OnCommit(ctx, r, err)
+		}
+	}
+}
+
+// topic restores hub topic of the record
+func (b *bridge) topic(r Record) (*hub.Topic, error) {
+	if s, ok := r.Headers[HeaderTopic]; ok {
+		return b.h.ParseTopicText(s)
+	}
+	return b.cfg.Topic(r), nil
+}
+
+// error reports err to OnError hook
+func (b *bridge) error(ctx context.Context, err error) {
+	if b.cfg.OnError != nil {
+		b.cfg.OnError(ctx, err)
+	}
+}
+
+// encode is default payload encoder
+func encode(p any) ([]byte, error) {
+	switch v := p.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,172 @@
+package hubkafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/lomik/hub"
+)
+
+// memKafka implements Producer and Consumer in memory
+type memKafka struct {
+	mu        sync.Mutex
+	produced  []Record
+	incoming  chan Record
+	committed []int64
+}
+
+func (k *memKafka) Produce(ctx context.Context, r Record) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.produced = append(k.produced, r)
+	return nil
+}
+
+func (k *memKafka) Fetch(ctx context.Context) (Record, error) {
+	select {
+	case r := <-k.incoming:
+		return r, nil
+	case <-ctx.Done():
+		return Record{}, ctx.Err()
+	}
+}
+
+func (k *memKafka) Commit(ctx context.Context, r Record) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.committed = append(k.committed, r.Offset)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h := hub.New()
+	k := &memKafka{incoming: make(chan Record)}
+
+	var mu sync.Mutex
+	var handled []string
+	_, _ = h.Subscribe(ctx, hub.T("type=order"), func(ctx context.Context, p []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, string(p))
+	})
+
+	commits := make(chan int64, 3)
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, h, Config{
+			Out:        hub.T("type=order"),
+			Producer:   k,
+			KafkaTopic: "orders",
+			KeyAttr:    "customer",
+			Consumer:   k,
+			Origin:     "dc1",
+			OnCommit: func(ctx context.Context, r Record, err error) {
+				commits <- r.Offset
+			},
+		})
+	}()
+
+	// incoming records are published into the hub and committed
+	k.incoming <- Record{Offset: 1, Value: []byte("from-header"), Headers: map[string]string{HeaderTopic: "type=order customer=1"}}
+	k.incoming <- Record{Topic: "orders", Offset: 2, Value: []byte("no-header")}
+	// record sent by this bridge came back through another one
+	k.incoming <- Record{Offset: 3, Value: []byte("looped"), Headers: map[string]string{HeaderTopic: "type=order _origin=dc1 _hops=2"}}
+	<-commits
+	<-commits
+	<-commits
+
+	// outgoing events are produced with partition key
+	_ = h.Publish(ctx, hub.T("type=order", "customer=42"), "local", hub.Sync(true))
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 2 || handled[0] != "from-header" || handled[1] != "local" {
+		t.Errorf("handled = %v", handled)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.committed) != 3 || k.committed[0] != 1 || k.committed[1] != 2 || k.committed[2] != 3 {
+		t.Errorf("committed = %v", k.committed)
+	}
+	// record received from Kafka is not sent back
+	if len(k.produced) != 1 {
+		t.Fatalf("produced = %+v", k.produced)
+	}
+	r := k.produced[0]
+	if r.Topic != "orders" || string(r.Key) != "42" || string(r.Value) != "local" || r.Headers[HeaderTopic] != "_hops=1 _origin=dc1 customer=42 type=order" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
+
+func TestRunEncryptor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enc, err := hub.NewAESGCM(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hub.New()
+	k := &memKafka{incoming: make(chan Record)}
+	received := make(chan string, 1)
+	_, _ = h.Subscribe(ctx, hub.T("type=secret"), func(ctx context.Context, p []byte) {
+		received <- string(p)
+	})
+
+	errs := make(chan error, 1)
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, h, Config{
+			Out:        hub.T("type=secret"),
+			Producer:   k,
+			KafkaTopic: "secrets",
+			Consumer:   k,
+			Encryptor:  enc,
+			OnError: func(ctx context.Context, err error) {
+				errs <- err
+			},
+		})
+	}()
+
+	for h.Len() < 2 {
+		// wait for the bridge subscription
+		runtime.Gosched()
+	}
+
+	// values are encrypted on egress
+	_ = h.Publish(ctx, hub.T("type=secret"), "token", hub.Sync(true))
+	<-received
+	k.mu.Lock()
+	r := k.produced[0]
+	k.mu.Unlock()
+	if bytes.Contains(r.Value, []byte("token")) {
+		t.Errorf("produced plaintext %q", r.Value)
+	}
+
+	// and decrypted on ingress
+	r.Headers = map[string]string{HeaderTopic: "type=secret"}
+	k.incoming <- r
+	if got := <-received; got != "token" {
+		t.Errorf("received %q, want token", got)
+	}
+	k.incoming <- Record{Value: []byte("plain"), Headers: r.Headers}
+	if err := <-errs; !errors.Is(err, hub.ErrDecrypt) {
+		t.Errorf("OnError() = %v, want ErrDecrypt", err)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,202 @@
+// Package hubmock provides a hand-written mock of hub.Interface for unit
+// tests of code publishing and subscribing to events.
+//
+// The mock records calls instead of delivering events. Handlers
+// registered with Subscribe can be invoked explicitly with Deliver:
+//
+//	func TestNotifier(t *testing.T) {
+//	    m := hubmock.New()
+//	    n := NewNotifier(m) // depends on hub.Interface
+//
+//	    n.OrderPaid(ctx, 42)
+//
+//	    if got := m.PublishedTo(hub.T("type=notification")); len(got) != 1 {
+//	        t.Fatalf("published %d notifications", len(got))
+//	    }
+//	}
+package hubmock
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/lomik/hub"
+)
+
+// Published is a recorded Publish call
+type Published struct {
+	Topic   *hub.Topic
+	Payload any
+	Opts    []hub.PublishOption
+}
+
+// Subscription is a recorded Subscribe call
+type Subscription struct {
+	ID       hub.SubID
+	Topic    *hub.Topic
+	Callback any
+	Opts     []hub.SubscribeOption
+	Active   bool // False after Unsubscribe or Close
+}
+
+// Mock implements hub.Interface recording calls.
+// Set PublishFunc and SubscribeFunc to inject errors.
+// It is safe for concurrent use.
+type Mock struct {
+	// PublishFunc is called by Publish if set, its error is returned
+	PublishFunc func(ctx context.Context, topic *hub.Topic, payload any) error
+	// SubscribeFunc is called by Subscribe if set, its error is returned
+	// and the subscription is not recorded
+	SubscribeFunc func(ctx context.Context, t *hub.Topic, cb any) error
+
+	mu        sync.Mutex
+	published []Published
+	subs      []Subscription
+	seq       hub.SubID
+	closed    bool
+}
+
+var _ hub.Interface = (*Mock)(nil)
+
+// New creates a Mock
+func New() *Mock {
+	return &Mock{}
+}
+
+// Subscribe implements hub.Interface
+func (m *Mock) Subscribe(ctx context.Context, t *hub.Topic, cb any, opts ...hub.SubscribeOption) (hub.SubID, error) {
+	if m.SubscribeFunc != nil {
+		if err := m.SubscribeFunc(ctx, t, cb); err != nil {
+			return 0, err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, hub.ErrClosed
+	}
+	m.seq++
+	m.subs = append(m.subs, Subscription{
+		ID:       m.seq,
+		Topic:    t,
+		Callback: cb,
+		Opts:     opts,
+		Active:   true,
+	})
+	return m.seq, nil
+}
+
+// Publish implements hub.Interface
+func (m *Mock) Publish(ctx context.Context, topic *hub.Topic, payload any, opts ...hub.PublishOption) error {
+	if m.PublishFunc != nil {
+		if err := m.PublishFunc(ctx, topic, payload); err != nil {
+			return err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return hub.ErrClosed
+	}
+	m.published = append(m.published, Published{
+		Topic:   topic,
+		Payload: payload,
+		Opts:    opts,
+	})
+	return nil
+}
+
+// Unsubscribe implements hub.Interface
+func (m *Mock) Unsubscribe(ctx context.Context, id hub.SubID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.subs {
+		if m.subs[i].ID == id {
+			m.subs[i].Active = false
+		}
+	}
+}
+
+// Len implements hub.Interface, returns the number of active subscriptions
+func (m *Mock) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, s := range m.subs {
+		if s.Active {
+			n++
+		}
+	}
+	return n
+}
+
+// Close implements hub.Interface
+func (m *Mock) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for i := range m.subs {
+		m.subs[i].Active = false
+	}
+	return nil
+}
+
+// Published returns recorded Publish calls in call order
+func (m *Mock) Published() []Published {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.published)
+}
+
+// PublishedTo returns recorded Publish calls with topics matching pattern
+func (m *Mock) PublishedTo(pattern *hub.Topic) []Published {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ret []Published
+	for _, p := range m.published {
+		if pattern.Match(p.Topic) {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// Subscriptions returns recorded Subscribe calls including inactive ones
+func (m *Mock) Subscriptions() []Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.subs)
+}
+
+// Reset discards recorded calls and subscriptions
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published = nil
+	m.subs = nil
+	m.closed = false
+}
+
+// Deliver calls handlers of active subscriptions matching topic in
+// the current goroutine, converting payload as hub.Hub does.
+// Returns errors of callback conversion and handlers joined.
+func (m *Mock) Deliver(ctx context.Context, topic *hub.Topic, payload any) error {
+	var errs []error
+	h := hub.New(hub.OnError(func(ctx context.Context, _ *hub.Topic, _ hub.SubID, err error) {
+		errs = append(errs, err)
+	}))
+	for _, s := range m.Subscriptions() {
+		if !s.Active {
+			continue
+		}
+		if _, err := h.Subscribe(ctx, s.Topic, s.Callback); err != nil {
+			return err
+		}
+	}
+	if err := h.Publish(ctx, topic, payload, hub.Sync(true)); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
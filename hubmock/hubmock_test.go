@@ -0,0 +1,90 @@
+package hubmock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lomik/hub"
+)
+
+// notifier is an example of code depending on hub.Interface
+type notifier struct {
+	h hub.Interface
+}
+
+func (n *notifier) orderPaid(ctx context.Context, id int) error {
+	return n.h.Publish(ctx, hub.T("type=notification", "kind=paid"), id)
+}
+
+func TestMock(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	n := &notifier{h: m}
+
+	if err := n.orderPaid(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	_ = m.Publish(ctx, hub.T("type=other"), nil)
+
+	got := m.PublishedTo(hub.T("type=notification"))
+	if len(got) != 1 || got[0].Payload != 42 || got[0].Topic.Get("kind") != "paid" {
+		t.Errorf("PublishedTo() = %+v", got)
+	}
+	if n := len(m.Published()); n != 2 {
+		t.Errorf("Published() = %d calls, want 2", n)
+	}
+
+	// injected error
+	errDown := errors.New("down")
+	m.PublishFunc = func(ctx context.Context, topic *hub.Topic, payload any) error {
+		return errDown
+	}
+	if err := n.orderPaid(ctx, 43); !errors.Is(err, errDown) {
+		t.Errorf("Publish() = %v, want %v", err, errDown)
+	}
+}
+
+func TestMockDeliver(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	var got []int
+	id, _ := m.Subscribe(ctx, hub.T("type=order"), func(ctx context.Context, id int) error {
+		if id < 0 {
+			return errors.New("negative")
+		}
+		got = append(got, id)
+		return nil
+	})
+	_, _ = m.Subscribe(ctx, hub.T("type=user"), func(ctx context.Context, name string) {
+		t.Error("unexpected delivery")
+	})
+
+	if err := m.Deliver(ctx, hub.T("type=order"), "7"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != 7 {
+		t.Errorf("delivered %v, want [7]", got)
+	}
+	if err := m.Deliver(ctx, hub.T("type=order"), -1); err == nil {
+		t.Error("Deliver() didn't return handler error")
+	}
+
+	m.Unsubscribe(ctx, id)
+	if n := m.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+	_ = m.Deliver(ctx, hub.T("type=order"), 8)
+	if len(got) != 1 {
+		t.Errorf("unsubscribed handler called: %v", got)
+	}
+
+	_ = m.Close()
+	if _, err := m.Subscribe(ctx, hub.T("type=order"), func(ctx context.Context) {}); !errors.Is(err, hub.ErrClosed) {
+		t.Errorf("Subscribe() after Close() = %v", err)
+	}
+	if m.Len() != 0 {
+		t.Errorf("Len() after Close() = %d", m.Len())
+	}
+}
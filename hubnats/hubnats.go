@@ -0,0 +1,244 @@
+// Package hubnats mirrors hub events to NATS subjects and vice versa,
+// so in-process pub/sub can scale out to multiple processes.
+//
+// It is a separate module, so the hub itself doesn't depend
+// on the NATS client.
+//
+// Topics are translated to subjects from values of Mapping.Keys:
+//
+//	type=alert severity=high  ->  hub.alert.high
+//
+// The full topic is carried in the HeaderTopic header, so receiving hubs
+// restore it exactly. Messages without the header (published by non-hub
+// clients) get a topic built from subject tokens.
+//
+// The bridge doesn't reconnect itself, it relies on nats.Conn: after a
+// reconnect the subscription of the incoming direction is restored, and
+// events sent while disconnected are buffered up to nats.ReconnectBufSize.
+// Events which can't be sent are reported to hub OnError hooks.
+package hubnats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/lomik/hub"
+	"github.com/nats-io/nats.go"
+)
+
+// HeaderTopic is a NATS header with the canonical hub topic
+const HeaderTopic = "Hub-Topic"
+
+// Mapping configures translation between hub topics and NATS subjects
+type Mapping struct {
+	// Prefix is the first subject token, "hub" if empty
+	Prefix string
+	// Keys are topic keys which values form subject tokens in order.
+	// Missing values are written as "_".
+	Keys []string
+	// Out selects hub events mirrored to NATS, nil disables outgoing direction
+	Out *hub.Topic
+	// In is NATS subject consumed into the hub, "<Prefix>.>" if empty
+	In string
+	// DisableIn skips incoming direction
+	DisableIn bool
+	// Encode converts payload to message data, []byte and string payloads
+	// are sent as is, others are encoded with json.Marshal by default
+	Encode func(p any) ([]byte, error)
+	// Origin identifies the bridge in AttrOrigin of forwarded topics,
+	// random if empty, see hub.BridgeLoop
+	Origin string
+	// MaxHops limits forwarding between bridges, hub.DefaultMaxHops if zero
+	MaxHops int
+	// Encryptor encrypts outgoing message data and decrypts incoming,
+	// data is sent in plaintext if nil
+	Encryptor hub.Encryptor
+}
+
+// ctxKey marks events published into the hub by a bridge
+type ctxKey struct{}
+
+// Bridge mirrors hub events matching m.Out to NATS and publishes messages
+// received from m.In into the hub. Events received from NATS are not
+// sent back, events coming back through other bridges are dropped
+// (see hub.BridgeLoop). Returns stop function which removes subscriptions
+// on both sides, it is also called when ctx is done. Create nc with
+// reconnect options (e.g. nats.MaxReconnects(-1)) to survive server
+// restarts, see the package doc.
+//
+// Example:
+//
+//	stop, err := hubnats.Bridge(ctx, h, nc, hubnats.Mapping{
+//	    Prefix: "events",
+//	    Keys:   []string{"type", "severity"},
+//	    Out:    hub.T("type=*"),
+//	})
+func Bridge(ctx context.Context, h *hub.Hub, nc *nats.Conn, m Mapping) (stop func() error, err error) {
+	if m.Prefix == "" {
+		m.Prefix = "hub"
+	}
+	if m.In == "" {
+		m.In = m.Prefix + ".>"
+	}
+	if m.Encode == nil {
+		m.Encode = encode
+	}
+	if m.Origin == "" {
+		m.Origin = hub.NewOrigin()
+	}
+
+	b := &bridge{h: h, nc: nc, m: m, loop: hub.BridgeLoop{Origin: m.Origin, MaxHops: m.MaxHops}}
+
+	if m.Out != nil {
+		b.subID, err = h.Subscribe(ctx, m.Out, b.out)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !m.DisableIn {
+		b.natsSub, err = nc.Subscribe(m.In, func(msg *nats.Msg) {
+			b.in(ctx, msg)
+		})
+		if err != nil {
+			_ = b.stop()
+			return nil, err
+		}
+	}
+
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			_ = b.stop()
+		}()
+	}
+
+	return b.stop, nil
+}
+
+// bridge holds state of running Bridge
+type bridge struct {
+	h       *hub.Hub
+	nc      *nats.Conn
+	m       Mapping
+	loop    hub.BridgeLoop
+	subID   hub.SubID
+	natsSub *nats.Subscription
+	once    sync.Once
+	stopErr error
+}
+
+// stop removes subscriptions on both sides, safe to call several times
+func (b *bridge) stop() error {
+	b.once.Do(func() {
+		if b.subID != 0 {
+			b.h.Unsubscribe(context.Background(), b.subID)
+		}
+		if b.natsSub != nil {
+			b.stopErr = b.natsSub.Unsubscribe()
+		}
+	})
+	return b.stopErr
+}
+
+// out sends hub event to NATS
+func (b *bridge) out(ctx context.Context, t *hub.Topic, p any) error {
+	if ctx.Value(ctxKey{}) == b {
+		// received from NATS by this bridge
+		return nil
+	}
+	t, ok := b.loop.Outgoing(t)
+	if !ok {
+		return nil
+	}
+	data, err := b.m.Encode(p)
+	if err != nil {
+		return err
+	}
+	if b.m.Encryptor != nil {
+		if data, err = b.m.Encryptor.Encrypt(data); err != nil {
+			return err
+		}
+	}
+	msg := nats.NewMsg(Subject(b.m.Prefix, b.m.Keys, t))
+	msg.Data = data
+	msg.Header.Set(HeaderTopic, t.String())
+	return b.nc.PublishMsg(msg)
+}
+
+// in publishes NATS message into the hub
+func (b *bridge) in(ctx context.Context, msg *nats.Msg) {
+	t, err := b.topic(msg)
+	if err != nil || !b.loop.Incoming(t) {
+		return
+	}
+	data := msg.Data
+	if b.m.Encryptor != nil {
+		if data, err = b.m.Encryptor.Decrypt(data); err != nil {
+			return
+		}
+	}
+	_ = b.h.Publish(context.WithValue(ctx, ctxKey{}, b), t, data)
+}
+
+// topic restores hub topic of NATS message
+func (b *bridge) topic(msg *nats.Msg) (*hub.Topic, error) {
+	if s := msg.Header.Get(HeaderTopic); s != "" {
+		return b.h.ParseTopicText(s)
+	}
+
+	tokens := strings.Split(msg.Subject, ".")
+	if len(tokens) == 0 || tokens[0] != b.m.Prefix {
+		return nil, errors.New("unexpected subject: " + msg.Subject)
+	}
+	q := url.Values{}
+	for i, k := range b.m.Keys {
+		if i+1 >= len(tokens) {
+			break
+		}
+		if v := tokens[i+1]; v != "_" {
+			q.Set(k, v)
+		}
+	}
+	return hub.TFromQuery(q), nil
+}
+
+// Subject builds NATS subject from prefix and values of keys in topic t.
+// Characters not allowed in subject tokens are replaced with '_'.
+func Subject(prefix string, keys []string, t *hub.Topic) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for _, k := range keys {
+		sb.WriteByte('.')
+		v := t.Get(k)
+		if v == "" {
+			sb.WriteByte('_')
+			continue
+		}
+		for _, r := range v {
+			switch r {
+			case '.', '*', '>', ' ', '\t', '\r', '\n':
+				sb.WriteByte('_')
+			default:
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// encode is default payload encoder
+func encode(p any) ([]byte, error) {
+	switch v := p.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
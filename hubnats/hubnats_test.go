@@ -0,0 +1,342 @@
+package hubnats
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+func TestSubject(t *testing.T) {
+	tests := []struct {
+		topic *hub.Topic
+		want  string
+	}{
+		{hub.T("type=alert", "severity=high"), "hub.alert.high"},
+		{hub.T("type=alert"), "hub.alert._"},
+		{hub.T("type", "a.b c"), "hub.a_b_c._"},
+		{hub.T("other=x"), "hub._._"},
+	}
+	for _, tt := range tests {
+		if got := Subject("hub", []string{"type", "severity"}, tt.topic); got != tt.want {
+			t.Errorf("Subject(%s) = %q, want %q", tt.topic, got, tt.want)
+		}
+	}
+}
+
+// runServer starts an embedded NATS server on a random port
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	s := natstest.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+// connect connects to s, the connection reconnects forever
+func connect(t *testing.T, s *server.Server) *nats.Conn {
+	t.Helper()
+	nc, err := nats.Connect(s.ClientURL(), nats.MaxReconnects(-1), nats.ReconnectWait(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+// receive returns the next value of ch or fails after a timeout
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+	var zero T
+	return zero
+}
+
+// event is a hub event received by a test subscriber
+type event struct {
+	topic   string
+	payload string
+}
+
+// collect subscribes to hub events matching topic
+func collect(t *testing.T, h *hub.Hub, topic *hub.Topic) <-chan event {
+	t.Helper()
+	ch := make(chan event, 16)
+	_, err := h.Subscribe(context.Background(), topic, func(ctx context.Context, t *hub.Topic, p any) error {
+		b, _ := p.([]byte)
+		ch <- event{t.String(), string(b)}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+func TestBridgeOut(t *testing.T) {
+	ctx := context.Background()
+	nc := connect(t, runServer(t))
+	h := hub.New()
+
+	msgs := make(chan *nats.Msg, 16)
+	if _, err := nc.ChanSubscribe("events.>", msgs); err != nil {
+		t.Fatal(err)
+	}
+	stop, err := Bridge(ctx, h, nc, Mapping{
+		Prefix:    "events",
+		Keys:      []string{"type", "severity"},
+		Out:       hub.T("type=*"),
+		DisableIn: true,
+		Origin:    "a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	_ = h.Publish(ctx, hub.T("type=alert", "severity=high", "id=1"), map[string]int{"n": 1}, hub.Sync(true))
+	msg := receive(t, msgs)
+	if msg.Subject != "events.alert.high" || string(msg.Data) != `{"n":1}` {
+		t.Errorf("message %s %q", msg.Subject, msg.Data)
+	}
+	if got := msg.Header.Get(HeaderTopic); got != "_hops=1 _origin=a id=1 severity=high type=alert" {
+		t.Errorf("%s = %q", HeaderTopic, got)
+	}
+}
+
+func TestBridgeIn(t *testing.T) {
+	ctx := context.Background()
+	nc := connect(t, runServer(t))
+	h := hub.New()
+	events := collect(t, h, hub.T())
+
+	stop, err := Bridge(ctx, h, nc, Mapping{Keys: []string{"type", "severity"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	_ = nc.Flush()
+
+	// the topic is restored from the header
+	msg := nats.NewMsg("hub.alert.high")
+	msg.Data = []byte("full")
+	msg.Header.Set(HeaderTopic, "id=1 severity=high type=alert")
+	if err := nc.PublishMsg(msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, events); got != (event{"id=1 severity=high type=alert", "full"}) {
+		t.Errorf("event = %+v", got)
+	}
+
+	// or built from subject tokens
+	_ = nc.Publish("hub.alert._", []byte("plain"))
+	if got := receive(t, events); got != (event{"type=alert", "plain"}) {
+		t.Errorf("event = %+v", got)
+	}
+}
+
+func TestBridgeEcho(t *testing.T) {
+	ctx := context.Background()
+	nc := connect(t, runServer(t))
+	h := hub.New()
+
+	msgs := make(chan *nats.Msg, 16)
+	if _, err := nc.ChanSubscribe("hub.>", msgs); err != nil {
+		t.Fatal(err)
+	}
+	stop, err := Bridge(ctx, h, nc, Mapping{Keys: []string{"type"}, Out: hub.T("type=*")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	events := collect(t, h, hub.T("type=*"))
+	_ = nc.Flush()
+
+	// message received from NATS is not sent back
+	_ = nc.Publish("hub.order", []byte("remote"))
+	receive(t, msgs)
+	receive(t, events)
+	if err := h.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_ = nc.Flush()
+	select {
+	case msg := <-msgs:
+		t.Errorf("echoed message %s %q", msg.Subject, msg.Data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBridgeLoop(t *testing.T) {
+	ctx := context.Background()
+	s := runServer(t)
+	a, b := hub.New(), hub.New()
+
+	// two hubs bridged both ways through one subject space
+	for origin, h := range map[string]*hub.Hub{"a": a, "b": b} {
+		nc := connect(t, s)
+		stop, err := Bridge(ctx, h, nc, Mapping{Keys: []string{"type"}, Out: hub.T("type=*"), Origin: origin})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stop()
+		_ = nc.Flush()
+	}
+	received := collect(t, b, hub.T("type=*"))
+	returned := collect(t, a, hub.T("type=*"))
+
+	_ = a.Publish(ctx, hub.T("type=order"), []byte("1"))
+	if got := receive(t, received); got != (event{"_hops=1 _origin=a type=order", "1"}) {
+		t.Errorf("event in b = %+v", got)
+	}
+	receive(t, returned) // local delivery in a
+	select {
+	case e := <-returned:
+		t.Errorf("event came back to a: %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBridgeEncryptor(t *testing.T) {
+	ctx := context.Background()
+	s := runServer(t)
+	enc, err := hub.NewAESGCM(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := hub.New(), hub.New()
+	raw := connect(t, s)
+	msgs := make(chan *nats.Msg, 16)
+	if _, err := raw.ChanSubscribe("hub.>", msgs); err != nil {
+		t.Fatal(err)
+	}
+
+	stopA, err := Bridge(ctx, a, connect(t, s), Mapping{Keys: []string{"type"}, Out: hub.T("type=*"), DisableIn: true, Encryptor: enc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopA()
+	ncB := connect(t, s)
+	stopB, err := Bridge(ctx, b, ncB, Mapping{Keys: []string{"type"}, Encryptor: enc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopB()
+	_ = ncB.Flush()
+	events := collect(t, b, hub.T("type=*"))
+
+	_ = a.Publish(ctx, hub.T("type=secret"), "token", hub.Sync(true))
+	if msg := receive(t, msgs); bytes.Contains(msg.Data, []byte("token")) {
+		t.Errorf("sent plaintext %q", msg.Data)
+	}
+	if got := receive(t, events); got.payload != "token" {
+		t.Errorf("decrypted %q, want token", got.payload)
+	}
+
+	// messages which can't be decrypted are dropped
+	_ = raw.Publish("hub.secret", []byte("plain"))
+	_ = raw.Flush()
+	select {
+	case e := <-events:
+		t.Errorf("undecryptable message published: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBridgeStop(t *testing.T) {
+	s := runServer(t)
+	nc := connect(t, s)
+	h := hub.New()
+	events := collect(t, h, hub.T("type=*"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop, err := Bridge(ctx, h, nc, Mapping{Keys: []string{"type"}, Out: hub.T("type=*")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 2 || nc.NumSubscriptions() != 1 {
+		t.Fatalf("subscriptions: hub %d, nats %d", h.Len(), nc.NumSubscriptions())
+	}
+
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stop(); err != nil {
+		t.Errorf("second stop() = %v", err)
+	}
+	if h.Len() != 1 || nc.NumSubscriptions() != 0 {
+		t.Errorf("subscriptions after stop: hub %d, nats %d", h.Len(), nc.NumSubscriptions())
+	}
+	_ = nc.Publish("hub.order", []byte("late"))
+	_ = nc.Flush()
+	select {
+	case e := <-events:
+		t.Errorf("event after stop: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// canceled ctx stops the bridge too
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	if _, err := Bridge(ctx2, h, nc, Mapping{Keys: []string{"type"}, Out: hub.T("type=*")}); err != nil {
+		t.Fatal(err)
+	}
+	cancel2()
+	cancel()
+	for deadline := time.Now().Add(2 * time.Second); h.Len() != 1 || nc.NumSubscriptions() != 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscriptions after cancel: hub %d, nats %d", h.Len(), nc.NumSubscriptions())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBridgeReconnect(t *testing.T) {
+	ctx := context.Background()
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	s := natstest.RunServer(&opts)
+
+	reconnected := make(chan struct{}, 1)
+	nc, err := nats.Connect(s.ClientURL(), nats.MaxReconnects(-1), nats.ReconnectWait(10*time.Millisecond),
+		nats.ReconnectHandler(func(*nats.Conn) { reconnected <- struct{}{} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	h := hub.New()
+	events := collect(t, h, hub.T("type=*"))
+	stop, err := Bridge(ctx, h, nc, Mapping{Keys: []string{"type"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// restart the server on the same port
+	restart := natstest.DefaultTestOptions
+	restart.Port = s.Addr().(*net.TCPAddr).Port
+	s.Shutdown()
+	s = natstest.RunServer(&restart)
+	defer s.Shutdown()
+	receive(t, reconnected)
+
+	// the incoming subscription is restored
+	other := connect(t, s)
+	_ = nc.Flush()
+	_ = other.Publish("hub.order", []byte("after"))
+	if got := receive(t, events); got != (event{"type=order", "after"}) {
+		t.Errorf("event = %+v", got)
+	}
+}
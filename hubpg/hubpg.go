@@ -0,0 +1,240 @@
+// Package hubpg bridges PostgreSQL LISTEN/NOTIFY and the hub.
+//
+// NOTIFY payloads on configured channels become hub events, and selected
+// hub events are sent with NOTIFY, giving database-driven apps a fan-out
+// path without extra infrastructure.
+//
+// The package doesn't depend on a PostgreSQL driver: Listener is a small
+// interface easily implemented on top of pgx or lib/pq, and SQLNotifier
+// sends notifications through database/sql.
+package hubpg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// AttrChannel is the topic attribute with notification channel
+const AttrChannel = "pg_channel"
+
+// Notification is a received NOTIFY
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Listener receives notifications.
+// Listen issues LISTEN for the channel, WaitForNotification blocks
+// until the next notification or ctx is done.
+type Listener interface {
+	Listen(ctx context.Context, channel string) error
+	WaitForNotification(ctx context.Context) (Notification, error)
+}
+
+// Notifier sends notifications
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+// SQLNotifier implements Notifier with pg_notify over database/sql
+type SQLNotifier struct {
+	DB *sql.DB
+}
+
+// Notify implements Notifier
+func (n SQLNotifier) Notify(ctx context.Context, channel, payload string) error {
+	_, err := n.DB.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
+
+// Route sends hub events matching Topic to Channel
+type Route struct {
+	Topic   *hub.Topic
+	Channel string
+}
+
+// Config configures the bridge
+type Config struct {
+	// Listener receives notifications of Channels, may be nil
+	Listener Listener
+	// Channels are listened for incoming notifications
+	Channels []string
+	// Topic builds hub topic for notification, "pg_channel=<channel>" by default
+	Topic func(n Notification) *hub.Topic
+
+	// Notifier sends outgoing notifications, required if Out is not empty
+	Notifier Notifier
+	// Out selects hub events sent with NOTIFY
+	Out []Route
+	// Encode converts payload to notification payload, string payloads
+	// are sent as is, others are encoded with json.Marshal by default
+	Encode func(p any) (string, error)
+	// Encryptor encrypts outgoing notification payloads and decrypts
+	// incoming, payloads are sent in plaintext if nil. NOTIFY payloads
+	// are text, so ciphertext is base64 encoded.
+	Encryptor hub.Encryptor
+
+	// OnError is called on listen, notify and encode errors, may be nil
+	OnError func(ctx context.Context, err error)
+}
+
+// waitRetryDelay is a pause after failed WaitForNotification
+const waitRetryDelay = 100 * time.Millisecond
+
+// ctxKey marks events published into the hub by a bridge
+type ctxKey struct{}
+
+// Run bridges notifications until ctx is done. Notification payloads are
+// published into the hub as strings. Events received by this bridge
+// are not sent back with NOTIFY. Returns ctx error, or error of initial
+// LISTEN and Subscribe calls.
+//
+// Example:
+//
+//	err := hubpg.Run(ctx, h, hubpg.Config{
+//	    Listener: listener,
+//	    Channels: []string{"orders"},
+//	    Notifier: hubpg.SQLNotifier{DB: db},
+//	    Out:      []hubpg.Route{{Topic: hub.T("type=invalidate"), Channel: "cache"}},
+//	})
+func Run(ctx context.Context, h *hub.Hub, cfg Config) error {
+	if cfg.Topic == nil {
+		cfg.Topic = func(n Notification) *hub.Topic {
+			return hub.T(AttrChannel, n.Channel)
+		}
+	}
+	if cfg.Encode == nil {
+		cfg.Encode = encode
+	}
+	if len(cfg.Out) > 0 && cfg.Notifier == nil {
+		return fmt.Errorf("hubpg: Notifier is required for outgoing events")
+	}
+
+	b := &bridge{h: h, cfg: cfg}
+
+	for _, r := range cfg.Out {
+		id, err := h.Subscribe(ctx, r.Topic, b.out(r.Channel))
+		if err != nil {
+			return err
+		}
+		defer h.Unsubscribe(context.Background(), id)
+	}
+
+	if cfg.Listener != nil && len(cfg.Channels) > 0 {
+		for _, ch := range cfg.Channels {
+			if err := cfg.Listener.Listen(ctx, ch); err != nil {
+				return err
+			}
+		}
+		b.listen(ctx)
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// bridge holds state of running bridge
+type bridge struct {
+	h   *hub.Hub
+	cfg Config
+}
+
+// out returns handler sending events to channel
+func (b *bridge) out(channel string) hub.Handler {
+	return func(ctx context.Context, t *hub.Topic, p any) error {
+		if ctx.Value(ctxKey{}) == b {
+			// received with LISTEN by this bridge
+			return nil
+		}
+		payload, err := b.cfg.Encode(p)
+		if err == nil && b.cfg.Encryptor != nil {
+			payload, err = b.encrypt(payload)
+		}
+		if err != nil {
+			b.error(ctx, err)
+			return err
+		}
+		if err := b.cfg.Notifier.Notify(ctx, channel, payload); err != nil {
+			b.error(ctx, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// listen publishes notifications into the hub until ctx is done
+func (b *bridge) listen(ctx context.Context) {
+	pubCtx := context.WithValue(ctx, ctxKey{}, b)
+	for {
+		n, err := b.cfg.Listener.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			b.error(ctx, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(waitRetryDelay):
+			}
+			continue
+		}
+		payload := n.Payload
+		if b.cfg.Encryptor != nil {
+			if payload, err = b.decrypt(payload); err != nil {
+				b.error(ctx, err)
+				continue
+			}
+		}
+		if err := b.h.Publish(pubCtx, b.cfg.Topic(n), payload); err != nil {
+			b.error(ctx, err)
+		}
+	}
+}
+
+// encrypt returns base64 encoded ciphertext of payload
+func (b *bridge) encrypt(payload string) (string, error) {
+	data, err := b.cfg.Encryptor.Encrypt([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decrypt returns plaintext of payload made by encrypt
+func (b *bridge) decrypt(payload string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("hubpg: %w: %w", hub.ErrDecrypt, err)
+	}
+	if data, err = b.cfg.Encryptor.Decrypt(data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// error reports err to OnError hook
+func (b *bridge) error(ctx context.Context, err error) {
+	if b.cfg.OnError != nil {
+		b.cfg.OnError(ctx, err)
+	}
+}
+
+// encode is default payload encoder
+func encode(p any) (string, error) {
+	switch v := p.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
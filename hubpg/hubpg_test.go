@@ -0,0 +1,154 @@
+package hubpg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// memPG implements Listener and Notifier in memory
+type memPG struct {
+	mu       sync.Mutex
+	channels map[string]bool
+	notified []Notification
+	incoming chan Notification
+}
+
+func (m *memPG) Listen(ctx context.Context, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channels[channel] = true
+	return nil
+}
+
+func (m *memPG) WaitForNotification(ctx context.Context) (Notification, error) {
+	select {
+	case n := <-m.incoming:
+		return n, nil
+	case <-ctx.Done():
+		return Notification{}, ctx.Err()
+	}
+}
+
+func (m *memPG) Notify(ctx context.Context, channel, payload string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notified = append(m.notified, Notification{channel, payload})
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h := hub.New()
+	pg := &memPG{channels: map[string]bool{}, incoming: make(chan Notification)}
+
+	got := make(chan string, 1)
+	_, _ = h.Subscribe(ctx, hub.T(AttrChannel, "cache"), func(ctx context.Context, p string) {
+		got <- p
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, h, Config{
+			Listener: pg,
+			Channels: []string{"cache"},
+			Notifier: pg,
+			Out: []Route{
+				{Topic: hub.T(AttrChannel, "cache"), Channel: "cache"},
+				{Topic: hub.T("type=order"), Channel: "orders"},
+			},
+		})
+	}()
+
+	pg.incoming <- Notification{Channel: "cache", Payload: "users:1"}
+	select {
+	case p := <-got:
+		if p != "users:1" {
+			t.Errorf("payload = %q", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification is not published")
+	}
+
+	_ = h.Publish(ctx, hub.T("type=order"), map[string]int{"id": 7}, hub.Sync(true))
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run() error = %v", err)
+	}
+
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	if !pg.channels["cache"] {
+		t.Error("LISTEN is not issued")
+	}
+	// notification received by the bridge is not sent back
+	if len(pg.notified) != 1 || pg.notified[0] != (Notification{"orders", `{"id":7}`}) {
+		t.Errorf("notified = %v", pg.notified)
+	}
+}
+
+func TestRunEncryptor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enc, err := hub.NewAESGCM(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hub.New()
+	pg := &memPG{channels: map[string]bool{}, incoming: make(chan Notification)}
+	received := make(chan string, 1)
+	_, _ = h.Subscribe(ctx, hub.T(AttrChannel, "secrets"), func(ctx context.Context, p string) {
+		received <- p
+	})
+
+	errs := make(chan error, 1)
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, h, Config{
+			Listener:  pg,
+			Channels:  []string{"secrets"},
+			Notifier:  pg,
+			Out:       []Route{{Topic: hub.T("type=secret"), Channel: "secrets"}},
+			Encryptor: enc,
+			OnError: func(ctx context.Context, err error) {
+				errs <- err
+			},
+		})
+	}()
+	for h.Len() < 2 {
+		// wait for the bridge subscription
+		time.Sleep(time.Millisecond)
+	}
+
+	// payloads are encrypted on egress
+	_ = h.Publish(ctx, hub.T("type=secret"), "token", hub.Sync(true))
+	pg.mu.Lock()
+	n := pg.notified[0]
+	pg.mu.Unlock()
+	if strings.Contains(n.Payload, "token") {
+		t.Errorf("notified plaintext %q", n.Payload)
+	}
+
+	// and decrypted on ingress
+	pg.incoming <- n
+	if got := <-received; got != "token" {
+		t.Errorf("received %q, want token", got)
+	}
+	pg.incoming <- Notification{Channel: "secrets", Payload: "plain"}
+	if err := <-errs; !errors.Is(err, hub.ErrDecrypt) {
+		t.Errorf("OnError() = %v, want ErrDecrypt", err)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,127 @@
+// Package hubprom exposes hub metrics to Prometheus.
+//
+// It is a separate module, so the hub itself doesn't depend
+// on the Prometheus client.
+//
+// Usage:
+//
+//	c := hubprom.NewCollector("myhub")
+//	prometheus.MustRegister(c)
+//	h := hub.New(hub.WithMetrics(c))
+package hubprom
+
+import (
+	"time"
+
+	"github.com/lomik/hub"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements hub.Metrics and prometheus.Collector.
+// Metrics are not labeled by topic to keep cardinality bounded.
+type Collector struct {
+	published     prometheus.Counter
+	delivered     prometheus.Counter
+	errors        prometheus.Counter
+	duration      prometheus.Histogram
+	inFlight      prometheus.Gauge
+	subscriptions prometheus.Gauge
+}
+
+var (
+	_ hub.Metrics          = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// NewCollector creates a Collector with metric names prefixed with namespace
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		published: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "hub",
+			Name:      "events_published_total",
+			Help:      "Number of events published.",
+		}),
+		delivered: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "hub",
+			Name:      "deliveries_total",
+			Help:      "Number of handler calls.",
+		}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "hub",
+			Name:      "handler_errors_total",
+			Help:      "Number of handler calls returned error.",
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "hub",
+			Name:      "handler_duration_seconds",
+			Help:      "Duration of handler calls.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "hub",
+			Name:      "handlers_in_flight",
+			Help:      "Number of handlers being executed.",
+		}),
+		subscriptions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "hub",
+			Name:      "subscriptions",
+			Help:      "Number of active subscriptions.",
+		}),
+	}
+}
+
+// collectors returns all underlying metrics
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.published,
+		c.delivered,
+		c.errors,
+		c.duration,
+		c.inFlight,
+		c.subscriptions,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.collectors() {
+		m.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.collectors() {
+		m.Collect(ch)
+	}
+}
+
+// EventPublished implements hub.Metrics
+func (c *Collector) EventPublished(*hub.Topic) {
+	c.published.Inc()
+}
+
+// EventDelivered implements hub.Metrics
+func (c *Collector) EventDelivered(_ *hub.Topic, _ hub.SubID, d time.Duration, err error) {
+	c.delivered.Inc()
+	c.duration.Observe(d.Seconds())
+	if err != nil {
+		c.errors.Inc()
+	}
+}
+
+// HandlersInFlight implements hub.Metrics
+func (c *Collector) HandlersInFlight(delta int) {
+	c.inFlight.Add(float64(delta))
+}
+
+// Subscriptions implements hub.Metrics
+func (c *Collector) Subscriptions(n int) {
+	c.subscriptions.Set(float64(n))
+}
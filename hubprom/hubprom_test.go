@@ -0,0 +1,46 @@
+package hubprom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lomik/hub"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector("test")
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+
+	h := hub.New(hub.WithMetrics(c))
+	_, _ = h.Subscribe(ctx, hub.T("type=a"), func(ctx context.Context) {})
+	_, _ = h.Subscribe(ctx, hub.T("type=a"), func(ctx context.Context) error {
+		return errors.New("fail")
+	})
+
+	_ = h.Publish(ctx, hub.T("type=a"), nil, hub.Sync(true))
+	_ = h.Publish(ctx, hub.T("type=b"), nil, hub.Sync(true))
+
+	if v := testutil.ToFloat64(c.published); v != 2 {
+		t.Errorf("published = %v, want 2", v)
+	}
+	if v := testutil.ToFloat64(c.delivered); v != 2 {
+		t.Errorf("delivered = %v, want 2", v)
+	}
+	if v := testutil.ToFloat64(c.errors); v != 1 {
+		t.Errorf("errors = %v, want 1", v)
+	}
+	if v := testutil.ToFloat64(c.inFlight); v != 0 {
+		t.Errorf("in flight = %v, want 0", v)
+	}
+	if v := testutil.ToFloat64(c.subscriptions); v != 2 {
+		t.Errorf("subscriptions = %v, want 2", v)
+	}
+	if n := testutil.CollectAndCount(c); n != 6 {
+		t.Errorf("collected %d metrics, want 6", n)
+	}
+}
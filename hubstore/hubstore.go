@@ -0,0 +1,351 @@
+// Package hubstore implements hub.Store in files and in memory.
+//
+// FileStore appends events as JSON lines to events.log in the store
+// directory, offsets of durable subscriptions to offsets.log, the last line
+// of a subscription wins. The offsets log is compacted on Open. A torn last
+// line left by a crash during write is discarded on Open, a line partially
+// written by a failed write (e.g. disk is full) is truncated right away.
+// Trim rewrites events.log without trimmed events.
+//
+// MemoryStore keeps events in memory, it is useful for tests and for
+// replay windows that don't need to survive restarts.
+//
+// Example:
+//
+//	store, err := hubstore.Open("/var/lib/app/events")
+//	if err != nil {
+//	    return err
+//	}
+//	defer store.Close()
+//	h := hub.New(hub.WithStore(store))
+package hubstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lomik/hub"
+)
+
+// File names in the store directory
+const (
+	EventsFile  = "events.log"
+	OffsetsFile = "offsets.log"
+)
+
+// offsetRecord is a line of the offsets log
+type offsetRecord struct {
+	Name string `json:"name"`
+	Next uint64 `json:"next"`
+}
+
+// FileStore is a hub.Store keeping events and offsets in files.
+// It is safe for concurrent use.
+type FileStore struct {
+	mu        sync.Mutex
+	dir       string
+	events    *os.File
+	offsets   *os.File
+	eventsEnd int64             // Size of complete lines in events.log
+	offsetEnd int64             // Size of complete lines in offsets.log
+	failed    error             // Set if a torn line can't be truncated
+	last      uint64            // Offset of the last event
+	committed map[string]uint64 // Offsets of durable subscriptions
+}
+
+// writeFile writes to store files, replaced in tests to simulate failures
+var writeFile = (*os.File).Write
+
+var _ hub.Store = (*FileStore)(nil)
+
+// Open opens the store in dir creating it if needed
+func Open(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &FileStore{
+		dir:       dir,
+		committed: make(map[string]uint64),
+	}
+	if err := s.openEvents(); err != nil {
+		return nil, err
+	}
+	if err := s.openOffsets(); err != nil {
+		s.events.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openEvents opens the events log and finds the last offset
+func (s *FileStore) openEvents() error {
+	f, err := os.OpenFile(filepath.Join(s.dir, EventsFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	good, err := scan(f, func(line []byte) error {
+		var ev hub.StoredEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return err
+		}
+		s.last = ev.Offset
+		return nil
+	})
+	if err == nil {
+		// drop a torn tail and append after the last complete line
+		err = f.Truncate(good)
+	}
+	if err == nil {
+		_, err = f.Seek(good, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("hubstore: %s: %w", EventsFile, err)
+	}
+	s.events = f
+	s.eventsEnd = good
+	return nil
+}
+
+// openOffsets reads the offsets log and rewrites it compacted
+func (s *FileStore) openOffsets() error {
+	name := filepath.Join(s.dir, OffsetsFile)
+	if f, err := os.Open(name); err == nil {
+		_, err = scan(f, func(line []byte) error {
+			var rec offsetRecord
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return err
+			}
+			s.committed[rec.Name] = rec.Next
+			return nil
+		})
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("hubstore: %s: %w", OffsetsFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for name, next := range s.committed {
+		appendRecord(&buf, offsetRecord{Name: name, Next: next})
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.offsets = f
+	s.offsetEnd = int64(buf.Len())
+	return nil
+}
+
+// scan calls fn for every complete line of r and returns the size of
+// the prefix holding them. An incomplete last line is skipped.
+func scan(r io.Reader, fn func(line []byte) error) (int64, error) {
+	br := bufio.NewReader(r)
+	var pos int64
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return pos, nil
+		}
+		if err != nil {
+			return pos, err
+		}
+		if err := fn(line); err != nil {
+			return pos, err
+		}
+		pos += int64(len(line))
+	}
+}
+
+// appendRecord appends JSON line of v to buf
+func appendRecord(buf *bytes.Buffer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	buf.WriteByte('\n')
+	return nil
+}
+
+// appendLine writes a complete line to f ending at *end. A partially
+// written line is truncated, so the next line doesn't continue it.
+// If truncation fails the store is marked failed. Must be called under s.mu.
+func (s *FileStore) appendLine(f *os.File, end *int64, line []byte) error {
+	if s.failed != nil {
+		return s.failed
+	}
+	n, err := writeFile(f, line)
+	if err == nil {
+		*end += int64(n)
+		return nil
+	}
+	if n > 0 {
+		terr := f.Truncate(*end)
+		if terr == nil {
+			_, terr = f.Seek(*end, io.SeekStart)
+		}
+		if terr != nil {
+			s.failed = fmt.Errorf("hubstore: %s has a torn line: %w", filepath.Base(f.Name()), errors.Join(err, terr))
+			return s.failed
+		}
+	}
+	return err
+}
+
+// Append implements hub.EventStore
+func (s *FileStore) Append(ev hub.StoredEvent) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ev.Offset = s.last + 1
+	var buf bytes.Buffer
+	if err := appendRecord(&buf, ev); err != nil {
+		return 0, err
+	}
+	if err := s.appendLine(s.events, &s.eventsEnd, buf.Bytes()); err != nil {
+		return 0, err
+	}
+	s.last = ev.Offset
+	return ev.Offset, nil
+}
+
+// Range implements hub.EventStore
+func (s *FileStore) Range(from uint64, fn func(ev hub.StoredEvent) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(filepath.Join(s.dir, EventsFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = scan(f, func(line []byte) error {
+		var ev hub.StoredEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return err
+		}
+		if ev.Offset < from {
+			return nil
+		}
+		return fn(ev)
+	})
+	return err
+}
+
+// Trim implements hub.EventStore. The last event is always kept, so
+// offsets continue from it after reopening.
+func (s *FileStore) Trim(before uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before = min(before, s.last)
+	name := filepath.Join(s.dir, EventsFile)
+	tmp := name + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	var size int64
+	if _, err := s.events.Seek(0, io.SeekStart); err != nil {
+		out.Close()
+		return err
+	}
+	_, err = scan(s.events, func(line []byte) error {
+		var ev struct {
+			Offset uint64 `json:"offset"`
+		}
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return err
+		}
+		if ev.Offset < before {
+			return nil
+		}
+		size += int64(len(line))
+		_, err := w.Write(line)
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		// keep appending to the old file
+		_, serr := s.events.Seek(0, io.SeekEnd)
+		return errors.Join(fmt.Errorf("hubstore: trim: %w", err), serr)
+	}
+
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("hubstore: trim: %w", err)
+	}
+	s.events.Close()
+	s.events = f
+	s.eventsEnd = size
+	return nil
+}
+
+// Commit implements hub.Store
+func (s *FileStore) Commit(name string, next uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := appendRecord(&buf, offsetRecord{Name: name, Next: next}); err != nil {
+		return err
+	}
+	if err := s.appendLine(s.offsets, &s.offsetEnd, buf.Bytes()); err != nil {
+		return err
+	}
+	s.committed[name] = next
+	return nil
+}
+
+// Committed implements hub.Store
+func (s *FileStore) Committed(name string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.committed[name], nil
+}
+
+// Sync flushes written events and offsets to stable storage.
+// Without it data survives a process crash but not a power loss.
+func (s *FileStore) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.events.Sync(), s.offsets.Sync())
+}
+
+// Close closes the store files
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.events.Close(), s.offsets.Close())
+}
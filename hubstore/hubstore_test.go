@@ -0,0 +1,230 @@
+package hubstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, payload := range []string{`"a"`, `{"n":1}`, `2`} {
+		off, err := s.Append(hub.StoredEvent{Topic: hub.T("type=x"), Payload: json.RawMessage(payload)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if off != uint64(i+1) {
+			t.Errorf("offset = %d, want %d", off, i+1)
+		}
+	}
+	if err := s.Commit("sub", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit("sub", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// torn write of a crashed process
+	f, err := os.OpenFile(filepath.Join(dir, EventsFile), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"offset":4,"topic":"ty`)
+	f.Close()
+
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if n, _ := s.Committed("sub"); n != 3 {
+		t.Errorf("Committed() = %d, want 3", n)
+	}
+	if off, _ := s.Append(hub.StoredEvent{Topic: hub.T("type=y"), Payload: json.RawMessage(`null`)}); off != 4 {
+		t.Errorf("offset after reopen = %d, want 4", off)
+	}
+
+	var got []string
+	err = s.Range(3, func(ev hub.StoredEvent) error {
+		got = append(got, ev.Topic.String()+" "+string(ev.Payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "type=x 2" || got[1] != "type=y null" {
+		t.Errorf("Range() = %q", got)
+	}
+}
+
+func TestFileStoreFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	event := func(n int) hub.StoredEvent {
+		return hub.StoredEvent{Topic: hub.T("type=x"), Payload: json.RawMessage(strconv.Itoa(n))}
+	}
+
+	// disk is full in the middle of a line
+	full := func(f *os.File, b []byte) (int, error) {
+		n, _ := f.Write(b[:len(b)/2])
+		return n, syscall.ENOSPC
+	}
+	writeFile = full
+	defer func() { writeFile = (*os.File).Write }()
+	if _, err := s.Append(event(1)); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("Append() = %v, want ENOSPC", err)
+	}
+	if err := s.Commit("sub", 1); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("Commit() = %v, want ENOSPC", err)
+	}
+	writeFile = (*os.File).Write
+
+	for n := 1; n <= 2; n++ {
+		if off, err := s.Append(event(n)); err != nil || off != uint64(n) {
+			t.Fatalf("Append() = %d, %v", off, err)
+		}
+	}
+	if err := s.Commit("sub", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open() after failed write = %v", err)
+	}
+	if n, _ := s.Committed("sub"); n != 2 {
+		t.Errorf("Committed() = %d, want 2", n)
+	}
+	var got []string
+	_ = s.Range(0, func(ev hub.StoredEvent) error {
+		got = append(got, string(ev.Payload))
+		return nil
+	})
+	if !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("Range() = %q", got)
+	}
+}
+
+func TestDurableRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	type order struct {
+		ID int `json:"id"`
+	}
+
+	// first process publishes events, nobody is subscribed yet
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hub.New(hub.WithStore(s))
+	for i := 1; i <= 3; i++ {
+		if err := h.Publish(ctx, hub.T("type=order"), order{ID: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	// second process receives them with typed handler
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	h = hub.New(hub.WithStore(s))
+	ch := make(chan int, 3)
+	_, err = h.Subscribe(ctx, hub.T("type=order"), func(ctx context.Context, o order) {
+		ch <- o.ID
+	}, hub.Durable("orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := 1; want <= 3; want++ {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("got order %d, want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("order %d not delivered", want)
+		}
+	}
+}
+
+// offsets returns offsets of events kept by the store
+func offsets(t *testing.T, s hub.EventStore, from uint64) []uint64 {
+	t.Helper()
+	var ret []uint64
+	err := s.Range(from, func(ev hub.StoredEvent) error {
+		ret = append(ret, ev.Offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestFileStoreTrim(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		if _, err := s.Append(hub.StoredEvent{Topic: hub.T("type=x"), Payload: json.RawMessage(`1`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Trim(3); err != nil {
+		t.Fatal(err)
+	}
+	if got := offsets(t, s, 0); !slices.Equal(got, []uint64{3, 4, 5}) {
+		t.Errorf("Range() after Trim(3) = %v", got)
+	}
+	if off, _ := s.Append(hub.StoredEvent{Topic: hub.T("type=x"), Payload: json.RawMessage(`1`)}); off != 6 {
+		t.Errorf("offset after Trim = %d, want 6", off)
+	}
+
+	// the last event survives trimming everything
+	if err := s.Trim(100); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := offsets(t, s, 0); !slices.Equal(got, []uint64{6}) {
+		t.Errorf("Range() after reopen = %v", got)
+	}
+	if off, _ := s.Append(hub.StoredEvent{Topic: hub.T("type=x"), Payload: json.RawMessage(`1`)}); off != 7 {
+		t.Errorf("offset after reopen = %d, want 7", off)
+	}
+}
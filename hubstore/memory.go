@@ -0,0 +1,89 @@
+package hubstore
+
+import (
+	"sync"
+
+	"github.com/lomik/hub"
+)
+
+// MemoryStore is a hub.Store keeping events and offsets in memory.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu        sync.Mutex
+	events    []hub.StoredEvent
+	last      uint64            // Offset of the last event
+	committed map[string]uint64 // Offsets of durable subscriptions
+}
+
+var _ hub.Store = (*MemoryStore)(nil)
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		committed: make(map[string]uint64),
+	}
+}
+
+// Append implements hub.EventStore
+func (s *MemoryStore) Append(ev hub.StoredEvent) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last++
+	ev.Offset = s.last
+	s.events = append(s.events, ev)
+	return ev.Offset, nil
+}
+
+// Range implements hub.EventStore. Events appended by fn are not visited.
+func (s *MemoryStore) Range(from uint64, fn func(ev hub.StoredEvent) error) error {
+	s.mu.Lock()
+	events := s.events
+	s.mu.Unlock()
+
+	if len(events) > 0 && from > events[0].Offset {
+		events = events[min(from-events[0].Offset, uint64(len(events))):]
+	}
+	for _, ev := range events {
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Trim implements hub.EventStore
+func (s *MemoryStore) Trim(before uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.events) == 0 || before <= s.events[0].Offset {
+		return nil
+	}
+	n := min(before-s.events[0].Offset, uint64(len(s.events)))
+	// copy the rest, so trimmed events can be collected
+	s.events = append([]hub.StoredEvent(nil), s.events[n:]...)
+	return nil
+}
+
+// Commit implements hub.Store
+func (s *MemoryStore) Commit(name string, next uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.committed[name] = next
+	return nil
+}
+
+// Committed implements hub.Store
+func (s *MemoryStore) Committed(name string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.committed[name], nil
+}
+
+// Len returns the number of kept events
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
@@ -0,0 +1,77 @@
+package hubstore
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemory()
+	for i := range 5 {
+		off, err := s.Append(hub.StoredEvent{Topic: hub.T("type=x"), Payload: json.RawMessage(`1`)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if off != uint64(i+1) {
+			t.Errorf("offset = %d, want %d", off, i+1)
+		}
+	}
+	if got := offsets(t, s, 4); !slices.Equal(got, []uint64{4, 5}) {
+		t.Errorf("Range(4) = %v", got)
+	}
+	if got := offsets(t, s, 10); len(got) != 0 {
+		t.Errorf("Range(10) = %v", got)
+	}
+
+	_ = s.Trim(3)
+	_ = s.Trim(2)
+	if got := offsets(t, s, 0); !slices.Equal(got, []uint64{3, 4, 5}) {
+		t.Errorf("Range() after Trim(3) = %v", got)
+	}
+	_ = s.Trim(100)
+	if s.Len() != 0 {
+		t.Errorf("Len() = %d after trimming all", s.Len())
+	}
+	if off, _ := s.Append(hub.StoredEvent{Topic: hub.T("type=x")}); off != 6 {
+		t.Errorf("offset after Trim = %d, want 6", off)
+	}
+
+	_ = s.Commit("sub", 4)
+	if n, _ := s.Committed("sub"); n != 4 {
+		t.Errorf("Committed() = %d, want 4", n)
+	}
+}
+
+func TestMemoryStoreDurable(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	h := hub.New(hub.WithStore(s))
+	for i := 1; i <= 4; i++ {
+		_ = h.Publish(ctx, hub.T("type=order"), i)
+	}
+	// events before the retention window are not replayed
+	_ = s.Trim(3)
+
+	ch := make(chan int, 4)
+	_, err := h.Subscribe(ctx, hub.T("type=order"), func(ctx context.Context, n int) {
+		ch <- n
+	}, hub.Durable("orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := 3; want <= 4; want++ {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("got %d, want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%d not delivered", want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package hubtest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// Clock is a fake hub.Clock for tests. Time stands still until Advance
+// or Set moves it, then due timers fire in order of their deadlines.
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	clock := hubtest.NewClock(time.Now())
+//	h := hub.New(hub.WithClock(clock))
+//	rec := hubtest.NewRecorder(t, h, hub.T("type=reminder"))
+//	h.PublishAfter(ctx, time.Minute, hub.T("type=reminder"), nil, hub.Sync(true))
+//	clock.Advance(time.Minute)
+//	rec.ExpectPublished(t, hub.T("type=reminder"), time.Second)
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer
+}
+
+var _ hub.Clock = (*Clock)(nil)
+
+// clockTimer is a pending call of Clock
+type clockTimer struct {
+	c        *Clock
+	deadline time.Time
+	f        func()
+}
+
+// NewClock creates a fake clock showing start
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now implements hub.Clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After implements hub.Clock
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() {
+		ch <- c.Now()
+	})
+	return ch
+}
+
+// AfterFunc implements hub.Clock. Unlike time.AfterFunc, f is called in
+// the goroutine moving the clock, so its effects are visible when
+// Advance returns.
+func (c *Clock) AfterFunc(d time.Duration, f func()) hub.Timer {
+	c.mu.Lock()
+	t := &clockTimer{c: c, deadline: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+	if d <= 0 {
+		c.fire()
+	}
+	return t
+}
+
+// Stop implements hub.Timer
+func (t *clockTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	i := slices.Index(t.c.timers, t)
+	if i < 0 {
+		return false
+	}
+	t.c.timers = slices.Delete(t.c.timers, i, i+1)
+	return true
+}
+
+// Advance moves the clock forward by d and fires due timers
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+	c.fire()
+}
+
+// Set moves the clock to t and fires due timers
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+	c.fire()
+}
+
+// Pending returns the number of timers waiting to fire
+func (c *Clock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// fire calls due timers one by one in order of deadlines, timers created
+// by the calls fire too if they are due
+func (c *Clock) fire() {
+	for {
+		c.mu.Lock()
+		next := -1
+		for i, t := range c.timers {
+			if t.deadline.After(c.now) {
+				continue
+			}
+			if next < 0 || t.deadline.Before(c.timers[next].deadline) {
+				next = i
+			}
+		}
+		if next < 0 {
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[next]
+		c.timers = slices.Delete(c.timers, next, next+1)
+		c.mu.Unlock()
+		t.f()
+	}
+}
@@ -0,0 +1,94 @@
+package hubtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+
+	var fired []int
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, 2) })
+	c.AfterFunc(time.Second, func() {
+		fired = append(fired, 1)
+		c.AfterFunc(0, func() { fired = append(fired, 10) })
+	})
+	stopped := c.AfterFunc(time.Second, func() { fired = append(fired, -1) })
+	ch := c.After(3 * time.Second)
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Stop() should succeed once")
+	}
+	if n := c.Pending(); n != 3 {
+		t.Errorf("Pending() = %d, want 3", n)
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	if len(fired) != 2 || fired[0] != 1 || fired[1] != 10 {
+		t.Errorf("fired = %v, want [1 10]", fired)
+	}
+	c.Set(start.Add(time.Hour))
+	if len(fired) != 3 || fired[2] != 2 {
+		t.Errorf("fired = %v, want [1 10 2]", fired)
+	}
+	select {
+	case now := <-ch:
+		if !now.Equal(start.Add(time.Hour)) {
+			t.Errorf("After() sent %v", now)
+		}
+	default:
+		t.Error("After() channel is empty")
+	}
+	if !c.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("Now() = %v", c.Now())
+	}
+}
+
+func TestClockHub(t *testing.T) {
+	ctx := context.Background()
+	clock := NewClock(time.Now())
+	h := hub.New(hub.WithClock(clock))
+	rec := NewRecorder(t, h, hub.T("type=reminder"))
+
+	_, _ = h.PublishAfter(ctx, time.Minute, hub.T("type=reminder"), "later", hub.Sync(true))
+	d, _ := h.PublishAfter(ctx, time.Minute, hub.T("type=reminder"), "canceled", hub.Sync(true))
+	d.Cancel()
+	clock.Advance(59 * time.Second)
+	if rec.Len() != 0 {
+		t.Fatalf("published before the delay: %v", rec.Events())
+	}
+	clock.Advance(time.Second)
+	if ev := rec.Events(); len(ev) != 1 || ev[0].Payload != "later" {
+		t.Fatalf("events = %v, want only later", ev)
+	}
+
+	// a busy serialized subscriber lets the event expire in its queue
+	release := make(chan struct{})
+	got := make(chan int, 2)
+	_, _ = h.Subscribe(ctx, hub.T("type=metric"), func(ctx context.Context, v int) {
+		if v == 1 {
+			<-release
+		}
+		got <- v
+	}, hub.Serialized(true))
+
+	_ = h.Publish(ctx, hub.T("type=metric"), 1)
+	_ = h.Publish(ctx, hub.T("type=metric"), 2, hub.EventTTL(time.Second))
+	clock.Advance(2 * time.Second)
+	close(release)
+	if v := <-got; v != 1 {
+		t.Errorf("got %d, want 1", v)
+	}
+	_ = h.Publish(ctx, hub.T("type=metric"), 3, hub.Wait(true))
+	if v := <-got; v != 3 {
+		t.Errorf("got %d, want expired event dropped", v)
+	}
+	if n := h.Health().Expired; n != 1 {
+		t.Errorf("Health().Expired = %d, want 1", n)
+	}
+}
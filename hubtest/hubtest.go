@@ -0,0 +1,246 @@
+// Package hubtest helps to test event flows of applications using hub.
+//
+// A Recorder subscribes to a topic pattern and keeps received events,
+// ExpectPublished and ExpectNone wait for them with a deadline instead
+// of hand-rolled WaitGroups and sleeps:
+//
+//	func TestCheckout(t *testing.T) {
+//	    h := hub.New()
+//	    rec := hubtest.NewRecorder(t, h, hub.T("type=order"))
+//
+//	    checkout(ctx, h, cart)
+//
+//	    rec.ExpectPublished(t, hub.T("type=order", "status=paid"), time.Second,
+//	        hubtest.PayloadFunc(func(o Order) bool { return o.Total == 42 }))
+//	    rec.ExpectNone(t, hub.T("type=order", "status=failed"), 100*time.Millisecond)
+//	}
+package hubtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// Event is an event received by a Recorder
+type Event struct {
+	ID      hub.EventID // Event ID, see hub.EventIDFromContext
+	Topic   *hub.Topic
+	Payload any
+}
+
+// Recorder records events matching a topic pattern.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	events  []Event
+	changed chan struct{} // Closed and replaced when an event is recorded
+	h       *hub.Hub
+	id      hub.SubID
+}
+
+// NewRecorder subscribes to pattern and records matching events until
+// the test ends. Recording runs in the publisher goroutine, so events
+// published with hub.Sync(true) are visible right after Publish returns.
+func NewRecorder(t testing.TB, h *hub.Hub, pattern *hub.Topic) *Recorder {
+	t.Helper()
+	r := &Recorder{
+		changed: make(chan struct{}),
+		h:       h,
+	}
+	id, err := h.Subscribe(context.Background(), pattern, r.record, hub.Inline(true))
+	if err != nil {
+		t.Fatalf("hubtest: subscribe %s: %v", pattern, err)
+	}
+	r.id = id
+	t.Cleanup(r.Close)
+	return r
+}
+
+// record is the handler of the recorder subscription
+func (r *Recorder) record(ctx context.Context, t *hub.Topic, payload any) {
+	id, _ := hub.EventIDFromContext(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, Event{ID: id, Topic: t, Payload: payload})
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Close stops recording, recorded events are kept
+func (r *Recorder) Close() {
+	r.h.Unsubscribe(context.Background(), r.id)
+}
+
+// Events returns a copy of recorded events in the order of receiving
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Len returns the number of recorded events
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+// Reset discards recorded events
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// find returns the first recorded event matching topic and matchers
+// and a channel closed when a new event is recorded
+func (r *Recorder) find(topic *hub.Topic, matchers []Matcher) (Event, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if matches(e, topic, matchers) {
+			return e, true, nil
+		}
+	}
+	return Event{}, false, r.changed
+}
+
+// wait waits for an event matching topic and matchers during within
+func (r *Recorder) wait(topic *hub.Topic, within time.Duration, matchers []Matcher) (Event, bool) {
+	timer := time.NewTimer(within)
+	defer timer.Stop()
+	for {
+		e, ok, changed := r.find(topic, matchers)
+		if ok {
+			return e, true
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return Event{}, false
+		}
+	}
+}
+
+// ExpectPublished waits until an event matching topic pattern and all
+// matchers is recorded, events recorded before the call count too.
+// Fails the test if there is none within the duration.
+func (r *Recorder) ExpectPublished(t testing.TB, topic *hub.Topic, within time.Duration, matchers ...Matcher) Event {
+	t.Helper()
+	e, ok := r.wait(topic, within, matchers)
+	if !ok {
+		t.Fatalf("hubtest: no event %s%s published within %s, recorded:\n%s",
+			topic, describe(matchers), within, r.dump())
+	}
+	return e
+}
+
+// ExpectNone fails the test if an event matching topic pattern and all
+// matchers is recorded before or during the duration
+func (r *Recorder) ExpectNone(t testing.TB, topic *hub.Topic, within time.Duration, matchers ...Matcher) {
+	t.Helper()
+	if e, ok := r.wait(topic, within, matchers); ok {
+		t.Errorf("hubtest: unexpected event %s with payload %#v", e.Topic, e.Payload)
+	}
+}
+
+// dump describes recorded events for failure messages
+func (r *Recorder) dump() string {
+	events := r.Events()
+	if len(events) == 0 {
+		return "\t(none)"
+	}
+	var buf strings.Builder
+	for _, e := range events {
+		fmt.Fprintf(&buf, "\t%s: %#v\n", e.Topic, e.Payload)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Matcher checks payload of an event
+type Matcher interface {
+	// Match reports whether payload satisfies the matcher
+	Match(payload any) bool
+	// String describes the matcher in failure messages
+	String() string
+}
+
+// matches reports whether e matches topic pattern and all matchers
+func matches(e Event, topic *hub.Topic, matchers []Matcher) bool {
+	if !topic.Match(e.Topic) {
+		return false
+	}
+	for _, m := range matchers {
+		if !m.Match(e.Payload) {
+			return false
+		}
+	}
+	return true
+}
+
+// describe formats matchers for failure messages
+func describe(matchers []Matcher) string {
+	if len(matchers) == 0 {
+		return ""
+	}
+	s := make([]string, len(matchers))
+	for i, m := range matchers {
+		s[i] = m.String()
+	}
+	return " with payload " + strings.Join(s, " and ")
+}
+
+// matcherFunc implements Matcher with a function
+type matcherFunc struct {
+	fn   func(payload any) bool
+	desc string
+}
+
+// Match implements Matcher
+func (m matcherFunc) Match(payload any) bool {
+	return m.fn(payload)
+}
+
+// String implements Matcher
+func (m matcherFunc) String() string {
+	return m.desc
+}
+
+// Payload matches payloads deeply equal to v
+func Payload(v any) Matcher {
+	return matcherFunc{
+		fn: func(payload any) bool {
+			return reflect.DeepEqual(payload, v)
+		},
+		desc: fmt.Sprintf("%#v", v),
+	}
+}
+
+// PayloadFunc matches payloads of type T satisfying fn
+func PayloadFunc[T any](fn func(v T) bool) Matcher {
+	return matcherFunc{
+		fn: func(payload any) bool {
+			v, ok := payload.(T)
+			return ok && fn(v)
+		},
+		desc: fmt.Sprintf("%s satisfying func", reflect.TypeFor[T]()),
+	}
+}
+
+// PayloadType matches payloads of type T
+func PayloadType[T any]() Matcher {
+	return matcherFunc{
+		fn: func(payload any) bool {
+			_, ok := payload.(T)
+			return ok
+		},
+		desc: fmt.Sprintf("of type %s", reflect.TypeFor[T]()),
+	}
+}
@@ -0,0 +1,110 @@
+package hubtest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// fakeT records failures instead of failing the test
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// run calls fn with fakeT in its own goroutine, so Fatalf can stop it
+func run(fn func(t *fakeT)) []string {
+	f := &fakeT{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(f)
+	}()
+	<-done
+	return f.failures
+}
+
+type order struct {
+	ID    int
+	Total int
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	h := hub.New()
+	rec := NewRecorder(t, h, hub.T("type=order"))
+
+	_ = h.Publish(ctx, hub.T("type=order", "status=new"), order{ID: 1, Total: 10}, hub.Sync(true))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = h.Publish(ctx, hub.T("type=order", "status=paid"), order{ID: 1, Total: 10})
+	}()
+	_ = h.Publish(ctx, hub.T("type=user"), "ignored")
+
+	e := rec.ExpectPublished(t, hub.T("type=order", "status=paid"), time.Second,
+		PayloadFunc(func(o order) bool { return o.Total == 10 }))
+	if e.Payload.(order).ID != 1 {
+		t.Errorf("ExpectPublished() = %+v", e)
+	}
+	rec.ExpectPublished(t, hub.T("status=new"), 0, Payload(order{ID: 1, Total: 10}), PayloadType[order]())
+	rec.ExpectNone(t, hub.T("status=failed"), 10*time.Millisecond)
+
+	if n := rec.Len(); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+	rec.Reset()
+	if n := len(rec.Events()); n != 0 {
+		t.Errorf("Events() after Reset() = %d", n)
+	}
+}
+
+func TestRecorderFailures(t *testing.T) {
+	ctx := context.Background()
+	h := hub.New()
+	rec := NewRecorder(t, h, hub.T("type=order"))
+	_ = h.Publish(ctx, hub.T("type=order", "status=new"), 1, hub.Sync(true))
+
+	failures := run(func(ft *fakeT) {
+		rec.ExpectPublished(ft, hub.T("status=paid"), 10*time.Millisecond)
+	})
+	if len(failures) != 1 || !strings.Contains(failures[0], "status=new type=order: 1") {
+		t.Errorf("ExpectPublished() failures = %q", failures)
+	}
+
+	failures = run(func(ft *fakeT) {
+		rec.ExpectPublished(ft, hub.T("status=new"), 0, Payload(2))
+	})
+	if len(failures) != 1 || !strings.Contains(failures[0], "with payload 2") {
+		t.Errorf("ExpectPublished() with matcher failures = %q", failures)
+	}
+
+	failures = run(func(ft *fakeT) {
+		rec.ExpectNone(ft, hub.T("status=new"), 0)
+	})
+	if len(failures) != 1 {
+		t.Errorf("ExpectNone() failures = %q", failures)
+	}
+
+	// closed recorder keeps events but doesn't record new ones
+	rec.Close()
+	_ = h.Publish(ctx, hub.T("type=order"), 3, hub.Sync(true))
+	if n := rec.Len(); n != 1 {
+		t.Errorf("Len() after Close() = %d, want 1", n)
+	}
+}
@@ -0,0 +1,145 @@
+package hubws
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// WebSocket opcodes (RFC 6455)
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes (RFC 6455, section 7.4.1)
+const (
+	closeNormal          = 1000
+	closeProtocolError   = 1002
+	closeInvalidPayload  = 1007
+	closeMessageTooLarge = 1009
+)
+
+// errMessageTooLarge is returned for messages exceeding the read limit
+var errMessageTooLarge = errors.New("websocket: message too large")
+
+// errProtocol is returned for frames violating RFC 6455
+var errProtocol = errors.New("websocket: protocol error")
+
+// frame is a single WebSocket frame
+type frame struct {
+	fin     bool
+	masked  bool
+	opcode  byte
+	payload []byte
+}
+
+// isControl reports whether the frame is a close, ping or pong frame
+func (f frame) isControl() bool {
+	return f.opcode&0x8 != 0
+}
+
+// readFrame reads one frame, unmasking the payload.
+// Payloads longer than limit are rejected with errMessageTooLarge,
+// reserved bits, fragmented or long control frames with errProtocol.
+func readFrame(r *bufio.Reader, limit int64) (frame, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{
+		fin:    hdr[0]&0x80 != 0,
+		masked: hdr[1]&0x80 != 0,
+		opcode: hdr[0] & 0x0F,
+	}
+	if hdr[0]&0x70 != 0 {
+		// no extensions are negotiated
+		return frame{}, errProtocol
+	}
+
+	n := int64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if f.isControl() && (!f.fin || n > 125) {
+		return frame{}, errProtocol
+	}
+	if n < 0 || n > limit {
+		return frame{}, errMessageTooLarge
+	}
+
+	var mask [4]byte
+	if f.masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return frame{}, err
+		}
+	}
+
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+	if f.masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// closePayload returns the payload of a close frame with status code
+func closePayload(code uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, code)
+}
+
+// closeReply returns the status code to answer a close frame with payload p
+func closeReply(p []byte) uint16 {
+	if len(p) == 0 {
+		return closeNormal
+	}
+	if len(p) == 1 {
+		return closeProtocolError
+	}
+	switch code := binary.BigEndian.Uint16(p); {
+	case code < 1000 || code == 1004 || code == 1005 || code == 1006 ||
+		(code > 1011 && code < 3000) || code > 4999:
+		return closeProtocolError
+	case !utf8.Valid(p[2:]):
+		return closeInvalidPayload
+	default:
+		return code
+	}
+}
+
+// appendFrame appends unmasked final frame to buf
+func appendFrame(buf []byte, opcode byte, payload []byte) []byte {
+	buf = append(buf, 0x80|opcode)
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, 126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	return append(buf, payload...)
+}
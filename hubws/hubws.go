@@ -0,0 +1,321 @@
+// Package hubws is a WebSocket gateway to the hub for browsers
+// and remote clients.
+//
+// Clients send JSON commands as text messages:
+//
+//	{"op": "subscribe", "topic": "type=alert"}
+//	{"op": "unsubscribe", "topic": "type=alert"}
+//	{"op": "publish", "topic": "type=chat", "payload": {"text": "hi"}}
+//
+// and receive matching events:
+//
+//	{"topic": "type=alert severity=high", "payload": "server is down"}
+//
+// Errors are reported as {"error": "..."}. Subscriptions of a connection
+// are removed when it is closed.
+//
+// The package implements the server side of RFC 6455 with the standard
+// library only.
+package hubws
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lomik/hub"
+)
+
+// Default limits
+const (
+	DefaultMaxMessageSize = 1 << 20
+	DefaultWriteTimeout   = 10 * time.Second
+)
+
+// acceptGUID is appended to Sec-WebSocket-Key by the handshake
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Options configures the gateway
+type Options struct {
+	// AllowPublish enables "publish" command
+	AllowPublish bool
+	// CheckOrigin accepts or rejects the upgrade request,
+	// all origins are accepted if nil
+	CheckOrigin func(r *http.Request) bool
+	// MaxMessageSize limits size of client messages, DefaultMaxMessageSize if 0
+	MaxMessageSize int64
+	// WriteTimeout limits sending of one message, DefaultWriteTimeout if 0
+	WriteTimeout time.Duration
+}
+
+// Command is a message sent by client
+type Command struct {
+	Op      string          `json:"op"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Message is a message sent to client
+type Message struct {
+	Topic   string `json:"topic,omitempty"`
+	Payload any    `json:"payload,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Handler returns http.Handler upgrading requests to WebSocket
+// connections attached to hub h.
+//
+// Example:
+//
+//	http.Handle("/events", hubws.Handler(h, hubws.Options{}))
+func Handler(h *hub.Hub, opts Options) http.Handler {
+	if opts.MaxMessageSize == 0 {
+		opts.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = DefaultWriteTimeout
+	}
+	return &gateway{h: h, opts: opts}
+}
+
+// gateway implements http.Handler
+type gateway struct {
+	h    *hub.Hub
+	opts Options
+}
+
+// ServeHTTP performs the handshake and serves the connection
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return
+	}
+	if g.opts.CheckOrigin != nil && !g.opts.CheckOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	netConn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	if _, err := netConn.Write([]byte(resp)); err != nil {
+		netConn.Close()
+		return
+	}
+
+	c := &conn{
+		g:    g,
+		nc:   netConn,
+		r:    rw.Reader,
+		subs: make(map[string]hub.SubID),
+	}
+	c.serve(r.Context())
+}
+
+// headerContains checks comma separated header for token, case insensitive
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conn is a client connection
+type conn struct {
+	g  *gateway
+	nc net.Conn
+	r  *bufio.Reader
+
+	wmu sync.Mutex // Serializes writes
+
+	mu   sync.Mutex
+	subs map[string]hub.SubID // Subscriptions by canonical topic
+}
+
+// serve reads commands until the connection is closed
+func (c *conn) serve(ctx context.Context) {
+	defer c.close(ctx)
+
+	var (
+		msg    []byte
+		text   bool // msg is a text message
+		inside bool // continuation frames of msg are expected
+	)
+	for {
+		f, err := readFrame(c.r, c.g.opts.MaxMessageSize)
+		switch {
+		case errors.Is(err, errProtocol):
+			c.closeWith(closeProtocolError)
+			return
+		case errors.Is(err, errMessageTooLarge):
+			c.closeWith(closeMessageTooLarge)
+			return
+		case err != nil:
+			return
+		case !f.masked:
+			// clients must mask all frames
+			c.closeWith(closeProtocolError)
+			return
+		}
+		switch f.opcode {
+		case opClose:
+			c.closeWith(closeReply(f.payload))
+			return
+		case opPing:
+			if err := c.write(opPong, f.payload); err != nil {
+				return
+			}
+			continue
+		case opPong:
+			continue
+		case opText, opBinary:
+			if inside {
+				c.closeWith(closeProtocolError)
+				return
+			}
+			text, inside = f.opcode == opText, true
+		case opContinuation:
+			if !inside {
+				c.closeWith(closeProtocolError)
+				return
+			}
+		default:
+			c.closeWith(closeProtocolError)
+			return
+		}
+		msg = append(msg, f.payload...)
+		if int64(len(msg)) > c.g.opts.MaxMessageSize {
+			c.closeWith(closeMessageTooLarge)
+			return
+		}
+		if !f.fin {
+			continue
+		}
+		inside = false
+		if text && !utf8.Valid(msg) {
+			c.closeWith(closeInvalidPayload)
+			return
+		}
+		if err := c.command(ctx, msg); err != nil {
+			if c.send(Message{Error: err.Error()}) != nil {
+				return
+			}
+		}
+		msg = msg[:0]
+	}
+}
+
+// command executes client command
+func (c *conn) command(ctx context.Context, data []byte) error {
+	var cmd Command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return err
+	}
+	t, err := c.g.h.ParseTopicText(cmd.Topic)
+	if err != nil {
+		return err
+	}
+	key := t.String()
+
+	switch cmd.Op {
+	case "subscribe":
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subs[key]; ok {
+			return nil
+		}
+		id, err := c.g.h.Subscribe(ctx, t, c.deliver, hub.Async(true))
+		if err != nil {
+			return err
+		}
+		c.subs[key] = id
+		return nil
+	case "unsubscribe":
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if id, ok := c.subs[key]; ok {
+			c.g.h.Unsubscribe(ctx, id)
+			delete(c.subs, key)
+		}
+		return nil
+	case "publish":
+		if !c.g.opts.AllowPublish {
+			return errors.New("publish is not allowed")
+		}
+		var payload any
+		if len(cmd.Payload) > 0 {
+			payload = cmd.Payload
+		}
+		return c.g.h.Publish(ctx, t, payload)
+	default:
+		return errors.New("unknown op: " + cmd.Op)
+	}
+}
+
+// deliver sends hub event to the client
+func (c *conn) deliver(ctx context.Context, t *hub.Topic, p any) error {
+	return c.send(Message{Topic: t.String(), Payload: p})
+}
+
+// send writes JSON message as text frame
+func (c *conn) send(m Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return c.write(opText, data)
+}
+
+// write sends one frame
+func (c *conn) write(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_ = c.nc.SetWriteDeadline(time.Now().Add(c.g.opts.WriteTimeout))
+	_, err := c.nc.Write(appendFrame(nil, opcode, payload))
+	return err
+}
+
+// closeWith sends a close frame with status code
+func (c *conn) closeWith(code uint16) {
+	_ = c.write(opClose, closePayload(code))
+}
+
+// close removes subscriptions and closes the connection
+func (c *conn) close(ctx context.Context) {
+	c.mu.Lock()
+	for key, id := range c.subs {
+		c.g.h.Unsubscribe(ctx, id)
+		delete(c.subs, key)
+	}
+	c.mu.Unlock()
+	c.nc.Close()
+}
@@ -0,0 +1,233 @@
+package hubws
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lomik/hub"
+)
+
+// dial performs client handshake over raw connection
+func dial(t *testing.T, url string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	nc, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := nc.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	// example from RFC 6455
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return nc, r
+}
+
+// writeMasked sends masked text frame as clients do
+func writeMasked(t *testing.T, nc net.Conn, s string) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	buf := appendFrame(nil, opText, []byte(s))
+	buf[1] |= 0x80
+	hdr := len(buf) - len(s)
+	out := append(buf[:hdr:hdr], mask[:]...)
+	for i := 0; i < len(s); i++ {
+		out = append(out, s[i]^mask[i%4])
+	}
+	if _, err := nc.Write(out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readMessage reads server message
+func readMessage(t *testing.T, nc net.Conn, r *bufio.Reader) Message {
+	t.Helper()
+	_ = nc.SetReadDeadline(time.Now().Add(time.Second))
+	f, err := readFrame(r, DefaultMaxMessageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Message
+	if err := json.Unmarshal(f.payload, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// waitLen waits for number of hub subscriptions
+func waitLen(t *testing.T, h *hub.Hub, n int) {
+	t.Helper()
+	for i := 0; i < 100 && h.Len() != n; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if h.Len() != n {
+		t.Fatalf("subscriptions = %d, want %d", h.Len(), n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	h := hub.New()
+	srv := httptest.NewServer(Handler(h, Options{AllowPublish: true}))
+	defer srv.Close()
+
+	nc, r := dial(t, srv.URL)
+	defer nc.Close()
+
+	writeMasked(t, nc, `{"op":"subscribe","topic":"type=alert"}`)
+	waitLen(t, h, 1)
+
+	_ = h.Publish(ctx, hub.T("type=alert", "severity=high"), "server is down")
+	if m := readMessage(t, nc, r); m.Topic != "severity=high type=alert" || m.Payload != "server is down" {
+		t.Errorf("unexpected message: %+v", m)
+	}
+
+	// publish from client is delivered back through the hub
+	writeMasked(t, nc, `{"op":"publish","topic":"type=alert","payload":{"n":1}}`)
+	if m := readMessage(t, nc, r); m.Topic != "type=alert" || m.Payload.(map[string]any)["n"] != 1.0 {
+		t.Errorf("unexpected message: %+v", m)
+	}
+
+	writeMasked(t, nc, `{"op":"bogus","topic":""}`)
+	if m := readMessage(t, nc, r); m.Error == "" {
+		t.Errorf("expected error, got %+v", m)
+	}
+
+	writeMasked(t, nc, `{"op":"unsubscribe","topic":"type=alert"}`)
+	waitLen(t, h, 0)
+
+	// subscriptions are removed on disconnect
+	writeMasked(t, nc, `{"op":"subscribe","topic":"type=other"}`)
+	waitLen(t, h, 1)
+	nc.Close()
+	waitLen(t, h, 0)
+}
+
+func TestHandlerRejects(t *testing.T) {
+	h := hub.New()
+	srv := httptest.NewServer(Handler(h, Options{
+		CheckOrigin: func(r *http.Request) bool { return false },
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain request status = %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerPublishDisabled(t *testing.T) {
+	h := hub.New()
+	srv := httptest.NewServer(Handler(h, Options{}))
+	defer srv.Close()
+
+	nc, r := dial(t, srv.URL)
+	defer nc.Close()
+
+	writeMasked(t, nc, `{"op":"publish","topic":"type=a"}`)
+	if m := readMessage(t, nc, r); m.Error != "publish is not allowed" {
+		t.Errorf("unexpected message: %+v", m)
+	}
+}
+
+func TestHandlerTopicLimits(t *testing.T) {
+	h := hub.New(hub.WithTopicPolicy(hub.TopicPolicy{MaxAttributes: 2}))
+	srv := httptest.NewServer(Handler(h, Options{}))
+	defer srv.Close()
+
+	nc, r := dial(t, srv.URL)
+	defer nc.Close()
+
+	for _, topic := range []string{`a=1 b=2 c=3`, `msg=\u001b[2J`} {
+		writeMasked(t, nc, `{"op":"subscribe","topic":"`+topic+`"}`)
+		m := readMessage(t, nc, r)
+		if m.Error == "" || strings.ContainsFunc(m.Error, func(r rune) bool { return r < ' ' }) {
+			t.Errorf("subscribe %q: unexpected message %+v", topic, m)
+		}
+	}
+	if h.Len() != 0 {
+		t.Errorf("subscriptions = %d, want 0", h.Len())
+	}
+}
+
+// maskedFrame builds a client frame with the first header byte b0
+func maskedFrame(b0 byte, payload []byte) []byte {
+	mask := [4]byte{1, 2, 3, 4}
+	buf := []byte{b0, 0x80 | byte(len(payload))}
+	buf = append(buf, mask[:]...)
+	for i, c := range payload {
+		buf = append(buf, c^mask[i%4])
+	}
+	return buf
+}
+
+func TestHandlerProtocolErrors(t *testing.T) {
+	h := hub.New()
+	srv := httptest.NewServer(Handler(h, Options{}))
+	defer srv.Close()
+
+	unmasked := appendFrame(nil, opText, []byte(`{"op":"subscribe","topic":"a=1"}`))
+	long := strings.Repeat("x", 126)
+	longPing := append([]byte{0x80 | opPing, 0x80 | 126, 0, 126, 0, 0, 0, 0}, long...)
+	tests := []struct {
+		name  string
+		input []byte
+		code  uint16
+	}{
+		{"unmasked", unmasked, closeProtocolError},
+		{"reserved bits", maskedFrame(0xC0|opText, []byte("{}")), closeProtocolError},
+		{"fragmented ping", maskedFrame(opPing, nil), closeProtocolError},
+		{"long ping", longPing, closeProtocolError},
+		{"unexpected continuation", maskedFrame(0x80|opContinuation, []byte("{}")), closeProtocolError},
+		{"interleaved text", append(maskedFrame(opText, []byte("{")), maskedFrame(0x80|opText, []byte("}"))...), closeProtocolError},
+		{"unknown opcode", maskedFrame(0x83, nil), closeProtocolError},
+		{"invalid utf8", maskedFrame(0x80|opText, []byte("\xff")), closeInvalidPayload},
+		{"close", maskedFrame(0x80|opClose, append(closePayload(closeNormal), "bye"...)), closeNormal},
+		{"close bad code", maskedFrame(0x80|opClose, closePayload(1005)), closeProtocolError},
+		{"close one byte", maskedFrame(0x80|opClose, []byte{3}), closeProtocolError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nc, r := dial(t, srv.URL)
+			defer nc.Close()
+			if _, err := nc.Write(tt.input); err != nil {
+				t.Fatal(err)
+			}
+			_ = nc.SetReadDeadline(time.Now().Add(time.Second))
+			f, err := readFrame(r, DefaultMaxMessageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.opcode != opClose || len(f.payload) != 2 {
+				t.Fatalf("got frame %+v, want close", f)
+			}
+			if code := binary.BigEndian.Uint16(f.payload); code != tt.code {
+				t.Errorf("close code = %d, want %d", code, tt.code)
+			}
+		})
+	}
+	waitLen(t, h, 0)
+}
@@ -0,0 +1,317 @@
+package hub
+
+import (
+	"maps"
+	"sort"
+	"sync/atomic"
+
+	"github.com/lomik/hub/pkg/kv"
+)
+
+// numShards is the number of index shards, must not exceed 64
+const numShards = 32
+
+// indexes holds immutable index structures of a shard.
+// Subscribe and Unsubscribe build a new version, so Publish reads
+// a consistent snapshot without locks.
+type indexes struct {
+	indexKeyValue map[string]*valueIndex // Exact key-value pair index
+	indexKey      map[string]*sublist    // All subscriptions with the key
+	indexKeyOp    map[string]*sublist    // Operator index (key!=value, key>=value, ...)
+	exact         map[uint64]*sublist    // Plain topics by hash, see exactIndex
+	hints         capacityHints
+}
+
+// capacityHints holds expected sizes set by WithCapacityHints
+type capacityHints struct {
+	keys   int // Distinct keys in the hub
+	values int // Distinct values per key
+	subs   int // Subscriptions in the hub
+}
+
+// newIndexes creates empty indexes of a shard
+func newIndexes(hints capacityHints) *indexes {
+	keys := hints.keys / numShards
+	return &indexes{
+		indexKeyValue: make(map[string]*valueIndex, keys),
+		indexKey:      make(map[string]*sublist, keys),
+		indexKeyOp:    make(map[string]*sublist, keys),
+		exact:         make(map[uint64]*sublist, hints.subs/numShards),
+		hints:         hints,
+	}
+}
+
+// cloneSized is maps.Clone that preallocates at least hint entries
+func cloneSized[M ~map[K]V, K comparable, V any](m M, hint int) M {
+	if m != nil && len(m) >= hint {
+		return maps.Clone(m)
+	}
+	c := make(M, hint)
+	maps.Copy(c, m)
+	return c
+}
+
+// clone returns a shallow copy for modification.
+// Value indexes, exact map and sublists are shared and must be copied before changes.
+func (ix *indexes) clone() *indexes {
+	keys := ix.hints.keys / numShards
+	return &indexes{
+		indexKeyValue: cloneSized(ix.indexKeyValue, keys),
+		indexKey:      cloneSized(ix.indexKey, keys),
+		indexKeyOp:    cloneSized(ix.indexKeyOp, keys),
+		exact:         ix.exact,
+		hints:         ix.hints,
+	}
+}
+
+// add indexes subscription by pair p, ix must be a private clone
+func (ix *indexes) add(s *sub, p kv.KV) {
+	k := p.Key()
+
+	if p.Op() != kv.OpEq {
+		// Operators can't be resolved by value lookup,
+		// check them against every event with this key
+		ix.indexKeyOp[k] = ix.indexKeyOp[k].with(s)
+	} else {
+		// Multi-value pairs are indexed under each alternative
+		vals := ix.indexKeyValue[k].clone()
+		if vals.hint == 0 {
+			vals.hint = ix.hints.values / valueBuckets
+		}
+		p.EachValue(func(v string) {
+			vals.set(v, vals.get(v).with(s))
+		})
+		ix.indexKeyValue[k] = vals
+	}
+
+	// Add to wildcard index for this key,
+	// presized for an even share of the expected subscriptions
+	sl := ix.indexKey[k]
+	if sl == nil && ix.hints.keys > 0 && ix.hints.subs/ix.hints.keys > 1 {
+		sl = &sublist{lst: make([]*sub, 0, ix.hints.subs/ix.hints.keys)}
+	}
+	ix.indexKey[k] = sl.with(s)
+}
+
+// remove deletes subscription indexed by pair p, ix must be a private clone
+func (ix *indexes) remove(id SubID, p kv.KV) {
+	k := p.Key()
+
+	// Remove from operator index
+	if p.Op() != kv.OpEq {
+		if sl := ix.indexKeyOp[k].without(id); sl != nil {
+			ix.indexKeyOp[k] = sl
+		} else {
+			delete(ix.indexKeyOp, k)
+		}
+	}
+
+	// Remove from exact value index
+	if vals, exists := ix.indexKeyValue[k]; exists && p.Op() == kv.OpEq {
+		vals = vals.clone()
+		p.EachValue(func(v string) {
+			vals.set(v, vals.get(v).without(id))
+		})
+		if vals.len() == 0 {
+			delete(ix.indexKeyValue, k)
+		} else {
+			ix.indexKeyValue[k] = vals
+		}
+	}
+
+	// Remove from wildcard index
+	if sl := ix.indexKey[k].without(id); sl != nil {
+		ix.indexKey[k] = sl
+	} else {
+		delete(ix.indexKey, k)
+	}
+}
+
+// candidates appends sublists possibly matching event pair p
+func (ix *indexes) candidates(p kv.KV, dst []*sublist) []*sublist {
+	k := p.Key()
+	wildcardAdded := false
+
+	p.EachValue(func(v string) {
+		// For any values add only list by key
+		if v == Any {
+			if sl, exists := ix.indexKey[k]; exists {
+				dst = append(dst, sl)
+			}
+			return
+		}
+
+		// Check exact value matches
+		if vals, exists := ix.indexKeyValue[k]; exists {
+			if sl := vals.get(v); sl != nil {
+				dst = append(dst, sl)
+			}
+			// Check wildcard matches for this key
+			if sl := vals.get(Any); sl != nil && !wildcardAdded {
+				dst = append(dst, sl)
+				wildcardAdded = true
+			}
+		}
+	})
+
+	// Operator subscriptions are verified by Match
+	if sl, exists := ix.indexKeyOp[k]; exists {
+		dst = append(dst, sl)
+	}
+	return dst
+}
+
+// shard holds current version of indexes for keys hashed into it
+type shard struct {
+	p atomic.Pointer[indexes]
+}
+
+// load returns current indexes of the shard
+func (sh *shard) load() *indexes {
+	return sh.p.Load()
+}
+
+// shardIndex returns shard number of key k
+func shardIndex(k string) int {
+	return int(fnv32(k) % numShards)
+}
+
+// fnv32 returns FNV-1a hash of s
+func fnv32(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// valueBuckets is the number of buckets of a valueIndex
+const valueBuckets = 64
+
+// valueIndex maps values of a key to subscriptions. Values are spread
+// over buckets, so a new version copies only one small map even for keys
+// with thousands of values (e.g. "id=...").
+type valueIndex struct {
+	buckets [valueBuckets]map[string]*sublist
+	n       int // Number of values
+	hint    int // Preallocated entries per bucket
+}
+
+// get returns subscriptions with value v, nil if none. vi may be nil.
+func (vi *valueIndex) get(v string) *sublist {
+	if vi == nil {
+		return nil
+	}
+	return vi.buckets[fnv32(v)%valueBuckets][v]
+}
+
+// len returns number of values. vi may be nil.
+func (vi *valueIndex) len() int {
+	if vi == nil {
+		return 0
+	}
+	return vi.n
+}
+
+// clone returns a copy for modification, buckets are shared until set.
+// vi may be nil.
+func (vi *valueIndex) clone() *valueIndex {
+	if vi == nil {
+		return &valueIndex{}
+	}
+	c := *vi
+	return &c
+}
+
+// set replaces subscriptions of value v, nil sl deletes the value.
+// vi must be a private clone.
+func (vi *valueIndex) set(v string, sl *sublist) {
+	b := fnv32(v) % valueBuckets
+	m := cloneSized(vi.buckets[b], vi.hint)
+	_, existed := m[v]
+	if sl != nil {
+		m[v] = sl
+		if !existed {
+			vi.n++
+		}
+	} else if existed {
+		delete(m, v)
+		vi.n--
+	}
+	vi.buckets[b] = m
+}
+
+// each calls fn for every value in unspecified order
+func (vi *valueIndex) each(fn func(v string, sl *sublist)) {
+	if vi == nil {
+		return
+	}
+	for _, m := range vi.buckets {
+		for v, sl := range m {
+			fn(v, sl)
+		}
+	}
+}
+
+// updateShards applies fn to private clones of indexes of shards holding
+// keys of topic t and publishes new versions.
+// Must be called while holding the Hub's lock (h.Lock()).
+func (h *Hub) updateShards(t *Topic, fn func(ix *indexes, p kv.KV)) {
+	var next [numShards]*indexes
+	for p := range t.pairs() {
+		i := shardIndex(p.Key())
+		if next[i] == nil {
+			next[i] = h.shards[i].load().clone()
+		}
+		fn(next[i], p)
+	}
+	for i, ix := range next {
+		if ix != nil {
+			h.shards[i].p.Store(ix)
+		}
+	}
+}
+
+// with returns a new sorted list with subscription s added.
+// sl may be nil and is not modified.
+//
+// New subscriptions have the largest IDs, so they are usually appended
+// in place into the spare capacity: readers of sl never look beyond
+// its length and sl is not modified anymore once replaced in the index.
+func (sl *sublist) with(s *sub) *sublist {
+	var lst []*sub
+	if sl != nil {
+		lst = sl.lst
+	}
+	idx := sort.Search(len(lst), func(i int) bool {
+		return lst[i].id >= s.id
+	})
+	if idx == len(lst) {
+		return &sublist{lst: append(lst, s)}
+	}
+	n := make([]*sub, 0, len(lst)+1)
+	n = append(n, lst[:idx]...)
+	n = append(n, s)
+	n = append(n, lst[idx:]...)
+	return &sublist{lst: n}
+}
+
+// without returns a new list without subscription id, nil if it is empty.
+// sl may be nil and is not modified.
+func (sl *sublist) without(id SubID) *sublist {
+	if sl == nil {
+		return nil
+	}
+	idx := sl.find(id)
+	if idx == -1 {
+		return sl
+	}
+	if len(sl.lst) == 1 {
+		return nil
+	}
+	n := make([]*sub, 0, len(sl.lst)-1)
+	n = append(n, sl.lst[:idx]...)
+	n = append(n, sl.lst[idx+1:]...)
+	return &sublist{lst: n}
+}
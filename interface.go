@@ -0,0 +1,15 @@
+package hub
+
+import "context"
+
+// Interface is the core API of Hub. Depend on it instead of *Hub to
+// substitute the hub in tests, see package hubmock.
+type Interface interface {
+	Subscribe(ctx context.Context, t *Topic, cb any, opts ...SubscribeOption) (SubID, error)
+	Publish(ctx context.Context, topic *Topic, payload any, opts ...PublishOption) error
+	Unsubscribe(ctx context.Context, id SubID)
+	Len() int
+	Close() error
+}
+
+var _ Interface = (*Hub)(nil)
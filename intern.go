@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxInternedTopics limits the number of topics cached by Hub.T,
+// topics beyond the limit are parsed on every call
+const maxInternedTopics = 65536
+
+// internCache stores parsed topics by their arguments
+type internCache struct {
+	mu     sync.RWMutex
+	topics map[string]*Topic // joined args -> topic
+}
+
+// T creates a new Topic from key-value pairs like the package level T.
+// When interning is enabled (see WithInterning), parsed topics are cached
+// by their arguments and repeated calls return the same immutable *Topic
+// without parsing and allocating keys and values again.
+// Panics on invalid input.
+//
+// Example:
+//
+//	h := hub.New(hub.WithInterning(true))
+//	h.Publish(ctx, h.T("type=metrics", "source=api"), stats)
+func (h *Hub) T(args ...string) *Topic {
+	if h.intern == nil {
+		return T(slices.Clone(args)...)
+	}
+
+	var key string
+	if len(args) == 1 {
+		key = args[0]
+	} else {
+		// Arguments can't contain zero byte in practice,
+		// so it is safe as a separator
+		key = strings.Join(args, "\x00")
+	}
+
+	c := h.intern
+	c.mu.RLock()
+	t, ok := c.topics[key]
+	c.mu.RUnlock()
+	if ok {
+		return t
+	}
+
+	// Arguments are cloned on both parse paths to keep them
+	// on caller's stack for cache hits
+	t = T(slices.Clone(args)...).intern()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.topics[key]; ok {
+		return cached
+	}
+	if len(c.topics) < maxInternedTopics {
+		c.topics[key] = t
+	}
+	return t
+}
+
+// intern returns the Topic with keys and values interned
+func (t *Topic) intern() *Topic {
+	return &Topic{mp: t.mp.Intern()}
+}
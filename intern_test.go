@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHubT(t *testing.T) {
+	t.Run("interning disabled", func(t *testing.T) {
+		h := New()
+		a := h.T("type=alert", "priority=high")
+		b := h.T("type=alert", "priority=high")
+		if a == b {
+			t.Error("Expected new topic on every call")
+		}
+		if !a.Equal(b) {
+			t.Error("Expected equal topics")
+		}
+	})
+
+	t.Run("interning enabled", func(t *testing.T) {
+		h := New(WithInterning(true))
+		a := h.T("type=alert", "priority=high")
+		b := h.T("type=alert", "priority=high")
+		if a != b {
+			t.Error("Expected cached topic")
+		}
+		if c := h.T("type=alert"); c == a {
+			t.Error("Expected different topic for different arguments")
+		}
+
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = h.T("type=alert")
+		})
+		if allocs != 0 {
+			t.Errorf("T() allocs = %v, want 0", allocs)
+		}
+	})
+
+	t.Run("panics on invalid input", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected T() to panic on invalid input")
+			}
+		}()
+		New(WithInterning(true)).T("type")
+	})
+
+	t.Run("publish with interned topics", func(t *testing.T) {
+		ctx := context.Background()
+		h := New(WithInterning(true))
+		n := 0
+		h.Subscribe(ctx, h.T("type=alert"), func(ctx context.Context) {
+			n++
+		})
+		h.Publish(ctx, h.T("type=alert"), nil, Sync(true))
+		h.Publish(ctx, h.T("type=alert"), nil, Sync(true))
+		if n != 2 {
+			t.Errorf("Handler called %d times, want 2", n)
+		}
+	})
+}
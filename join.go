@@ -0,0 +1,210 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// JoinConfig configures Hub.Join
+type JoinConfig struct {
+	Left *Topic // Pattern of events of the left side
+	// Pattern of events of the right side, must not match Left or be
+	// matched by it. Events matching both sides are ignored.
+	Right *Topic
+	Key   string // Attribute correlating events of both sides, e.g. "order_id"
+	// Timeout for the other side to arrive after an event is buffered
+	Timeout time.Duration
+	// Topic receiving *Joined payloads, Key attribute is set on it
+	Topic *Topic
+	// TimeoutTopic receives *JoinTimeout payloads for events which
+	// were not joined in time, Key attribute is set on it.
+	// If nil such events are dropped.
+	TimeoutTopic *Topic
+}
+
+// Joined is the payload published by Hub.Join for a pair of events
+type Joined struct {
+	Key        string // Value of JoinConfig.Key
+	LeftTopic  *Topic
+	Left       any
+	RightTopic *Topic
+	Right      any
+}
+
+// JoinTimeout is the payload published to JoinConfig.TimeoutTopic for an
+// event whose other side didn't arrive in time
+type JoinTimeout struct {
+	Key     string // Value of JoinConfig.Key
+	Left    bool   // The event is of the left side
+	Topic   *Topic
+	Payload any
+}
+
+// Join correlates events of two topics: when events matching cfg.Left and
+// cfg.Right with the same value of cfg.Key arrive within cfg.Timeout of
+// each other, a *Joined event is published to cfg.Topic. Several buffered
+// events of one side are joined in arrival order. Events without the key
+// attribute or matching both sides are ignored. Buffers and timers use the hub Clock. Join stops
+// when ctx is done, buffered events are discarded.
+//
+// Example:
+//
+//	err := h.Join(ctx, hub.JoinConfig{
+//	    Left:         hub.T("type=order", "status=paid"),
+//	    Right:        hub.T("type=shipment", "status=ready"),
+//	    Key:          "order_id",
+//	    Timeout:      time.Hour,
+//	    Topic:        hub.T("type=order", "status=ready_to_ship"),
+//	    TimeoutTopic: hub.T("type=order", "status=stuck"),
+//	})
+func (h *Hub) Join(ctx context.Context, cfg JoinConfig) error {
+	if cfg.Left == nil || cfg.Right == nil || cfg.Topic == nil || cfg.Key == "" || cfg.Timeout <= 0 {
+		return errors.New("hub: join requires Left, Right, Key, Timeout and Topic")
+	}
+	if cfg.Left.Match(cfg.Right) || cfg.Right.Match(cfg.Left) {
+		return fmt.Errorf("hub: join sides %s and %s overlap", cfg.Left, cfg.Right)
+	}
+	for _, dst := range []*Topic{cfg.Topic, cfg.TimeoutTopic} {
+		if dst != nil && (cfg.Left.Match(dst) || cfg.Right.Match(dst)) {
+			return fmt.Errorf("hub: join destination %s matches a source", dst)
+		}
+	}
+
+	j := &joiner{h: h, ctx: context.WithoutCancel(ctx), cfg: cfg, pending: make(map[string][]*joinItem)}
+	var ids []SubID
+	for _, left := range []bool{true, false} {
+		src := cfg.Right
+		if left {
+			src = cfg.Left
+		}
+		id, err := h.Subscribe(ctx, src, func(ctx context.Context, t *Topic, payload any) error {
+			return j.add(ctx, left, t, payload)
+		})
+		if err != nil {
+			for _, id := range ids {
+				h.Unsubscribe(ctx, id)
+			}
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	context.AfterFunc(ctx, func() {
+		for _, id := range ids {
+			h.Unsubscribe(j.ctx, id)
+		}
+		j.stop()
+	})
+	return nil
+}
+
+// joiner buffers events of Hub.Join
+type joiner struct {
+	h   *Hub
+	ctx context.Context // Context of timeout events
+	cfg JoinConfig
+
+	mu      sync.Mutex
+	pending map[string][]*joinItem // Not joined events of one side by key
+	stopped bool
+}
+
+// joinItem is a buffered event
+type joinItem struct {
+	left    bool
+	topic   *Topic
+	payload any
+	timer   Timer
+}
+
+// add joins the event with the oldest buffered event of the other side
+// or buffers it
+func (j *joiner) add(ctx context.Context, left bool, t *Topic, payload any) error {
+	key := t.Get(j.cfg.Key)
+	if key == "" {
+		return nil
+	}
+	// partially overlapping sides: the event must not be joined with itself
+	if j.cfg.Left.Match(t) && j.cfg.Right.Match(t) {
+		return nil
+	}
+
+	j.mu.Lock()
+	if j.stopped {
+		j.mu.Unlock()
+		return nil
+	}
+	if items := j.pending[key]; len(items) > 0 && items[0].left != left {
+		other := items[0]
+		j.remove(key, other)
+		j.mu.Unlock()
+		other.timer.Stop()
+
+		joined := &Joined{Key: key}
+		if left {
+			joined.LeftTopic, joined.Left = t, payload
+			joined.RightTopic, joined.Right = other.topic, other.payload
+		} else {
+			joined.LeftTopic, joined.Left = other.topic, other.payload
+			joined.RightTopic, joined.Right = t, payload
+		}
+		return j.h.Publish(ctx, &Topic{mp: j.cfg.Topic.mp.Set(j.cfg.Key, key)}, joined)
+	}
+	it := &joinItem{left: left, topic: t, payload: payload}
+	j.pending[key] = append(j.pending[key], it)
+	// timer is set under the lock, so it is never nil in remove callers
+	it.timer = j.h.clock.AfterFunc(j.cfg.Timeout, func() {
+		j.expire(key, it)
+	})
+	j.mu.Unlock()
+	return nil
+}
+
+// expire publishes a buffered event which was not joined in time
+func (j *joiner) expire(key string, it *joinItem) {
+	j.mu.Lock()
+	ok := !j.stopped && j.remove(key, it)
+	j.mu.Unlock()
+	if !ok || j.cfg.TimeoutTopic == nil {
+		return
+	}
+	_ = j.h.Publish(j.ctx, &Topic{mp: j.cfg.TimeoutTopic.mp.Set(j.cfg.Key, key)}, &JoinTimeout{
+		Key:     key,
+		Left:    it.left,
+		Topic:   it.topic,
+		Payload: it.payload,
+	})
+}
+
+// remove deletes buffered event it, returns false if it was already
+// removed. Must be called under j.mu.
+func (j *joiner) remove(key string, it *joinItem) bool {
+	items := j.pending[key]
+	i := slices.Index(items, it)
+	if i < 0 {
+		return false
+	}
+	if len(items) == 1 {
+		delete(j.pending, key)
+	} else {
+		j.pending[key] = slices.Delete(items, i, i+1)
+	}
+	return true
+}
+
+// stop discards buffered events
+func (j *joiner) stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.stopped = true
+	for _, items := range j.pending {
+		for _, it := range items {
+			it.timer.Stop()
+		}
+	}
+	clear(j.pending)
+}
@@ -0,0 +1,149 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestJoin(t *testing.T) {
+	t.Parallel()
+
+	clock := &manualClock{now: time.Unix(0, 0)}
+	h := New(WithClock(clock))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []string
+	record := func(ctx context.Context, p any) {
+		switch p := p.(type) {
+		case *Joined:
+			got = append(got, fmt.Sprintf("joined %s %v+%v", p.Key, p.Left, p.Right))
+		case *JoinTimeout:
+			got = append(got, fmt.Sprintf("timeout %s %v left=%v %s", p.Key, p.Payload, p.Left, TopicFromContext(ctx)))
+		}
+	}
+	for _, topic := range []*Topic{T("type=ready"), T("type=stuck")} {
+		if _, err := h.Subscribe(ctx, topic, record, Inline(true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := h.Join(ctx, JoinConfig{
+		Left:         T("type=order"),
+		Right:        T("type=shipment"),
+		Key:          "id",
+		Timeout:      time.Minute,
+		Topic:        T("type=ready"),
+		TimeoutTopic: T("type=stuck"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(topic *Topic, payload string) {
+		t.Helper()
+		if err := h.Publish(ctx, topic, payload, Sync(true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	publish(T("type=order", "id=1"), "o1")
+	publish(T("type=order", "id=1"), "o1b")
+	publish(T("type=order", "id=2"), "o2")
+	publish(T("type=order"), "no key")
+	clock.advance(30 * time.Second)
+	publish(T("type=shipment", "id=1"), "s1") // joined with the oldest order
+	publish(T("type=shipment", "id=3"), "s3")
+	clock.advance(30 * time.Second) // o1b and o2 time out
+	publish(T("type=order", "id=3"), "o3")
+	clock.advance(time.Minute)
+
+	want := []string{
+		"joined 1 o1+s1",
+		"timeout 1 o1b left=true id=1 type=stuck",
+		"timeout 2 o2 left=true id=2 type=stuck",
+		"joined 3 o3+s3",
+	}
+	if len(got) >= 3 {
+		slices.Sort(got[1:3]) // timers of one instant fire in any order
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events:\n%q\nwant:\n%q", got, want)
+	}
+
+	// stopped join discards buffered events
+	publish(T("type=order", "id=4"), "o4")
+	cancel()
+	for h.Len() != 2 || clock.pending() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJoinPartialOverlap(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []string
+	_, _ = h.Subscribe(ctx, T("type=ready"), func(ctx context.Context, j *Joined) {
+		got = append(got, fmt.Sprintf("%v+%v", j.Left, j.Right))
+	}, Inline(true))
+	err := h.Join(ctx, JoinConfig{
+		Left:    T("type=order"),
+		Right:   T("status=paid"),
+		Key:     "id",
+		Timeout: time.Minute,
+		Topic:   T("type=ready"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []struct {
+		topic   *Topic
+		payload string
+	}{
+		{T("type=order", "status=paid", "id=1"), "both"},
+		{T("type=order", "id=1"), "order"},
+		{T("status=paid", "id=1"), "payment"},
+	} {
+		if err := h.Publish(ctx, p.topic, p.payload, Sync(true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"order+payment"}; !slices.Equal(got, want) {
+		t.Errorf("joined %q, want %q", got, want)
+	}
+}
+
+func TestJoinInvalid(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx := context.Background()
+	cfg := JoinConfig{Left: T("type=a"), Right: T("type=b"), Key: "id", Timeout: time.Second, Topic: T("type=ab")}
+	if err := h.Join(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := cfg
+	bad.Key = ""
+	if h.Join(ctx, bad) == nil {
+		t.Error("Join() without Key succeeded")
+	}
+	bad = cfg
+	bad.TimeoutTopic = T("type=a", "status=timeout")
+	if h.Join(ctx, bad) == nil {
+		t.Error("Join() with TimeoutTopic matching Left succeeded")
+	}
+	for _, right := range []*Topic{T("type=a"), T("type=*"), T("type=a", "status=paid")} {
+		bad = cfg
+		bad.Right = right
+		if h.Join(ctx, bad) == nil {
+			t.Errorf("Join() with Right %s overlapping Left succeeded", right)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package hub
+
+import "sync"
+
+// MaxPriority is the highest priority lane, see Priority
+const MaxPriority = 7
+
+// laneQueue is a FIFO queue of tasks split into priority lanes.
+// Tasks of higher lanes are popped first. Lanes are added on demand,
+// up to MaxPriority.
+// Not safe for concurrent use.
+type laneQueue struct {
+	lanes [][]func()
+	n     int // Number of queued tasks
+}
+
+// push appends fn to lane prio clamped to [0, MaxPriority]
+func (q *laneQueue) push(prio int, fn func()) {
+	prio = min(max(prio, 0), MaxPriority)
+	for len(q.lanes) <= prio {
+		q.lanes = append(q.lanes, nil)
+	}
+	q.lanes[prio] = append(q.lanes[prio], fn)
+	q.n++
+}
+
+// pop removes the first task of the highest non-empty lane, nil if empty
+func (q *laneQueue) pop() func() {
+	if q.n == 0 {
+		return nil
+	}
+	for i := len(q.lanes) - 1; i >= 0; i-- {
+		lane := q.lanes[i]
+		if len(lane) == 0 {
+			continue
+		}
+		fn := lane[0]
+		lane[0] = nil
+		q.lanes[i] = lane[1:]
+		q.n--
+		return fn
+	}
+	return nil
+}
+
+// workerPool runs tasks on a limited number of goroutines taking
+// queued tasks by priority, see WithWorkers.
+// Workers exit when the queue is empty, an idle pool has no goroutines.
+type workerPool struct {
+	mu      sync.Mutex
+	queue   laneQueue
+	running int // Number of started workers
+	size    int // Maximum number of workers
+}
+
+// submit queues task into lane prio and starts a worker if the
+// pool is not full
+func (p *workerPool) submit(prio int, task func()) {
+	p.mu.Lock()
+	p.queue.push(prio, task)
+	if p.running >= p.size {
+		p.mu.Unlock()
+		return
+	}
+	p.running++
+	p.mu.Unlock()
+	go p.work()
+}
+
+// work runs queued tasks until the queue is empty
+func (p *workerPool) work() {
+	for {
+		p.mu.Lock()
+		task := p.queue.pop()
+		if task == nil {
+			p.running--
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+		task()
+	}
+}
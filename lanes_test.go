@@ -0,0 +1,47 @@
+package hub
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestLaneQueue(t *testing.T) {
+	var q laneQueue
+	var got []string
+	push := func(prio int, name string) {
+		q.push(prio, func() { got = append(got, name) })
+	}
+	push(0, "low1")
+	push(2, "urgent")
+	push(-1, "low2")
+	push(1, "high")
+	push(2, "urgent2")
+
+	for fn := q.pop(); fn != nil; fn = q.pop() {
+		fn()
+	}
+
+	want := []string{"urgent", "urgent2", "high", "low1", "low2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if q.n != 0 {
+		t.Errorf("n = %d after draining", q.n)
+	}
+
+	// huge priorities share the top lane instead of allocating lanes
+	got = got[:0]
+	push(math.MaxInt, "max")
+	push(1<<30, "huge")
+	push(MaxPriority, "top")
+	if len(q.lanes) != MaxPriority+1 {
+		t.Errorf("lanes = %d, want %d", len(q.lanes), MaxPriority+1)
+	}
+	for fn := q.pop(); fn != nil; fn = q.pop() {
+		fn()
+	}
+	if want := []string{"max", "huge", "top"}; !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
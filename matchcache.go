@@ -0,0 +1,96 @@
+package hub
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// Stats holds hub internal counters returned by Hub.Stats
+type Stats struct {
+	Subscriptions    int    // Number of active subscriptions
+	MatchCacheHits   uint64 // Publishes resolved from the match cache
+	MatchCacheMisses uint64 // Publishes matched against indexes while the cache is enabled
+}
+
+// Stats returns internal counters of the hub.
+//
+// Example:
+//
+//	st := h.Stats()
+//	log.Printf("match cache hit rate: %.2f",
+//	    float64(st.MatchCacheHits)/float64(st.MatchCacheHits+st.MatchCacheMisses))
+func (h *Hub) Stats() Stats {
+	st := Stats{
+		Subscriptions: h.Len(),
+	}
+	if h.cache != nil {
+		st.MatchCacheHits = h.cache.hits.Load()
+		st.MatchCacheMisses = h.cache.misses.Load()
+	}
+	return st
+}
+
+// matchCacheEntry is an immutable resolved subscriber set of a topic
+type matchCacheEntry struct {
+	gen   uint64
+	topic *Topic
+	subs  []*sub
+}
+
+// matchCache is a direct-mapped cache of match results by topic hash.
+// Entries are valid only for the generation they were resolved in,
+// every Subscribe, Unsubscribe and Clear starts a new generation.
+type matchCache struct {
+	gen     atomic.Uint64
+	mask    uint64
+	entries []atomic.Pointer[matchCacheEntry]
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+// newMatchCache creates cache with size rounded up to a power of two
+func newMatchCache(size int) *matchCache {
+	if size < 1 {
+		size = 1
+	}
+	n := 1 << bits.Len(uint(size-1))
+	return &matchCache{
+		mask:    uint64(n - 1),
+		entries: make([]atomic.Pointer[matchCacheEntry], n),
+	}
+}
+
+// invalidate drops all cached results
+func (c *matchCache) invalidate() {
+	if c != nil {
+		c.gen.Add(1)
+	}
+}
+
+// cachedMatch appends subscriptions matching t to dst using cache if possible
+func (h *Hub) cachedMatch(t *Topic, dst []*sub) []*sub {
+	c := h.cache
+	if c == nil {
+		return h.match(t, dst)
+	}
+
+	// Generation is read before matching: concurrent changes
+	// make the stored result stale instead of wrong
+	gen := c.gen.Load()
+	hash := t.Hash()
+	slot := &c.entries[hash&c.mask]
+	if e := slot.Load(); e != nil && e.gen == gen && e.topic.Equal(t) {
+		c.hits.Add(1)
+		return append(dst, e.subs...)
+	}
+
+	c.misses.Add(1)
+	n := len(dst)
+	dst = h.match(t, dst)
+	slot.Store(&matchCacheEntry{
+		gen:   gen,
+		topic: t,
+		subs:  append([]*sub(nil), dst[n:]...),
+	})
+	return dst
+}
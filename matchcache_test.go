@@ -0,0 +1,74 @@
+package hub
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMatchCache(t *testing.T) {
+	ctx := context.Background()
+	h := New(MatchCache(16))
+
+	var calls atomic.Int32
+	cb := func(ctx context.Context) { calls.Add(1) }
+
+	id, _ := h.Subscribe(ctx, T("type=alert"), cb)
+
+	publish := func() {
+		if err := h.Publish(ctx, T("type=alert", "level=high"), nil, Sync(true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publish()
+	publish()
+	if st := h.Stats(); st.MatchCacheHits != 1 || st.MatchCacheMisses != 1 {
+		t.Errorf("Stats() = %+v, want 1 hit and 1 miss", st)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+
+	// New subscription invalidates cached result
+	_, _ = h.Subscribe(ctx, T("level=high"), cb)
+	publish()
+	if calls.Load() != 4 {
+		t.Errorf("calls after subscribe = %d, want 4", calls.Load())
+	}
+
+	h.Unsubscribe(ctx, id)
+	publish()
+	if calls.Load() != 5 {
+		t.Errorf("calls after unsubscribe = %d, want 5", calls.Load())
+	}
+
+	h.Clear(ctx)
+	publish()
+	if calls.Load() != 5 {
+		t.Errorf("calls after clear = %d, want 5", calls.Load())
+	}
+
+	if st := h.Stats(); st.MatchCacheHits != 1 || st.MatchCacheMisses != 4 || st.Subscriptions != 0 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestMatchCacheDisabled(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	_, _ = h.Subscribe(ctx, T("type=alert"), func(ctx context.Context) {})
+	_ = h.Publish(ctx, T("type=alert"), nil, Sync(true))
+
+	if st := h.Stats(); st.MatchCacheHits != 0 || st.MatchCacheMisses != 0 || st.Subscriptions != 1 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestNewMatchCacheSize(t *testing.T) {
+	for size, want := range map[int]int{0: 1, 1: 1, 3: 4, 16: 16, 17: 32} {
+		if got := len(newMatchCache(size).entries); got != want {
+			t.Errorf("newMatchCache(%d) size = %d, want %d", size, got, want)
+		}
+	}
+}
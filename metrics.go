@@ -0,0 +1,79 @@
+package hub
+
+import "time"
+
+// Metrics receives hub instrumentation events (see WithMetrics).
+// Implementations must be safe for concurrent use and cheap:
+// methods are called on the publish and delivery hot paths.
+type Metrics interface {
+	// EventPublished is called for every event accepted by Publish
+	EventPublished(t *Topic)
+	// EventDelivered is called after a handler returns.
+	// err is the handler error (nil on success).
+	EventDelivered(t *Topic, id SubID, d time.Duration, err error)
+	// HandlersInFlight is called with +1 before and -1 after a handler call,
+	// the running sum is the number of handlers being executed.
+	HandlersInFlight(delta int)
+	// Subscriptions is called with the number of active subscriptions
+	// after it changes.
+	Subscriptions(n int)
+}
+
+// NoopMetrics implements Metrics doing nothing.
+// Embed it to implement only a part of the interface.
+type NoopMetrics struct{}
+
+// EventPublished implements Metrics
+func (NoopMetrics) EventPublished(*Topic) {}
+
+// EventDelivered implements Metrics
+func (NoopMetrics) EventDelivered(*Topic, SubID, time.Duration, error) {}
+
+// HandlersInFlight implements Metrics
+func (NoopMetrics) HandlersInFlight(int) {}
+
+// Subscriptions implements Metrics
+func (NoopMetrics) Subscriptions(int) {}
+
+// multiMetrics reports to several Metrics
+type multiMetrics []Metrics
+
+// EventPublished implements Metrics
+func (mm multiMetrics) EventPublished(t *Topic) {
+	for _, m := range mm {
+		m.EventPublished(t)
+	}
+}
+
+// EventDelivered implements Metrics
+func (mm multiMetrics) EventDelivered(t *Topic, id SubID, d time.Duration, err error) {
+	for _, m := range mm {
+		m.EventDelivered(t, id, d, err)
+	}
+}
+
+// HandlersInFlight implements Metrics
+func (mm multiMetrics) HandlersInFlight(delta int) {
+	for _, m := range mm {
+		m.HandlersInFlight(delta)
+	}
+}
+
+// Subscriptions implements Metrics
+func (mm multiMetrics) Subscriptions(n int) {
+	for _, m := range mm {
+		m.Subscriptions(n)
+	}
+}
+
+// addMetrics adds m to metrics sinks of the hub
+func (h *Hub) addMetrics(m Metrics) {
+	switch cur := h.metrics.(type) {
+	case nil:
+		h.metrics = m
+	case multiMetrics:
+		h.metrics = append(cur, m)
+	default:
+		h.metrics = multiMetrics{cur, m}
+	}
+}
@@ -0,0 +1,90 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testMetrics records calls of Metrics methods
+type testMetrics struct {
+	mu            sync.Mutex
+	published     int
+	delivered     int
+	errors        int
+	inFlight      int
+	maxInFlight   int
+	subscriptions int
+}
+
+func (m *testMetrics) EventPublished(*Topic) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published++
+}
+
+func (m *testMetrics) EventDelivered(_ *Topic, _ SubID, _ time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delivered++
+	if err != nil {
+		m.errors++
+	}
+}
+
+func (m *testMetrics) HandlersInFlight(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight += delta
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+}
+
+func (m *testMetrics) Subscriptions(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscriptions = n
+}
+
+func TestWithMetrics(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	m := &testMetrics{}
+	h := New(WithMetrics(m))
+
+	id, _ := h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {})
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) error {
+		return errors.New("fail")
+	})
+	if m.subscriptions != 2 {
+		t.Errorf("subscriptions = %d, want 2", m.subscriptions)
+	}
+
+	_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+	_ = h.Publish(ctx, T("type=b"), nil, Sync(true))
+
+	if m.published != 2 || m.delivered != 2 || m.errors != 1 {
+		t.Errorf("published=%d delivered=%d errors=%d, want 2 2 1", m.published, m.delivered, m.errors)
+	}
+	if m.inFlight != 0 || m.maxInFlight < 1 {
+		t.Errorf("inFlight=%d maxInFlight=%d", m.inFlight, m.maxInFlight)
+	}
+
+	h.Unsubscribe(ctx, id)
+	if m.subscriptions != 1 {
+		t.Errorf("subscriptions = %d after Unsubscribe, want 1", m.subscriptions)
+	}
+	h.Clear(ctx)
+	if m.subscriptions != 0 {
+		t.Errorf("subscriptions = %d after Clear, want 0", m.subscriptions)
+	}
+}
+
+func TestNoopMetrics(t *testing.T) {
+	var m Metrics = NoopMetrics{}
+	m.EventPublished(nil)
+	m.EventDelivered(nil, 0, 0, nil)
+	m.HandlersInFlight(1)
+	m.Subscriptions(1)
+}
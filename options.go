@@ -1,6 +1,9 @@
 package hub
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // SubscribeOption defines an interface for modifying subscription parameters
 type SubscribeOption interface {
@@ -30,6 +33,152 @@ func Once(v bool) SubscribeOption {
 	}
 }
 
+// optionSubscribeAsync implements subscription option for asynchronous delivery
+type optionSubscribeAsync struct {
+	v bool // Flag indicating whether to always run in own goroutine
+}
+
+// modifySub applies the async flag to the subscription
+func (o *optionSubscribeAsync) modifySub(ctx context.Context, s *sub) {
+	s.async = o.v
+}
+
+// Async creates a SubscribeOption that forces the handler to run in its own
+// goroutine even when the event is published with Sync(true).
+// Long-running subscribers can't stall publishers that asked for sync
+// dispatch of the fast handlers. OnFinish callbacks still run after
+// all handlers complete; Publish with Sync(true) and Wait(true) waits
+// for async handlers as well.
+func Async(v bool) SubscribeOption {
+	return &optionSubscribeAsync{
+		v: v,
+	}
+}
+
+// optionSubscribeInline implements subscription option for inline delivery
+type optionSubscribeInline struct {
+	v bool // Flag indicating whether to run in publisher goroutine
+}
+
+// modifySub applies the inline flag to the subscription
+func (o *optionSubscribeInline) modifySub(ctx context.Context, s *sub) {
+	s.inline = o.v
+}
+
+// Inline creates a SubscribeOption that forces the handler to run
+// synchronously in the publisher goroutine even for async publishes.
+// Intended for ultra-cheap handlers (counters) where goroutine spawn
+// costs dominate. The handler must not block. Async takes precedence
+// if both options are enabled.
+func Inline(v bool) SubscribeOption {
+	return &optionSubscribeInline{
+		v: v,
+	}
+}
+
+// optionSubscribeMaxInFlight implements subscription option for concurrency cap
+type optionSubscribeMaxInFlight struct {
+	v int // Maximum simultaneous invocations, 0 for unlimited
+}
+
+// modifySub applies the concurrency cap to the subscription
+func (o *optionSubscribeMaxInFlight) modifySub(ctx context.Context, s *sub) {
+	if o.v <= 0 {
+		s.inFlight = nil
+		return
+	}
+	s.inFlight = make(chan struct{}, o.v)
+}
+
+// MaxInFlight creates a SubscribeOption that limits how many invocations
+// of the handler run simultaneously. Extra deliveries wait for a free slot
+// (inline deliveries block the publisher) and are dropped if the publish
+// context is done while waiting. Use DropWhenBusy to drop them immediately.
+// Dropped deliveries are counted in HealthInfo.Dropped.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=email"), sendEmail, hub.MaxInFlight(4))
+func MaxInFlight(n int) SubscribeOption {
+	return &optionSubscribeMaxInFlight{
+		v: n,
+	}
+}
+
+// optionSubscribeDropWhenBusy implements subscription option for dropping
+// deliveries beyond MaxInFlight
+type optionSubscribeDropWhenBusy struct {
+	v bool // Flag indicating whether to drop instead of waiting
+}
+
+// modifySub applies the drop flag to the subscription
+func (o *optionSubscribeDropWhenBusy) modifySub(ctx context.Context, s *sub) {
+	s.dropWhenBusy = o.v
+}
+
+// DropWhenBusy creates a SubscribeOption that drops deliveries instead of
+// queueing them when MaxInFlight invocations are already running.
+// Has no effect without MaxInFlight.
+func DropWhenBusy(v bool) SubscribeOption {
+	return &optionSubscribeDropWhenBusy{
+		v: v,
+	}
+}
+
+// optionSubscribeSerialized implements subscription option for serial invocation
+type optionSubscribeSerialized struct {
+	v bool // Flag indicating whether invocations must not overlap
+}
+
+// modifySub applies the serialized flag to the subscription
+func (o *optionSubscribeSerialized) modifySub(ctx context.Context, s *sub) {
+	if o.v {
+		s.serial = &serialQueue{}
+	} else {
+		s.serial = nil
+	}
+}
+
+// Serialized creates a SubscribeOption that guarantees the handler is never
+// invoked concurrently with itself. Async deliveries are queued per
+// subscription and run one by one in publish order by a single goroutine,
+// so stateful subscribers don't need their own mutexes.
+// The handler must not publish events delivered to itself with Sync(true).
+//
+// Example:
+//
+//	var total int
+//	h.Subscribe(ctx, hub.T("type=sale"), func(ctx context.Context, n int) {
+//	    total += n // no data race
+//	}, hub.Serialized(true))
+func Serialized(v bool) SubscribeOption {
+	return &optionSubscribeSerialized{
+		v: v,
+	}
+}
+
+// optionSubscribeName implements subscription option for handler name
+type optionSubscribeName struct {
+	v string // Name of the handler
+}
+
+// modifySub applies the name to the subscription
+func (o *optionSubscribeName) modifySub(ctx context.Context, s *sub) {
+	s.name = o.v
+}
+
+// Name creates a SubscribeOption that names the handler, so the
+// subscription can be transferred to another Hub with Export and Import.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=audit"), auditHandler, hub.Name("audit"))
+func Name(v string) SubscribeOption {
+	return &optionSubscribeName{
+		v: v,
+	}
+}
+
 // optionPublishSync implements synchronous publishing option
 type optionPublishSync struct {
 	v bool // Flag indicating synchronous processing
@@ -88,3 +237,290 @@ func OnFinish(cb func(ctx context.Context)) PublishOption {
 		cb: cb,
 	}
 }
+
+// optionPublishFinishIn implements placement option of OnFinish callbacks
+type optionPublishFinishIn struct {
+	v FinishPlacement // Goroutine running callbacks
+}
+
+// modifyEvent applies the placement to the event
+func (o *optionPublishFinishIn) modifyEvent(ctx context.Context, e *event) {
+	e.finishIn = o.v
+}
+
+// FinishIn creates a PublishOption that selects the goroutine running
+// OnFinish callbacks. In every mode callbacks run after all handlers complete.
+//
+// Example:
+//
+//	// release the buffer in the publisher's goroutine
+//	h.Publish(ctx, topic, buf, hub.OnFinish(release), hub.FinishIn(hub.FinishInPublisher))
+func FinishIn(v FinishPlacement) PublishOption {
+	return &optionPublishFinishIn{
+		v: v,
+	}
+}
+
+// optionPublishWaitFirstError implements errgroup-style waiting option
+type optionPublishWaitFirstError struct {
+	v bool // Flag indicating fail-fast waiting
+}
+
+// modifyEvent enables or disables error collection for the event
+func (o *optionPublishWaitFirstError) modifyEvent(ctx context.Context, e *event) {
+	if o.v {
+		e.group = &eventGroup{}
+	} else {
+		e.group = nil
+	}
+}
+
+// WaitFirstError creates a PublishOption that treats the event as a unit of
+// work, like errgroup: Publish waits for all handlers and returns the first
+// handler error. The error cancels the context of the remaining handlers,
+// context.Cause returns it. Errors are still reported to OnError hooks.
+//
+// Example:
+//
+//	err := h.Publish(ctx, hub.T("type=order", "step=prepare"), order, hub.WaitFirstError(true))
+//	if err != nil {
+//	    // at least one participant failed, others saw ctx canceled
+//	}
+func WaitFirstError(v bool) PublishOption {
+	return &optionPublishWaitFirstError{
+		v: v,
+	}
+}
+
+// optionPublishEventTTL implements time-to-live option of queued deliveries
+type optionPublishEventTTL struct {
+	d time.Duration // Maximum age of a delivery when it starts
+}
+
+// modifyEvent sets the maximum age of deliveries of the event
+func (o *optionPublishEventTTL) modifyEvent(ctx context.Context, e *event) {
+	e.ttl = max(o.d, 0)
+}
+
+// EventTTL creates a PublishOption that limits the age of deliveries.
+// Deliveries still waiting in queues (Serialized, MaxInFlight, MaxPending,
+// WithExecutor pools) longer than d after Publish are dropped and counted
+// in HealthInfo.Expired. Zero or negative d disables the limit.
+//
+// Example:
+//
+//	// stale telemetry is useless after a backlog
+//	h.Publish(ctx, hub.T("type=telemetry"), sample, hub.EventTTL(5*time.Second))
+func EventTTL(d time.Duration) PublishOption {
+	return &optionPublishEventTTL{
+		d: d,
+	}
+}
+
+// optionPublishPriority implements priority lane option
+type optionPublishPriority struct {
+	v int // Lane number, higher runs first
+}
+
+// modifyEvent sets the priority lane of the event
+func (o *optionPublishPriority) modifyEvent(ctx context.Context, e *event) {
+	e.priority = min(max(o.v, 0), MaxPriority)
+}
+
+// Priority creates a PublishOption that selects the priority lane of queued
+// deliveries: the worker pool set by WithWorkers and queues of Serialized
+// subscriptions start deliveries of higher lanes first. Default lane is 0,
+// negative values are treated as 0 and values above MaxPriority as
+// MaxPriority. Without queueing it has no effect.
+//
+// Example:
+//
+//	h.Publish(ctx, hub.T("type=alert"), alert, hub.Priority(1))
+func Priority(v int) PublishOption {
+	return &optionPublishPriority{
+		v: v,
+	}
+}
+
+// optionSubscribeSample implements subscription option for 1-of-n sampling
+type optionSubscribeSample struct {
+	n int // Deliver 1 of every n events
+}
+
+// modifySub applies the sampling interval to the subscription
+func (o *optionSubscribeSample) modifySub(ctx context.Context, s *sub) {
+	s.sampleN = uint64(max(o.n, 0))
+}
+
+// Sample creates a SubscribeOption that delivers only 1 out of every n
+// matching events: the first, the (n+1)-th and so on. Skipped events are not
+// counted as dropped. Values below 2 deliver every event.
+//
+// Example:
+//
+//	// aggregate every 100th request
+//	h.Subscribe(ctx, hub.T("type=request"), aggregate, hub.Sample(100))
+func Sample(n int) SubscribeOption {
+	return &optionSubscribeSample{
+		n: n,
+	}
+}
+
+// optionSubscribeSampleRate implements subscription option for random sampling
+type optionSubscribeSampleRate struct {
+	p float64 // Probability of delivery
+}
+
+// modifySub applies the sampling probability to the subscription
+func (o *optionSubscribeSampleRate) modifySub(ctx context.Context, s *sub) {
+	s.sampleRate = o.p
+}
+
+// SampleRate creates a SubscribeOption that delivers every matching event
+// with probability p, e.g. 0.01 for about 1% of events.
+// Values outside of (0, 1) deliver every event.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=request"), aggregate, hub.SampleRate(0.01))
+func SampleRate(p float64) SubscribeOption {
+	return &optionSubscribeSampleRate{
+		p: p,
+	}
+}
+
+// optionSubscribeQueueGroup implements subscription option for queue groups
+type optionSubscribeQueueGroup struct {
+	v string // Group name
+}
+
+// modifySub applies the queue group to the subscription
+func (o *optionSubscribeQueueGroup) modifySub(ctx context.Context, s *sub) {
+	s.group = o.v
+}
+
+// QueueGroup creates a SubscribeOption that joins the subscription to
+// a named queue group. Every event is delivered to only one member of
+// the group among matching ones, spread round robin or by StickyBy.
+// Subscriptions without a group receive events as usual.
+//
+// Example:
+//
+//	for i := 0; i < 4; i++ {
+//	    h.Subscribe(ctx, hub.T("type=job"), worker, hub.QueueGroup("workers"))
+//	}
+func QueueGroup(name string) SubscribeOption {
+	return &optionSubscribeQueueGroup{
+		v: name,
+	}
+}
+
+// optionSubscribeStickyBy implements subscription option for sticky delivery
+type optionSubscribeStickyBy struct {
+	v          string // Topic attribute key
+	consistent bool   // Use consistent hashing
+}
+
+// modifySub applies the sticky key to the subscription
+func (o *optionSubscribeStickyBy) modifySub(ctx context.Context, s *sub) {
+	s.stickyBy = o.v
+	s.consistent = o.consistent
+}
+
+// StickyBy creates a SubscribeOption that routes events of a queue group
+// by the value of topic attribute key: events with the same value go to
+// the same member while different values spread evenly across members.
+// Values are assigned by hash modulo number of members, so adding or
+// removing a member reassigns most of them, see ConsistentHashBy.
+// Events without the attribute are spread round robin. The key of the
+// member with the smallest ID is used if members disagree.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=order"), handler,
+//	    hub.QueueGroup("orders"), hub.StickyBy("order_id"))
+func StickyBy(key string) SubscribeOption {
+	return &optionSubscribeStickyBy{
+		v: key,
+	}
+}
+
+// ConsistentHashBy creates a SubscribeOption that routes events of a queue
+// group like StickyBy, but with consistent (rendezvous) hashing: when a member
+// subscribes or unsubscribes only values of that member are reassigned.
+// Selection costs a hash per member, suited for groups of up to hundreds.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=session"), handler,
+//	    hub.QueueGroup("sessions"), hub.ConsistentHashBy("session_id"))
+func ConsistentHashBy(key string) SubscribeOption {
+	return &optionSubscribeStickyBy{
+		v:          key,
+		consistent: true,
+	}
+}
+
+// optionSubscribeRequireAck implements subscription option for acknowledged delivery
+type optionSubscribeRequireAck struct {
+	cfg AckConfig
+}
+
+// modifySub applies the acknowledgement settings to the subscription
+func (o *optionSubscribeRequireAck) modifySub(ctx context.Context, s *sub) {
+	cfg := o.cfg
+	s.ack = &cfg
+}
+
+// RequireAck creates a SubscribeOption for deliveries that must be
+// acknowledged with Ack, possibly after the handler returns (see AckFrom).
+// Nack, a handler error or missing Ack within the timeout redeliver the event
+// up to cfg.MaxRedeliveries times, then it is published to cfg.DeadLetter.
+// Redeliveries run asynchronously and are not awaited by Wait.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=payment"), func(ctx context.Context, p Payment) error {
+//	    if err := charge(p); err != nil {
+//	        return err // Nack
+//	    }
+//	    hub.Ack(ctx)
+//	    return nil
+//	}, hub.RequireAck(hub.AckConfig{
+//	    Timeout:         10 * time.Second,
+//	    MaxRedeliveries: 3,
+//	    DeadLetter:      hub.T("type=dead_letter"),
+//	}))
+func RequireAck(cfg AckConfig) SubscribeOption {
+	return &optionSubscribeRequireAck{
+		cfg: cfg,
+	}
+}
+
+// optionSubscribeDurable implements subscription option for durable delivery
+type optionSubscribeDurable struct {
+	v string // Name of offset in the store
+}
+
+// modifySub applies the durable name to the subscription
+func (o *optionSubscribeDurable) modifySub(ctx context.Context, s *sub) {
+	s.durable = o.v
+}
+
+// Durable creates a SubscribeOption that makes the subscription resume from
+// its last processed event in the hub Store (see WithStore): on Subscribe
+// stored events after the committed offset are replayed, then new ones are
+// delivered. Delivery is at-least-once: the offset is committed after the
+// handler returns, an event processed before a crash may be delivered again.
+// A new name receives all stored events. Durable subscriptions are
+// Serialized, run asynchronously and are not awaited by Wait and OnFinish.
+// Without a store it only makes the subscription Serialized.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=order"), project, hub.Durable("orders-projection"))
+func Durable(name string) SubscribeOption {
+	return &optionSubscribeDurable{
+		v: name,
+	}
+}
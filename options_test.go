@@ -2,7 +2,12 @@ package hub
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestOnce(t *testing.T) {
@@ -25,6 +30,38 @@ func TestOnce(t *testing.T) {
 	})
 }
 
+func TestAsync(t *testing.T) {
+	t.Run("sets async flag", func(t *testing.T) {
+		s := &sub{}
+		Async(true).modifySub(context.Background(), s)
+		if !s.async {
+			t.Error("Async(true) didn't set sub.async to true")
+		}
+		Async(false).modifySub(context.Background(), s)
+		if s.async {
+			t.Error("Async(false) didn't set sub.async to false")
+		}
+	})
+}
+
+func TestInline(t *testing.T) {
+	t.Run("sets inline flag", func(t *testing.T) {
+		s := &sub{}
+		Inline(true).modifySub(context.Background(), s)
+		if !s.inline || !s.runInline() {
+			t.Error("Inline(true) didn't set sub.inline to true")
+		}
+		Async(true).modifySub(context.Background(), s)
+		if s.runInline() {
+			t.Error("Async should take precedence over Inline")
+		}
+		Inline(false).modifySub(context.Background(), s)
+		if s.inline {
+			t.Error("Inline(false) didn't set sub.inline to false")
+		}
+	})
+}
+
 func TestSync(t *testing.T) {
 	t.Run("enables sync mode", func(t *testing.T) {
 		opt := Sync(true)
@@ -116,3 +153,315 @@ func TestOptionChaining(t *testing.T) {
 		}
 	})
 }
+
+func TestMaxInFlight(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("queue", func(t *testing.T) {
+		h := New()
+		var cur, peak, calls atomic.Int32
+		_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {
+			n := cur.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			cur.Add(-1)
+			calls.Add(1)
+		}, MaxInFlight(2))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+			}()
+		}
+		wg.Wait()
+
+		if calls.Load() != 6 {
+			t.Errorf("calls = %d, want 6", calls.Load())
+		}
+		if peak.Load() > 2 {
+			t.Errorf("peak concurrency = %d, want <= 2", peak.Load())
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		h := New()
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		var calls atomic.Int32
+		_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) {
+			calls.Add(1)
+			started <- struct{}{}
+			<-release
+		}, MaxInFlight(1), DropWhenBusy(true))
+
+		done := make(chan struct{})
+		go func() {
+			_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+			close(done)
+		}()
+		<-started
+		for i := 0; i < 3; i++ {
+			_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+		}
+		close(release)
+		<-done
+
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+		if d := h.Health().Dropped; d != 3 {
+			t.Errorf("Dropped = %d, want 3", d)
+		}
+	})
+}
+
+func TestSerialized(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+
+	var got []int
+	var cur atomic.Int32
+	_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context, n int) {
+		if cur.Add(1) != 1 {
+			t.Error("concurrent invocation")
+		}
+		got = append(got, n) // no mutex needed
+		cur.Add(-1)
+	}, Serialized(true))
+
+	const n = 100
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		_ = h.Publish(ctx, T("type=a"), i, OnFinish(func(ctx context.Context) { wg.Done() }))
+	}
+	// inline deliveries are serialized with queued ones too
+	_ = h.Publish(ctx, T("type=a"), n, Sync(true))
+	wg.Wait()
+
+	if len(got) != n+1 {
+		t.Fatalf("got %d calls, want %d", len(got), n+1)
+	}
+	prev := -1
+	for _, v := range got {
+		if v == n {
+			continue
+		}
+		if v < prev {
+			t.Fatalf("async deliveries out of order: %v", got)
+		}
+		prev = v
+	}
+}
+
+func TestFinishIn(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	modes := map[string][]PublishOption{
+		"sync":       {Sync(true)},
+		"async":      nil,
+		"async wait": {Wait(true)},
+	}
+	placements := []FinishPlacement{FinishDefault, FinishInPublisher, FinishInHandler}
+
+	for name, opts := range modes {
+		for _, p := range placements {
+			t.Run(name+"/"+p.String(), func(t *testing.T) {
+				h := New()
+				const n = 5
+				var completed atomic.Int32
+				for i := 0; i < n; i++ {
+					_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {
+						time.Sleep(time.Millisecond)
+						completed.Add(1)
+					}, Async(i%2 == 0))
+				}
+
+				finished := make(chan int32, 1)
+				opts := append(opts, FinishIn(p), OnFinish(func(ctx context.Context) {
+					finished <- completed.Load()
+				}))
+				_ = h.Publish(ctx, T("type=job"), nil, opts...)
+
+				if p == FinishInPublisher && len(finished) == 0 {
+					t.Error("OnFinish didn't run before Publish returned")
+				}
+				select {
+				case got := <-finished:
+					if got != n {
+						t.Errorf("OnFinish ran after %d of %d handlers", got, n)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("OnFinish not called")
+				}
+			})
+		}
+	}
+
+	t.Run("no handlers", func(t *testing.T) {
+		h := New()
+		var called bool
+		_ = h.Publish(ctx, T("type=none"), nil, FinishIn(FinishInHandler), OnFinish(func(ctx context.Context) {
+			called = true
+		}))
+		if !called {
+			t.Error("OnFinish didn't run in the publisher without handlers")
+		}
+	})
+}
+
+func TestOnFinishPanic(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var reported []error
+	h := New(OnError(func(ctx context.Context, _ *Topic, id SubID, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if id != 0 {
+			t.Errorf("OnFinish panic reported with subscription %d", id)
+		}
+		reported = append(reported, err)
+	}))
+
+	var after bool
+	_ = h.Publish(ctx, T("type=job"), nil, Sync(true),
+		OnFinish(func(ctx context.Context) { panic("boom") }),
+		OnFinish(func(ctx context.Context) { after = true }),
+	)
+
+	if !after {
+		t.Error("callback after the panicking one didn't run")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 {
+		t.Fatalf("reported = %v, want one error", reported)
+	}
+	var pe *PanicError
+	if !errors.As(reported[0], &pe) || pe.Value != "boom" {
+		t.Errorf("reported %v, want PanicError with boom", reported[0])
+	}
+}
+
+func TestWaitFirstError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var reported atomic.Int32
+	h := New(OnError(func(ctx context.Context, _ *Topic, _ SubID, err error) {
+		reported.Add(1)
+	}))
+
+	errFailed := errors.New("failed")
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) error {
+		return errFailed
+	})
+	var causes sync.Map
+	for i := 0; i < 3; i++ {
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				causes.Store(context.Cause(ctx), true)
+				return nil
+			case <-time.After(time.Second):
+				return errors.New("not canceled")
+			}
+		})
+	}
+
+	for _, sync := range []bool{false, true} {
+		causes.Clear()
+		reported.Store(0)
+		// sync handlers after the failed one see canceled context
+		err := h.Publish(ctx, T("type=job"), nil, WaitFirstError(true), Sync(sync))
+		if !errors.Is(err, errFailed) {
+			t.Errorf("sync=%v: Publish() = %v, want %v", sync, err, errFailed)
+		}
+		if _, ok := causes.Load(errFailed); !ok {
+			t.Errorf("sync=%v: handlers didn't see the failure as cancel cause", sync)
+		}
+		if n := reported.Load(); n != 1 {
+			t.Errorf("sync=%v: reported %d errors, want 1", sync, n)
+		}
+	}
+
+	if err := h.Publish(ctx, T("type=none"), nil, WaitFirstError(true)); err != nil {
+		t.Errorf("Publish() without handlers = %v", err)
+	}
+}
+
+func TestEventTTL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	var mu sync.Mutex
+	var got []int
+	_, _ = h.Subscribe(ctx, T("type=telemetry"), func(ctx context.Context, n int) {
+		mu.Lock()
+		got = append(got, n)
+		mu.Unlock()
+		if n == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}, Serialized(true))
+
+	done := make(chan struct{})
+	_ = h.Publish(ctx, T("type=telemetry"), 1, EventTTL(10*time.Millisecond))
+	_ = h.Publish(ctx, T("type=telemetry"), 2, EventTTL(10*time.Millisecond))
+	_ = h.Publish(ctx, T("type=telemetry"), 3)
+	_ = h.Publish(ctx, T("type=telemetry"), 4, EventTTL(time.Hour), OnFinish(func(ctx context.Context) {
+		close(done)
+	}))
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []int{1, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+	if info := h.Health(); info.Expired != 1 || info.Dropped != 1 {
+		t.Errorf("Expired = %d, Dropped = %d, want 1", info.Expired, info.Dropped)
+	}
+}
+
+func TestSample(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	var got []int
+	_, _ = h.Subscribe(ctx, T("type=metric"), func(ctx context.Context, n int) {
+		got = append(got, n)
+	}, Sample(3))
+	var all, rate atomic.Int32
+	_, _ = h.Subscribe(ctx, T("type=metric"), func(ctx context.Context) { all.Add(1) }, Sample(1))
+	_, _ = h.Subscribe(ctx, T("type=metric"), func(ctx context.Context) { rate.Add(1) }, SampleRate(0.5))
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		_ = h.Publish(ctx, T("type=metric"), i, Sync(true))
+	}
+
+	if len(got) != 334 || got[0] != 0 || got[1] != 3 {
+		t.Errorf("Sample(3) delivered %d events starting with %v", len(got), got[:2])
+	}
+	if all.Load() != n {
+		t.Errorf("Sample(1) delivered %d, want %d", all.Load(), n)
+	}
+	// bounds are 10 standard deviations of binomial distribution
+	if r := rate.Load(); r < 340 || r > 660 {
+		t.Errorf("SampleRate(0.5) delivered %d of %d", r, n)
+	}
+}
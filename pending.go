@@ -0,0 +1,52 @@
+package hub
+
+import "context"
+
+// OverflowPolicy defines what happens to an async delivery
+// when the MaxPending limit is reached
+type OverflowPolicy int
+
+const (
+	// OverflowDrop skips the delivery, it is counted in HealthInfo.Dropped
+	OverflowDrop OverflowPolicy = iota
+	// OverflowInline calls the handler in the publisher goroutine,
+	// slowing down the publisher instead of losing the event
+	OverflowInline
+	// OverflowBlock waits for a free slot. The delivery is dropped
+	// if the publish context is done while waiting.
+	OverflowBlock
+)
+
+// String returns policy name
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDrop:
+		return "drop"
+	case OverflowInline:
+		return "inline"
+	case OverflowBlock:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+// acquirePending reserves a slot for a queued async delivery.
+// Returns false if the limit is reached and the delivery must be
+// handled according to the overflow policy.
+func (h *Hub) acquirePending(ctx context.Context) bool {
+	select {
+	case h.pendingSem <- struct{}{}:
+		return true
+	default:
+	}
+	if h.overflow != OverflowBlock {
+		return false
+	}
+	select {
+	case h.pendingSem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
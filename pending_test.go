@@ -0,0 +1,88 @@
+package hub
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxPending(t *testing.T) {
+	ctx := context.Background()
+
+	// fill occupies all slots as if deliveries were queued
+	fill := func(h *Hub) {
+		for i := 0; i < cap(h.pendingSem); i++ {
+			h.pendingSem <- struct{}{}
+		}
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		h := New(MaxPending(2, OverflowDrop))
+		var calls atomic.Int32
+		_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) { calls.Add(1) })
+
+		_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+		fill(h)
+		_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+		if d := h.Health().Dropped; d != 1 {
+			t.Errorf("Dropped = %d, want 1", d)
+		}
+	})
+
+	t.Run("inline", func(t *testing.T) {
+		h := New(MaxPending(1, OverflowInline))
+		var calls atomic.Int32
+		_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) { calls.Add(1) })
+
+		fill(h)
+		_ = h.Publish(ctx, T("type=a"), nil)
+		// called in the publisher goroutine
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		h := New(MaxPending(1, OverflowBlock))
+		var calls atomic.Int32
+		_, _ = h.Subscribe(ctx, T("type=a"), func(ctx context.Context) { calls.Add(1) })
+
+		fill(h)
+		done := make(chan struct{})
+		go func() {
+			_ = h.Publish(ctx, T("type=a"), nil, Wait(true))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("Publish didn't block on full queue")
+		case <-time.After(20 * time.Millisecond):
+		}
+		<-h.pendingSem
+		<-done
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+
+		// Canceled context drops the delivery
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		fill(h)
+		_ = h.Publish(cctx, T("type=a"), nil, Wait(true))
+		if d := h.Health().Dropped; d != 1 {
+			t.Errorf("Dropped = %d, want 1", d)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if h := New(MaxPending(0, OverflowDrop)); h.pendingSem != nil {
+			t.Error("MaxPending(0) should disable the limit")
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrDrop can be returned by a Stage to drop the event. It is not
+// reported to OnError hooks and is counted in PipeStats.Dropped.
+var ErrDrop = errors.New("drop")
+
+// Stage transforms an event of a pipeline created by Hub.Pipe. It returns
+// the topic and payload passed to the next stage or ErrDrop to stop
+// processing of the event. Other errors are reported to OnError hooks.
+type Stage func(ctx context.Context, t *Topic, payload any) (*Topic, any, error)
+
+// MapStage creates a Stage replacing the payload with the result of fn
+func MapStage(fn func(ctx context.Context, payload any) (any, error)) Stage {
+	return func(ctx context.Context, t *Topic, payload any) (*Topic, any, error) {
+		payload, err := fn(ctx, payload)
+		return t, payload, err
+	}
+}
+
+// FilterStage creates a Stage dropping events for which fn returns false
+func FilterStage(fn func(ctx context.Context, t *Topic, payload any) bool) Stage {
+	return func(ctx context.Context, t *Topic, payload any) (*Topic, any, error) {
+		if !fn(ctx, t, payload) {
+			return nil, nil, ErrDrop
+		}
+		return t, payload, nil
+	}
+}
+
+// EnrichStage creates a Stage adding attributes returned by fn to the topic,
+// e.g. a region looked up by the customer ID
+func EnrichStage(fn func(ctx context.Context, t *Topic, payload any) (*Topic, error)) Stage {
+	return func(ctx context.Context, t *Topic, payload any) (*Topic, any, error) {
+		attrs, err := fn(ctx, t, payload)
+		if err != nil || attrs == nil {
+			return t, payload, err
+		}
+		return &Topic{mp: t.mp.Merge(attrs.mp)}, payload, nil
+	}
+}
+
+// PipeStats counts events processed by a Pipeline
+type PipeStats struct {
+	In      uint64 // Events received from the source topic
+	Out     uint64 // Events published to the destination topic
+	Dropped uint64 // Events dropped with ErrDrop
+	Failed  uint64 // Events failed in a stage or publish
+}
+
+// Pipeline is a chain of stages created by Hub.Pipe
+type Pipeline struct {
+	h      *Hub
+	id     SubID
+	src    *Topic
+	dst    *Topic
+	stages []Stage
+
+	in      atomic.Uint64
+	out     atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// Pipe subscribes to src and republishes its events to dst after running
+// them through stages in order. The published topic is the topic returned
+// by the last stage with attributes of dst set on it, so attributes of the
+// source event and ones added by EnrichStage are kept unless dst overrides
+// them. Stage and publish errors are reported to OnError hooks, e.g. to
+// WithErrorTopic. Events which would be received by the pipeline again are
+// not published and reported as errors, Pipe returns an error at once if
+// dst itself matches src.
+// The pipeline runs until Close or Hub.Close.
+//
+// Example:
+//
+//	p, err := h.Pipe(hub.T("type=order"), hub.T("type=order", "stage=priced"),
+//	    hub.FilterStage(func(ctx context.Context, t *hub.Topic, p any) bool {
+//	        return t.Get("test") != "true"
+//	    }),
+//	    hub.MapStage(func(ctx context.Context, p any) (any, error) {
+//	        return price(p.(Order))
+//	    }),
+//	)
+//	defer p.Close()
+func (h *Hub) Pipe(src, dst *Topic, stages ...Stage) (*Pipeline, error) {
+	if src.Match(dst) {
+		return nil, fmt.Errorf("hub: pipe destination %s matches source %s", dst, src)
+	}
+	p := &Pipeline{h: h, src: src, dst: dst, stages: stages}
+	id, err := h.Subscribe(context.Background(), src, p.run)
+	if err != nil {
+		return nil, err
+	}
+	p.id = id
+	return p, nil
+}
+
+// run processes one event of the source topic
+func (p *Pipeline) run(ctx context.Context, t *Topic, payload any) error {
+	p.in.Add(1)
+	for i, stage := range p.stages {
+		var err error
+		t, payload, err = stage(ctx, t, payload)
+		if errors.Is(err, ErrDrop) {
+			p.dropped.Add(1)
+			return nil
+		}
+		if err != nil {
+			p.failed.Add(1)
+			return fmt.Errorf("hub: pipe stage %d: %w", i, err)
+		}
+	}
+	out := &Topic{mp: t.mp.Merge(p.dst.mp)}
+	if p.src.Match(out) {
+		p.failed.Add(1)
+		return fmt.Errorf("hub: pipe output %s matches source %s", out, p.src)
+	}
+	if err := p.h.Publish(ctx, out, payload); err != nil {
+		p.failed.Add(1)
+		return fmt.Errorf("hub: pipe publish: %w", err)
+	}
+	p.out.Add(1)
+	return nil
+}
+
+// Stats returns counters of processed events
+func (p *Pipeline) Stats() PipeStats {
+	return PipeStats{
+		In:      p.in.Load(),
+		Out:     p.out.Load(),
+		Dropped: p.dropped.Load(),
+		Failed:  p.failed.Load(),
+	}
+}
+
+// Close stops the pipeline, events already being processed are published
+func (p *Pipeline) Close() error {
+	p.h.Unsubscribe(context.Background(), p.id)
+	return nil
+}
@@ -0,0 +1,103 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		errs   []error
+		events []string
+	)
+	h := New(OnError(func(ctx context.Context, t *Topic, id SubID, err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+	ctx := context.Background()
+
+	p, err := h.Pipe(T("type=order", "stage=new"), T("stage=priced"),
+		FilterStage(func(ctx context.Context, t *Topic, payload any) bool {
+			return t.Get("test") != "true"
+		}),
+		EnrichStage(func(ctx context.Context, t *Topic, payload any) (*Topic, error) {
+			return T("region=eu"), nil
+		}),
+		MapStage(func(ctx context.Context, payload any) (any, error) {
+			s := payload.(string)
+			if s == "" {
+				return nil, errors.New("empty order")
+			}
+			return strings.ToUpper(s), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Subscribe(ctx, T("type=order", "stage=priced"), func(ctx context.Context, s string) {
+		mu.Lock()
+		events = append(events, TopicFromContext(ctx).String()+" "+s)
+		mu.Unlock()
+	}, Inline(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		topic   *Topic
+		payload string
+	}{
+		{T("type=order", "stage=new", "id=1"), "a"},
+		{T("type=order", "stage=new", "id=2", "test=true"), "b"},
+		{T("type=order", "stage=new", "id=3"), ""},
+	} {
+		if err := h.Publish(ctx, tc.topic, tc.payload, Sync(true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	if len(events) != 1 || events[0] != "id=1 region=eu stage=priced type=order A" {
+		t.Errorf("pipeline published %q", events)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "empty order") {
+		t.Errorf("OnError got %v", errs)
+	}
+	mu.Unlock()
+	if got, want := p.Stats(), (PipeStats{In: 3, Out: 1, Dropped: 1, Failed: 1}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Publish(ctx, T("type=order", "stage=new"), "c", Sync(true))
+	if p.Stats().In != 3 {
+		t.Error("closed pipeline received event")
+	}
+}
+
+func TestPipeLoop(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	if _, err := h.Pipe(T("type=order"), T("type=order", "stage=priced")); err == nil {
+		t.Error("Pipe() with destination matching source succeeded")
+	}
+
+	// output matches the source only with attributes of the event
+	p, err := h.Pipe(T("type=order"), T("stage=priced"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Publish(context.Background(), T("type=order"), nil, Sync(true))
+	if got, want := p.Stats(), (PipeStats{In: 1, Failed: 1}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
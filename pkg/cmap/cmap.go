@@ -1,81 +1,190 @@
 package cmap
 
-import "sync"
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
 
-// CMap is a thread-safe (concurrent) implementation of map[string]int
+// Map is a thread-safe (concurrent) implementation of map[K]V
 // protected by a sync.RWMutex for safe concurrent access.
-type CMap struct {
-	mu sync.RWMutex
-	m  map[string]int
+// Entries set with SetWithTTL expire, see ttl.go.
+// The zero value is ready to use.
+type Map[K comparable, V any] struct {
+	mu      sync.RWMutex
+	m       map[K]V
+	expires map[K]time.Time // Deadlines of entries with TTL, nil if none
 }
 
-// New creates and returns a new initialized CMap instance.
-// The returned object is ready to use.
-func New() *CMap {
-	return &CMap{
-		m: make(map[string]int),
+// NewMap creates and returns a new initialized Map instance.
+func NewMap[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{
+		m: make(map[K]V),
 	}
 }
 
 // Get returns the value associated with the key and a boolean indicating existence.
 // Thread-safe read operation.
-func (c *CMap) Get(key string) (int, bool) {
+func (c *Map[K, V]) Get(key K) (V, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	val, ok := c.m[key]
+	if ok && c.expired(key, time.Time{}) {
+		var zero V
+		return zero, false
+	}
 	return val, ok
 }
 
 // Set updates or creates a key-value pair in the map.
 // Thread-safe write operation.
-func (c *CMap) Set(key string, value int) {
+func (c *Map[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[K]V)
+	}
+	c.m[key] = value
+	delete(c.expires, key)
+}
+
+// GetOrSet returns the existing value for the key and true, or sets
+// the key to value and returns it with false.
+// Thread-safe write operation.
+func (c *Map[K, V]) GetOrSet(key K, value V) (V, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if val, ok := c.lookup(key); ok {
+		return val, true
+	}
+	if c.m == nil {
+		c.m = make(map[K]V)
+	}
 	c.m[key] = value
+	return value, false
+}
+
+// Update replaces the value for a key with the result of f and returns it.
+// f receives the current value, zero if the key doesn't exist. TTL of
+// the key is kept. f is called under the write lock and MUST NOT access
+// the map.
+// Thread-safe write operation.
+func (c *Map[K, V]) Update(key K, f func(old V) V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[K]V)
+	}
+	old, _ := c.lookup(key)
+	val := f(old)
+	c.m[key] = val
+	return val
 }
 
 // Delete removes a key from the map. No-op if key doesn't exist.
 // Thread-safe write operation.
-func (c *CMap) Delete(key string) {
+func (c *Map[K, V]) Delete(key K) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.m, key)
+	delete(c.expires, key)
+}
+
+// DeleteIf removes the key if f returns true for its value. Returns true
+// if the key was removed. f is called under the write lock and MUST NOT
+// access the map.
+// Thread-safe write operation.
+func (c *Map[K, V]) DeleteIf(key K, f func(value V) bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	val, ok := c.lookup(key)
+	if !ok || !f(val) {
+		return false
+	}
+	delete(c.m, key)
+	delete(c.expires, key)
+	return true
 }
 
 // Len returns the current number of elements in the map.
 // The count reflects the state at the moment of calling.
-func (c *CMap) Len() int {
+func (c *Map[K, V]) Len() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return len(c.m)
+	return len(c.m) - c.countExpired()
 }
 
 // Iterate applies function f to all key-value pairs sequentially.
 // Iteration is performed under a read-lock, therefore:
 // - Order of iteration is not guaranteed (same as native Go map)
-// - Function f MUST NOT modify the map (may cause deadlock)
+// - Function f MUST NOT modify the map (may cause deadlock),
+// iterate over Snapshot or Keys to modify it
 // - Operation is safe for concurrent access
-func (c *CMap) Iterate(f func(key string, value int)) {
+func (c *Map[K, V]) Iterate(f func(key K, value V)) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	now := time.Now()
 	for k, v := range c.m {
-		f(k, v)
+		if !c.expired(k, now) {
+			f(k, v)
+		}
 	}
 }
 
-// Eq compares the internal map state with the provided map[string]int.
+// Snapshot returns a copy of the map made under the read lock.
+// The copy is never nil and belongs to the caller.
+// Unlike Iterate, the map may be modified while ranging over the copy.
+func (c *Map[K, V]) Snapshot() map[K]V {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ret := make(map[K]V, len(c.m))
+	maps.Copy(ret, c.m)
+	c.dropExpired(ret)
+	return ret
+}
+
+// Keys returns the keys of the map in unspecified order.
+// The map may be modified while ranging over the result.
+func (c *Map[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := slices.Collect(maps.Keys(c.m))
+	if len(c.expires) > 0 {
+		now := time.Now()
+		keys = slices.DeleteFunc(keys, func(k K) bool {
+			return c.expired(k, now)
+		})
+	}
+	return keys
+}
+
+// Clear removes all elements from the map.
+// Thread-safe write operation.
+func (c *Map[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m = make(map[K]V)
+	c.expires = nil
+}
+
+// Equal compares the state of c with the provided map.
 // Returns true if both maps have identical key-value pairs.
-func (c *CMap) Eq(compareWith map[string]int) bool {
+func Equal[K, V comparable](c *Map[K, V], compareWith map[K]V) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	// Fast path for different sizes
-	if len(c.m) != len(compareWith) {
+	if len(c.m)-c.countExpired() != len(compareWith) {
 		return false
 	}
 
 	// Compare all entries
+	now := time.Now()
 	for k, v := range c.m {
+		if c.expired(k, now) {
+			continue
+		}
 		if cmpVal, ok := compareWith[k]; !ok || cmpVal != v {
 			return false
 		}
@@ -84,18 +193,48 @@ func (c *CMap) Eq(compareWith map[string]int) bool {
 	return true
 }
 
-// Clear removes all elements from the map.
+// CompareAndSwap sets the key to new if its current value is old.
+// Returns true if the value was swapped, false if the key doesn't exist.
+// TTL of the key is kept.
 // Thread-safe write operation.
-func (c *CMap) Clear() {
+func CompareAndSwap[K, V comparable](c *Map[K, V], key K, old, new V) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.m = make(map[string]int)
+	if val, ok := c.lookup(key); !ok || val != old {
+		return false
+	}
+	c.m[key] = new
+	return true
+}
+
+// CMap is a thread-safe implementation of map[string]int, the Map
+// used by hub tests for counters.
+type CMap struct {
+	Map[string, int]
+}
+
+// New creates and returns a new initialized CMap instance.
+// The returned object is ready to use.
+func New() *CMap {
+	return &CMap{}
+}
+
+// Eq compares the internal map state with the provided map[string]int.
+// Returns true if both maps have identical key-value pairs.
+func (c *CMap) Eq(compareWith map[string]int) bool {
+	return Equal(&c.Map, compareWith)
+}
+
+// CompareAndSwap sets the key to new if its current value is old.
+// Returns true if the value was swapped.
+func (c *CMap) CompareAndSwap(key string, old, new int) bool {
+	return CompareAndSwap(&c.Map, key, old, new)
 }
 
 // Add increments the value for a key by specified delta.
 // Thread-safe write operation. If key doesn't exist, initializes it with delta.
 func (c *CMap) Add(key string, delta int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.m[key] += delta
+	c.Update(key, func(v int) int {
+		return v + delta
+	})
 }
@@ -1,6 +1,8 @@
 package cmap
 
 import (
+	"context"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -319,3 +321,202 @@ func TestCMap_Eq(t *testing.T) {
 		}
 	})
 }
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	type meta struct {
+		owner string
+		tags  []string
+	}
+
+	var m Map[uint64, meta] // zero value is usable
+	m.Set(1, meta{owner: "a", tags: []string{"x"}})
+	m.Update(1, func(v meta) meta {
+		v.tags = append(v.tags, "y")
+		return v
+	})
+	got := m.Update(2, func(v meta) meta {
+		if v.owner != "" {
+			t.Error("Update() got non-zero value for new key")
+		}
+		return meta{owner: "b"}
+	})
+	if got.owner != "b" || m.Len() != 2 {
+		t.Errorf("Update() = %+v, Len() = %d", got, m.Len())
+	}
+	if v, ok := m.Get(1); !ok || len(v.tags) != 2 {
+		t.Errorf("Get(1) = (%+v, %v)", v, ok)
+	}
+
+	ids := NewMap[string, uint64]()
+	ids.Set("a", 1)
+	if !Equal(ids, map[string]uint64{"a": 1}) || Equal(ids, map[string]uint64{"a": 2}) {
+		t.Error("Equal() mismatch")
+	}
+}
+
+func TestMap_Atomic(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	if v, loaded := c.GetOrSet("a", 1); loaded || v != 1 {
+		t.Errorf("GetOrSet() new = (%d, %v), want (1, false)", v, loaded)
+	}
+	if v, loaded := c.GetOrSet("a", 2); !loaded || v != 1 {
+		t.Errorf("GetOrSet() existing = (%d, %v), want (1, true)", v, loaded)
+	}
+
+	if c.CompareAndSwap("a", 5, 6) || c.CompareAndSwap("missing", 0, 1) {
+		t.Error("CompareAndSwap() swapped mismatched value")
+	}
+	if !c.CompareAndSwap("a", 1, 2) {
+		t.Error("CompareAndSwap() didn't swap matching value")
+	}
+	if _, ok := c.Get("missing"); ok {
+		t.Error("CompareAndSwap() created missing key")
+	}
+
+	if c.DeleteIf("a", func(v int) bool { return v == 1 }) {
+		t.Error("DeleteIf() removed key for false predicate")
+	}
+	if !c.DeleteIf("a", func(v int) bool { return v == 2 }) || c.Len() != 0 {
+		t.Error("DeleteIf() didn't remove key")
+	}
+
+	// concurrent increments by CompareAndSwap retries
+	c.Set("n", 0)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				for {
+					v, _ := c.Get("n")
+					if c.CompareAndSwap("n", v, v+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := c.Get("n"); v != 5000 {
+		t.Errorf("Get() after concurrent CompareAndSwap = %d, want 5000", v)
+	}
+}
+
+func TestMap_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	snap := c.Snapshot()
+	// modifying the map while ranging over the copy doesn't deadlock
+	for k, v := range snap {
+		c.Set(k, v*10)
+		c.Set(k+k, v)
+	}
+	if len(snap) != 2 || snap["a"] != 1 {
+		t.Errorf("Snapshot() changed with the map: %v", snap)
+	}
+
+	keys := c.Keys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "aa", "b", "bb"}) {
+		t.Errorf("Keys() = %q", keys)
+	}
+	for _, k := range keys {
+		c.Delete(k)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after deleting Keys()", c.Len())
+	}
+
+	var empty Map[string, int]
+	if snap := empty.Snapshot(); snap == nil || len(snap) != 0 || len(empty.Keys()) != 0 {
+		t.Error("Snapshot() or Keys() of zero Map not empty")
+	}
+}
+
+func TestMap_TTL(t *testing.T) {
+	t.Parallel()
+
+	const ttl = 50 * time.Millisecond
+	c := New()
+	c.SetWithTTL("a", 1, ttl)
+	c.SetWithTTL("b", 2, ttl)
+	c.SetWithTTL("c", 3, ttl)
+	c.Set("c", 3) // removes TTL
+	c.Set("d", 4)
+	c.Add("b", 1) // keeps TTL
+
+	if _, loaded := c.GetOrSetWithTTL("a", 5, ttl); !loaded {
+		t.Error("GetOrSetWithTTL() set live key")
+	}
+	if v, ok := c.Get("b"); !ok || v != 3 {
+		t.Errorf("Get() before expiration = (%d, %v), want (3, true)", v, ok)
+	}
+	if c.Len() != 4 {
+		t.Errorf("Len() before expiration = %d, want 4", c.Len())
+	}
+
+	time.Sleep(2 * ttl)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() returned expired key")
+	}
+	if c.Len() != 2 || !c.Eq(map[string]int{"c": 3, "d": 4}) {
+		t.Errorf("Snapshot() after expiration = %v", c.Snapshot())
+	}
+	keys := c.Keys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"c", "d"}) {
+		t.Errorf("Keys() after expiration = %q", keys)
+	}
+	c.Iterate(func(k string, v int) {
+		if k == "a" || k == "b" {
+			t.Errorf("Iterate() visited expired key %q", k)
+		}
+	})
+
+	// expired key is set again without TTL
+	c.Add("b", 1)
+	if v, _ := c.Get("b"); v != 1 {
+		t.Errorf("Add() to expired key = %d, want 1", v)
+	}
+	if n := c.Expire(); n != 1 {
+		t.Errorf("Expire() = %d, want 1", n)
+	}
+	if c.expires == nil || len(c.expires) != 0 || len(c.m) != 3 {
+		t.Errorf("Expire() left %d entries and %d deadlines", len(c.m), len(c.expires))
+	}
+}
+
+func TestMap_StartJanitor(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewMap[int, string]()
+	m.StartJanitor(ctx, time.Millisecond)
+	m.SetWithTTL(1, "x", time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mu.RLock()
+		n := len(m.m)
+		m.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("janitor didn't remove expired entry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,144 @@
+package cmap
+
+import (
+	"context"
+	"time"
+)
+
+// SetWithTTL sets the key to value which expires after ttl. Expired
+// entries are invisible to all methods and are removed lazily when
+// written, by Expire or by StartJanitor. Set of the key removes the TTL,
+// Update and CompareAndSwap keep it. Non-positive ttl expires the entry
+// at once.
+// Thread-safe write operation.
+//
+// Example:
+//
+//	seen := cmap.NewMap[string, struct{}]()
+//	seen.StartJanitor(ctx, time.Minute)
+//	...
+//	// drop events delivered again within ten minutes
+//	if _, dup := seen.GetOrSetWithTTL(eventID, struct{}{}, 10*time.Minute); dup {
+//	    return
+//	}
+func (c *Map[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setWithTTL(key, value, ttl)
+}
+
+// GetOrSetWithTTL is GetOrSet setting the key with SetWithTTL,
+// e.g. to remember keys seen within a deduplication window.
+// Thread-safe write operation.
+func (c *Map[K, V]) GetOrSetWithTTL(key K, value V, ttl time.Duration) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if val, ok := c.lookup(key); ok {
+		return val, true
+	}
+	c.setWithTTL(key, value, ttl)
+	return value, false
+}
+
+// setWithTTL stores an expiring entry, must be called under the write lock
+func (c *Map[K, V]) setWithTTL(key K, value V, ttl time.Duration) {
+	if c.m == nil {
+		c.m = make(map[K]V)
+	}
+	if c.expires == nil {
+		c.expires = make(map[K]time.Time)
+	}
+	c.m[key] = value
+	c.expires[key] = time.Now().Add(ttl)
+}
+
+// Expire removes expired entries and returns their number.
+// Thread-safe write operation.
+func (c *Map[K, V]) Expire() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for k, deadline := range c.expires {
+		if !now.Before(deadline) {
+			delete(c.m, k)
+			delete(c.expires, k)
+			n++
+		}
+	}
+	return n
+}
+
+// StartJanitor calls Expire every interval in a new goroutine until ctx
+// is done, so memory of expired entries which are never written again
+// is released.
+func (c *Map[K, V]) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.Expire()
+			}
+		}
+	}()
+}
+
+// expired returns true if the entry of key has expired by now,
+// zero now is replaced with the current time. Must be called under a lock.
+func (c *Map[K, V]) expired(key K, now time.Time) bool {
+	deadline, ok := c.expires[key]
+	if !ok {
+		return false
+	}
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return !now.Before(deadline)
+}
+
+// lookup returns the value of a live entry and removes an expired one.
+// Must be called under the write lock.
+func (c *Map[K, V]) lookup(key K) (V, bool) {
+	val, ok := c.m[key]
+	if ok && c.expired(key, time.Time{}) {
+		delete(c.m, key)
+		delete(c.expires, key)
+		var zero V
+		return zero, false
+	}
+	return val, ok
+}
+
+// countExpired returns the number of expired entries not yet removed.
+// Must be called under a lock.
+func (c *Map[K, V]) countExpired() int {
+	if len(c.expires) == 0 {
+		return 0
+	}
+	now := time.Now()
+	n := 0
+	for _, deadline := range c.expires {
+		if !now.Before(deadline) {
+			n++
+		}
+	}
+	return n
+}
+
+// dropExpired removes entries expired in c from its copy m.
+// Must be called under a lock.
+func (c *Map[K, V]) dropExpired(m map[K]V) {
+	if len(c.expires) == 0 {
+		return
+	}
+	now := time.Now()
+	for k := range m {
+		if c.expired(k, now) {
+			delete(m, k)
+		}
+	}
+}
@@ -1,14 +1,70 @@
 package kv
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+	"unique"
 )
 
+// ValueSeparator separates alternatives in multi-value pairs ("key=a|b")
+const ValueSeparator = '|'
+
+// Op is a comparison operator between key and value in a pair
+type Op uint8
+
+const (
+	// OpEq requires value to be equal ("key=value")
+	OpEq Op = iota
+	// OpNe requires value to be not equal ("key!=value")
+	OpNe
+	// OpLt requires numeric value to be less ("key<value")
+	OpLt
+	// OpLe requires numeric value to be less or equal ("key<=value")
+	OpLe
+	// OpGt requires numeric value to be greater ("key>value")
+	OpGt
+	// OpGe requires numeric value to be greater or equal ("key>=value")
+	OpGe
+)
+
+// String returns textual representation of the operator
+func (o Op) String() string {
+	switch o {
+	case OpNe:
+		return "!="
+	case OpLt:
+		return "<"
+	case OpLe:
+		return "<="
+	case OpGt:
+		return ">"
+	case OpGe:
+		return ">="
+	default:
+		return "="
+	}
+}
+
+// IsNumeric returns true for numeric comparison operators
+func (o Op) IsNumeric() bool {
+	return o >= OpLt && o <= OpGe
+}
+
 // KV represents a key-value pair with private fields
 type KV struct {
-	key   string
-	value string
+	key    string
+	value  string
+	values []string // alternatives for multi-value pairs, nil for plain pairs
+	op     Op
+	num    float64 // parsed value for numeric operators
 }
 
 // Key returns the key of the key-value pair
@@ -21,6 +77,51 @@ func (kv KV) Value() string {
 	return kv.value
 }
 
+// Op returns the comparison operator of the key-value pair
+func (kv KV) Op() Op {
+	return kv.op
+}
+
+// String returns the pair as "key<op>value"
+func (kv KV) String() string {
+	return kv.key + kv.op.String() + kv.value
+}
+
+// Values returns all alternative values of the pair.
+// For plain pairs it is a single element slice with Value().
+func (kv KV) Values() []string {
+	if kv.values != nil {
+		return kv.values
+	}
+	return []string{kv.value}
+}
+
+// IsSet returns true if the pair was created with multi-value syntax ("key=a|b")
+func (kv KV) IsSet() bool {
+	return kv.values != nil
+}
+
+// EachValue calls fn for every alternative value without allocations
+func (kv KV) EachValue(fn func(value string)) {
+	kv.eachValue(func(v string) bool {
+		fn(v)
+		return true
+	})
+}
+
+// eachValue calls fn for every alternative value until fn returns false
+func (kv KV) eachValue(fn func(value string) bool) bool {
+	if kv.values == nil {
+		return fn(kv.value)
+	}
+	for _, v := range kv.values {
+		if !fn(v) {
+			return false
+		}
+	}
+	return true
+}
+
 // Map stores collection of key-value pairs
 type Map struct {
 	data []KV
@@ -32,26 +133,59 @@ type Map struct {
 //  2. "key", "value" (two separate strings)
 //
 // Handles escaped '=' characters (like "\=" in keys/values)
+//
+// In "key=value" format value may be quoted: "key=\"hello world\"" or
+// "path='a=b'". Quoted value is taken as a single value without
+// alternatives. Backslash escapes any character inside double quotes,
+// single quotes keep everything literally. Use "\"" and "\'" for literal
+// quote at the start of unquoted value.
+//
+// In "key=value" format value may list several alternatives separated
+// by '|' ("priority=high|critical"). Such pair matches any of them.
+// Use "\|" for literal '|' in values. Values with empty alternatives
+// ("sep=|", "cmd=a||b") and values of "key", "value" form are literal.
+//
+// Operator "!=" instead of "=" negates the pair ("level!=debug"):
+// it matches any value except listed ones. Use "\!" for literal '!'
+// at the end of key.
+//
+// Numeric operators "<", "<=", ">", ">=" compare values as numbers
+// ("severity>=3"). Value must be a single number, it is parsed once here.
+// Operators "<" and ">" without '=' are recognized only before a number,
+// '<' and '>' elsewhere in a key are literal ("a<b=1", "x>y", "z").
+// Use "\<" and "\>" for literal '<' and '>' at the end of key.
+// A key ending with '<' or '>' and a number can't be used in "key", "value"
+// format: "x>1", "v" is a comparison followed by a key without value.
+// Write such pairs as "x\>1=v".
+//
 // Returns error if input format is invalid
 func Parse(d ...string) (Map, error) {
-	var ret Map
+	data, err := parseTo(make([]KV, 0, len(d)), d)
+	if err != nil {
+		return Map{}, err
+	}
+	ret := Map{data: data}
+	ret.sortKeys()
+	return ret, nil
+}
+
+// parseTo appends pairs parsed from d to dst, see Parse for the format
+func parseTo(dst []KV, d []string) ([]KV, error) {
 	if len(d) == 1 && d[0] == "" {
-		return ret, nil
+		return dst, nil
 	}
 	for i := 0; i < len(d); {
-		// Find first unescaped '=' position
-		p := findUnescapedEquals(d[i])
+		// Find first unescaped operator position
+		p, op, n := findOperator(d[i])
 		if p < 0 {
 			// Format: "key", "value" (separate strings)
 			if i+1 >= len(d) {
-				return Map{}, &ParseError{
-					Msg:  "missing value for key",
-					Key:  d[i],
-					Pos:  i,
-					Args: d,
-				}
+				return dst, newParseError(ErrMissingValue, d[i], i, d)
 			}
-			ret.data = append(ret.data, KV{
+			if d[i] == "" {
+				return dst, newParseError(ErrEmptyKey, d[i], i, d)
+			}
+			dst = append(dst, KV{
 				key:   d[i],
 				value: d[i+1],
 			})
@@ -59,27 +193,256 @@ func Parse(d ...string) (Map, error) {
 			continue
 		}
 
-		ret.data = append(ret.data, KV{
-			key:   unescape(d[i][:p]),
-			value: unescape(d[i][p+1:]),
-		})
+		if p == 0 {
+			return dst, newParseError(ErrEmptyKey, d[i], i, d)
+		}
+
+		var kv KV
+		if raw := d[i][p+n:]; isQuoted(raw) {
+			value, ok := unquote(raw)
+			if !ok {
+				return dst, newParseError(ErrBadQuote, d[i], i, d)
+			}
+			kv = KV{key: unescape(d[i][:p]), value: value}
+		} else {
+			if hasTrailingEscape(d[i]) {
+				return dst, newParseError(ErrBadEscape, d[i], i, d)
+			}
+			kv = parseValue(unescape(d[i][:p]), raw)
+		}
+		kv.op = op
+		if op.IsNumeric() {
+			num, err := strconv.ParseFloat(kv.value, 64)
+			if err != nil || kv.IsSet() {
+				return dst, newParseError(ErrInvalidNumber, kv.key, i, d)
+			}
+			kv.num = num
+		}
+		dst = append(dst, kv)
 		i += 1
 	}
+	return dst, nil
+}
+
+// Builder constructs Maps reusing its buffer, so building many maps
+// costs a single allocation each. The zero value is ready to use.
+//
+// Example:
+//
+//	var b kv.Builder
+//	for _, id := range ids {
+//	    b.Add("type", "user")
+//	    b.Add("id", id)
+//	    m := b.Map() // b is reset and ready for the next map
+//	}
+type Builder struct {
+	data []KV
+}
+
+// Add appends a plain pair, key and value are taken literally
+func (b *Builder) Add(key, value string) {
+	b.data = append(b.data, KV{key: key, value: value})
+}
+
+// Parse appends pairs in the format of Parse.
+// On error the builder is left unchanged.
+func (b *Builder) Parse(d ...string) error {
+	data, err := parseTo(b.data, d)
+	if err != nil {
+		clear(data[len(b.data):])
+		return err
+	}
+	b.data = data
+	return nil
+}
+
+// Len returns the number of pairs added since the last reset
+func (b *Builder) Len() int {
+	return len(b.data)
+}
+
+// Reset discards added pairs keeping the buffer
+func (b *Builder) Reset() {
+	clear(b.data)
+	b.data = b.data[:0]
+}
+
+// Sorted sorts added pairs by key and removes duplicate pairs with the
+// same key and operator, the last added pair wins. Pairs of a key with
+// different operators ("x>1", "x<5") are kept in the order of addition
+// like Parse does.
+func (b *Builder) Sorted() {
+	if uniqueSorted(b.data) {
+		return
+	}
+	slices.SortStableFunc(b.data, compareKeys)
+	out := b.data[:0]
+	group := 0 // Start of pairs with the key of kv in out
+	for _, kv := range b.data {
+		if len(out) > 0 && out[len(out)-1].key != kv.key {
+			group = len(out)
+		}
+		if i := slices.IndexFunc(out[group:], func(o KV) bool { return o.op == kv.op }); i >= 0 {
+			out[group+i] = kv
+			continue
+		}
+		out = append(out, kv)
+	}
+	clear(b.data[len(out):])
+	b.data = out
+}
+
+// uniqueSorted reports whether data is sorted by key without duplicate
+// pairs of the same key and operator
+func uniqueSorted(data []KV) bool {
+	for i := 1; i < len(data); i++ {
+		if data[i-1].key > data[i].key {
+			return false
+		}
+		if data[i-1].key == data[i].key {
+			// rare, check the whole group of the key
+			for j := i - 1; j >= 0 && data[j].key == data[i].key; j-- {
+				if data[j].op == data[i].op {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
 
+// Build returns a Map of added pairs, see Sorted.
+// The builder keeps the pairs, so more can be added for the next map.
+func (b *Builder) Build() Map {
+	b.Sorted()
+	return Map{data: slices.Clone(b.data)}
+}
+
+// Map returns a Map of added pairs and resets the builder, see Sorted
+func (b *Builder) Map() Map {
+	ret := b.Build()
+	b.Reset()
+	return ret
+}
+
+// FromValues creates Map from keys with lists of values (like url.Values).
+// Values are taken literally without escaping or operators.
+// Key with several values becomes a multi-value pair, empty lists are skipped.
+func FromValues(vals map[string][]string) Map {
+	ret := Map{
+		data: make([]KV, 0, len(vals)),
+	}
+	for k, vs := range vals {
+		switch len(vs) {
+		case 0:
+			continue
+		case 1:
+			ret.data = append(ret.data, KV{key: k, value: vs[0]})
+		default:
+			values := append([]string(nil), vs...)
+			ret.data = append(ret.data, KV{
+				key:    k,
+				value:  strings.Join(values, string(ValueSeparator)),
+				values: values,
+			})
+		}
+	}
 	ret.sortKeys()
-	return ret, nil
+	return ret
+}
+
+// parseValue creates pair from key and raw (still escaped) value.
+// Splits value by unescaped ValueSeparator into alternatives.
+func parseValue(key, raw string) KV {
+	p := findUnescaped(raw, ValueSeparator)
+	if p < 0 {
+		return KV{key: key, value: unescape(raw)}
+	}
+
+	var values []string
+	for p >= 0 {
+		values = append(values, unescape(raw[:p]))
+		raw = raw[p+1:]
+		p = findUnescaped(raw, ValueSeparator)
+	}
+	values = append(values, unescape(raw))
+
+	// Empty alternatives are not a list ("|", "a||b"), keep such values literal
+	value := strings.Join(values, string(ValueSeparator))
+	if slices.Contains(values, "") {
+		return KV{key: key, value: value}
+	}
+
+	return KV{
+		key:    key,
+		value:  value,
+		values: values,
+	}
 }
 
+// Parse error kinds, use errors.Is to check the kind of ParseError
+var (
+	ErrMissingValue    = errors.New("missing value for key")
+	ErrMissingOperator = errors.New("missing operator in pair")
+	ErrInvalidNumber   = errors.New("invalid numeric value for key")
+	ErrBadEscape       = errors.New("bad escape sequence in pair")
+	ErrEmptyKey        = errors.New("empty key in pair")
+	ErrBadQuote        = errors.New("unterminated or misplaced quote in pair")
+)
+
 // ParseError represents parsing error details
 type ParseError struct {
-	Msg  string
-	Key  string
-	Pos  int
-	Args []string
+	Err  error    // Error kind, one of Err* values
+	Msg  string   // Human readable description
+	Key  string   // Offending key or argument, escaped and truncated
+	Pos  int      // Index of offending argument in Args
+	Args []string // Raw arguments passed to parser
+}
+
+// newParseError creates ParseError of the given kind
+func newParseError(kind error, key string, pos int, args []string) *ParseError {
+	return &ParseError{
+		Err:  kind,
+		Msg:  kind.Error(),
+		Key:  errorKey(key),
+		Pos:  pos,
+		Args: args,
+	}
+}
+
+// maxErrorKeyLen limits the length of ParseError.Key in bytes
+const maxErrorKeyLen = 64
+
+// errorKey makes offending input safe for logs: non-printable characters
+// and invalid UTF-8 are escaped, long input is truncated
+func errorKey(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if b.Len() >= maxErrorKeyLen {
+			b.WriteString("...")
+			break
+		}
+		switch {
+		case r == utf8.RuneError && !strings.HasPrefix(s[i:], string(utf8.RuneError)):
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case !unicode.IsPrint(r):
+			q := strconv.QuoteRune(r)
+			b.WriteString(q[1 : len(q)-1])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
 }
 
+// Error implements the error interface for ParseError
 func (e *ParseError) Error() string {
-	return e.Msg + " '" + e.Key + "' at position " + string(rune(e.Pos))
+	return e.Msg + " '" + e.Key + "' at position " + strconv.Itoa(e.Pos)
+}
+
+// Unwrap returns the error kind for errors.Is
+func (e *ParseError) Unwrap() error {
+	return e.Err
 }
 
 // Get returns value by key (empty string if not found)
@@ -92,6 +455,26 @@ func (m Map) Get(key string) string {
 	return ""
 }
 
+// Lookup returns the pair by key
+func (m Map) Lookup(key string) (KV, bool) {
+	for _, kv := range m.data {
+		if kv.key == key {
+			return kv, true
+		}
+	}
+	return KV{}, false
+}
+
+// Has returns true if the key exists
+func (m Map) Has(key string) bool {
+	for _, kv := range m.data {
+		if kv.key == key {
+			return true
+		}
+	}
+	return false
+}
+
 // Keys returns all keys in sorted order
 func (m Map) Keys() []string {
 	keys := make([]string, len(m.data))
@@ -113,6 +496,35 @@ func (m Map) Each(fn func(key, value string)) {
 	}
 }
 
+// EachPair iterates over all pairs in sorted order
+func (m Map) EachPair(fn func(kv KV)) {
+	for _, kv := range m.data {
+		fn(kv)
+	}
+}
+
+// All returns an iterator over keys and values in sorted order
+func (m Map) All() iter.Seq2[string, string] {
+	return func(yield func(key, value string) bool) {
+		for _, kv := range m.data {
+			if !yield(kv.key, kv.value) {
+				return
+			}
+		}
+	}
+}
+
+// Pairs returns an iterator over all pairs in sorted order
+func (m Map) Pairs() iter.Seq[KV] {
+	return func(yield func(kv KV) bool) {
+		for _, kv := range m.data {
+			if !yield(kv) {
+				return
+			}
+		}
+	}
+}
+
 // ToMap converts to standard map[string]string
 func (m Map) ToMap() map[string]string {
 	result := make(map[string]string, len(m.data))
@@ -125,6 +537,12 @@ func (m Map) ToMap() map[string]string {
 // Match returns true if for all keys in current map:
 // - the key exists in other map
 // - values are equal OR one of the values is "*"
+// - for multi-value pairs: any of alternatives satisfies the rule above
+// - for negated pairs ("key!=value") in current map: value of other map
+// is "*" or differs from all listed values
+// - for numeric pairs ("key>=value") in current map: value of other map
+// is "*" or a number satisfying the comparison
+// Operators of the other map are ignored, its values are compared literally.
 // Uses the fact that both maps are sorted for O(n+m) comparison
 func (m Map) Match(other Map) bool {
 	i, j := 0, 0
@@ -142,8 +560,7 @@ func (m Map) Match(other Map) bool {
 			j++
 		default:
 			// Keys match - compare values
-			aVal, bVal := m.data[i].value, other.data[j].value
-			if aVal != "*" && bVal != "*" && aVal != bVal {
+			if !matchValues(m.data[i], other.data[j]) {
 				return false
 			}
 			i++
@@ -155,6 +572,254 @@ func (m Map) Match(other Map) bool {
 	return i == lenA
 }
 
+// Equal returns true if both maps contain exactly the same key-value pairs
+func (m Map) Equal(other Map) bool {
+	if len(m.data) != len(other.data) {
+		return false
+	}
+	for i := range m.data {
+		if !samePair(m.data[i], other.data[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains returns true if every pair of other map is present in current
+// map with the same key, value and operator. Unlike Match, "*" and
+// operators have no special meaning and are compared literally.
+// Uses the fact that both maps are sorted for O(n+m) comparison
+func (m Map) Contains(other Map) bool {
+	i := 0
+	for _, b := range other.data {
+		for i < len(m.data) && m.data[i].key < b.key {
+			i++
+		}
+		if i == len(m.data) || !samePair(m.data[i], b) {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// Subset returns true if every pair of current map is present in other map,
+// see Contains
+func (m Map) Subset(other Map) bool {
+	return other.Contains(m)
+}
+
+// samePair reports whether pairs are literally equal
+func samePair(a, b KV) bool {
+	return a.key == b.key && a.value == b.value && a.op == b.op && a.IsSet() == b.IsSet()
+}
+
+// Compare defines a total order over maps.
+// Pairs are compared one by one in sorted key order (key first, then value),
+// a map that is a prefix of another map is ordered first.
+// Returns -1 if m < other, 0 if m == other and +1 if m > other.
+func (m Map) Compare(other Map) int {
+	n := min(len(m.data), len(other.data))
+	for i := 0; i < n; i++ {
+		if c := strings.Compare(m.data[i].key, other.data[i].key); c != 0 {
+			return c
+		}
+		if c := strings.Compare(m.data[i].value, other.data[i].value); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(m.data[i].op, other.data[i].op); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(m.data), len(other.data))
+}
+
+// FNV-1a 64-bit parameters used by Hash
+const (
+	fnvOffset64 uint64 = 14695981039346656037
+	fnvPrime64  uint64 = 1099511628211
+)
+
+// Hash returns a stable 64-bit FNV-1a hash over sorted key-value pairs.
+// Equal maps always produce equal hashes, the value does not depend on
+// the process or Go version, so it is safe to persist.
+// Does not allocate.
+func (m Map) Hash() uint64 {
+	h := fnvOffset64
+	for _, kv := range m.data {
+		h = fnvString(h, kv.key)
+		h = fnvByte(h, 0)
+		h = fnvString(h, kv.value)
+		h = fnvByte(h, 0)
+		if kv.IsSet() {
+			h = fnvByte(h, ValueSeparator)
+		}
+		if kv.op != OpEq {
+			h = fnvByte(h, byte(kv.op))
+		}
+	}
+	return h
+}
+
+// fnvString mixes string bytes into FNV-1a hash
+func fnvString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h = fnvByte(h, s[i])
+	}
+	return h
+}
+
+// fnvByte mixes single byte into FNV-1a hash
+func fnvByte(h uint64, b byte) uint64 {
+	h ^= uint64(b)
+	h *= fnvPrime64
+	return h
+}
+
+// MatchValue reports whether the value of other pair satisfies this pair
+// using the same rules as Map.Match. Keys are not compared.
+func (kv KV) MatchValue(other KV) bool {
+	return matchValues(kv, other)
+}
+
+// matchValues checks if any alternative of a matches any alternative of b
+func matchValues(a, b KV) bool {
+	if a.op == OpNe {
+		return matchNotValues(a, b)
+	}
+	if a.op.IsNumeric() {
+		return matchNumeric(a, b)
+	}
+	if a.values == nil && b.values == nil {
+		return matchValue(a.value, b.value)
+	}
+	found := false
+	a.eachValue(func(av string) bool {
+		b.eachValue(func(bv string) bool {
+			found = matchValue(av, bv)
+			return !found
+		})
+		return !found
+	})
+	return found
+}
+
+// matchNotValues checks if any alternative of b differs from all alternatives of a
+func matchNotValues(a, b KV) bool {
+	found := false
+	b.eachValue(func(bv string) bool {
+		if bv == "*" {
+			found = true
+			return false
+		}
+		excluded := false
+		a.eachValue(func(av string) bool {
+			excluded = av == bv
+			return !excluded
+		})
+		found = !excluded
+		return !found
+	})
+	return found
+}
+
+// matchNumeric checks if any alternative of b satisfies numeric condition of a
+func matchNumeric(a, b KV) bool {
+	found := false
+	b.eachValue(func(bv string) bool {
+		if bv == "*" {
+			found = true
+			return false
+		}
+		n, err := strconv.ParseFloat(bv, 64)
+		if err != nil {
+			return true
+		}
+		switch a.op {
+		case OpLt:
+			found = n < a.num
+		case OpLe:
+			found = n <= a.num
+		case OpGt:
+			found = n > a.num
+		case OpGe:
+			found = n >= a.num
+		}
+		return !found
+	})
+	return found
+}
+
+// matchValue compares single values with respect to wildcard
+func matchValue(a, b string) bool {
+	return a == "*" || b == "*" || a == b
+}
+
+// Set creates new Map with the key set to the value.
+// Value is taken literally without escaping or operators.
+// The original map is not modified.
+func (m Map) Set(key, value string) Map {
+	idx := sort.Search(len(m.data), func(i int) bool {
+		return m.data[i].key >= key
+	})
+
+	result := Map{
+		data: make([]KV, 0, len(m.data)+1),
+	}
+	result.data = append(result.data, m.data[:idx]...)
+	result.data = append(result.data, KV{key: key, value: value})
+	if idx < len(m.data) && m.data[idx].key == key {
+		idx++ // Replace existing pair
+	}
+	result.data = append(result.data, m.data[idx:]...)
+	return result
+}
+
+// Delete creates new Map without the key.
+// Returns the original map if the key doesn't exist.
+func (m Map) Delete(key string) Map {
+	idx := sort.Search(len(m.data), func(i int) bool {
+		return m.data[i].key >= key
+	})
+	if idx == len(m.data) || m.data[idx].key != key {
+		return m
+	}
+
+	result := Map{
+		data: make([]KV, 0, len(m.data)-1),
+	}
+	result.data = append(result.data, m.data[:idx]...)
+	result.data = append(result.data, m.data[idx+1:]...)
+	return result
+}
+
+// Intern creates new Map with keys and values replaced by canonical
+// interned copies (see package unique), so equal strings of many maps
+// share the same memory.
+func (m Map) Intern() Map {
+	result := Map{
+		data: make([]KV, len(m.data)),
+	}
+	for i, kv := range m.data {
+		kv.key = intern(kv.key)
+		kv.value = intern(kv.value)
+		if kv.values != nil {
+			values := make([]string, len(kv.values))
+			for j, v := range kv.values {
+				values[j] = intern(v)
+			}
+			kv.values = values
+		}
+		result.data[i] = kv
+	}
+	return result
+}
+
+// intern returns canonical copy of the string
+func intern(s string) string {
+	return unique.Make(s).Value()
+}
+
 // Merge creates new Map with keys from both maps
 // Keys from the argument map override keys from the original map
 func (m Map) Merge(other Map) Map {
@@ -186,29 +851,251 @@ func (m Map) Merge(other Map) Map {
 	return result
 }
 
+// MarshalText implements encoding.TextMarshaler.
+//
+// Text form is a list of pairs separated by single spaces, each pair is
+// written as key, operator and value: "priority=high|critical type=alert".
+// Backslash escapes characters with special meaning in keys and values:
+// space, '\\', '=', '!', '<', '>' and '|' (except separators of multi-value pairs).
+// The result can be parsed back with UnmarshalText.
+func (m Map) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// Accepts text produced by MarshalText, extra spaces between pairs are ignored.
+// Every pair must contain an operator.
+func (m *Map) UnmarshalText(text []byte) error {
+	args, err := splitText(string(text))
+	if err != nil {
+		return err
+	}
+	parsed, err := Parse(args...)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ParseTextStrict parses text form of a map like UnmarshalText and checks
+// the result against limits like ParseStrict.
+func ParseTextStrict(l Limits, text string) (Map, error) {
+	args, err := splitText(text)
+	if err != nil {
+		return Map{}, err
+	}
+	return ParseStrict(l, args...)
+}
+
+// splitText splits text form of a map into pairs
+func splitText(s string) ([]string, error) {
+	var args []string
+	for len(s) > 0 {
+		p := findSpace(s)
+		if p < 0 {
+			p = len(s)
+		}
+		if p > 0 {
+			args = append(args, s[:p])
+		}
+		s = s[min(p+1, len(s)):]
+	}
+
+	for i, arg := range args {
+		if p, _, _ := findOperator(arg); p < 0 {
+			return nil, newParseError(ErrMissingOperator, arg, i, args)
+		}
+	}
+	return args, nil
+}
+
+// String returns text form of the map, see MarshalText
+func (m Map) String() string {
+	var buf strings.Builder
+	for i, kv := range m.data {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		escapeTo(&buf, kv.key)
+		buf.WriteString(kv.op.String())
+		if kv.values == nil {
+			escapeTo(&buf, kv.value)
+			continue
+		}
+		for j, v := range kv.values {
+			if j > 0 {
+				buf.WriteByte(ValueSeparator)
+			}
+			escapeTo(&buf, v)
+		}
+	}
+	return buf.String()
+}
+
+// escapeTo writes s with special characters escaped by backslash
+func escapeTo(buf *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\\', '=', '!', '<', '>', '"', '\'', ValueSeparator:
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+}
+
 // sortKeys sorts the key-value pairs by key
 func (m *Map) sortKeys() {
+	// Topics are usually written in sorted order already
+	if slices.IsSortedFunc(m.data, compareKeys) {
+		return
+	}
 	sort.Slice(m.data, func(i, j int) bool {
 		return m.data[i].key < m.data[j].key
 	})
 }
 
-// findUnescapedEquals locates the first '=' not preceded by backslash
-func findUnescapedEquals(s string) int {
+// compareKeys orders pairs by key
+func compareKeys(a, b KV) int {
+	return strings.Compare(a.key, b.key)
+}
+
+// hasTrailingEscape returns true if s ends with backslash escaping nothing
+func hasTrailingEscape(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			if i+1 == len(s) {
+				return true
+			}
+			i++ // Skip escaped character
+		}
+	}
+	return false
+}
+
+// findOperator locates the operator of a pair, escaped characters are
+// skipped. Operators ending with '=' are taken at the first '=', so
+// "a<b=1" is a plain pair with key "a<b". Without '=' the last '<' or '>'
+// is an operator only if a number follows it ("size<1024"), so it stays
+// a part of the key in "a>b", "c" form.
+// Returns operator position, operator and its length or -1 if not found.
+func findOperator(s string) (int, Op, int) {
+	prev := -1 // Position of the previous unescaped character
+	cmp := -1  // Position of the last unescaped '<' or '>'
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // Skip escaped character
+			prev = -1
+			continue
+		case '=':
+			if prev >= 0 {
+				switch s[prev] {
+				case '!':
+					return prev, OpNe, 2
+				case '<':
+					return prev, OpLe, 2
+				case '>':
+					return prev, OpGe, 2
+				}
+			}
+			return i, OpEq, 1
+		case '<', '>':
+			cmp = i
+		}
+		prev = i
+	}
+	if cmp >= 0 {
+		if _, err := strconv.ParseFloat(s[cmp+1:], 64); err == nil {
+			if s[cmp] == '<' {
+				return cmp, OpLt, 1
+			}
+			return cmp, OpGt, 1
+		}
+	}
+	return -1, OpEq, 0
+}
+
+// findUnescaped locates the first c not preceded by backslash
+func findUnescaped(s string, c byte) int {
 	for i := 0; i < len(s); i++ {
 		if s[i] == '\\' {
 			i++ // Skip escaped character
 			continue
 		}
-		if s[i] == '=' {
+		if s[i] == c {
 			return i
 		}
 	}
 	return -1
 }
 
+// findSpace locates the first space separating pairs: not preceded
+// by backslash and not inside a quoted value
+func findSpace(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // Skip escaped character
+		case ' ':
+			return i
+		case '"', '\'':
+			if i == 0 || !isOperatorChar(s[i-1]) {
+				continue
+			}
+			end := closingQuote(s[i:])
+			if end < 0 {
+				return -1
+			}
+			i += end
+		}
+	}
+	return -1
+}
+
+// isOperatorChar reports whether c may end an operator
+func isOperatorChar(c byte) bool {
+	return c == '=' || c == '<' || c == '>'
+}
+
+// isQuoted reports whether raw value starts with a quote
+func isQuoted(raw string) bool {
+	return len(raw) > 0 && (raw[0] == '"' || raw[0] == '\'')
+}
+
+// closingQuote returns position of the quote closing the one at s[0],
+// -1 if it is not terminated
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && q == '"':
+			i++ // Skip escaped character
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote returns the content of quoted raw value. Returns false if the
+// quote is not terminated or is followed by other characters.
+func unquote(raw string) (string, bool) {
+	end := closingQuote(raw)
+	if end != len(raw)-1 {
+		return "", false
+	}
+	if raw[0] == '\'' {
+		return raw[1:end], true
+	}
+	return unescape(raw[1:end]), true
+}
+
 // unescape removes backslash from escaped characters
 func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
 	var buf strings.Builder
 	for i := 0; i < len(s); i++ {
 		if s[i] == '\\' && i+1 < len(s) {
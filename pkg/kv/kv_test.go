@@ -1,9 +1,12 @@
 package kv
 
 import (
+	"errors"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"unsafe"
 )
 
 func TestParse(t *testing.T) {
@@ -77,6 +80,239 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseMultiValue(t *testing.T) {
+	t.Run("alternatives", func(t *testing.T) {
+		m, err := Parse("priority=high|critical")
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		kv := m.data[0]
+		if !kv.IsSet() {
+			t.Fatal("Expected multi-value pair")
+		}
+		if !reflect.DeepEqual(kv.Values(), []string{"high", "critical"}) {
+			t.Errorf("Values() = %v, want [high critical]", kv.Values())
+		}
+		if kv.Value() != "high|critical" {
+			t.Errorf("Value() = %q, want %q", kv.Value(), "high|critical")
+		}
+	})
+
+	t.Run("escaped separator", func(t *testing.T) {
+		m, err := Parse(`a=x\|y`)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		kv := m.data[0]
+		if kv.IsSet() {
+			t.Error("Escaped separator must not create multi-value pair")
+		}
+		if kv.Value() != "x|y" {
+			t.Errorf("Value() = %q, want %q", kv.Value(), "x|y")
+		}
+		if m.Equal(mustParse(t, "a=x|y")) {
+			t.Error("Literal value must not be equal to multi-value pair")
+		}
+	})
+
+	t.Run("literal values", func(t *testing.T) {
+		// Inputs valid before multi-value syntax keep their meaning
+		tests := []struct {
+			input []string
+			value string
+		}{
+			{[]string{"a=|"}, "|"},
+			{[]string{"a=||"}, "||"},
+			{[]string{"a=x|"}, "x|"},
+			{[]string{"a=|x"}, "|x"},
+			{[]string{"a=x||y"}, "x||y"},
+			{[]string{"a", "x|y"}, "x|y"},
+			{[]string{`a="x|y"`}, "x|y"},
+		}
+		for _, tt := range tests {
+			m, err := Parse(tt.input...)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			kv := m.data[0]
+			if kv.IsSet() || kv.Value() != tt.value {
+				t.Errorf("Parse(%q) = %q, want literal %q", tt.input, m.String(), tt.value)
+			}
+			literal, _ := Parse("a", tt.value)
+			if !m.Match(literal) || m.Match(mustParse(t, "a=x")) {
+				t.Errorf("Parse(%q) must match only the literal value", tt.input)
+			}
+
+			var back Map
+			if err := back.UnmarshalText([]byte(m.String())); err != nil || !back.Equal(m) {
+				t.Errorf("round trip of %q = %q, %v", m.String(), back.String(), err)
+			}
+		}
+	})
+
+	t.Run("plain values", func(t *testing.T) {
+		m := mustParse(t, "a=1")
+		if m.data[0].IsSet() {
+			t.Error("Expected plain pair")
+		}
+		if !reflect.DeepEqual(m.data[0].Values(), []string{"1"}) {
+			t.Errorf("Values() = %v, want [1]", m.data[0].Values())
+		}
+	})
+}
+
+func TestParseOperators(t *testing.T) {
+	tests := []struct {
+		input string
+		key   string
+		op    Op
+		value string
+	}{
+		{"a=1", "a", OpEq, "1"},
+		{"a!=1", "a", OpNe, "1"},
+		{`a\!=1`, "a!", OpEq, "1"},
+		{"a!b=1", "a!b", OpEq, "1"},
+		{"a!=1|2", "a", OpNe, "1|2"},
+		{"a<1", "a", OpLt, "1"},
+		{"a<=1.5", "a", OpLe, "1.5"},
+		{"a>-1", "a", OpGt, "-1"},
+		{"a>=1e3", "a", OpGe, "1e3"},
+		{`a\<b=1`, "a<b", OpEq, "1"},
+		{`a\<=1`, "a<", OpEq, "1"},
+		{"a>b>3", "a>b", OpGt, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			m, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			kv := m.data[0]
+			if kv.Key() != tt.key || kv.Op() != tt.op || kv.Value() != tt.value {
+				t.Errorf("Parse() = %q %q %q, want %q %q %q",
+					kv.Key(), kv.Op(), kv.Value(), tt.key, tt.op, tt.value)
+			}
+			if kv.String() != tt.key+tt.op.String()+tt.value {
+				t.Errorf("String() = %q", kv.String())
+			}
+		})
+	}
+}
+
+func TestParseQuoted(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		key   string
+		value string
+	}{
+		{"double quotes", `label="hello world"`, "label", "hello world"},
+		{"single quotes", `path='a=b'`, "path", "a=b"},
+		{"separator is literal", `a="x|y"`, "a", "x|y"},
+		{"escapes in double quotes", `a="say \"hi\" \\o/"`, "a", `say "hi" \o/`},
+		{"single quotes are raw", `a='C:\dir\'`, "a", `C:\dir\`},
+		{"empty", `a=""`, "a", ""},
+		{"escaped quote", `a=\"x`, "a", `"x`},
+		{"quote inside value", `a=x"y`, "a", `x"y`},
+		{"operator", `a!="x y"`, "a", "x y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			pair, ok := m.Lookup(tt.key)
+			if !ok || pair.Value() != tt.value || pair.IsSet() {
+				t.Errorf("Parse() = %q, want %s=%q", m.String(), tt.key, tt.value)
+			}
+
+			// canonical format round trip
+			var back Map
+			if err := back.UnmarshalText([]byte(m.String())); err != nil || !back.Equal(m) {
+				t.Errorf("round trip of %q = %q, %v", m.String(), back.String(), err)
+			}
+		})
+	}
+
+	for _, input := range []string{`a="x`, `a='x`, `a="x"y`, `a='x'y'`} {
+		_, err := Parse(input)
+		if !errors.Is(err, ErrBadQuote) {
+			t.Errorf("Parse(%q) error = %v, want ErrBadQuote", input, err)
+		}
+	}
+}
+
+func TestParseNumericErrors(t *testing.T) {
+	for _, input := range []string{"a>=x", "a<=1|2", "a>", "a<1|2"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+			if err == nil {
+				t.Error("Expected error for invalid numeric value")
+			}
+		})
+	}
+}
+
+func TestParseLiteralComparisons(t *testing.T) {
+	// Inputs valid before numeric operators keep their meaning
+	tests := []struct {
+		input []string
+		key   string
+		value string
+	}{
+		{[]string{"a<b=1"}, "a<b", "1"},
+		{[]string{"x>y=z"}, "x>y", "z"},
+		{[]string{"html=<b>"}, "html", "<b>"},
+		{[]string{"a<b", "1"}, "a<b", "1"},
+		{[]string{"a>", "x"}, "a>", "x"},
+		{[]string{"a<1|2", "x"}, "a<1|2", "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.input, ","), func(t *testing.T) {
+			m, err := Parse(tt.input...)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			kv := m.data[0]
+			if m.Len() != 1 || kv.Key() != tt.key || kv.Op() != OpEq || kv.Value() != tt.value {
+				t.Errorf("Parse() = %q, want %s=%s", m.String(), tt.key, tt.value)
+			}
+
+			var back Map
+			if err := back.UnmarshalText([]byte(m.String())); err != nil || !back.Equal(m) {
+				t.Errorf("round trip of %q = %q, %v", m.String(), back.String(), err)
+			}
+		})
+	}
+}
+
+func TestParseComparisonKeys(t *testing.T) {
+	// "key", "value" form with a key like a comparison is a comparison
+	if _, err := Parse("x>1", "v"); !errors.Is(err, ErrMissingValue) {
+		t.Errorf("Parse(x>1, v) error = %v, want ErrMissingValue", err)
+	}
+	m, err := Parse("x>1", "y<2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.String(); got != "x>1 y<2" {
+		t.Errorf("Parse(x>1, y<2) = %q", got)
+	}
+
+	// escaped form keeps the key
+	m, err = Parse(`x\>1=v`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv := m.data[0]; kv.Key() != "x>1" || kv.Op() != OpEq || kv.Value() != "v" {
+		t.Errorf("Parse(x\\>1=v) = %q", m.String())
+	}
+}
+
 func compareMaps(a, b Map) bool {
 	if a.Len() != b.Len() {
 		return false
@@ -172,6 +408,143 @@ func TestEach(t *testing.T) {
 	}
 }
 
+func TestAllPairs(t *testing.T) {
+	m := Map{data: []KV{
+		{key: "a", value: "1"},
+		{key: "b", value: "2", op: OpNe},
+	}}
+
+	var got []string
+	for k, v := range m.All() {
+		got = append(got, k+"="+v)
+	}
+	if want := []string{"a=1", "b=2"}; !slices.Equal(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+
+	got = got[:0]
+	for p := range m.Pairs() {
+		got = append(got, p.String())
+	}
+	if want := []string{"a=1", "b!=2"}; !slices.Equal(got, want) {
+		t.Errorf("Pairs() = %v, want %v", got, want)
+	}
+
+	for range m.Pairs() {
+		break
+	}
+}
+
+func TestParseSorted(t *testing.T) {
+	for _, args := range [][]string{
+		{"a=1", "b=2", "c=3"},
+		{"c=3", "a=1", "b=2"},
+		{"b", "2", "a", "1", "c=3"},
+	} {
+		m, err := Parse(args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.String(); got != "a=1 b=2 c=3" {
+			t.Errorf("Parse(%q) = %q", args, got)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	b.Add("type", "alert")
+	if err := b.Parse("level>=3", "a=x|y"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Parse("bad"); err == nil {
+		t.Error("Parse(bad) should fail")
+	}
+	if b.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", b.Len())
+	}
+
+	m := b.Map()
+	if got := m.String(); got != "a=x|y level>=3 type=alert" {
+		t.Errorf("Map() = %q", got)
+	}
+	if b.Len() != 0 {
+		t.Errorf("Len() after Map() = %d, want 0", b.Len())
+	}
+
+	// Buffer reuse must not affect built maps
+	b.Add("z", "1")
+	m2 := b.Map()
+	if got := m.String(); got != "a=x|y level>=3 type=alert" {
+		t.Errorf("first map changed to %q", got)
+	}
+	if got := m2.String(); got != "z=1" {
+		t.Errorf("second map = %q", got)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Add("a", "1")
+		b.Add("b", "2")
+		_ = b.Map()
+	})
+	if allocs != 1 {
+		t.Errorf("Map() allocs = %v, want 1", allocs)
+	}
+}
+
+func TestBuilderSorted(t *testing.T) {
+	var b Builder
+	b.Add("type", "alert")
+	b.Add("id", "1")
+	b.Add("type", "info")
+	b.Add("a", "x")
+	b.Add("id", "2")
+
+	b.Sorted()
+	if b.Len() != 3 {
+		t.Errorf("Len() after Sorted() = %d, want 3", b.Len())
+	}
+
+	m := b.Build()
+	if got := m.String(); got != "a=x id=2 type=info" {
+		t.Errorf("Build() = %q", got)
+	}
+
+	// Build keeps the pairs
+	b.Add("z", "1")
+	if got := b.Build().String(); got != "a=x id=2 type=info z=1" {
+		t.Errorf("second Build() = %q", got)
+	}
+	if got := m.String(); got != "a=x id=2 type=info" {
+		t.Errorf("first map changed to %q", got)
+	}
+}
+
+func TestBuilderSortedOperators(t *testing.T) {
+	// Pairs of one key with different operators are kept like Parse does
+	var b Builder
+	if err := b.Parse("x>1", "x<5", "type=a"); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := Parse("x>1", "x<5", "type=a")
+	if m := b.Map(); !m.Equal(want) {
+		t.Errorf("Map() = %q, want %q", m.String(), want.String())
+	}
+
+	// the same operator is replaced
+	_ = b.Parse("x>1", "x<5", "x>2")
+	if got := b.Map().String(); got != "x>2 x<5" {
+		t.Errorf("Map() = %q, want %q", got, "x>2 x<5")
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = Parse("level=high", "source=db", "type=alert")
+	}
+}
+
 func TestToMap(t *testing.T) {
 	m := Map{data: []KV{
 		{key: "a", value: "1"},
@@ -262,6 +635,90 @@ func TestMatch(t *testing.T) {
 			b:      "color=blue size=large",
 			expect: true,
 		},
+		{
+			name:   "multi-value in a",
+			a:      "color=red|blue",
+			b:      "color=blue size=large",
+			expect: true,
+		},
+		{
+			name:   "multi-value in a mismatch",
+			a:      "color=red|blue",
+			b:      "color=green",
+			expect: false,
+		},
+		{
+			name:   "multi-value in both",
+			a:      "color=red|blue",
+			b:      "color=green|blue",
+			expect: true,
+		},
+		{
+			name:   "negation",
+			a:      "color!=red",
+			b:      "color=blue",
+			expect: true,
+		},
+		{
+			name:   "negation mismatch",
+			a:      "color!=red|blue",
+			b:      "color=blue",
+			expect: false,
+		},
+		{
+			name:   "negation with wildcard in b",
+			a:      "color!=red",
+			b:      "color=*",
+			expect: true,
+		},
+		{
+			name:   "negation missing key in b",
+			a:      "color!=red",
+			b:      "size=large",
+			expect: false,
+		},
+		{
+			name:   "numeric greater or equal",
+			a:      "severity>=3",
+			b:      "severity=3",
+			expect: true,
+		},
+		{
+			name:   "numeric greater or equal mismatch",
+			a:      "severity>=3",
+			b:      "severity=2.5",
+			expect: false,
+		},
+		{
+			name:   "numeric less",
+			a:      "size<1024",
+			b:      "size=512",
+			expect: true,
+		},
+		{
+			name:   "numeric not a number in b",
+			a:      "size<1024",
+			b:      "size=small",
+			expect: false,
+		},
+		{
+			name:   "numeric multi-value in b",
+			a:      "size>10",
+			b:      "size=5|20",
+			expect: true,
+		},
+		{
+			name:   "numeric wildcard in b",
+			a:      "size>10",
+			b:      "size=*",
+			expect: true,
+		},
+		{
+			name:   "multi-value with wildcard in b",
+			a:      "color=red|blue",
+			b:      "color=*",
+			expect: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -372,3 +829,360 @@ func formatMap(m Map) string {
 	}
 	return sb.String()
 }
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      string
+		b      string
+		expect bool
+	}{
+		{"same pairs", "a=1 b=2", "a=1 b=2", true},
+		{"different order", "b=2 a=1", "a=1 b=2", true},
+		{"different value", "a=1 b=2", "a=1 b=3", false},
+		{"extra key", "a=1", "a=1 b=2", false},
+		{"wildcard is literal", "a=*", "a=1", false},
+		{"both empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mustParse(t, tt.a)
+			b := mustParse(t, tt.b)
+			if got := a.Equal(b); got != tt.expect {
+				t.Errorf("Equal() = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      string
+		b      string
+		expect bool
+	}{
+		{"same pairs", "a=1 b=2", "a=1 b=2", true},
+		{"subset", "a=1 b=2 c=3", "a=1 c=3", true},
+		{"empty other", "a=1", "", true},
+		{"both empty", "", "", true},
+		{"missing key", "a=1", "a=1 b=2", false},
+		{"different value", "a=1 b=2", "b=3", false},
+		{"wildcard is literal", "a=1", "a=*", false},
+		{"same wildcard", "a=*", "a=*", true},
+		{"operator is literal", "a=1", "a!=2", false},
+		{"same operator", "a>=2 b=1", "a>=2", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mustParse(t, tt.a)
+			b := mustParse(t, tt.b)
+			if got := a.Contains(b); got != tt.expect {
+				t.Errorf("Contains() = %v, want %v", got, tt.expect)
+			}
+			if got := b.Subset(a); got != tt.expect {
+				t.Errorf("Subset() = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      string
+		b      string
+		expect int
+	}{
+		{"equal", "a=1 b=2", "a=1 b=2", 0},
+		{"smaller key", "a=1", "b=1", -1},
+		{"bigger value", "a=2", "a=1", 1},
+		{"prefix first", "a=1", "a=1 b=2", -1},
+		{"longer last", "a=1 b=2", "a=1", 1},
+		{"empty first", "", "a=1", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mustParse(t, tt.a)
+			b := mustParse(t, tt.b)
+			if got := a.Compare(b); got != tt.expect {
+				t.Errorf("Compare() = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	t.Run("order independent", func(t *testing.T) {
+		a := mustParse(t, "a=1 b=2")
+		b := mustParse(t, "b=2 a=1")
+		if a.Hash() != b.Hash() {
+			t.Error("Expected equal hashes for equal maps")
+		}
+	})
+
+	t.Run("pair boundaries", func(t *testing.T) {
+		a := mustParse(t, "ab=c")
+		b := mustParse(t, "a=bc")
+		if a.Hash() == b.Hash() {
+			t.Error("Expected different hashes for different pairs")
+		}
+	})
+
+	t.Run("stable value", func(t *testing.T) {
+		if got := (Map{}).Hash(); got != fnvOffset64 {
+			t.Errorf("Hash() of empty map = %d, want %d", got, fnvOffset64)
+		}
+	})
+
+	t.Run("no allocations", func(t *testing.T) {
+		m := mustParse(t, "a=1 b=2 c=3")
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = m.Hash()
+		})
+		if allocs != 0 {
+			t.Errorf("Hash() allocs = %v, want 0", allocs)
+		}
+	})
+}
+
+func TestFromValues(t *testing.T) {
+	m := FromValues(map[string][]string{
+		"b":     {"2"},
+		"a":     {"1", "x=y"},
+		"empty": {},
+	})
+
+	if m.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", m.Len())
+	}
+	if !reflect.DeepEqual(m.Keys(), []string{"a", "b"}) {
+		t.Errorf("Keys() = %v, want [a b]", m.Keys())
+	}
+	if !m.data[0].IsSet() || !reflect.DeepEqual(m.data[0].Values(), []string{"1", "x=y"}) {
+		t.Errorf("Values() = %v, want [1 x=y]", m.data[0].Values())
+	}
+	if m.Get("b") != "2" {
+		t.Errorf("Get(b) = %q, want 2", m.Get("b"))
+	}
+}
+
+func TestHas(t *testing.T) {
+	m := mustParse(t, "a=1 b=")
+	if !m.Has("a") || !m.Has("b") {
+		t.Error("Has() = false for existing key")
+	}
+	if m.Has("c") {
+		t.Error("Has() = true for missing key")
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		key    string
+		value  string
+		expect string
+	}{
+		{"add to empty", "", "a", "1", "a=1"},
+		{"add first", "b=2", "a", "1", "a=1 b=2"},
+		{"add middle", "a=1 c=3", "b", "2", "a=1 b=2 c=3"},
+		{"add last", "a=1", "b", "2", "a=1 b=2"},
+		{"replace", "a=1 b=2", "a", "3", "a=3 b=2"},
+		{"replace operator", "a!=1", "a", "2", "a=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := mustParse(t, tt.base)
+			before := formatMap(base)
+
+			got := base.Set(tt.key, tt.value)
+			if formatMap(got) != tt.expect {
+				t.Errorf("Set() = %v, want %v", formatMap(got), tt.expect)
+			}
+			if formatMap(base) != before {
+				t.Error("Original map was modified")
+			}
+		})
+	}
+
+	t.Run("literal value", func(t *testing.T) {
+		m := Map{}.Set("a", "x|y")
+		if m.data[0].IsSet() {
+			t.Error("Set() must not create multi-value pair")
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		key    string
+		expect string
+	}{
+		{"delete first", "a=1 b=2", "a", "b=2"},
+		{"delete last", "a=1 b=2", "b", "a=1"},
+		{"delete missing", "a=1", "c", "a=1"},
+		{"delete from empty", "", "a", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := mustParse(t, tt.base)
+			before := formatMap(base)
+
+			got := base.Delete(tt.key)
+			if formatMap(got) != tt.expect {
+				t.Errorf("Delete() = %v, want %v", formatMap(got), tt.expect)
+			}
+			if formatMap(base) != before {
+				t.Error("Original map was modified")
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Map
+		text string
+	}{
+		{"empty", Map{}, ""},
+		{"simple", mustParse(t, "b=2 a=1"), "a=1 b=2"},
+		{"operators", mustParse(t, "a!=1 b>=2 c<3"), "a!=1 b>=2 c<3"},
+		{"multi-value", mustParse(t, "a=1|2"), "a=1|2"},
+		{"literal separator", Map{}.Set("a", "1|2"), `a=1\|2`},
+		{"special chars", Map{}.Set("k y", "v=a b!"), `k\ y=v\=a\ b\!`},
+		{"backslash", Map{}.Set("a", `x\y`), `a=x\\y`},
+		{"quotes", Map{}.Set("a", `"x'`), `a=\"x\'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.m.MarshalText()
+			if err != nil {
+				t.Fatalf("MarshalText() error = %v", err)
+			}
+			if string(data) != tt.text {
+				t.Errorf("MarshalText() = %q, want %q", data, tt.text)
+			}
+
+			var back Map
+			if err := back.UnmarshalText(data); err != nil {
+				t.Fatalf("UnmarshalText() error = %v", err)
+			}
+			if !back.Equal(tt.m) {
+				t.Errorf("UnmarshalText() = %q, want %q", back.String(), tt.m.String())
+			}
+		})
+	}
+
+	t.Run("extra spaces", func(t *testing.T) {
+		var m Map
+		if err := m.UnmarshalText([]byte("  a=1   b=2 ")); err != nil {
+			t.Fatalf("UnmarshalText() error = %v", err)
+		}
+		if m.String() != "a=1 b=2" {
+			t.Errorf("UnmarshalText() = %q", m.String())
+		}
+	})
+
+	t.Run("quoted values", func(t *testing.T) {
+		var m Map
+		if err := m.UnmarshalText([]byte(`label="hello world" path='a=b c' n=1`)); err != nil {
+			t.Fatalf("UnmarshalText() error = %v", err)
+		}
+		if m.Get("label") != "hello world" || m.Get("path") != "a=b c" || m.Get("n") != "1" {
+			t.Errorf("UnmarshalText() = %q", m.String())
+		}
+	})
+
+	t.Run("missing operator", func(t *testing.T) {
+		var m Map
+		if err := m.UnmarshalText([]byte("a 1")); err == nil {
+			t.Error("Expected error for pair without operator")
+		}
+	})
+}
+
+func TestIntern(t *testing.T) {
+	a := mustParse(t, "type=alert priority=high|critical")
+	b := mustParse(t, "type=alert priority=high|critical")
+
+	ia, ib := a.Intern(), b.Intern()
+	if !ia.Equal(a) {
+		t.Errorf("Intern() = %v, want %v", ia.String(), a.String())
+	}
+	if unsafe.StringData(ia.data[1].value) != unsafe.StringData(ib.data[1].value) {
+		t.Error("Expected interned values to share memory")
+	}
+	if unsafe.StringData(ia.data[0].values[1]) != unsafe.StringData(ib.data[0].values[1]) {
+		t.Error("Expected interned alternatives to share memory")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		kind  error
+		pos   int
+		msg   string
+	}{
+		{"missing value", []string{"a=1", "b"}, ErrMissingValue, 1, "missing value for key 'b' at position 1"},
+		{"empty key", []string{"=1"}, ErrEmptyKey, 0, "empty key in pair '=1' at position 0"},
+		{"empty key separate", []string{"", "1"}, ErrEmptyKey, 0, "empty key in pair '' at position 0"},
+		{"bad escape", []string{"a=1", "b=2", `c=3\`}, ErrBadEscape, 2, `bad escape sequence in pair 'c=3\' at position 2`},
+		{"invalid number", []string{"a>=x"}, ErrInvalidNumber, 0, "invalid numeric value for key 'a' at position 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input...)
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.kind)
+			}
+
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Parse() error type = %T, want *ParseError", err)
+			}
+			if pe.Pos != tt.pos {
+				t.Errorf("Pos = %d, want %d", pe.Pos, tt.pos)
+			}
+			if !reflect.DeepEqual(pe.Args, tt.input) {
+				t.Errorf("Args = %v, want %v", pe.Args, tt.input)
+			}
+			if pe.Error() != tt.msg {
+				t.Errorf("Error() = %q, want %q", pe.Error(), tt.msg)
+			}
+		})
+	}
+
+	t.Run("offending input is escaped and truncated", func(t *testing.T) {
+		_, err := Parse("a=1", "evil\x1b[2J\n\xff"+strings.Repeat("x", 100))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("Parse() error = %v, want *ParseError", err)
+		}
+		want := `evil\x1b[2J\n\xff` + strings.Repeat("x", 47) + "..."
+		if pe.Key != want {
+			t.Errorf("Key = %q, want %q", pe.Key, want)
+		}
+	})
+
+	t.Run("missing operator", func(t *testing.T) {
+		var m Map
+		err := m.UnmarshalText([]byte("a=1 b"))
+		if !errors.Is(err, ErrMissingOperator) {
+			t.Errorf("UnmarshalText() error = %v, want %v", err, ErrMissingOperator)
+		}
+	})
+}
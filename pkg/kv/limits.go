@@ -0,0 +1,91 @@
+package kv
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limit error kinds returned by ParseStrict and Limits.Check as ParseError
+var (
+	ErrTooManyPairs  = errors.New("too many pairs")
+	ErrKeyTooLong    = errors.New("key too long")
+	ErrValueTooLong  = errors.New("value too long")
+	ErrForbiddenRune = errors.New("forbidden character in pair")
+)
+
+// Limits restricts maps parsed from untrusted input, e.g. topics received
+// from network clients. Zero values of the size fields disable
+// corresponding checks.
+type Limits struct {
+	MaxPairs    int // Maximum number of pairs
+	MaxKeyLen   int // Maximum key length in bytes
+	MaxValueLen int // Maximum value length in bytes, alternatives included
+	// ValidRune reports whether the character is allowed in keys and values.
+	// If nil, control characters are rejected. Invalid UTF-8 is always rejected.
+	ValidRune func(r rune) bool
+}
+
+// ParseStrict parses pairs like Parse and checks the result against limits.
+// Input with more arguments than MaxPairs allows is rejected before parsing.
+//
+// Example:
+//
+//	m, err := kv.ParseStrict(kv.Limits{MaxPairs: 8, MaxKeyLen: 32, MaxValueLen: 256}, args...)
+//	if errors.Is(err, kv.ErrTooManyPairs) {
+//	    ...
+//	}
+func ParseStrict(l Limits, d ...string) (Map, error) {
+	if l.MaxPairs > 0 && len(d) > 2*l.MaxPairs {
+		return Map{}, newParseError(ErrTooManyPairs, "", l.MaxPairs, d)
+	}
+	m, err := Parse(d...)
+	if err != nil {
+		return Map{}, err
+	}
+	if err := l.Check(m); err != nil {
+		err.(*ParseError).Args = d
+		return Map{}, err
+	}
+	return m, nil
+}
+
+// Check validates m against limits. Returns *ParseError with Pos set to
+// the index of the offending pair in key order. Key of the error is empty
+// if the key itself violates limits.
+func (l Limits) Check(m Map) error {
+	if l.MaxPairs > 0 && m.Len() > l.MaxPairs {
+		return newParseError(ErrTooManyPairs, "", l.MaxPairs, nil)
+	}
+	for i, kv := range m.data {
+		switch {
+		// offending input is not echoed in errors, they may end up in logs
+		case !l.validString(kv.key):
+			return newParseError(ErrForbiddenRune, "", i, nil)
+		case l.MaxKeyLen > 0 && len(kv.key) > l.MaxKeyLen:
+			return newParseError(ErrKeyTooLong, "", i, nil)
+		case !l.validString(kv.value):
+			return newParseError(ErrForbiddenRune, kv.key, i, nil)
+		case l.MaxValueLen > 0 && len(kv.value) > l.MaxValueLen:
+			return newParseError(ErrValueTooLong, kv.key, i, nil)
+		}
+	}
+	return nil
+}
+
+// validString checks all characters of s with ValidRune
+func (l Limits) validString(s string) bool {
+	for _, r := range s {
+		if r == utf8.RuneError {
+			return false
+		}
+		if l.ValidRune == nil {
+			if unicode.IsControl(r) {
+				return false
+			}
+		} else if !l.ValidRune(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package kv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestParseStrict(t *testing.T) {
+	l := Limits{MaxPairs: 2, MaxKeyLen: 4, MaxValueLen: 8}
+	tests := []struct {
+		name  string
+		l     Limits
+		input []string
+		kind  error
+		pos   int
+	}{
+		{"ok", l, []string{"type=user", "id", "12345678"}, nil, 0},
+		{"alternatives", l, []string{"lvl=warn|err"}, nil, 0},
+		{"too many pairs", l, []string{"a=1", "b=2", "c=3"}, ErrTooManyPairs, 2},
+		{"too many args", l, []string{"a", "1", "b", "2", "c"}, ErrTooManyPairs, 2},
+		{"long key", l, []string{"a=1", "typed=x"}, ErrKeyTooLong, 1},
+		{"long value", l, []string{"id=123456789"}, ErrValueTooLong, 0},
+		{"long alternatives", l, []string{"lvl=warn|error"}, ErrValueTooLong, 0},
+		{"control in value", Limits{}, []string{"msg=a\nb"}, ErrForbiddenRune, 0},
+		{"control in key", Limits{}, []string{"a=1", "b\x1b[31m=2"}, ErrForbiddenRune, 1},
+		{"invalid utf8", Limits{}, []string{"a=\xff"}, ErrForbiddenRune, 0},
+		{"valid rune", Limits{ValidRune: unicode.IsLower}, []string{"type=User"}, ErrForbiddenRune, 0},
+		{"parse error", l, []string{"=1"}, ErrEmptyKey, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseStrict(tt.l, tt.input...)
+			if tt.kind == nil {
+				if err != nil {
+					t.Fatalf("ParseStrict() error = %v", err)
+				}
+				want, _ := Parse(tt.input...)
+				if !m.Equal(want) {
+					t.Errorf("ParseStrict() = %v, want %v", m, want)
+				}
+				return
+			}
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("ParseStrict() error = %v, want %v", err, tt.kind)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("ParseStrict() error type = %T, want *ParseError", err)
+			}
+			if pe.Pos != tt.pos {
+				t.Errorf("Pos = %d, want %d", pe.Pos, tt.pos)
+			}
+			if strings.ContainsFunc(pe.Error(), unicode.IsControl) {
+				t.Errorf("Error() = %q contains control characters", pe.Error())
+			}
+		})
+	}
+}
+
+func TestParseTextStrict(t *testing.T) {
+	l := Limits{MaxPairs: 2, MaxValueLen: 8}
+	m, err := ParseTextStrict(l, `type=chat room="a b"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Get("room") != "a b" {
+		t.Errorf("Get(room) = %q", m.Get("room"))
+	}
+	if _, err := ParseTextStrict(l, "a=1 b=2 c=3"); !errors.Is(err, ErrTooManyPairs) {
+		t.Errorf("ParseTextStrict() = %v, want ErrTooManyPairs", err)
+	}
+	if _, err := ParseTextStrict(l, "msg=\x1b[2J"); !errors.Is(err, ErrForbiddenRune) {
+		t.Errorf("ParseTextStrict() = %v, want ErrForbiddenRune", err)
+	}
+	if _, err := ParseTextStrict(l, "a=1 b"); !errors.Is(err, ErrMissingOperator) {
+		t.Errorf("ParseTextStrict() = %v, want ErrMissingOperator", err)
+	}
+}
@@ -0,0 +1,128 @@
+package hub
+
+import (
+	"slices"
+	"unicode/utf8"
+
+	"github.com/lomik/hub/pkg/kv"
+)
+
+// TopicPolicy describes rules enforced by the hub for topics
+// passed to Subscribe and Publish (see WithTopicPolicy).
+// Zero values of the fields disable corresponding checks.
+type TopicPolicy struct {
+	// RequiredKeys must be present in every published topic.
+	// Not enforced for subscriptions, so they can still match broadly.
+	RequiredKeys []string
+	// AllowedKeys limits attribute keys to the listed ones.
+	AllowedKeys []string
+	// MaxAttributes limits the number of attributes in a topic.
+	MaxAttributes int
+	// MaxKeyLen limits the length of attribute keys in bytes.
+	MaxKeyLen int
+	// MaxValueLen limits the length of attribute values in bytes.
+	MaxValueLen int
+	// ValidRune reports whether the character is allowed in keys and values.
+	ValidRune func(r rune) bool
+}
+
+// check validates the topic against the policy.
+// publish enables checks specific to published topics.
+func (p *TopicPolicy) check(t *Topic, publish bool) error {
+	if t == nil {
+		return nil
+	}
+
+	if p.MaxAttributes > 0 && t.Len() > p.MaxAttributes {
+		return newPolicyError(t, "", "too many attributes")
+	}
+
+	if publish {
+		for _, k := range p.RequiredKeys {
+			if !t.mp.Has(k) {
+				return newPolicyError(t, k, "required key is missing")
+			}
+		}
+	}
+
+	for k, v := range t.All() {
+		if len(p.AllowedKeys) > 0 && !slices.Contains(p.AllowedKeys, k) {
+			return newPolicyError(t, k, "key is not allowed")
+		}
+		if p.MaxKeyLen > 0 && len(k) > p.MaxKeyLen {
+			return newPolicyError(t, k, "key is too long")
+		}
+		if p.MaxValueLen > 0 && len(v) > p.MaxValueLen {
+			return newPolicyError(t, k, "value is too long")
+		}
+		if p.ValidRune != nil && (!p.validString(k) || !p.validString(v)) {
+			return newPolicyError(t, k, "invalid character")
+		}
+	}
+	return nil
+}
+
+// validString checks all characters of s with ValidRune
+func (p *TopicPolicy) validString(s string) bool {
+	for _, r := range s {
+		if r == utf8.RuneError || !p.ValidRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// limits returns parser limits enforcing the policy
+func (p *TopicPolicy) limits() kv.Limits {
+	return kv.Limits{
+		MaxPairs:    p.MaxAttributes,
+		MaxKeyLen:   p.MaxKeyLen,
+		MaxValueLen: p.MaxValueLen,
+		ValidRune:   p.ValidRune,
+	}
+}
+
+// ParseTopic creates a Topic from untrusted input, e.g. received by HTTP or
+// WebSocket gateways. Unlike NewTopic it rejects control characters
+// (unless allowed by TopicPolicy.ValidRune), invalid UTF-8 and, with
+// WithTopicPolicy, input exceeding MaxAttributes, MaxKeyLen and MaxValueLen
+// before the topic reaches logs or indexes. Errors are *kv.ParseError,
+// their messages contain at most a short escaped prefix of the input.
+//
+// Example:
+//
+//	h := hub.New(hub.WithTopicPolicy(hub.TopicPolicy{
+//	    MaxAttributes: 8,
+//	    MaxKeyLen:     32,
+//	    MaxValueLen:   256,
+//	}))
+//	t, err := h.ParseTopic(r.URL.Query()["topic"]...)
+func (h *Hub) ParseTopic(args ...string) (*Topic, error) {
+	var l kv.Limits
+	if h.policy != nil {
+		l = h.policy.limits()
+	}
+	mp, err := kv.ParseStrict(l, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Topic{mp: mp}, nil
+}
+
+// ParseTopicText is ParseTopic for the text form of a topic, see
+// Topic.UnmarshalText. Gateways use it for topics sent by clients.
+//
+// Example:
+//
+//	t, err := h.ParseTopicText(`type=chat room="general"`)
+func (h *Hub) ParseTopicText(text string) (*Topic, error) {
+	var l kv.Limits
+	if h.policy != nil {
+		l = h.policy.limits()
+	}
+	mp, err := kv.ParseTextStrict(l, text)
+	if err != nil {
+		return nil, err
+	}
+	return &Topic{mp: mp}, nil
+}
@@ -0,0 +1,130 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/lomik/hub/pkg/kv"
+)
+
+func TestTopicPolicy(t *testing.T) {
+	policy := &TopicPolicy{
+		RequiredKeys:  []string{"type"},
+		AllowedKeys:   []string{"type", "priority", "source"},
+		MaxAttributes: 2,
+		MaxValueLen:   8,
+		ValidRune: func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '*'
+		},
+	}
+
+	tests := []struct {
+		name    string
+		topic   *Topic
+		publish bool
+		wantErr string
+	}{
+		{"valid publish", T("type=alert", "priority=high"), true, ""},
+		{"missing required key", T("priority=high"), true, "topic policy violation: required key is missing: type"},
+		{"required key not enforced for subscribe", T("priority=high"), false, ""},
+		{"not allowed key", T("type=alert", "prio=high"), true, "topic policy violation: key is not allowed: prio"},
+		{"too many attributes", T("type=alert", "priority=high", "source=db"), true, "topic policy violation: too many attributes"},
+		{"invalid character", T("type=alert\n"), true, "topic policy violation: invalid character: type"},
+		{"wildcard subscribe", T("type=*"), false, ""},
+		{"long value", T("type=alert", "source=postgresql"), true, "topic policy violation: value is too long: source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.check(tt.topic, tt.publish)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithTopicPolicy(t *testing.T) {
+	ctx := context.Background()
+	h := New(WithTopicPolicy(TopicPolicy{
+		AllowedKeys: []string{"type"},
+	}))
+
+	called := false
+	if _, err := h.Subscribe(ctx, T("type=alert"), func(ctx context.Context) {
+		called = true
+	}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	_, err := h.Subscribe(ctx, T("tpye=alert"), func(ctx context.Context) {})
+	var pe *PolicyError
+	if !errors.As(err, &pe) || pe.Key != "tpye" {
+		t.Errorf("Expected PolicyError for subscribe, got %v", err)
+	}
+
+	err = h.Publish(ctx, T("type=alert", "tpye=alert"), nil, Sync(true))
+	if !errors.As(err, &pe) {
+		t.Errorf("Expected PolicyError for publish, got %v", err)
+	}
+	if called {
+		t.Error("Rejected event must not be delivered")
+	}
+
+	if err := h.Publish(ctx, T("type=alert"), nil, Sync(true)); err != nil {
+		t.Errorf("Publish() error = %v", err)
+	}
+	if !called {
+		t.Error("Valid event was not delivered")
+	}
+}
+
+func TestParseTopic(t *testing.T) {
+	h := New(WithTopicPolicy(TopicPolicy{
+		MaxAttributes: 2,
+		MaxKeyLen:     8,
+		MaxValueLen:   16,
+	}))
+
+	topic, err := h.ParseTopic("type=alert", "source=db")
+	if err != nil {
+		t.Fatalf("ParseTopic() error = %v", err)
+	}
+	if !topic.Equal(T("type=alert", "source=db")) {
+		t.Errorf("ParseTopic() = %v", topic)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		kind error
+	}{
+		{[]string{"type=alert", "a=1", "b=2"}, kv.ErrTooManyPairs},
+		{[]string{"priority_level=high"}, kv.ErrKeyTooLong},
+		{[]string{"type=" + strings.Repeat("x", 17)}, kv.ErrValueTooLong},
+		{[]string{"type=alert\r\nlevel=forged"}, kv.ErrForbiddenRune},
+		{[]string{"type", "alert\x1b[2J"}, kv.ErrForbiddenRune},
+		{[]string{"type"}, kv.ErrMissingValue},
+	} {
+		_, err := h.ParseTopic(tc.args...)
+		if !errors.Is(err, tc.kind) {
+			t.Errorf("ParseTopic(%q) error = %v, want %v", tc.args, err, tc.kind)
+		}
+	}
+
+	// without policy only characters are checked
+	if _, err := New().ParseTopic("msg=" + strings.Repeat("x", 1000)); err != nil {
+		t.Errorf("ParseTopic() error = %v", err)
+	}
+	if _, err := New().ParseTopic("msg=a\tb"); !errors.Is(err, kv.ErrForbiddenRune) {
+		t.Errorf("ParseTopic() error = %v, want ErrForbiddenRune", err)
+	}
+}
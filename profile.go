@@ -0,0 +1,50 @@
+package hub
+
+import "context"
+
+// Profile holds default options for topics matching Pattern.
+// Nil Pattern matches all topics.
+//
+// Defaults are applied before options passed to Publish and Subscribe,
+// so explicit options override them. When several profiles match,
+// they are applied in registration order.
+type Profile struct {
+	Pattern   *Topic
+	Publish   []PublishOption   // Defaults for events published to matching topics
+	Subscribe []SubscribeOption // Defaults for subscriptions to matching topics
+}
+
+// matches reports whether the profile applies to topic t
+func (p *Profile) matches(t *Topic) bool {
+	return p.Pattern == nil || p.Pattern.Match(t)
+}
+
+// applyPublishProfiles applies default options of matching profiles to e
+func (h *Hub) applyPublishProfiles(ctx context.Context, e *event) {
+	for i := range h.profiles {
+		p := &h.profiles[i]
+		if len(p.Publish) == 0 || !p.matches(e.topic) {
+			continue
+		}
+		for _, o := range p.Publish {
+			if o != nil {
+				o.modifyEvent(ctx, e)
+			}
+		}
+	}
+}
+
+// applySubscribeProfiles applies default options of matching profiles to s
+func (h *Hub) applySubscribeProfiles(ctx context.Context, s *sub) {
+	for i := range h.profiles {
+		p := &h.profiles[i]
+		if len(p.Subscribe) == 0 || !p.matches(s.topic) {
+			continue
+		}
+		for _, o := range p.Subscribe {
+			if o != nil {
+				o.modifySub(ctx, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package hub
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	ctx := context.Background()
+	h := New(
+		WithProfile(Profile{
+			Pattern:   T("type=audit"),
+			Publish:   []PublishOption{Sync(true)},
+			Subscribe: []SubscribeOption{Once(true)},
+		}),
+		WithProfile(Profile{
+			Subscribe: []SubscribeOption{nil},
+		}),
+	)
+
+	var audit, other atomic.Int32
+	_, _ = h.Subscribe(ctx, T("type=audit"), func(ctx context.Context) { audit.Add(1) })
+	_, _ = h.Subscribe(ctx, T("type=other"), func(ctx context.Context) { other.Add(1) }, Inline(true))
+	// Explicit option overrides profile default
+	_, _ = h.Subscribe(ctx, T("type=audit", "team=a"), func(ctx context.Context) { audit.Add(1) }, Once(false))
+
+	// Sync by profile: handlers are finished when Publish returns
+	_ = h.Publish(ctx, T("type=audit", "team=a"), nil)
+	if audit.Load() != 2 {
+		t.Fatalf("audit calls = %d, want 2", audit.Load())
+	}
+	_ = h.Publish(ctx, T("type=audit", "team=a"), nil)
+	if audit.Load() != 3 {
+		t.Errorf("audit calls = %d, want 3 (Once from profile)", audit.Load())
+	}
+	if h.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", h.Len())
+	}
+
+	_ = h.Publish(ctx, T("type=other"), nil, Sync(true))
+	if other.Load() != 1 {
+		t.Errorf("other calls = %d, want 1", other.Load())
+	}
+}
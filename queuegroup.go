@@ -0,0 +1,97 @@
+package hub
+
+// targets returns subscriptions receiving the event: matching ones with
+// a single member of every queue group, except durable ones fed by the Store
+func (h *Hub) targets(e *event, dst []*sub) []*sub {
+	subs := pickMembers(e, h.withoutDurables(h.cachedMatch(e.topic, dst)))
+	e.trace.matched(subs)
+	return subs
+}
+
+// pickMembers keeps one subscription of every queue group in subs,
+// at the position of the first member. subs is modified in place.
+func pickMembers(e *event, subs []*sub) []*sub {
+	grouped := false
+	for _, s := range subs {
+		if s.group != "" {
+			grouped = true
+			break
+		}
+	}
+	if !grouped {
+		return subs
+	}
+
+	members := make(map[string][]*sub)
+	for _, s := range subs {
+		if s.group != "" {
+			members[s.group] = append(members[s.group], s)
+		}
+	}
+
+	// every iteration appends at most one subscription, so the write
+	// position never passes the read one
+	out := subs[:0]
+	for _, s := range subs {
+		if s.group == "" {
+			out = append(out, s)
+			continue
+		}
+		m, ok := members[s.group]
+		if !ok {
+			// member of this group is already picked
+			continue
+		}
+		delete(members, s.group)
+		out = append(out, pickMember(e, m))
+	}
+	return out
+}
+
+// pickMember selects the member of a queue group receiving the event.
+// With StickyBy or ConsistentHashBy events with the same attribute value
+// go to the same member, others are spread round robin by event ID.
+func pickMember(e *event, members []*sub) *sub {
+	if len(members) == 1 {
+		return members[0]
+	}
+	var by *sub // member defining the key
+	for _, s := range members {
+		if s.stickyBy != "" {
+			by = s
+			break
+		}
+	}
+	if by != nil {
+		if v := e.topic.Get(by.stickyBy); v != "" {
+			if by.consistent {
+				return pickRendezvous(v, members)
+			}
+			return members[fnv32(v)%uint32(len(members))]
+		}
+	}
+	return members[uint64(e.id)%uint64(len(members))]
+}
+
+// pickRendezvous selects the member with the highest hash of value v
+// combined with its ID. A new or removed member moves only its own
+// share of values.
+func pickRendezvous(v string, members []*sub) *sub {
+	best, bestScore := members[0], rendezvous(v, members[0].id)
+	for _, s := range members[1:] {
+		if score := rendezvous(v, s.id); score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	return best
+}
+
+// rendezvous returns FNV-1a hash of value v combined with subscription id
+func rendezvous(v string, id SubID) uint32 {
+	h := fnv32(v)
+	for i := 0; i < 8; i++ {
+		h ^= uint32(byte(id >> (8 * i)))
+		h *= 16777619
+	}
+	return h
+}
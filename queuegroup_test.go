@@ -0,0 +1,97 @@
+package hub
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestQueueGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	counts := make([]int, 3)
+	for i := range counts {
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) { counts[i]++ }, QueueGroup("workers"))
+	}
+	var other, plain int
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) { other++ }, QueueGroup("audit"))
+	_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context) { plain++ })
+
+	for i := 0; i < 30; i++ {
+		_ = h.Publish(ctx, T("type=job"), nil, Sync(true))
+	}
+
+	for i, n := range counts {
+		if n != 10 {
+			t.Errorf("member %d got %d events, want 10", i, n)
+		}
+	}
+	if other != 30 || plain != 30 {
+		t.Errorf("single member group got %d, plain subscription got %d, want 30", other, plain)
+	}
+}
+
+func TestStickyBy(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		opt  SubscribeOption
+	}{
+		{"sticky", StickyBy("order_id")},
+		{"consistent", ConsistentHashBy("order_id")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			h := New()
+
+			owner := make(map[string]int) // order -> member
+			moved := 0
+			var ids []SubID
+			for i := 0; i < 4; i++ {
+				id, _ := h.Subscribe(ctx, T("type=order"), func(ctx context.Context, order string) {
+					if prev, ok := owner[order]; ok && prev != i {
+						moved++
+					}
+					owner[order] = i
+				}, QueueGroup("orders"), tc.opt)
+				ids = append(ids, id)
+			}
+
+			publish := func() {
+				for i := 0; i < 100; i++ {
+					order := strconv.Itoa(i)
+					_ = h.Publish(ctx, T("type=order", "order_id="+order), order, Sync(true))
+				}
+			}
+			publish()
+			publish()
+			if moved != 0 {
+				t.Errorf("%d orders moved between members", moved)
+			}
+
+			used := make(map[int]bool)
+			for _, m := range owner {
+				used[m] = true
+			}
+			if len(used) != 4 {
+				t.Errorf("orders spread over %d members, want 4", len(used))
+			}
+
+			// orders of the removed member are spread over others,
+			// the rest stay with consistent hashing
+			h.Unsubscribe(ctx, ids[0])
+			for order, m := range owner {
+				if m == 0 {
+					delete(owner, order)
+				}
+			}
+			publish()
+			if tc.name == "consistent" && moved != 0 {
+				t.Errorf("%d orders of remaining members moved", moved)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package hub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is matched by QuotaError with errors.Is
+var ErrQuotaExceeded = errors.New("hub: quota exceeded")
+
+// Quota identifies a limit reported by QuotaError
+type Quota int
+
+const (
+	QuotaSubscriptions      Quota = iota + 1 // Active subscriptions
+	QuotaTopicSubscriptions                  // Active subscriptions with the same topic key
+	QuotaPublishRate                         // Events published per second
+	QuotaPending                             // Events being delivered
+)
+
+// String returns the name of the quota
+func (q Quota) String() string {
+	switch q {
+	case QuotaSubscriptions:
+		return "subscriptions"
+	case QuotaTopicSubscriptions:
+		return "topic key subscriptions"
+	case QuotaPublishRate:
+		return "publish rate"
+	case QuotaPending:
+		return "pending"
+	default:
+		return fmt.Sprintf("Quota(%d)", int(q))
+	}
+}
+
+// QuotaError is returned by Subscribe and Publish when the operation
+// exceeds a limit set by WithMaxSubscriptions, WithMaxTopicSubscriptions,
+// WithPublishRate or TenantQuota.
+type QuotaError struct {
+	Quota Quota   // Exceeded quota
+	Limit float64 // Configured limit
+	Topic *Topic  // Topic of the rejected operation
+	Key   string  // Topic key at the limit for QuotaTopicSubscriptions
+}
+
+// Error implements the error interface for QuotaError.
+func (e *QuotaError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("hub: %s quota exceeded for key %q (limit %g)", e.Quota, e.Key, e.Limit)
+	}
+	return fmt.Sprintf("hub: %s quota exceeded (limit %g)", e.Quota, e.Limit)
+}
+
+// Unwrap returns ErrQuotaExceeded.
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// quotas holds hub-wide limits, zero values disable them
+type quotas struct {
+	subs      int            // Max active subscriptions
+	topicSubs int            // Max active subscriptions per topic key
+	perKey    map[string]int // Subscriptions by topic key, nil if topicSubs is 0
+	rate      *rateLimiter   // nil if publish rate is not limited
+}
+
+// checkSubscribe returns QuotaError if subscription to t exceeds a limit.
+// Must be called under h.Lock().
+func (h *Hub) checkSubscribe(t *Topic) error {
+	q := &h.quotas
+	if q.subs > 0 && len(h.subs) >= q.subs {
+		return &QuotaError{Quota: QuotaSubscriptions, Limit: float64(q.subs), Topic: t}
+	}
+	if q.topicSubs > 0 {
+		for k := range t.All() {
+			if q.perKey[k] >= q.topicSubs {
+				return &QuotaError{Quota: QuotaTopicSubscriptions, Limit: float64(q.topicSubs), Topic: t, Key: k}
+			}
+		}
+	}
+	return nil
+}
+
+// countTopic adjusts the number of subscriptions to each key of t by delta.
+// Must be called under h.Lock().
+func (q *quotas) countTopic(t *Topic, delta int) {
+	if q.perKey == nil {
+		return
+	}
+	for k := range t.All() {
+		if n := q.perKey[k] + delta; n > 0 {
+			q.perKey[k] = n
+		} else {
+			delete(q.perKey, k)
+		}
+	}
+}
+
+// rateLimiter is a token bucket refilled with rate tokens per second
+// up to burst
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time // Zero before the first call
+}
+
+// newRateLimiter creates a limiter allowing rate events per second,
+// burst is at least 1
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(max(burst, 1))}
+}
+
+// allow takes a token, returns false if none is available at now
+func (r *rateLimiter) allow(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last.IsZero() {
+		r.tokens = r.burst
+	} else if now.After(r.last) {
+		r.tokens = min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+	}
+	r.last = now
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
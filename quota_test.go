@@ -0,0 +1,89 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionQuotas(t *testing.T) {
+	t.Parallel()
+
+	h := New(WithMaxSubscriptions(3), WithMaxTopicSubscriptions(2))
+	ctx := context.Background()
+	noop := func(ctx context.Context) {}
+
+	// The limit is per key, values don't matter
+	var ids []SubID
+	for _, tp := range []*Topic{T("order=1"), T("order=2", "type=paid")} {
+		id, err := h.Subscribe(ctx, tp, noop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	_, err := h.Subscribe(ctx, T("type=new", "order=3"), noop)
+	var qe *QuotaError
+	if !errors.As(err, &qe) || qe.Quota != QuotaTopicSubscriptions || qe.Key != "order" || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Subscribe() over topic key quota = %v", err)
+	}
+
+	if _, err := h.Subscribe(ctx, T("type=user"), noop); err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.Subscribe(ctx, T("job=1"), noop)
+	if !errors.As(err, &qe) || qe.Quota != QuotaSubscriptions || qe.Limit != 3 {
+		t.Fatalf("Subscribe() over total quota = %v", err)
+	}
+
+	h.Unsubscribe(ctx, ids[1])
+	if _, err := h.Subscribe(ctx, T("order=3"), noop); err != nil {
+		t.Errorf("Subscribe() after Unsubscribe = %v", err)
+	}
+	h.Clear(ctx)
+	for i := range 2 {
+		if _, err := h.Subscribe(ctx, T("order=1").WithInt("n", i), noop); err != nil {
+			t.Errorf("Subscribe() after Clear = %v", err)
+		}
+	}
+}
+
+func TestPublishRate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	h := New(WithPublishRate(10, 2), WithClock(testClock{now: &now}))
+	ctx := context.Background()
+
+	publish := func() error {
+		return h.Publish(ctx, T("type=tick"), nil)
+	}
+	for range 2 {
+		if err := publish(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var qe *QuotaError
+	if err := publish(); !errors.As(err, &qe) || qe.Quota != QuotaPublishRate {
+		t.Fatalf("Publish() over rate = %v", err)
+	}
+
+	now = now.Add(100 * time.Millisecond)
+	if err := publish(); err != nil {
+		t.Errorf("Publish() after refill = %v", err)
+	}
+	if err := publish(); err == nil {
+		t.Error("Publish() succeeded with empty bucket")
+	}
+}
+
+// testClock is a Clock with time set by the test, timers are not supported
+type testClock struct {
+	realClock
+	now *time.Time
+}
+
+func (c testClock) Now() time.Time {
+	return *c.now
+}
@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoReply is returned by Request when no reply-returning handler
+// matched the topic.
+var ErrNoReply = errors.New("no reply")
+
+// ctxKey is a type for hub context keys
+type ctxKey int
+
+const (
+	ctxKeyReply   ctxKey = iota // *replies collector of Request/Gather
+	ctxKeyEvent                 // *event being dispatched to handlers
+	ctxKeySub                   // *sub whose handler is running
+	ctxKeyAck                   // *AckHandle of the running delivery
+	ctxKeyAttempt               // int number of redelivery attempt
+)
+
+// replies collects results of reply-returning handlers
+type replies struct {
+	mu     sync.Mutex
+	values []any
+	errs   []error
+	first  chan struct{} // closed on the first successful reply
+}
+
+// newReplies creates empty collector
+func newReplies() *replies {
+	return &replies{
+		first: make(chan struct{}),
+	}
+}
+
+// add records a handler result
+func (r *replies) add(v any, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errs = append(r.errs, err)
+		return
+	}
+	r.values = append(r.values, v)
+	if len(r.values) == 1 {
+		close(r.first)
+	}
+}
+
+// result returns collected replies and joined errors
+func (r *replies) result() ([]any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]any(nil), r.values...), errors.Join(r.errs...)
+}
+
+// addReply routes handler result to Request/Gather caller if any
+func addReply(ctx context.Context, v any, err error) {
+	if r, ok := ctx.Value(ctxKeyReply).(*replies); ok {
+		r.add(v, err)
+	}
+}
+
+// Request publishes the payload and returns the first reply produced by
+// a reply-returning handler:
+//
+//	func(ctx context.Context, in T) (out R, err error)
+//
+// Returns as soon as the first reply is available, remaining handlers
+// keep running in background. If all handlers fail, their errors are
+// returned joined. Returns ErrNoReply if no handler replied.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("rpc=sum"), func(ctx context.Context, in []int) (int, error) {
+//	    return in[0] + in[1], nil
+//	})
+//
+//	out, err := h.Request(ctx, hub.T("rpc=sum"), []int{1, 2}) // 3, nil
+func (h *Hub) Request(ctx context.Context, t *Topic, payload any, opts ...PublishOption) (any, error) {
+	r := newReplies()
+	done := make(chan struct{})
+
+	opts = append(opts[:len(opts):len(opts)], OnFinish(func(ctx context.Context) { close(done) }))
+	if err := h.Publish(context.WithValue(ctx, ctxKeyReply, r), t, payload, opts...); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-r.first:
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	values, err := r.result()
+	if len(values) > 0 {
+		return values[0], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, ErrNoReply
+}
+
+// Gather publishes the payload, waits for all matched handlers and
+// returns replies of all reply-returning handlers (see Request).
+// Errors of failed handlers are returned joined together with
+// replies of successful ones.
+//
+// Example:
+//
+//	replies, err := h.Gather(ctx, hub.T("type=healthcheck"), nil)
+func (h *Hub) Gather(ctx context.Context, t *Topic, payload any, opts ...PublishOption) ([]any, error) {
+	r := newReplies()
+
+	opts = append(opts[:len(opts):len(opts)], Wait(true))
+	if err := h.Publish(context.WithValue(ctx, ctxKeyReply, r), t, payload, opts...); err != nil {
+		return nil, err
+	}
+
+	return r.result()
+}
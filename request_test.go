@@ -0,0 +1,139 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRequest(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+
+	h.Subscribe(ctx, T("rpc=sum"), func(ctx context.Context, in []int) (int, error) {
+		return in[0] + in[1], nil
+	})
+	h.Subscribe(ctx, T("rpc=echo"), func(ctx context.Context, in any) (any, error) {
+		return in, nil
+	})
+	h.Subscribe(ctx, T("rpc=fail"), func(ctx context.Context, in any) (any, error) {
+		return nil, errors.New("failed")
+	})
+	h.Subscribe(ctx, T("rpc=silent"), func(ctx context.Context) {})
+
+	t.Run("typed reply", func(t *testing.T) {
+		out, err := h.Request(ctx, T("rpc=sum"), []int{1, 2})
+		if err != nil || out != 3 {
+			t.Errorf("Request() = %v, %v, want 3", out, err)
+		}
+	})
+
+	t.Run("any reply", func(t *testing.T) {
+		out, err := h.Request(ctx, T("rpc=echo"), "hello")
+		if err != nil || out != "hello" {
+			t.Errorf("Request() = %v, %v, want hello", out, err)
+		}
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		_, err := h.Request(ctx, T("rpc=fail"), nil)
+		if err == nil || err.Error() != "failed" {
+			t.Errorf("Request() error = %v, want failed", err)
+		}
+	})
+
+	t.Run("no reply", func(t *testing.T) {
+		if _, err := h.Request(ctx, T("rpc=silent"), nil); !errors.Is(err, ErrNoReply) {
+			t.Errorf("Request() error = %v, want ErrNoReply", err)
+		}
+		if _, err := h.Request(ctx, T("rpc=missing"), nil); !errors.Is(err, ErrNoReply) {
+			t.Errorf("Request() error = %v, want ErrNoReply", err)
+		}
+	})
+
+	t.Run("returns first reply", func(t *testing.T) {
+		h := New()
+		h.Subscribe(ctx, T("rpc=race"), func(ctx context.Context, in any) (any, error) {
+			return "fast", nil
+		})
+		h.Subscribe(ctx, T("rpc=race"), func(ctx context.Context, in any) (any, error) {
+			time.Sleep(time.Second)
+			return "slow", nil
+		})
+
+		start := time.Now()
+		out, err := h.Request(ctx, T("rpc=race"), nil)
+		if err != nil || out != "fast" {
+			t.Errorf("Request() = %v, %v, want fast", out, err)
+		}
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("Request() waited for slow handler")
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		h := New()
+		h.Subscribe(ctx, T("rpc=slow"), func(ctx context.Context, in any) (any, error) {
+			time.Sleep(200 * time.Millisecond)
+			return nil, nil
+		})
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := h.Request(ctx, T("rpc=slow"), nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Request() error = %v, want DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestGather(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+
+	for _, name := range []string{"db", "cache"} {
+		h.Subscribe(ctx, T("type=health"), func(ctx context.Context, in any) (string, error) {
+			return name, nil
+		})
+	}
+	h.Subscribe(ctx, T("type=health"), func(ctx context.Context, in any) (string, error) {
+		return "", errors.New("queue is down")
+	})
+
+	replies, err := h.Gather(ctx, T("type=health"), nil)
+	if err == nil || err.Error() != "queue is down" {
+		t.Errorf("Gather() error = %v, want queue is down", err)
+	}
+
+	var got []string
+	for _, r := range replies {
+		got = append(got, r.(string))
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "cache" || got[1] != "db" {
+		t.Errorf("Gather() = %v, want [cache db]", got)
+	}
+}
+
+func TestRequestKeepsCallerOptions(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	h.Subscribe(ctx, T("rpc=echo"), func(ctx context.Context, in string) (string, error) {
+		return in, nil
+	})
+
+	// Spare capacity of the caller's slice must not be written to
+	opts := make([]PublishOption, 1, 2)
+	opts[0] = Priority(1)
+	spare := opts[:2]
+	if _, err := h.Request(ctx, T("rpc=echo"), "a", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Gather(ctx, T("rpc=echo"), "b", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if spare[1] != nil {
+		t.Errorf("Request() wrote %T into the caller's options", spare[1])
+	}
+}
@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"strconv"
+	"time"
+)
+
+// Reserved attributes added to published topics by WithSystemAttributes
+const (
+	AttrTimestamp = "_ts"     // Publish time in RFC3339 format with nanoseconds, UTC
+	AttrSequence  = "_seq"    // Per-hub publish sequence number starting from 1
+	AttrSource    = "_source" // Name of the publishing hub or service
+)
+
+// SystemAttributes selects reserved attributes stamped onto every
+// published topic (see WithSystemAttributes).
+type SystemAttributes struct {
+	// Timestamp adds AttrTimestamp with the publish time
+	Timestamp bool
+	// Sequence adds AttrSequence with monotonically increasing number
+	Sequence bool
+	// Source adds AttrSource with this value if not empty
+	Source string
+}
+
+// stamp returns a copy of the topic with system attributes set.
+// Publisher provided values of reserved attributes are overwritten.
+func (h *Hub) stamp(t *Topic, id EventID) *Topic {
+	a := h.sysAttrs
+	mp := t.mp
+	if a.Timestamp {
+		mp = mp.Set(AttrTimestamp, h.clock.Now().UTC().Format(time.RFC3339Nano))
+	}
+	if a.Sequence {
+		mp = mp.Set(AttrSequence, strconv.FormatUint(uint64(id), 10))
+	}
+	if a.Source != "" {
+		mp = mp.Set(AttrSource, a.Source)
+	}
+	return &Topic{mp: mp}
+}
@@ -0,0 +1,51 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithSystemAttributes(t *testing.T) {
+	ctx := context.Background()
+	h := New(WithSystemAttributes(SystemAttributes{
+		Timestamp: true,
+		Sequence:  true,
+		Source:    "billing",
+	}))
+
+	var topics []*Topic
+	h.Subscribe(ctx, T("type=order"), func(ctx context.Context, topic *Topic, p any) {
+		topics = append(topics, topic)
+	})
+
+	start := time.Now()
+	h.Publish(ctx, T("type=order"), nil, Sync(true))
+	h.Publish(ctx, T("type=order", "_source=fake"), nil, Sync(true))
+
+	if len(topics) != 2 {
+		t.Fatalf("Handler called %d times, want 2", len(topics))
+	}
+
+	for i, topic := range topics {
+		if topic.Get("type") != "order" {
+			t.Errorf("Original attributes lost: %v", topic)
+		}
+		if topic.Get(AttrSource) != "billing" {
+			t.Errorf("%s = %q, want billing", AttrSource, topic.Get(AttrSource))
+		}
+		if seq, err := topic.GetInt(AttrSequence); err != nil || seq != i+1 {
+			t.Errorf("%s = %v, %v, want %d", AttrSequence, seq, err, i+1)
+		}
+		if ts, err := topic.GetTime(AttrTimestamp); err != nil || ts.Before(start.Add(-time.Second)) {
+			t.Errorf("%s = %v, %v", AttrTimestamp, ts, err)
+		}
+	}
+}
+
+func TestWithSystemAttributesDisabled(t *testing.T) {
+	h := New(WithSystemAttributes(SystemAttributes{}))
+	if h.sysAttrs != nil {
+		t.Error("Empty SystemAttributes must disable stamping")
+	}
+}
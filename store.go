@@ -0,0 +1,237 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// StoredEvent is an event written to a Store
+type StoredEvent struct {
+	Offset  uint64          `json:"offset"`  // Position in the log, assigned by Append
+	Topic   *Topic          `json:"topic"`   // Topic of the event
+	Payload json.RawMessage `json:"payload"` // JSON encoded payload
+}
+
+// EventStore is a log of published events. It keeps the replay window of
+// the hub, so the window is limited by the store rather than by process
+// memory. Methods are called under the hub's store lock and don't need
+// to be safe for concurrent use by the hub. See hubstore for in-memory
+// and file-based implementations.
+type EventStore interface {
+	// Append writes the event and returns its offset.
+	// Offsets start from 1 and grow by one, also after Trim.
+	Append(ev StoredEvent) (uint64, error)
+	// Range calls fn for kept events with offset >= from in offset order
+	// and stops on the first error returned by fn.
+	Range(from uint64, fn func(ev StoredEvent) error) error
+	// Trim discards events with offset < before, e.g. ones processed by
+	// all durable subscriptions or older than the retention period.
+	Trim(before uint64) error
+}
+
+// Store is a write-ahead log of published events with offsets of durable
+// subscriptions, see WithStore.
+type Store interface {
+	EventStore
+	// Commit saves offset of the next event to deliver to durable
+	// subscription name
+	Commit(name string, next uint64) error
+	// Committed returns the offset saved by Commit, 0 if none
+	Committed(name string) (uint64, error)
+}
+
+// persist writes the event to the store and queues it for matching durable
+// subscriptions. Queueing under the store lock keeps deliveries of every
+// durable subscription in offset order.
+func (h *Hub) persist(ctx context.Context, e *event) error {
+	payload, err := h.encodePayload(e.payload)
+	if err != nil {
+		return fmt.Errorf("hub: store event %s: %w", e.topic, err)
+	}
+
+	var start []*sub
+	h.storeMu.Lock()
+	e.offset, err = h.store.Append(StoredEvent{Topic: e.topic, Payload: payload})
+	if err == nil {
+		for _, s := range *h.durables.Load() {
+			if s.topic.Match(e.topic) && h.enqueueDurable(ctx, s, e) {
+				start = append(start, s)
+			}
+		}
+	}
+	h.storeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("hub: store event %s: %w", e.topic, err)
+	}
+
+	// workers are started outside of the lock: an executor may run
+	// them in this goroutine
+	for _, s := range start {
+		h.spawn(0, s.serial.work)
+	}
+	return nil
+}
+
+// encodePayload encodes payload to JSON for the store. With WithEncryptor
+// the result is a JSON string with base64 encoded ciphertext.
+func (h *Hub) encodePayload(p any) (json.RawMessage, error) {
+	data, err := json.Marshal(p)
+	if err != nil || h.encryptor == nil {
+		return data, err
+	}
+	if data, err = h.encryptor.Encrypt(data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
+
+// decodePayload decodes payload written by encodePayload
+func (h *Hub) decodePayload(raw json.RawMessage) (any, error) {
+	if h.encryptor != nil {
+		var data []byte
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+		var err error
+		if raw, err = h.encryptor.Decrypt(data); err != nil {
+			return nil, err
+		}
+	}
+	var p any
+	err := json.Unmarshal(raw, &p)
+	return p, err
+}
+
+// enqueueDurable adds delivery of the stored event to the queue of durable
+// subscription s, returns true if a queue worker must be started.
+// The delivery is counted by Drain and InFlight until it completes.
+// Must be called under h.storeMu.
+func (h *Hub) enqueueDurable(ctx context.Context, s *sub, e *event) bool {
+	// delivery may happen long after the publish
+	ctx = context.WithoutCancel(ctx)
+	h.active.begin()
+	h.deliveries.begin()
+	return s.serial.enqueue(0, func() {
+		defer h.active.end()
+		defer h.deliveries.end()
+		h.call(ctx, s, e)
+		if err := h.commit(s, e.offset+1); err != nil {
+			for _, cb := range h.onError {
+				cb(ctx, e.topic, s.id, err)
+			}
+		}
+		if s.shouldRemove() {
+			h.Unsubscribe(ctx, s.id)
+		}
+	})
+}
+
+// commit saves offset of durable subscription s
+func (h *Hub) commit(s *sub, next uint64) error {
+	h.storeMu.Lock()
+	defer h.storeMu.Unlock()
+	if err := h.store.Commit(s.durable, next); err != nil {
+		return fmt.Errorf("hub: commit %q: %w", s.durable, err)
+	}
+	return nil
+}
+
+// enqueueFailed queues a report of stored event which can't be delivered
+// to durable subscription s, so the report is made outside of the locks
+// and in offset order. Returns true if a queue worker must be started.
+// Must be called under h.storeMu.
+func (h *Hub) enqueueFailed(ctx context.Context, s *sub, ev StoredEvent, err error) bool {
+	ctx = context.WithoutCancel(ctx)
+	h.active.begin()
+	h.deliveries.begin()
+	return s.serial.enqueue(0, func() {
+		defer h.active.end()
+		defer h.deliveries.end()
+		if cerr := h.commit(s, ev.Offset+1); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+		for _, cb := range h.onError {
+			cb(ctx, ev.Topic, s.id, err)
+		}
+	})
+}
+
+// addDurable queues stored events not yet processed by durable subscription s
+// and makes it receive new ones. Returns true if the caller must start
+// a worker running s.serial.work. Must be called under h.Lock().
+func (h *Hub) addDurable(ctx context.Context, s *sub) (bool, error) {
+	h.storeMu.Lock()
+	defer h.storeMu.Unlock()
+
+	from, err := h.store.Committed(s.durable)
+	if err != nil {
+		return false, fmt.Errorf("hub: durable %q: %w", s.durable, err)
+	}
+	start := false
+	err = h.store.Range(from, func(ev StoredEvent) error {
+		// with upcasters the upgraded topic is matched
+		if h.upcasters == nil && !s.topic.Match(ev.Topic) {
+			return nil
+		}
+		payload, err := h.decodePayload(ev.Payload)
+		if err != nil {
+			return fmt.Errorf("offset %d: %w", ev.Offset, err)
+		}
+		topic := ev.Topic
+		if h.upcasters != nil {
+			topic, payload, err = h.upcast(ctx, topic, payload)
+			if err != nil {
+				if h.enqueueFailed(ctx, s, ev, err) {
+					start = true
+				}
+				return nil
+			}
+			if !s.topic.Match(topic) {
+				return nil
+			}
+		}
+		e := &event{topic: topic, payload: payload, offset: ev.Offset}
+		if h.enqueueDurable(context.WithValue(ctx, ctxKeyEvent, e), s, e) {
+			start = true
+		}
+		return nil
+	})
+	if err != nil {
+		// queued deliveries never run, complete them for Drain
+		s.serial.mu.Lock()
+		for s.serial.queue.pop() != nil {
+			h.deliveries.end()
+			h.active.end()
+		}
+		s.serial.mu.Unlock()
+		return false, fmt.Errorf("hub: replay %q: %w", s.durable, err)
+	}
+
+	durables := append(slices.Clip(*h.durables.Load()), s)
+	h.durables.Store(&durables)
+	return start, nil
+}
+
+// removeDurable stops delivery of new events to s.
+// Must be called under h.Lock().
+func (h *Hub) removeDurable(s *sub) {
+	durables := *h.durables.Load()
+	if i := slices.Index(durables, s); i >= 0 {
+		durables = slices.Delete(slices.Clone(durables), i, i+1)
+		h.durables.Store(&durables)
+	}
+}
+
+// withoutDurables removes durable subscriptions delivered by persist from
+// subs in place
+func (h *Hub) withoutDurables(subs []*sub) []*sub {
+	if h.store == nil || len(*h.durables.Load()) == 0 {
+		return subs
+	}
+	return slices.DeleteFunc(subs, func(s *sub) bool {
+		return s.durable != ""
+	})
+}
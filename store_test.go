@@ -0,0 +1,133 @@
+package hub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory Store for tests
+type memStore struct {
+	mu        sync.Mutex
+	events    []StoredEvent
+	last      uint64
+	committed map[string]uint64
+}
+
+func newMemStore() *memStore {
+	return &memStore{committed: make(map[string]uint64)}
+}
+
+func (m *memStore) Append(ev StoredEvent) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last++
+	ev.Offset = m.last
+	m.events = append(m.events, ev)
+	return ev.Offset, nil
+}
+
+func (m *memStore) Trim(before uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.events) > 0 && m.events[0].Offset < before {
+		m.events = m.events[1:]
+	}
+	return nil
+}
+
+func (m *memStore) Range(from uint64, fn func(ev StoredEvent) error) error {
+	m.mu.Lock()
+	events := m.events
+	m.mu.Unlock()
+	for _, ev := range events {
+		if ev.Offset < from {
+			continue
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memStore) Commit(name string, next uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.committed[name] = next
+	return nil
+}
+
+func (m *memStore) Committed(name string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.committed[name], nil
+}
+
+// collect subscribes durable handler sending payloads to the channel
+func collect(t *testing.T, h *Hub, name string) <-chan string {
+	ch := make(chan string, 16)
+	_, err := h.Subscribe(context.Background(), T("type=order"), func(ctx context.Context, id string) {
+		ch <- id
+	}, Durable(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+// expect receives want from ch in order
+func expect(t *testing.T, ch <-chan string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-ch:
+			if got != w {
+				t.Fatalf("got %q, want %q", got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q not delivered", w)
+		}
+	}
+	select {
+	case got := <-ch:
+		t.Fatalf("unexpected %q", got)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestDurable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newMemStore()
+
+	h := New(WithStore(store))
+	_ = h.Publish(ctx, T("type=order"), "1")
+	_ = h.Publish(ctx, T("type=other"), "skip")
+
+	// replays stored events and receives new ones
+	ch := collect(t, h, "projection")
+	_ = h.Publish(ctx, T("type=order"), "2", Sync(true))
+	expect(t, ch, "1", "2")
+	// offset is committed after the handler returns
+	for n, _ := store.Committed("projection"); n != 4; n, _ = store.Committed("projection") {
+		time.Sleep(time.Millisecond)
+	}
+
+	// events published while the subscriber is down
+	h = New(WithStore(store))
+	_ = h.Publish(ctx, T("type=order"), "3")
+	_ = h.Publish(ctx, T("type=order"), "4")
+
+	// restarted subscriber resumes after the committed offset
+	ch = collect(t, h, "projection")
+	expect(t, ch, "3", "4")
+
+	// other names have their own offsets
+	expect(t, collect(t, h, "audit"), "1", "2", "3", "4")
+
+	if err := h.Publish(ctx, T("type=order"), func() {}); err == nil {
+		t.Error("Publish() of unencodable payload succeeded")
+	}
+}
@@ -0,0 +1,123 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// StructTagName is the struct tag with topic of func fields for SubscribeStruct
+const StructTagName = "hub"
+
+// MethodTopics can be implemented by objects passed to SubscribeStruct
+// to subscribe their exported methods. The map keys are method names.
+type MethodTopics interface {
+	HubTopics() map[string]*Topic
+}
+
+// Subscriptions is a handle of subscriptions created together
+// by SubscribeStruct
+type Subscriptions struct {
+	h   *Hub
+	ids []SubID
+}
+
+// IDs returns identifiers of all subscriptions in the group
+func (s *Subscriptions) IDs() []SubID {
+	return append([]SubID(nil), s.ids...)
+}
+
+// Unsubscribe removes all subscriptions of the group
+func (s *Subscriptions) Unsubscribe(ctx context.Context) {
+	for _, id := range s.ids {
+		s.h.Unsubscribe(ctx, id)
+	}
+}
+
+// SubscribeStruct subscribes handlers of a controller-like object at once.
+// Handlers are collected from:
+//   - Exported func fields with `hub` tag holding the topic in text form
+//     (see Topic.UnmarshalText), nil fields are skipped
+//   - Exported methods listed by HubTopics() if obj implements MethodTopics
+//
+// Every handler must have one of signatures supported by Subscribe.
+// Options are applied to all subscriptions. If any subscription fails,
+// already created ones are removed and the error is returned.
+//
+// Example:
+//
+//	type Orders struct {
+//	    OnCreated func(ctx context.Context, o Order) error `hub:"type=order status=created"`
+//	}
+//
+//	func (c *Orders) HubTopics() map[string]*hub.Topic {
+//	    return map[string]*hub.Topic{"Cancel": hub.T("type=order", "status=canceled")}
+//	}
+//
+//	func (c *Orders) Cancel(ctx context.Context, o Order) error { ... }
+//
+//	subs, err := h.SubscribeStruct(ctx, &Orders{OnCreated: create})
+//	defer subs.Unsubscribe(ctx)
+func (h *Hub) SubscribeStruct(ctx context.Context, obj any, opts ...SubscribeOption) (*Subscriptions, error) {
+	subs := &Subscriptions{h: h}
+
+	subscribe := func(name string, t *Topic, cb any) error {
+		id, err := h.Subscribe(ctx, t, cb, opts...)
+		if err != nil {
+			subs.Unsubscribe(ctx)
+			return fmt.Errorf("subscribe %s: %w", name, err)
+		}
+		subs.ids = append(subs.ids, id)
+		return nil
+	}
+
+	// Tagged func fields
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		vt := v.Type()
+		for i := 0; i < vt.NumField(); i++ {
+			f := vt.Field(i)
+			tag, ok := f.Tag.Lookup(StructTagName)
+			if !ok || !f.IsExported() || f.Type.Kind() != reflect.Func || v.Field(i).IsNil() {
+				continue
+			}
+
+			t := &Topic{}
+			if err := t.UnmarshalText([]byte(tag)); err != nil {
+				subs.Unsubscribe(ctx)
+				return nil, fmt.Errorf("subscribe %s: %w", f.Name, err)
+			}
+			if err := subscribe(f.Name, t, v.Field(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Listed methods
+	if mt, ok := obj.(MethodTopics); ok {
+		topics := mt.HubTopics()
+		names := make([]string, 0, len(topics))
+		for name := range topics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		ov := reflect.ValueOf(obj)
+		for _, name := range names {
+			m := ov.MethodByName(name)
+			if !m.IsValid() {
+				subs.Unsubscribe(ctx)
+				return nil, fmt.Errorf("subscribe %s: method not found", name)
+			}
+			if err := subscribe(name, topics[name], m.Interface()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return subs, nil
+}
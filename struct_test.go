@@ -0,0 +1,102 @@
+package hub
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lomik/hub/pkg/cmap"
+)
+
+type testController struct {
+	c *cmap.CMap
+
+	OnCreated func(ctx context.Context, id int) error `hub:"type=order status=created"`
+	OnAny     func(ctx context.Context)               `hub:"type=order"`
+	Skipped   func(ctx context.Context)               `hub:"type=order"`
+	NoTag     func(ctx context.Context)
+}
+
+func (c *testController) HubTopics() map[string]*Topic {
+	return map[string]*Topic{
+		"Cancel": T("type=order", "status=canceled"),
+	}
+}
+
+func (c *testController) Cancel(ctx context.Context, id int) error {
+	c.c.Add("canceled", id)
+	return nil
+}
+
+func TestSubscribeStruct(t *testing.T) {
+	ctx := context.Background()
+	h := New()
+	c := cmap.New()
+
+	ctrl := &testController{
+		c: c,
+		OnCreated: func(ctx context.Context, id int) error {
+			c.Add("created", id)
+			return nil
+		},
+		OnAny: func(ctx context.Context) {
+			c.Add("any", 1)
+		},
+		NoTag: func(ctx context.Context) {
+			c.Add("notag", 1)
+		},
+	}
+
+	subs, err := h.SubscribeStruct(ctx, ctrl)
+	if err != nil {
+		t.Fatalf("SubscribeStruct() error = %v", err)
+	}
+	if len(subs.IDs()) != 3 || h.Len() != 3 {
+		t.Fatalf("Expected 3 subscriptions, got %d", len(subs.IDs()))
+	}
+
+	h.Publish(ctx, T("type=order", "status=created"), 10, Sync(true))
+	h.Publish(ctx, T("type=order", "status=canceled"), "5", Sync(true))
+
+	if !c.Eq(map[string]int{"created": 10, "canceled": 5, "any": 2}) {
+		t.Error("Result mismatch")
+	}
+
+	subs.Unsubscribe(ctx)
+	if h.Len() != 0 {
+		t.Errorf("Expected 0 subscriptions after Unsubscribe, got %d", h.Len())
+	}
+}
+
+func TestSubscribeStructErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid tag", func(t *testing.T) {
+		h := New()
+		_, err := h.SubscribeStruct(ctx, &struct {
+			OK  func(ctx context.Context) `hub:"type=ok"`
+			Bad func(ctx context.Context) `hub:"type"`
+		}{
+			OK:  func(ctx context.Context) {},
+			Bad: func(ctx context.Context) {},
+		})
+		if err == nil || !strings.HasPrefix(err.Error(), "subscribe Bad") {
+			t.Errorf("SubscribeStruct() error = %v", err)
+		}
+		if h.Len() != 0 {
+			t.Error("Subscriptions must be rolled back on error")
+		}
+	})
+
+	t.Run("unsupported handler", func(t *testing.T) {
+		h := New()
+		_, err := h.SubscribeStruct(ctx, struct {
+			Bad func() `hub:"type=bad"`
+		}{
+			Bad: func() {},
+		})
+		if err == nil {
+			t.Error("Expected error for unsupported handler")
+		}
+	})
+}
@@ -2,9 +2,41 @@ package hub
 
 import (
 	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
 	"sync/atomic"
 )
 
+// ErrUnsubscribe can be returned by a handler to remove its own subscription.
+// It is not reported to OnError hooks.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=job"), func(ctx context.Context, status string) error {
+//	    if status == "done" {
+//	        return hub.ErrUnsubscribe
+//	    }
+//	    return nil
+//	})
+var ErrUnsubscribe = errors.New("unsubscribe")
+
+// ErrStopPropagation can be returned by a handler of an event published
+// with Sync(true) to skip remaining handlers. Handlers are called in
+// subscription order, so earlier subscribers can validate or veto events.
+// Handlers already running in own goroutines are not affected.
+// It is not reported to OnError hooks and is ignored in async delivery.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=order"), func(ctx context.Context, o Order) error {
+//	    if o.Amount <= 0 {
+//	        return hub.ErrStopPropagation
+//	    }
+//	    return nil
+//	})
+var ErrStopPropagation = errors.New("stop propagation")
+
 type SubID uint64
 
 type sub struct {
@@ -13,6 +45,110 @@ type sub struct {
 	topic   *Topic
 	handler Handler
 	once    bool
+	async   bool        // Always run in own goroutine
+	inline  bool        // Run in publisher goroutine for async publishes
+	removed atomic.Bool // Handler asked to remove the subscription
+
+	inFlight     chan struct{} // Slots of running invocations, nil if unlimited
+	dropWhenBusy bool          // Drop deliveries when inFlight is full
+	serial       *serialQueue  // nil if invocations may run concurrently
+	name         string        // Handler name for Export, see Name
+
+	ack        *AckConfig // nil unless deliveries must be acknowledged
+	durable    string     // Name of durable subscription, see Durable
+	group      string     // Queue group, see QueueGroup
+	stickyBy   string     // Attribute selecting the group member, see StickyBy
+	consistent bool       // stickyBy uses consistent hashing, see ConsistentHashBy
+
+	sampleN    uint64        // Deliver 1 of every sampleN events, 0 or 1 for all
+	sampleRate float64       // Probability of delivery, used if 0 < sampleRate < 1
+	sampleSeen atomic.Uint64 // Events matched by a Sample subscription
+}
+
+// serialQueue runs async deliveries of a Serialized subscription
+// one by one in publish order within a priority lane
+type serialQueue struct {
+	exec    sync.Mutex // Held during every invocation, including inline ones
+	mu      sync.Mutex // Protects queue and running
+	queue   laneQueue
+	running bool
+}
+
+// push adds delivery to lane prio of the queue and starts a worker
+// with spawn if there is none
+func (q *serialQueue) push(prio int, fn func(), spawn func(prio int, task func())) {
+	if q.enqueue(prio, fn) {
+		spawn(prio, q.work)
+	}
+}
+
+// enqueue adds delivery to lane prio of the queue.
+// Returns true if the caller must start a worker running q.work.
+func (q *serialQueue) enqueue(prio int, fn func()) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queue.push(prio, fn)
+	if q.running {
+		return false
+	}
+	q.running = true
+	return true
+}
+
+// work runs queued deliveries until the queue is empty
+func (q *serialQueue) work() {
+	for {
+		q.mu.Lock()
+		fn := q.queue.pop()
+		if fn == nil {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+		fn()
+	}
+}
+
+// sampled reports whether the event must be delivered according to
+// Sample and SampleRate of the subscription
+func (s *sub) sampled() bool {
+	if s.sampleN > 1 && (s.sampleSeen.Add(1)-1)%s.sampleN != 0 {
+		return false
+	}
+	if s.sampleRate > 0 && s.sampleRate < 1 && rand.Float64() >= s.sampleRate {
+		return false
+	}
+	return true
+}
+
+// acquire takes an invocation slot if the subscription has MaxInFlight.
+// Returns false if the delivery must be dropped.
+func (s *sub) acquire(ctx context.Context) bool {
+	if s.inFlight == nil {
+		return true
+	}
+	select {
+	case s.inFlight <- struct{}{}:
+		return true
+	default:
+	}
+	if s.dropWhenBusy {
+		return false
+	}
+	select {
+	case s.inFlight <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees the slot taken by acquire
+func (s *sub) release() {
+	if s.inFlight != nil {
+		<-s.inFlight
+	}
 }
 
 func (s *sub) call(ctx context.Context, e *event) error {
@@ -20,12 +156,42 @@ func (s *sub) call(ctx context.Context, e *event) error {
 	if s.once && c > 1 {
 		return nil
 	}
+	if s.removed.Load() {
+		return nil
+	}
 	if s.handler != nil {
-		return s.handler(ctx, e.topic, e.payload)
+		return s.handler(context.WithValue(ctx, ctxKeySub, s), e.topic, e.payload)
 	}
 	return nil
 }
 
+// runInline reports whether the handler runs in the publisher goroutine
+// for async publishes. Async takes precedence over Inline.
+func (s *sub) runInline() bool {
+	return s.inline && !s.async
+}
+
 func (s *sub) shouldRemove() bool {
-	return s.once && s.counter.Load() > 0
+	return (s.once && s.counter.Load() > 0) || s.removed.Load()
+}
+
+// UnsubscribeSelf removes the subscription whose handler is running.
+// The handler is not called for further events, the subscription is
+// removed from the hub after the handler returns.
+// Returns false if called outside of a handler.
+//
+// Example:
+//
+//	h.Subscribe(ctx, hub.T("type=job"), func(ctx context.Context, status string) {
+//	    if status == "done" {
+//	        hub.UnsubscribeSelf(ctx)
+//	    }
+//	})
+func UnsubscribeSelf(ctx context.Context) bool {
+	s, ok := ctx.Value(ctxKeySub).(*sub)
+	if !ok {
+		return false
+	}
+	s.removed.Store(true)
+	return true
 }
@@ -3,6 +3,9 @@ package hub
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"sync"
 	"testing"
 )
 
@@ -45,3 +48,103 @@ func TestSubCall(t *testing.T) {
 		}
 	})
 }
+
+func TestUnsubscribeSelf(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	if UnsubscribeSelf(ctx) {
+		t.Error("UnsubscribeSelf() should fail outside of handler")
+	}
+
+	var reported []error
+	h := New(OnError(func(ctx context.Context, _ *Topic, _ SubID, err error) {
+		reported = append(reported, err)
+	}))
+
+	var viaCall, viaErr []string
+	_, err := h.Subscribe(ctx, T("type=job"), func(ctx context.Context, status string) {
+		viaCall = append(viaCall, status)
+		if status == "done" && !UnsubscribeSelf(ctx) {
+			t.Error("UnsubscribeSelf() failed inside handler")
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.Subscribe(ctx, T("type=job"), func(ctx context.Context, status string) error {
+		viaErr = append(viaErr, status)
+		if status == "done" {
+			return fmt.Errorf("finished: %w", ErrUnsubscribe)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range []string{"running", "done", "after"} {
+		_ = h.Publish(ctx, T("type=job"), status, Sync(true))
+	}
+
+	want := []string{"running", "done"}
+	if !slices.Equal(viaCall, want) {
+		t.Errorf("UnsubscribeSelf handler got %v, want %v", viaCall, want)
+	}
+	if !slices.Equal(viaErr, want) {
+		t.Errorf("ErrUnsubscribe handler got %v, want %v", viaErr, want)
+	}
+	if n := len(h.subs); n != 0 {
+		t.Errorf("subscriptions = %d, want 0", n)
+	}
+	if len(reported) != 0 {
+		t.Errorf("ErrUnsubscribe reported to OnError: %v", reported)
+	}
+}
+
+func TestStopPropagation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var reported []error
+	h := New(OnError(func(ctx context.Context, _ *Topic, _ SubID, err error) {
+		reported = append(reported, err)
+	}))
+
+	var mu sync.Mutex
+	var calls []string
+	_, _ = h.Subscribe(ctx, T("type=order"), func(ctx context.Context, amount int) error {
+		mu.Lock()
+		calls = append(calls, "validate")
+		mu.Unlock()
+		if amount <= 0 {
+			return fmt.Errorf("invalid amount %d: %w", amount, ErrStopPropagation)
+		}
+		return nil
+	})
+	_, _ = h.Subscribe(ctx, T("type=order"), func(ctx context.Context, amount int) {
+		mu.Lock()
+		calls = append(calls, "process")
+		mu.Unlock()
+	})
+
+	_ = h.Publish(ctx, T("type=order"), 10, Sync(true))
+	_ = h.Publish(ctx, T("type=order"), -1, Sync(true))
+
+	want := []string{"validate", "process", "validate"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if len(reported) != 0 {
+		t.Errorf("ErrStopPropagation reported to OnError: %v", reported)
+	}
+
+	// Async delivery ignores it
+	calls = nil
+	_ = h.Publish(ctx, T("type=order"), -1, Wait(true))
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 {
+		t.Errorf("async calls = %v, want both handlers", calls)
+	}
+}
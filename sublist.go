@@ -9,40 +9,6 @@ type sublist struct {
 	lst []*sub
 }
 
-// add inserts new subscription while maintaining sort order by SubID
-func (sl *sublist) add(s *sub) {
-	// Find insertion point using binary search
-	idx := sort.Search(len(sl.lst), func(i int) bool {
-		return sl.lst[i].id >= s.id
-	})
-
-	// Grow slice
-	sl.lst = append(sl.lst, nil)
-
-	// Shift elements if needed
-	if idx < len(sl.lst)-1 {
-		copy(sl.lst[idx+1:], sl.lst[idx:])
-	}
-
-	// Insert new sub
-	sl.lst[idx] = s
-}
-
-// remove deletes subscription by ID while maintaining order
-func (sl *sublist) remove(id SubID) {
-	// Find index using binary search
-	idx := sort.Search(len(sl.lst), func(i int) bool {
-		return sl.lst[i].id >= id
-	})
-
-	// Check if found
-	if idx < len(sl.lst) && sl.lst[idx].id == id {
-		// Remove by shifting
-		copy(sl.lst[idx:], sl.lst[idx+1:])
-		sl.lst = sl.lst[:len(sl.lst)-1]
-	}
-}
-
 // find returns subscription index or -1 if not found
 func (sl *sublist) find(id SubID) int {
 	idx := sort.Search(len(sl.lst), func(i int) bool {
@@ -65,10 +31,17 @@ func (sl *sublist) len() int {
 
 // mergeSubLists returns an iterator over all subscriptions from given sublists.
 // Lists must be sorted by SubID. Duplicates are automatically skipped.
+// Up to 8 lists are merged without allocations.
 func mergeSubLists(lists ...*sublist) iter.Seq[*sub] {
 	return func(yield func(*sub) bool) {
 		// Create pointers array for each list
-		indices := make([]int, len(lists))
+		var buf [8]int
+		indices := buf[:0]
+		if len(lists) <= len(buf) {
+			indices = buf[:len(lists)]
+		} else {
+			indices = make([]int, len(lists))
+		}
 		prevID := SubID(0) // Tracks last emitted ID
 
 		for {
@@ -1,77 +1,14 @@
 package hub
 
 import (
+	"slices"
+	"sync"
 	"testing"
 )
 
-func Test_sublist_add(t *testing.T) {
-	sl := &sublist{}
-
-	t.Run("add to empty list", func(t *testing.T) {
-		sl.add(&sub{id: 2})
-		if len(sl.lst) != 1 || sl.lst[0].id != 2 {
-			t.Errorf("add() failed, got %v, want [2]", sl.lst)
-		}
-	})
-
-	t.Run("add in order", func(t *testing.T) {
-		sl.add(&sub{id: 1})
-		sl.add(&sub{id: 3})
-		if len(sl.lst) != 3 || sl.lst[0].id != 1 || sl.lst[1].id != 2 || sl.lst[2].id != 3 {
-			t.Errorf("add() ordering failed, got %v, want [1 2 3]", sl.lst)
-		}
-	})
-
-	t.Run("add duplicate", func(t *testing.T) {
-		prevLen := len(sl.lst)
-		sl.add(&sub{id: 2}) // Дубликат
-		if len(sl.lst) != prevLen+1 {
-			t.Error("add() should allow duplicates")
-		}
-	})
-}
-
-func Test_sublist_remove(t *testing.T) {
-	sl := &sublist{}
-
-	// Setup initial state
-	sl.add(&sub{id: 1})
-	sl.add(&sub{id: 2})
-	sl.add(&sub{id: 3})
-
-	t.Run("remove middle", func(t *testing.T) {
-		sl.remove(2)
-		if len(sl.lst) != 2 || sl.lst[0].id != 1 || sl.lst[1].id != 3 {
-			t.Errorf("remove() failed, got %v, want [1 3]", sl.lst)
-		}
-	})
-
-	t.Run("remove first", func(t *testing.T) {
-		sl.remove(1)
-		if len(sl.lst) != 1 || sl.lst[0].id != 3 {
-			t.Errorf("remove() failed, got %v, want [3]", sl.lst)
-		}
-	})
-
-	t.Run("remove last", func(t *testing.T) {
-		sl.remove(3)
-		if len(sl.lst) != 0 {
-			t.Errorf("remove() failed, got %v, want empty", sl.lst)
-		}
-	})
-
-	t.Run("remove non-existent", func(t *testing.T) {
-		sl.remove(99) // Не должен паниковать
-	})
-}
-
 func Test_sublist_find(t *testing.T) {
-	sl := &sublist{}
-
-	// Setup initial state
-	sl.add(&sub{id: 1})
-	sl.add(&sub{id: 3})
-	sl.add(&sub{id: 5})
+	var sl *sublist
+	sl = sl.with(&sub{id: 1}).with(&sub{id: 3}).with(&sub{id: 5})
 
 	tests := []struct {
 		name string
@@ -94,12 +31,10 @@ func Test_sublist_find(t *testing.T) {
 }
 
 func Test_sublist_maintains_order(t *testing.T) {
-	sl := &sublist{}
+	var sl *sublist
 
 	// Добавляем в разном порядке
-	sl.add(&sub{id: 3})
-	sl.add(&sub{id: 1})
-	sl.add(&sub{id: 2})
+	sl = sl.with(&sub{id: 3}).with(&sub{id: 1}).with(&sub{id: 2})
 
 	// Проверяем порядок
 	for i, id := range []SubID{1, 2, 3} {
@@ -109,7 +44,7 @@ func Test_sublist_maintains_order(t *testing.T) {
 	}
 
 	// Удаляем и проверяем порядок
-	sl.remove(2)
+	sl = sl.without(2)
 	if sl.lst[0].id != 1 || sl.lst[1].id != 3 {
 		t.Errorf("Order after remove failed, got %v, want [1 3]", sl.lst)
 	}
@@ -220,29 +155,111 @@ func Test_sublist_len(t *testing.T) {
 	})
 
 	t.Run("single element", func(t *testing.T) {
-		sl := &sublist{}
-		sl.add(&sub{id: 1})
+		sl := (*sublist)(nil).with(&sub{id: 1})
 		if got := sl.len(); got != 1 {
 			t.Errorf("len() = %v, want 1 for single element", got)
 		}
 	})
 
 	t.Run("multiple elements", func(t *testing.T) {
-		sl := &sublist{}
-		sl.add(&sub{id: 1})
-		sl.add(&sub{id: 2})
+		sl := (*sublist)(nil).with(&sub{id: 1}).with(&sub{id: 2})
 		if got := sl.len(); got != 2 {
 			t.Errorf("len() = %v, want 2 for two elements", got)
 		}
 	})
 
 	t.Run("after removal", func(t *testing.T) {
-		sl := &sublist{}
-		sl.add(&sub{id: 1})
-		sl.add(&sub{id: 2})
-		sl.remove(1)
+		sl := (*sublist)(nil).with(&sub{id: 1}).with(&sub{id: 2}).without(1)
 		if got := sl.len(); got != 1 {
 			t.Errorf("len() = %v, want 1 after removal", got)
 		}
 	})
 }
+
+func Test_sublist_with_without_immutable(t *testing.T) {
+	var sl *sublist
+	sl = sl.with(&sub{id: 1})
+	v1 := sl.with(&sub{id: 3})
+	v2 := v1.with(&sub{id: 4}) // appended in place
+	v3 := v2.with(&sub{id: 2}) // inserted in the middle
+	v4 := v3.without(3)
+
+	check := func(name string, sl *sublist, want ...SubID) {
+		t.Helper()
+		var got []SubID
+		for _, s := range sl.lst {
+			got = append(got, s.id)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	check("v1", v1, 1, 3)
+	check("v2", v2, 1, 3, 4)
+	check("v3", v3, 1, 2, 3, 4)
+	check("v4", v4, 1, 2, 4)
+
+	if v4.without(1).without(2).without(4) != nil {
+		t.Error("without() of last element should return nil")
+	}
+	if v4.without(10) != v4 {
+		t.Error("without() of missing element should return the same list")
+	}
+}
+
+func Test_sublist_with_snapshots(t *testing.T) {
+	// Writers append into spare capacity in place,
+	// snapshots taken by readers must never change
+	sl := &sublist{lst: make([]*sub, 0, 1024)}
+	var (
+		mu   sync.Mutex
+		cur  = sl
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				mu.Lock()
+				snap := cur
+				mu.Unlock()
+				want := slices.Clone(snap.lst)
+				for i := 0; i < 10; i++ {
+					if !slices.Equal(snap.lst, want) {
+						t.Errorf("snapshot of %d subscriptions was modified", len(want))
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= 1000; i++ {
+		mu.Lock()
+		next := cur.with(&sub{id: SubID(i)})
+		if i%7 == 0 {
+			next = next.without(SubID(i - 3))
+		}
+		cur = next
+		mu.Unlock()
+	}
+	close(done)
+	wg.Wait()
+
+	// Removal copies, so the next append goes into fresh capacity
+	// and must not touch the list it was derived from
+	base := sl.with(&sub{id: 1}).with(&sub{id: 2}).without(1)
+	want := slices.Clone(base.lst)
+	_ = base.with(&sub{id: 3})
+	_ = base.with(&sub{id: 0})
+	if !slices.Equal(base.lst, want) {
+		t.Error("base list was modified")
+	}
+}
@@ -0,0 +1,54 @@
+//go:build go1.25
+
+package hub
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestSynctest(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		h := New(WithWorkers(4))
+		start := time.Now()
+
+		var got []int
+		_, _ = h.Subscribe(ctx, T("type=job"), func(ctx context.Context, n int) {
+			time.Sleep(time.Duration(n) * time.Hour)
+			got = append(got, n)
+		}, Serialized(true))
+
+		_ = h.Publish(ctx, T("type=job"), 2)
+		_ = h.Publish(ctx, T("type=job"), 1)
+		if err := h.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0] != 2 || got[1] != 1 {
+			t.Errorf("got %v, want [2 1]", got)
+		}
+		if d := time.Since(start); d != 3*time.Hour {
+			t.Errorf("virtual time elapsed %v, want 3h", d)
+		}
+
+		// delayed publish and TTL use the bubble clock
+		_, _ = h.PublishAfter(ctx, time.Minute, T("type=job"), 0)
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		if err := h.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 3 || got[2] != 0 {
+			t.Errorf("got %v, want delayed 0", got)
+		}
+
+		_ = h.Publish(ctx, T("type=job"), 1)
+		_ = h.Publish(ctx, T("type=job"), 5, EventTTL(time.Minute))
+		_ = h.Drain(ctx)
+		if len(got) != 4 || h.Health().Expired != 1 {
+			t.Errorf("got %v, expired %d, want event with TTL dropped", got, h.Health().Expired)
+		}
+	})
+}
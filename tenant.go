@@ -0,0 +1,254 @@
+package hub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// AttrTenant is the reserved attribute tagging topics of a Tenant
+const AttrTenant = "_tenant"
+
+// TenantQuota limits resources used by a tenant.
+// Zero values of the fields disable corresponding limits.
+type TenantQuota struct {
+	// MaxSubscriptions limits active subscriptions of the tenant
+	MaxSubscriptions int
+	// PublishRate limits events published per second
+	PublishRate float64
+	// PublishBurst is the number of events which may be published at once
+	// above PublishRate, 1 if zero
+	PublishBurst int
+	// MaxPending limits events of the tenant being delivered: published
+	// but not yet processed by all handlers
+	MaxPending int
+}
+
+// TenantStats is a snapshot of tenant activity returned by Tenant.Stats
+type TenantStats struct {
+	Subscriptions int    // Active subscriptions
+	Pending       int64  // Events being delivered
+	Published     uint64 // Events published
+	Rejected      uint64 // Publish and Subscribe calls rejected by the quota
+}
+
+// Tenant is a namespace of a hub returned by Hub.Tenant. Topics passed to
+// its methods are tagged with AttrTenant, so tenant subscriptions receive
+// only events published by the same tenant, and Durable subscription
+// names don't clash with other tenants. Subscriptions made on the hub
+// itself receive events of all tenants. It is safe for concurrent use.
+type Tenant struct {
+	h      *Hub
+	name   string
+	closed atomic.Bool
+
+	mu     sync.Mutex
+	quota  TenantQuota
+	subs   map[SubID]struct{}
+	adding int          // Subscribe calls in progress
+	rate   *rateLimiter // nil if publish rate is not limited
+
+	pending   atomic.Int64
+	published atomic.Uint64
+	rejected  atomic.Uint64
+}
+
+var _ Interface = (*Tenant)(nil)
+
+// Tenant returns the namespace of tenant name, creating it on the first
+// call. Later calls return the same Tenant, quotas are set with SetQuota.
+//
+// Example:
+//
+//	acme := h.Tenant("acme")
+//	acme.SetQuota(hub.TenantQuota{MaxSubscriptions: 100, PublishRate: 50})
+//	acme.Subscribe(ctx, hub.T("type=order"), handler) // acme orders only
+//	acme.Publish(ctx, hub.T("type=order"), order)
+func (h *Hub) Tenant(name string) *Tenant {
+	h.tenantsMu.Lock()
+	defer h.tenantsMu.Unlock()
+	if t, ok := h.tenants[name]; ok {
+		return t
+	}
+	if h.tenants == nil {
+		h.tenants = make(map[string]*Tenant)
+	}
+	t := &Tenant{h: h, name: name, subs: make(map[SubID]struct{})}
+	h.tenants[name] = t
+	return t
+}
+
+// Name returns the tenant name
+func (t *Tenant) Name() string {
+	return t.name
+}
+
+// SetQuota replaces the quota of the tenant. Existing subscriptions
+// exceeding the new MaxSubscriptions are kept.
+func (t *Tenant) SetQuota(q TenantQuota) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.quota = q
+	t.rate = nil
+	if q.PublishRate > 0 {
+		t.rate = newRateLimiter(q.PublishRate, q.PublishBurst)
+	}
+}
+
+// tag returns topic with AttrTenant of the tenant
+func (t *Tenant) tag(topic *Topic) *Topic {
+	if topic == nil {
+		topic = &Topic{}
+	}
+	return &Topic{mp: topic.mp.Set(AttrTenant, t.name)}
+}
+
+// Subscribe subscribes to events of the tenant matching topic,
+// see Hub.Subscribe. Returns QuotaError if the tenant has
+// MaxSubscriptions active subscriptions.
+func (t *Tenant) Subscribe(ctx context.Context, topic *Topic, cb any, opts ...SubscribeOption) (SubID, error) {
+	if t.closed.Load() {
+		return 0, ErrClosed
+	}
+	opts = append([]SubscribeOption(nil), opts...)
+	for i, o := range opts {
+		if d, ok := o.(*optionSubscribeDurable); ok && d.v != "" {
+			opts[i] = Durable(t.name + "/" + d.v)
+		}
+	}
+
+	// the slot is held while subscribing, so concurrent calls can't
+	// exceed the quota
+	t.mu.Lock()
+	t.prune()
+	if limit := t.quota.MaxSubscriptions; limit > 0 && len(t.subs)+t.adding >= limit {
+		t.mu.Unlock()
+		t.rejected.Add(1)
+		return 0, &QuotaError{Quota: QuotaSubscriptions, Limit: float64(limit), Topic: topic}
+	}
+	t.adding++
+	t.mu.Unlock()
+
+	id, err := t.h.Subscribe(ctx, t.tag(topic), cb, opts...)
+
+	t.mu.Lock()
+	t.adding--
+	if err == nil {
+		t.subs[id] = struct{}{}
+	}
+	t.mu.Unlock()
+	if err == nil && t.closed.Load() {
+		// Close was called while subscribing
+		t.Unsubscribe(ctx, id)
+		return 0, ErrClosed
+	}
+	return id, err
+}
+
+// prune forgets removed subscriptions, e.g. ones with Once.
+// Must be called under t.mu.
+func (t *Tenant) prune() {
+	for id := range t.subs {
+		if !t.h.has(id) {
+			delete(t.subs, id)
+		}
+	}
+}
+
+// Publish publishes event of the tenant, see Hub.Publish. Returns
+// QuotaError if PublishRate or MaxPending is exceeded.
+func (t *Tenant) Publish(ctx context.Context, topic *Topic, payload any, opts ...PublishOption) error {
+	if t.closed.Load() {
+		return ErrClosed
+	}
+	if err := t.allow(topic); err != nil {
+		t.rejected.Add(1)
+		return err
+	}
+	// with WaitFirstError a handler error is returned after OnFinish
+	var released atomic.Bool
+	release := func(context.Context) {
+		if released.CompareAndSwap(false, true) {
+			t.pending.Add(-1)
+		}
+	}
+	opts = append(opts[:len(opts):len(opts)], OnFinish(release))
+	err := t.h.Publish(ctx, t.tag(topic), payload, opts...)
+	if err != nil {
+		release(ctx)
+		return err
+	}
+	t.published.Add(1)
+	return nil
+}
+
+// allow takes a publish token and a pending slot
+func (t *Tenant) allow(topic *Topic) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if limit := t.quota.MaxPending; limit > 0 && t.pending.Load() >= int64(limit) {
+		return &QuotaError{Quota: QuotaPending, Limit: float64(limit), Topic: topic}
+	}
+	if t.rate != nil && !t.rate.allow(t.h.clock.Now()) {
+		return &QuotaError{Quota: QuotaPublishRate, Limit: t.quota.PublishRate, Topic: topic}
+	}
+	t.pending.Add(1)
+	return nil
+}
+
+// Unsubscribe removes subscription of the tenant, subscriptions of
+// other tenants and of the hub itself are not touched
+func (t *Tenant) Unsubscribe(ctx context.Context, id SubID) {
+	t.mu.Lock()
+	_, ok := t.subs[id]
+	delete(t.subs, id)
+	t.mu.Unlock()
+	if ok {
+		t.h.Unsubscribe(ctx, id)
+	}
+}
+
+// Len returns the number of active subscriptions of the tenant
+func (t *Tenant) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune()
+	return len(t.subs)
+}
+
+// Close removes all subscriptions of the tenant, further Subscribe and
+// Publish calls return ErrClosed. The hub and other tenants keep working.
+func (t *Tenant) Close() error {
+	if t.closed.Swap(true) {
+		return nil
+	}
+	t.mu.Lock()
+	ids := make([]SubID, 0, len(t.subs))
+	for id := range t.subs {
+		ids = append(ids, id)
+	}
+	clear(t.subs)
+	t.mu.Unlock()
+	for _, id := range ids {
+		t.h.Unsubscribe(context.Background(), id)
+	}
+	return nil
+}
+
+// Stats returns current activity of the tenant
+func (t *Tenant) Stats() TenantStats {
+	return TenantStats{
+		Subscriptions: t.Len(),
+		Pending:       t.pending.Load(),
+		Published:     t.published.Load(),
+		Rejected:      t.rejected.Load(),
+	}
+}
+
+// has returns true if subscription id is active
+func (h *Hub) has(id SubID) bool {
+	h.RLock()
+	defer h.RUnlock()
+	_, ok := h.subs[id]
+	return ok
+}
@@ -0,0 +1,125 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestTenant(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx := context.Background()
+	acme, other := h.Tenant("acme"), h.Tenant("other")
+	if h.Tenant("acme") != acme {
+		t.Fatal("Tenant() returned a new instance for the same name")
+	}
+
+	var got, all []string
+	if _, err := acme.Subscribe(ctx, T("type=order"), func(ctx context.Context, s string) {
+		got = append(got, s)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Subscribe(ctx, T("type=order"), func(ctx context.Context, s string) {
+		all = append(all, TopicFromContext(ctx).Get(AttrTenant)+":"+s)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range []error{
+		acme.Publish(ctx, T("type=order"), "a1", Sync(true)),
+		other.Publish(ctx, T("type=order"), "o1", Sync(true)),
+		h.Publish(ctx, T("type=order"), "h1", Sync(true)),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(got, []string{"a1"}) {
+		t.Errorf("tenant received %q, want [a1]", got)
+	}
+	if !slices.Equal(all, []string{"acme:a1", "other:o1", ":h1"}) {
+		t.Errorf("hub received %q", all)
+	}
+
+	// other tenant can't remove subscriptions of acme
+	id, _ := acme.Subscribe(ctx, T("type=x"), func(ctx context.Context) {})
+	other.Unsubscribe(ctx, id)
+	if acme.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", acme.Len())
+	}
+
+	if err := acme.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 1 {
+		t.Errorf("hub Len() after tenant Close = %d, want 1", h.Len())
+	}
+	if err := acme.Publish(ctx, T("type=order"), "a2"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish() after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestTenantQuota(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx := context.Background()
+	acme := h.Tenant("acme")
+	acme.SetQuota(TenantQuota{MaxSubscriptions: 1, PublishRate: 0.001, PublishBurst: 2})
+
+	if _, err := acme.Subscribe(ctx, T("type=a"), func(ctx context.Context) {}, Once(true)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acme.Subscribe(ctx, T("type=b"), func(ctx context.Context) {}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Subscribe() over quota = %v, want ErrQuotaExceeded", err)
+	}
+
+	// the burst is spent, Once subscription is removed
+	for i := range 3 {
+		err := acme.Publish(ctx, T("type=a"), nil, Sync(true))
+		if want := i == 2; errors.Is(err, ErrQuotaExceeded) != want {
+			t.Errorf("Publish() #%d = %v", i+1, err)
+		}
+	}
+	if _, err := acme.Subscribe(ctx, T("type=b"), func(ctx context.Context) {}); err != nil {
+		t.Errorf("Subscribe() after Once removal = %v", err)
+	}
+
+	want := TenantStats{Subscriptions: 1, Published: 2, Rejected: 2}
+	if got := acme.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTenantMaxPending(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx := context.Background()
+	acme := h.Tenant("acme")
+	acme.SetQuota(TenantQuota{MaxPending: 1})
+
+	release := make(chan struct{})
+	if _, err := acme.Subscribe(ctx, T("type=slow"), func(ctx context.Context) {
+		<-release
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.Publish(ctx, T("type=slow"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.Publish(ctx, T("type=slow"), nil); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Publish() over MaxPending = %v, want ErrQuotaExceeded", err)
+	}
+	close(release)
+	if err := h.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.Publish(ctx, T("type=slow"), nil); err != nil {
+		t.Errorf("Publish() after delivery = %v", err)
+	}
+}
@@ -1,6 +1,16 @@
 package hub
 
-import "github.com/lomik/hub/pkg/kv"
+import (
+	"fmt"
+	"iter"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/lomik/hub/pkg/kv"
+	"github.com/spf13/cast"
+)
 
 // Any is a special value that matches any other value in topic matching
 const Any string = "*"
@@ -16,6 +26,11 @@ type Topic struct {
 //   - "key=value" strings
 //   - Separate "key", "value" arguments
 //
+// In "key=value" format several alternative values can be listed
+// with '|' separator: "priority=high|critical".
+// Operator "!=" matches everything except listed values: "level!=debug".
+// Operators "<", "<=", ">", ">=" compare values as numbers: "severity>=3".
+//
 // Returns error if input format is invalid.
 //
 // Example:
@@ -43,6 +58,52 @@ func T(args ...string) *Topic {
 	return &Topic{mp: mp}
 }
 
+// TopicBuilder constructs topics reusing its internal buffer.
+// Useful when many topics are built per second, e.g. per request.
+// The zero value is ready to use. Not safe for concurrent use.
+//
+// Example:
+//
+//	var b hub.TopicBuilder
+//	for _, u := range users {
+//	    b.Set("type", "user")
+//	    b.Set("id", u.ID)
+//	    h.Publish(ctx, b.Topic(), u)
+//	}
+type TopicBuilder struct {
+	b kv.Builder
+}
+
+// Set adds attribute, key and value are taken literally
+func (tb *TopicBuilder) Set(k, v string) *TopicBuilder {
+	tb.b.Add(k, v)
+	return tb
+}
+
+// Parse adds attributes in the format of NewTopic.
+// On error the builder is left unchanged.
+func (tb *TopicBuilder) Parse(args ...string) error {
+	return tb.b.Parse(args...)
+}
+
+// Topic returns a Topic of added attributes and resets the builder
+func (tb *TopicBuilder) Topic() *Topic {
+	return &Topic{mp: tb.b.Map()}
+}
+
+// TFromQuery creates a new Topic from URL query parameters.
+// Parameter with several values becomes a multi-value attribute.
+// Values are taken literally, operators and escaping are not interpreted.
+//
+// Example:
+//
+//	// ?type=alert&priority=high&priority=critical
+//	t := TFromQuery(r.URL.Query())
+//	// t is type=alert, priority=high|critical
+func TFromQuery(q url.Values) *Topic {
+	return &Topic{mp: kv.FromValues(q)}
+}
+
 // With creates a new Topic by merging current attributes with new ones.
 // New attributes override existing ones with the same keys.
 // Panics if new attributes have invalid format.
@@ -60,6 +121,48 @@ func (t *Topic) With(args ...string) *Topic {
 	return &Topic{mp: t.mp.Merge(other)}
 }
 
+// Without creates a new Topic without the specified keys.
+// Missing keys are ignored.
+//
+// Example:
+//
+//	t1 := T("type=alert", "severity=high")
+//	t2 := t1.Without("severity")
+//	// t2 now has: type=alert
+func (t *Topic) Without(keys ...string) *Topic {
+	mp := t.mp
+	for _, k := range keys {
+		mp = mp.Delete(k)
+	}
+	return &Topic{mp: mp}
+}
+
+// WithInt creates a new Topic with the key set to the integer value.
+//
+// Example:
+//
+//	t2 := t.WithInt("attempt", 3) // attempt=3
+func (t *Topic) WithInt(k string, v int) *Topic {
+	return &Topic{mp: t.mp.Set(k, strconv.Itoa(v))}
+}
+
+// WithBool creates a new Topic with the key set to "true" or "false".
+func (t *Topic) WithBool(k string, v bool) *Topic {
+	return &Topic{mp: t.mp.Set(k, strconv.FormatBool(v))}
+}
+
+// WithTime creates a new Topic with the key set to the time
+// formatted as RFC3339 with nanoseconds.
+func (t *Topic) WithTime(k string, v time.Time) *Topic {
+	return &Topic{mp: t.mp.Set(k, v.Format(time.RFC3339Nano))}
+}
+
+// WithDuration creates a new Topic with the key set to the duration
+// formatted like "1m30s".
+func (t *Topic) WithDuration(k string, v time.Duration) *Topic {
+	return &Topic{mp: t.mp.Set(k, v.String())}
+}
+
 // Get returns the value for the specified key.
 // Returns empty string if key doesn't exist.
 //
@@ -71,6 +174,56 @@ func (t *Topic) Get(k string) string {
 	return t.mp.Get(k)
 }
 
+// GetInt returns the value for the specified key converted to int.
+// Returns ErrKeyNotFound if key doesn't exist or CastError if the value
+// can't be converted.
+//
+// Example:
+//
+//	t := T("attempt=3")
+//	n, err := t.GetInt("attempt") // returns 3, nil
+func (t *Topic) GetInt(k string) (int, error) {
+	return getTyped(t, k, cast.ToIntE)
+}
+
+// GetBool returns the value for the specified key converted to bool.
+// Returns ErrKeyNotFound if key doesn't exist or CastError if the value
+// can't be converted.
+func (t *Topic) GetBool(k string) (bool, error) {
+	return getTyped(t, k, cast.ToBoolE)
+}
+
+// GetTime returns the value for the specified key converted to time.Time.
+// Most common formats including RFC3339 are supported.
+// Returns ErrKeyNotFound if key doesn't exist or CastError if the value
+// can't be converted.
+func (t *Topic) GetTime(k string) (time.Time, error) {
+	return getTyped(t, k, cast.ToTimeE)
+}
+
+// GetDuration returns the value for the specified key converted to time.Duration.
+// Values like "1m30s" are parsed with time.ParseDuration,
+// plain numbers are treated as nanoseconds.
+// Returns ErrKeyNotFound if key doesn't exist or CastError if the value
+// can't be converted.
+func (t *Topic) GetDuration(k string) (time.Duration, error) {
+	return getTyped(t, k, cast.ToDurationE)
+}
+
+// getTyped looks up the key and converts its value with castFunc
+func getTyped[T any](t *Topic, k string, castFunc func(any) (T, error)) (T, error) {
+	var zero T
+	if !t.mp.Has(k) {
+		return zero, fmt.Errorf("%w: %s", ErrKeyNotFound, k)
+	}
+	p := t.mp.Get(k)
+	v, err := castFunc(p)
+	if err != nil {
+		return zero, newPayloadCastError(err, reflect.TypeFor[T](), p, t)
+	}
+	return v, nil
+}
+
 // Each iterates over all key-value pairs in the Topic.
 // Pairs are processed in sorted key order.
 //
@@ -87,10 +240,38 @@ func (t *Topic) Each(cb func(k, v string)) {
 	t.mp.Each(cb)
 }
 
+// All returns an iterator over key-value pairs in sorted key order.
+// Unlike Each it supports break and doesn't allocate.
+//
+// Example:
+//
+//	for k, v := range t.All() {
+//	    fmt.Printf("%s=%s\n", k, v)
+//	}
+func (t *Topic) All() iter.Seq2[string, string] {
+	return t.mp.All()
+}
+
+// eachPair iterates over all attributes as kv pairs in sorted key order
+func (t *Topic) eachPair(cb func(p kv.KV)) {
+	t.mp.EachPair(cb)
+}
+
+// pairs returns an iterator over all attributes as kv pairs in sorted key order
+func (t *Topic) pairs() iter.Seq[kv.KV] {
+	return t.mp.Pairs()
+}
+
 // Match checks if this Topic matches another Topic.
 // A Topic matches if:
 //   - All keys in this Topic exist in the other Topic
 //   - Corresponding values are equal or one of them is Any ("*")
+//   - For multi-value attributes ("priority=high|critical") any of
+//     the alternatives satisfies the rule above
+//   - For negated attributes ("level!=debug") the value in the other
+//     Topic is Any or differs from all listed values
+//   - For numeric attributes ("severity>=3") the value in the other
+//     Topic is Any or a number satisfying the comparison
 //
 // Does not consider additional keys in the other Topic.
 //
@@ -103,6 +284,83 @@ func (t *Topic) Match(other *Topic) bool {
 	return t.mp.Match(other.mp)
 }
 
+// Equal reports whether both Topics have exactly the same attributes.
+// Wildcard values are compared literally, use Match for pattern matching.
+//
+// Example:
+//
+//	T("a=1", "b=2").Equal(T("b=2", "a=1")) // returns true
+//	T("a=*").Equal(T("a=1"))               // returns false
+func (t *Topic) Equal(other *Topic) bool {
+	return t.mp.Equal(other.mp)
+}
+
+// Compare defines a total order over Topics suitable for sorting.
+// Attributes are compared pairwise in sorted key order.
+// Returns -1 if t < other, 0 if t == other and +1 if t > other.
+//
+// Example:
+//
+//	slices.SortFunc(topics, (*Topic).Compare)
+func (t *Topic) Compare(other *Topic) int {
+	return t.mp.Compare(other.mp)
+}
+
+// Hash returns a stable 64-bit hash of the Topic attributes.
+// Equal topics always have equal hashes regardless of the order
+// in which attributes were provided. Collisions are possible,
+// so use Equal to confirm identity when it matters.
+//
+// Example:
+//
+//	cache := map[uint64][]*Topic{}
+//	cache[t.Hash()] = append(cache[t.Hash()], t)
+func (t *Topic) Hash() uint64 {
+	return t.mp.Hash()
+}
+
+// Query converts the Topic to URL query parameters, TFromQuery converts
+// them back. Multi-value attributes are expanded to several values of the
+// same key. Operators other than "=" are not representable in query,
+// topics with them return ErrQueryOperator.
+//
+// Example:
+//
+//	q, err := T("type=alert", "priority=high").Query()
+//	u.RawQuery = q.Encode() // priority=high&type=alert
+func (t *Topic) Query() (url.Values, error) {
+	q := make(url.Values, t.Len())
+	for p := range t.pairs() {
+		if p.Op() != kv.OpEq {
+			return nil, fmt.Errorf("%w: %s", ErrQueryOperator, p)
+		}
+		q[p.Key()] = append(q[p.Key()], p.Values()...)
+	}
+	return q, nil
+}
+
+// String returns canonical text form of the Topic: sorted attributes
+// separated by spaces, special characters are escaped by backslash.
+//
+// Example:
+//
+//	T("type=alert", "priority=high").String() // "priority=high type=alert"
+func (t *Topic) String() string {
+	return t.mp.String()
+}
+
+// MarshalText implements encoding.TextMarshaler, so topics can be used
+// in JSON/YAML configs and flags. The format is the same as String.
+func (t *Topic) MarshalText() ([]byte, error) {
+	return t.mp.MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// Accepts text in the format produced by MarshalText.
+func (t *Topic) UnmarshalText(text []byte) error {
+	return t.mp.UnmarshalText(text)
+}
+
 // Len returns the number of key-value pairs
 func (t *Topic) Len() int {
 	return t.mp.Len()
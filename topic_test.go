@@ -1,8 +1,13 @@
 package hub
 
 import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewTopic(t *testing.T) {
@@ -121,6 +126,17 @@ func TestTopic_With(t *testing.T) {
 	})
 }
 
+func TestTopic_Without(t *testing.T) {
+	base := T("type=alert", "priority=high", "source=server")
+	got := base.Without("priority", "missing")
+	if got.String() != "source=server type=alert" {
+		t.Errorf("Without() = %v", got.String())
+	}
+	if base.Len() != 3 {
+		t.Error("Original topic was modified")
+	}
+}
+
 func TestTopic_Get(t *testing.T) {
 	topic := T("type=alert", "priority=high")
 
@@ -162,6 +178,47 @@ func TestTopic_Each(t *testing.T) {
 	}
 }
 
+func TestTopicBuilder(t *testing.T) {
+	var b TopicBuilder
+	b.Set("type", "alert").Set("id", "a=b")
+	if err := b.Parse("level!=debug"); err != nil {
+		t.Fatal(err)
+	}
+	got := b.Topic()
+	if want := T("type=alert", "id", "a=b", "level!=debug"); !got.Equal(want) {
+		t.Errorf("Topic() = %s, want %s", got, want)
+	}
+	if b.Topic().Len() != 0 {
+		t.Error("builder is not reset by Topic()")
+	}
+}
+
+func TestTopic_All(t *testing.T) {
+	topic := T("c=3", "b=2", "a=1")
+
+	var got []string
+	for k, v := range topic.All() {
+		got = append(got, k+"="+v)
+		if k == "b" {
+			break
+		}
+	}
+
+	want := []string{"a=1", "b=2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for k, v := range topic.All() {
+			_, _ = k, v
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("All() allocs = %v, want 0", allocs)
+	}
+}
+
 func TestTopic_Match(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -217,6 +274,30 @@ func TestTopic_Match(t *testing.T) {
 			b:      "type=alert priority=high",
 			expect: true,
 		},
+		{
+			name:   "negation",
+			a:      "type=log level!=debug",
+			b:      "type=log level=info",
+			expect: true,
+		},
+		{
+			name:   "negation mismatch",
+			a:      "type=log level!=debug",
+			b:      "type=log level=debug",
+			expect: false,
+		},
+		{
+			name:   "negation requires key",
+			a:      "type=log level!=debug",
+			b:      "type=log",
+			expect: false,
+		},
+		{
+			name:   "negation of multiple values",
+			a:      "level!=debug|trace",
+			b:      "level=trace",
+			expect: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -239,11 +320,156 @@ func TestTopic_Match(t *testing.T) {
 	}
 }
 
-// Helper method for string representation
-func (t *Topic) String() string {
-	var s []string
-	t.Each(func(k, v string) {
-		s = append(s, k+"="+v)
+func TestTopic_Equal(t *testing.T) {
+	if !T("type=alert", "priority=high").Equal(T("priority=high", "type=alert")) {
+		t.Error("Expected topics with same attributes to be equal")
+	}
+	if T("type=alert").Equal(T("type=*")) {
+		t.Error("Wildcard must be compared literally")
+	}
+	if T("type=alert").Equal(T("type=alert", "priority=high")) {
+		t.Error("Expected topics with different keys to be not equal")
+	}
+}
+
+func TestTopic_Compare(t *testing.T) {
+	topics := []*Topic{
+		T("type=b"),
+		T("type=a", "x=1"),
+		T(),
+		T("type=a"),
+	}
+	slices.SortFunc(topics, (*Topic).Compare)
+
+	want := []string{"", "type=a", "type=a x=1", "type=b"}
+	for i := range want {
+		if topics[i].String() != want[i] {
+			t.Errorf("Sorted[%d] = %q, want %q", i, topics[i].String(), want[i])
+		}
+	}
+
+	if c := T("a=1").Compare(T("a=1")); c != 0 {
+		t.Errorf("Compare() of equal topics = %d, want 0", c)
+	}
+}
+
+func TestTopic_Hash(t *testing.T) {
+	if T("type=alert", "priority=high").Hash() != T("priority=high", "type=alert").Hash() {
+		t.Error("Expected equal topics to have equal hashes")
+	}
+	if T("type=alert").Hash() == T("type=info").Hash() {
+		t.Error("Expected different topics to have different hashes")
+	}
+}
+
+func TestTFromQuery(t *testing.T) {
+	q, err := url.ParseQuery("type=alert&priority=high&priority=critical&empty=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := TFromQuery(q)
+	want := "empty= priority=high|critical type=alert"
+	if got.String() != want {
+		t.Errorf("TFromQuery() = %v, want %v", got.String(), want)
+	}
+
+	if !got.Match(T("type=alert", "priority=critical", "empty=")) {
+		t.Error("Expected multi-value attribute from repeated query parameter")
+	}
+}
+
+func TestTopic_Query(t *testing.T) {
+	q, err := T("type=alert", "priority=high|critical").Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Encode(); got != "priority=high&priority=critical&type=alert" {
+		t.Errorf("Query() = %v", got)
+	}
+
+	if back := TFromQuery(q); !back.Equal(T("type=alert", "priority=high|critical")) {
+		t.Errorf("Round trip = %v", back.String())
+	}
+
+	// dropping the operator would invert the filter
+	for _, topic := range []*Topic{T("level!=debug"), T("n>=5"), T("type=alert", "n<3")} {
+		if q, err := topic.Query(); !errors.Is(err, ErrQueryOperator) {
+			t.Errorf("%s.Query() = %v, %v, want ErrQueryOperator", topic, q.Encode(), err)
+		}
+	}
+}
+
+func TestTopic_TypedGetters(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	topic := T("attempt=3", "retry=true", "bad=abc").
+		WithTime("at", ts).
+		WithDuration("timeout", 90*time.Second)
+
+	t.Run("int", func(t *testing.T) {
+		if v, err := topic.GetInt("attempt"); err != nil || v != 3 {
+			t.Errorf("GetInt() = %v, %v, want 3", v, err)
+		}
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		if v, err := topic.GetBool("retry"); err != nil || !v {
+			t.Errorf("GetBool() = %v, %v, want true", v, err)
+		}
 	})
-	return strings.Join(s, " ")
+
+	t.Run("time", func(t *testing.T) {
+		if v, err := topic.GetTime("at"); err != nil || !v.Equal(ts) {
+			t.Errorf("GetTime() = %v, %v, want %v", v, err, ts)
+		}
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		if v, err := topic.GetDuration("timeout"); err != nil || v != 90*time.Second {
+			t.Errorf("GetDuration() = %v, %v, want 1m30s", v, err)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, err := topic.GetInt("missing"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected ErrKeyNotFound, got %v", err)
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		_, err := topic.GetInt("bad")
+		var ce *CastError
+		if !errors.As(err, &ce) {
+			t.Errorf("Expected CastError, got %v", err)
+		}
+	})
+}
+
+func TestTopic_TypedWith(t *testing.T) {
+	topic := T("type=job").WithInt("attempt", 3).WithBool("retry", false)
+	if got, want := topic.String(), "attempt=3 retry=false type=job"; got != want {
+		t.Errorf("With*() = %v, want %v", got, want)
+	}
+}
+
+func TestTopic_Text(t *testing.T) {
+	var cfg struct {
+		Topic *Topic `json:"topic"`
+	}
+
+	err := json.Unmarshal([]byte(`{"topic": "type=alert priority=high|critical label=a\\ b"}`), &cfg)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !cfg.Topic.Equal(T("type=alert", "priority=high|critical", "label", "a b")) {
+		t.Errorf("Unmarshal() = %v", cfg.Topic)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"topic":"label=a\\ b priority=high|critical type=alert"}`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
 }
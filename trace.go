@@ -0,0 +1,193 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeliveryOutcome describes what happened to a delivery of a traced event
+type DeliveryOutcome int
+
+const (
+	// DeliveryNotCalled means the subscription matched, but the handler
+	// wasn't called (yet), e.g. a previous handler stopped propagation
+	DeliveryNotCalled DeliveryOutcome = iota
+	// DeliveryHandled means the handler returned nil, ErrUnsubscribe or
+	// ErrStopPropagation
+	DeliveryHandled
+	// DeliveryFailed means the handler returned an error
+	DeliveryFailed
+	// DeliveryCanceled means the event was canceled before the call
+	DeliveryCanceled
+	// DeliverySampledOut means the event was skipped by Sample or SampleRate
+	DeliverySampledOut
+	// DeliveryBusy means the delivery was dropped by MaxInFlight
+	DeliveryBusy
+	// DeliveryExpired means the delivery was dropped by EventTTL
+	DeliveryExpired
+)
+
+// String returns human readable outcome
+func (o DeliveryOutcome) String() string {
+	switch o {
+	case DeliveryNotCalled:
+		return "not called"
+	case DeliveryHandled:
+		return "handled"
+	case DeliveryFailed:
+		return "failed"
+	case DeliveryCanceled:
+		return "canceled"
+	case DeliverySampledOut:
+		return "sampled out"
+	case DeliveryBusy:
+		return "busy"
+	case DeliveryExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// DeliveryTrace describes a delivery of a traced event to a subscription
+type DeliveryTrace struct {
+	SubID   SubID           // Matched subscription
+	Name    string          // Handler name, see Name
+	Outcome DeliveryOutcome // What happened to the delivery
+	Start   time.Time       // Handler call start, zero if not called
+	End     time.Time       // Handler call end, zero if not called
+	Err     error           // Error returned by the handler
+}
+
+// EventTrace records deliveries of an event published with Trace(true).
+// It is safe for concurrent use, async deliveries are recorded as they
+// complete.
+type EventTrace struct {
+	ID    EventID // Event ID
+	Topic *Topic  // Event topic
+
+	mu         sync.Mutex
+	deliveries []DeliveryTrace
+}
+
+// Deliveries returns a copy of recorded deliveries in order of matching
+func (t *EventTrace) Deliveries() []DeliveryTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]DeliveryTrace(nil), t.deliveries...)
+}
+
+// String returns multiline report, one delivery per line
+func (t *EventTrace) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event %d %s", t.ID, t.Topic)
+	for _, d := range t.Deliveries() {
+		fmt.Fprintf(&b, "\n  sub %d", d.SubID)
+		if d.Name != "" {
+			fmt.Fprintf(&b, " (%s)", d.Name)
+		}
+		b.WriteString(": " + d.Outcome.String())
+		if !d.Start.IsZero() {
+			fmt.Fprintf(&b, " in %s", d.End.Sub(d.Start))
+		}
+		if d.Err != nil {
+			fmt.Fprintf(&b, ": %v", d.Err)
+		}
+	}
+	return b.String()
+}
+
+// matched records subscriptions selected for the event
+func (t *EventTrace) matched(subs []*sub) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range subs {
+		t.deliveries = append(t.deliveries, DeliveryTrace{SubID: s.id, Name: s.name})
+	}
+}
+
+// update applies fn to the delivery of s, recording it if missing
+func (t *EventTrace) update(s *sub, fn func(d *DeliveryTrace)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.deliveries {
+		if t.deliveries[i].SubID == s.id {
+			fn(&t.deliveries[i])
+			return
+		}
+	}
+	t.deliveries = append(t.deliveries, DeliveryTrace{SubID: s.id, Name: s.name})
+	fn(&t.deliveries[len(t.deliveries)-1])
+}
+
+// skip records a delivery dropped before the handler call
+func (t *EventTrace) skip(s *sub, o DeliveryOutcome) {
+	if t == nil {
+		return
+	}
+	t.update(s, func(d *DeliveryTrace) {
+		d.Outcome = o
+	})
+}
+
+// called records a completed handler call
+func (t *EventTrace) called(s *sub, start, end time.Time, err error) {
+	if t == nil {
+		return
+	}
+	t.update(s, func(d *DeliveryTrace) {
+		d.Outcome = DeliveryHandled
+		if err != nil && !errors.Is(err, ErrUnsubscribe) && !errors.Is(err, ErrStopPropagation) {
+			d.Outcome = DeliveryFailed
+		}
+		d.Start, d.End, d.Err = start, end, err
+	})
+}
+
+// optionPublishTrace implements the trace option
+type optionPublishTrace struct {
+	v bool
+}
+
+// modifyEvent enables or disables the trace of the event
+func (o *optionPublishTrace) modifyEvent(ctx context.Context, e *event) {
+	if !o.v {
+		e.trace = nil
+		return
+	}
+	e.trace = &EventTrace{ID: e.id, Topic: e.topic}
+}
+
+// Trace creates a PublishOption recording which subscriptions matched the
+// event and the outcome, timing and error of every delivery. The trace
+// is returned by TraceFrom in OnFinish callbacks and handlers. Meant for
+// debugging: tracing slows the delivery down. Deliveries of durable
+// subscriptions fed by the Store are not traced.
+//
+// Example:
+//
+//	h.Publish(ctx, topic, payload, hub.Trace(true), hub.OnFinish(func(ctx context.Context) {
+//	    log.Println(hub.TraceFrom(ctx))
+//	}))
+func Trace(v bool) PublishOption {
+	return &optionPublishTrace{
+		v: v,
+	}
+}
+
+// TraceFrom returns the trace of the event published with Trace(true).
+// Returns nil for events without trace and outside of handlers and
+// OnFinish callbacks.
+func TraceFrom(ctx context.Context) *EventTrace {
+	if e := eventFromContext(ctx); e != nil {
+		return e.trace
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := New()
+
+	failErr := errors.New("fail")
+	okID, _ := h.Subscribe(ctx, T("type=order"), func(ctx context.Context) {}, Name("audit"))
+	failID, _ := h.Subscribe(ctx, T("type=order"), func(ctx context.Context) error {
+		return failErr
+	})
+	stopID, _ := h.Subscribe(ctx, T("type=order"), func(ctx context.Context) error {
+		return ErrStopPropagation
+	})
+	lastID, _ := h.Subscribe(ctx, T("type=order"), func(ctx context.Context) {})
+	_, _ = h.Subscribe(ctx, T("type=user"), func(ctx context.Context) {})
+
+	var trace *EventTrace
+	_ = h.Publish(ctx, T("type=order"), nil, Sync(true), Trace(true), OnFinish(func(ctx context.Context) {
+		trace = TraceFrom(ctx)
+	}))
+	if trace == nil {
+		t.Fatal("TraceFrom() = nil in OnFinish")
+	}
+	if trace.ID != h.LastEventID() || !trace.Topic.Equal(T("type=order")) {
+		t.Errorf("trace of event %d %s", trace.ID, trace.Topic)
+	}
+
+	want := map[SubID]DeliveryOutcome{
+		okID:   DeliveryHandled,
+		failID: DeliveryFailed,
+		stopID: DeliveryHandled,
+		lastID: DeliveryNotCalled,
+	}
+	got := trace.Deliveries()
+	if len(got) != len(want) {
+		t.Fatalf("deliveries = %+v, want %d", got, len(want))
+	}
+	for _, d := range got {
+		if d.Outcome != want[d.SubID] {
+			t.Errorf("sub %d outcome = %s, want %s", d.SubID, d.Outcome, want[d.SubID])
+		}
+		if called := !d.Start.IsZero(); called != (d.Outcome != DeliveryNotCalled) || d.End.Before(d.Start) {
+			t.Errorf("sub %d timing %v - %v", d.SubID, d.Start, d.End)
+		}
+		if d.SubID == failID && !errors.Is(d.Err, failErr) {
+			t.Errorf("sub %d error = %v", d.SubID, d.Err)
+		}
+	}
+	if s := trace.String(); !strings.Contains(s, "(audit): handled") || !strings.Contains(s, "failed in") {
+		t.Errorf("String() = %q", s)
+	}
+
+	// async deliveries and skipped ones
+	var traced []bool
+	_, _ = h.Subscribe(ctx, T("type=metric"), func(ctx context.Context) {
+		traced = append(traced, TraceFrom(ctx) != nil)
+	})
+	sampled, _ := h.Subscribe(ctx, T("type=metric"), func(ctx context.Context) {}, Sample(2))
+	_ = h.Publish(ctx, T("type=metric"), nil, Wait(true))
+	_ = h.Publish(ctx, T("type=metric"), nil, Wait(true), Trace(true), OnFinish(func(ctx context.Context) {
+		trace = TraceFrom(ctx)
+	}))
+	if len(traced) != 2 || traced[0] || !traced[1] {
+		t.Errorf("TraceFrom() in handler = %v, want [false true]", traced)
+	}
+	for _, d := range trace.Deliveries() {
+		if d.SubID == sampled && d.Outcome != DeliverySampledOut {
+			t.Errorf("sampled sub outcome = %s", d.Outcome)
+		} else if d.SubID != sampled && d.Outcome != DeliveryHandled {
+			t.Errorf("async sub outcome = %s", d.Outcome)
+		}
+	}
+
+	// untraced events
+	_ = h.Publish(ctx, T("type=order"), nil, Sync(true), OnFinish(func(ctx context.Context) {
+		if TraceFrom(ctx) != nil {
+			t.Error("TraceFrom() without Trace option")
+		}
+	}))
+}
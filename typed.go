@@ -0,0 +1,46 @@
+package hub
+
+import "context"
+
+// Typed is a view of a Hub for homogeneous event streams: payloads are
+// always of type T, so neither callers nor handlers deal with any
+// or casting. The topic index of the underlying Hub is shared, several
+// Typed views of different types can use one Hub with disjoint topics.
+//
+// Example:
+//
+//	orders := hub.NewTyped[Order](h)
+//	orders.Subscribe(ctx, hub.T("type=order"), func(ctx context.Context, o Order) error {
+//	    return ship(o)
+//	})
+//	orders.Publish(ctx, hub.T("type=order"), Order{ID: "42"})
+type Typed[T any] struct {
+	h *Hub
+}
+
+// NewTyped creates a typed view of the hub
+func NewTyped[T any](h *Hub) *Typed[T] {
+	return &Typed[T]{h: h}
+}
+
+// Hub returns the underlying hub
+func (tp *Typed[T]) Hub() *Hub {
+	return tp.h
+}
+
+// Publish sends v to subscribers of matching topics, see Hub.Publish
+func (tp *Typed[T]) Publish(ctx context.Context, t *Topic, v T, opts ...PublishOption) error {
+	return tp.h.Publish(ctx, t, v, opts...)
+}
+
+// Subscribe registers a handler of T payloads, see SubscribeT.
+// Events with payload of other type published directly to the Hub
+// produce CastError and don't invoke the handler.
+func (tp *Typed[T]) Subscribe(ctx context.Context, t *Topic, cb func(ctx context.Context, v T) error, opts ...SubscribeOption) (SubID, error) {
+	return SubscribeT(ctx, tp.h, t, cb, opts...)
+}
+
+// Unsubscribe removes a subscription by ID
+func (tp *Typed[T]) Unsubscribe(ctx context.Context, id SubID) {
+	tp.h.Unsubscribe(ctx, id)
+}
@@ -0,0 +1,50 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	type Order struct {
+		ID string
+	}
+
+	ctx := context.Background()
+	var castErrs int
+	h := New(OnError(func(ctx context.Context, _ *Topic, _ SubID, err error) {
+		var ce *CastError
+		if errors.As(err, &ce) {
+			castErrs++
+		}
+	}))
+	orders := NewTyped[Order](h)
+	if orders.Hub() != h {
+		t.Error("Hub() returned another hub")
+	}
+
+	var got []Order
+	id, err := orders.Subscribe(ctx, T("type=order"), func(ctx context.Context, o Order) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = orders.Publish(ctx, T("type=order"), Order{ID: "1"}, Sync(true))
+	_ = h.Publish(ctx, T("type=order"), "not an order", Sync(true))
+
+	if len(got) != 1 || got[0].ID != "1" {
+		t.Errorf("got %v, want [{1}]", got)
+	}
+	if castErrs != 1 {
+		t.Errorf("cast errors = %d, want 1", castErrs)
+	}
+
+	orders.Unsubscribe(ctx, id)
+	if h.Len() != 0 {
+		t.Errorf("Len() = %d after Unsubscribe", h.Len())
+	}
+}
@@ -0,0 +1,48 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+)
+
+// AttrVersion is the topic attribute with the payload format version
+// used by WithUpcaster
+const AttrVersion = "version"
+
+// Upcaster converts payloads of one version to the next one
+type Upcaster func(ctx context.Context, payload any) (any, error)
+
+// upcaster is a registered conversion step
+type upcaster struct {
+	to string
+	fn Upcaster
+}
+
+// upcast upgrades payload of the event topic version through registered
+// steps until there is no step for the current version. Returns the
+// topic with updated AttrVersion and the converted payload.
+func (h *Hub) upcast(ctx context.Context, t *Topic, payload any) (*Topic, any, error) {
+	version, ok := t.mp.Lookup(AttrVersion)
+	if !ok {
+		return t, payload, nil
+	}
+	from := version.Value()
+	v := from
+	// every step is taken at most once, so cycles end
+	for range len(h.upcasters) {
+		step, ok := h.upcasters[v]
+		if !ok {
+			break
+		}
+		var err error
+		payload, err = step.fn(ctx, payload)
+		if err != nil {
+			return t, payload, fmt.Errorf("hub: upcast %s from version %s to %s: %w", t, v, step.to, err)
+		}
+		v = step.to
+	}
+	if v == from {
+		return t, payload, nil
+	}
+	return &Topic{mp: t.mp.Set(AttrVersion, v)}, payload, nil
+}
@@ -0,0 +1,135 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type userV3 struct {
+	Name  string
+	Email string
+}
+
+func TestUpcaster(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var reported []error
+	h := New(
+		WithUpcaster("1", "2", func(ctx context.Context, p any) (any, error) {
+			s, ok := p.(string)
+			if !ok {
+				return nil, errors.New("want string")
+			}
+			return map[string]any{"Name": s}, nil
+		}),
+		WithUpcaster("2", "3", func(ctx context.Context, p any) (any, error) {
+			m := p.(map[string]any)
+			m["Email"] = strings.ToLower(m["Name"].(string)) + "@example.com"
+			return m, nil
+		}),
+		// cycle must not hang publishing
+		WithUpcaster("a", "b", func(ctx context.Context, p any) (any, error) { return p, nil }),
+		WithUpcaster("b", "a", func(ctx context.Context, p any) (any, error) { return p, nil }),
+		OnError(func(ctx context.Context, _ *Topic, _ SubID, err error) {
+			reported = append(reported, err)
+		}),
+	)
+
+	var got []userV3
+	var topics []*Topic
+	_, err := h.Subscribe(ctx, T("type=user", "version=3"), func(ctx context.Context, u userV3) {
+		got = append(got, u)
+		topics = append(topics, TopicFromContext(ctx))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = h.Subscribe(ctx, T("type=user", "version=1"), func(ctx context.Context) {
+		t.Error("handler of old version called")
+	})
+
+	_ = h.Publish(ctx, T("type=user", "version=1"), "Bob", Sync(true))
+	_ = h.Publish(ctx, T("type=user", "version=2"), map[string]any{"Name": "Ann"}, Sync(true))
+	_ = h.Publish(ctx, T("type=user", "version=3"), userV3{Name: "Eve", Email: "eve@mail"}, Sync(true))
+
+	want := []userV3{{"Bob", "bob@example.com"}, {"Ann", "ann@example.com"}, {"Eve", "eve@mail"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+		if topics[i].Get(AttrVersion) != "3" {
+			t.Errorf("topic = %s, want version 3", topics[i])
+		}
+	}
+
+	if err := h.Publish(ctx, T("type=user", "version=1"), 42, Sync(true)); err == nil || !strings.Contains(err.Error(), "want string") {
+		t.Errorf("Publish() error = %v, want upcast error", err)
+	}
+	if err := h.Publish(ctx, T("type=user", "version=a"), nil, Sync(true)); err != nil {
+		t.Errorf("Publish() with cyclic upcasters error = %v", err)
+	}
+	if err := h.Publish(ctx, T("type=user"), nil, Sync(true)); err != nil {
+		t.Errorf("Publish() without version error = %v", err)
+	}
+	if len(reported) != 0 {
+		t.Errorf("reported = %v", reported)
+	}
+}
+
+func TestUpcasterReplay(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newMemStore()
+
+	// events written by an old release
+	old := New(WithStore(store))
+	_ = old.Publish(ctx, T("type=user", "version=1"), "Bob")
+	_ = old.Publish(ctx, T("type=user", "version=1"), 42)
+	_ = old.Publish(ctx, T("type=user", "version=1"), "Ann")
+
+	errs := make(chan error, 1)
+	h := New(
+		WithStore(store),
+		WithUpcaster("1", "2", func(ctx context.Context, p any) (any, error) {
+			s, ok := p.(string)
+			if !ok {
+				return nil, errors.New("want string")
+			}
+			return userV3{Name: s}, nil
+		}),
+		OnError(func(ctx context.Context, _ *Topic, _ SubID, err error) {
+			errs <- err
+		}),
+	)
+	names := make(chan string, 3)
+	_, err := h.Subscribe(ctx, T("type=user", "version=2"), func(ctx context.Context, u userV3) {
+		names <- u.Name
+	}, Durable("users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"Bob", "Ann"} {
+		select {
+		case got := <-names:
+			if got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not replayed", want)
+		}
+	}
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "want string") {
+		t.Errorf("reported %v, want upcast error", err)
+	}
+	if n, _ := store.Committed("users"); n != 4 {
+		t.Errorf("Committed() = %d, want 4", n)
+	}
+}
@@ -0,0 +1,133 @@
+package hub
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/lomik/hub/pkg/kv"
+)
+
+// Watch returns a sequence of distinct values of topic key in events
+// published while the sequence is iterated, e.g. IDs of devices as they
+// appear. Every value is yielded once, in the order of first appearance.
+// Iteration blocks waiting for new values and ends when ctx is done or the
+// loop breaks. Values are collected by an Inline subscription to key=*,
+// so slow loop bodies don't delay publishers. The sequence yields nothing
+// if the subscription is rejected, e.g. by WithAuthorizer.
+//
+// Example:
+//
+//	for id := range h.Watch(ctx, "device") {
+//	    ui.AddDevice(id)
+//	}
+func (h *Hub) Watch(ctx context.Context, key string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		w := &watcher{
+			seen:   make(map[string]struct{}),
+			notify: make(chan struct{}, 1),
+		}
+		topic := &Topic{mp: kv.Map{}.Set(key, Any)}
+		id, err := h.Subscribe(ctx, topic, func(ctx context.Context) {
+			w.add(TopicFromContext(ctx).Get(key))
+		}, Inline(true))
+		if err != nil {
+			return
+		}
+		defer h.Unsubscribe(context.Background(), id)
+
+		var batch []string
+		for {
+			batch = w.take(batch[:0])
+			for _, v := range batch {
+				if !yield(v) {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.notify:
+			}
+		}
+	}
+}
+
+// watcher collects distinct values for Watch
+type watcher struct {
+	mu     sync.Mutex
+	seen   map[string]struct{}
+	queue  []string      // Values not yet yielded
+	notify chan struct{} // Signaled when queue becomes non-empty
+}
+
+// add queues v if it wasn't seen before
+func (w *watcher) add(v string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.seen[v]; ok {
+		return
+	}
+	w.seen[v] = struct{}{}
+	w.queue = append(w.queue, v)
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take moves queued values to dst
+func (w *watcher) take(dst []string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dst = append(dst, w.queue...)
+	w.queue = w.queue[:0]
+	return dst
+}
+
+// WaitForSubscriber blocks until a subscription receiving events with topic
+// t exists, so a producer starting before its consumers doesn't lose the
+// first events. Returns ctx.Err() if ctx is done first and ErrClosed if the
+// hub is closed.
+//
+// Example:
+//
+//	go startWorkers(h)
+//	if err := h.WaitForSubscriber(ctx, hub.T("type=job")); err != nil {
+//	    return err
+//	}
+//	h.Publish(ctx, hub.T("type=job"), job)
+func (h *Hub) WaitForSubscriber(ctx context.Context, t *Topic) error {
+	var buf [1]*sub
+	for {
+		h.Lock()
+		if h.closed.Load() {
+			h.Unlock()
+			return ErrClosed
+		}
+		if len(h.match(t, buf[:0])) > 0 {
+			h.Unlock()
+			return nil
+		}
+		if h.added == nil {
+			h.added = make(chan struct{})
+		}
+		added := h.added
+		h.Unlock()
+
+		select {
+		case <-added:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// notifyAdded wakes WaitForSubscriber calls after subscriptions are added
+// or the hub is closed. Must be called under h.Lock().
+func (h *Hub) notifyAdded() {
+	if h.added != nil {
+		close(h.added)
+		h.added = nil
+	}
+}
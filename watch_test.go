@@ -0,0 +1,114 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// publish once the watch subscription is made
+	go func() {
+		for h.Len() == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			runtime.Gosched()
+		}
+		for _, id := range []string{"a", "b", "a", "c", "b"} {
+			_ = h.Publish(ctx, T("type=status", "device="+id), nil)
+		}
+		_ = h.Publish(ctx, T("type=status"), nil)
+	}()
+
+	var got []string
+	for v := range h.Watch(ctx, "device") {
+		got = append(got, v)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("Watch() = %q, want [a b c]", got)
+	}
+	if h.Len() != 0 {
+		t.Errorf("Len() after break = %d, want 0", h.Len())
+	}
+
+	// canceled ctx ends the iteration
+	cancel()
+	for v := range h.Watch(ctx, "device") {
+		t.Errorf("Watch() with canceled ctx yielded %q", v)
+	}
+}
+
+func TestWaitForSubscriber(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.WaitForSubscriber(ctx, T("type=job", "queue=high"))
+	}()
+
+	// not matching subscription doesn't wake the producer
+	if _, err := h.Subscribe(ctx, T("type=user"), func(ctx context.Context) {}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("WaitForSubscriber() = %v before matching Subscribe", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if _, err := h.Subscribe(ctx, T("type=job"), func(ctx context.Context) {}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WaitForSubscriber() = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForSubscriber() not woken by Subscribe")
+	}
+
+	// existing subscription returns at once
+	if err := h.WaitForSubscriber(ctx, T("type=job")); err != nil {
+		t.Errorf("WaitForSubscriber() = %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := h.WaitForSubscriber(timeout, T("type=none")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForSubscriber() = %v, want DeadlineExceeded", err)
+	}
+
+	go func() {
+		done <- h.WaitForSubscriber(ctx, T("type=none"))
+	}()
+	for {
+		h.Lock()
+		waiting := h.added != nil
+		h.Unlock()
+		if waiting {
+			break
+		}
+		runtime.Gosched()
+	}
+	h.Close()
+	if err := <-done; !errors.Is(err, ErrClosed) {
+		t.Errorf("WaitForSubscriber() after Close = %v, want ErrClosed", err)
+	}
+}